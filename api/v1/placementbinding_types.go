@@ -20,6 +20,11 @@ type Subject struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinLength=1
 	Name string `json:"name"`
+	// When true, the name is a pattern, such as team-a-*, that selects every policy in the namespace of the
+	// PlacementBinding with a matching name, including the policies created later. The pattern uses the syntax of the
+	// Go path.Match function. This is only supported for the Policy kind.
+	// +optional
+	NameIsPattern bool `json:"nameIsPattern,omitempty"`
 }
 
 // PlacementSubject defines the resource that can be used as PlacementBinding placementRef
@@ -30,7 +35,7 @@ type PlacementSubject struct {
 	APIGroup string `json:"apiGroup"`
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinLength=1
-	// +kubebuilder:validation:Enum=PlacementRule;Placement
+	// +kubebuilder:validation:Enum=PlacementRule;Placement;ManagedClusterSet
 	Kind string `json:"kind"`
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinLength=1
@@ -38,7 +43,15 @@ type PlacementSubject struct {
 }
 
 // PlacementBindingStatus defines the observed state of PlacementBinding
-type PlacementBindingStatus struct{}
+type PlacementBindingStatus struct {
+	// Conditions represent the latest available observations of the PlacementBinding, such as whether its
+	// placementRef could be resolved
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// PlacementRefResolved is the condition type on a PlacementBinding that indicates if the resource referenced by its
+// placementRef exists
+const PlacementRefResolved string = "PlacementRefResolved"
 
 // BindingOverrides defines the overrides to the Subjects
 type BindingOverrides struct {
@@ -68,8 +81,14 @@ type PlacementBinding struct {
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Enum=restricted
 	SubFilter SubFilter `json:"subFilter,omitempty"`
-	// +kubebuilder:validation:Required
-	PlacementRef PlacementSubject `json:"placementRef"`
+	// Selects the managed clusters by their labels instead of with the placementRef, so that simple targeting doesn't
+	// require a PlacementRule or a Placement. An empty selector matches all the managed clusters. This is only used
+	// when the propagator is started with the --enable-binding-cluster-selector flag.
+	// +kubebuilder:validation:Optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// The resource that selects the managed clusters. Exactly one of placementRef and clusterSelector must be set.
+	// +kubebuilder:validation:Optional
+	PlacementRef PlacementSubject `json:"placementRef,omitempty"`
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinItems=1
 	Subjects []Subject              `json:"subjects"`
@@ -83,6 +83,77 @@ type PolicySpec struct {
 
 	// PolicyDependencies that apply to each template in this Policy
 	Dependencies []PolicyDependency `json:"dependencies,omitempty"`
+
+	// Limits the clusters the policy is replicated to, such as for a canary rollout. When not set, the policy is
+	// replicated to all the placed clusters.
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// Replicates the policy to the decision groups of the Placement one group at a time, in the order of the group
+	// index. The policy is replicated to the next group once it's Compliant on every cluster of the current group. The
+	// clusters placed by a PlacementRule or a ManagedClusterSet are in the first group. When not set, the policy is
+	// replicated to all the decision groups at once. This is not included in the replicated policies.
+	DecisionGroupRollout *DecisionGroupRollout `json:"decisionGroupRollout,omitempty"`
+
+	// Further limits the placed clusters the policy is replicated to by their labels. The policy is only replicated to
+	// the clusters that are both placed and matched by this selector. When not set, the policy is replicated to all the
+	// placed clusters. The selector is not included in the replicated policies.
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// Changes to the policy templates of the replicated policies on the managed clusters matching each cluster
+	// selector. The overrides are applied in order, so when multiple overrides match a cluster and change the same
+	// field, the last one takes precedence. The overrides are not included in the replicated policies.
+	ClusterOverrides []ClusterOverride `json:"clusterOverrides,omitempty"`
+}
+
+// ClusterOverride defines changes to the policy templates for the managed clusters matching the cluster selector
+type ClusterOverride struct {
+	// Selects the managed clusters by their labels. An empty selector matches all the managed clusters.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	// The changes to the policy templates
+	PolicyTemplates []PolicyTemplateOverride `json:"policyTemplates"`
+}
+
+// PolicyTemplateOverride defines a change to a policy template
+type PolicyTemplateOverride struct {
+	// The metadata.name of the objectDefinition of the policy template to change
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// A JSON merge patch (RFC 7386) applied to the objectDefinition of the policy template
+	Patch runtime.RawExtension `json:"patch"`
+}
+
+// RolloutStrategy defines how many of the placed clusters the policy is replicated to
+type RolloutStrategy struct {
+	// The maximum number of clusters the policy is replicated to. The clusters are chosen in order of the cluster
+	// name, except that clusters which already have the replicated policy always keep it, so raising or lowering
+	// this value never removes a replicated policy from a cluster that is still placed. If this is greater than or
+	// equal to the number of placed clusters, the policy is replicated to all of them.
+	// +kubebuilder:validation:Minimum=0
+	MaxClusters int32 `json:"maxClusters"`
+}
+
+// DecisionGroupRollout defines how the policy is rolled out to the decision groups of the Placement
+type DecisionGroupRollout struct {
+	// How long to wait for the policy to be Compliant on every cluster of a decision group. Once this passes, the
+	// policy is replicated to the next group even if it's not Compliant. When not set, a group on which the policy
+	// never becomes Compliant blocks the rollout.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DecisionGroupRolloutStatus defines the progress of the rollout to the decision groups of the Placement
+type DecisionGroupRolloutStatus struct {
+	// The index of the last decision group the policy is replicated to
+	CurrentGroup int32 `json:"currentGroup"`
+	// The generation of the root policy that is rolled out. A new generation is rolled out again from the first
+	// decision group.
+	Generation int64 `json:"generation,omitempty"`
+	// When the policy was first replicated to the current decision group
+	StartTime metav1.Time `json:"startTime"`
+	// Whether the policy is replicated to every decision group and is Compliant or timed out on each of them
+	Completed bool `json:"completed,omitempty"`
 }
 
 // PlacementDecision defines the decision made by controller
@@ -93,11 +164,12 @@ type PlacementDecision struct {
 
 // Placement defines the placement results
 type Placement struct {
-	PlacementBinding string                     `json:"placementBinding,omitempty"`
-	PlacementRule    string                     `json:"placementRule,omitempty"`
-	Placement        string                     `json:"placement,omitempty"`
-	Decisions        []appsv1.PlacementDecision `json:"decisions,omitempty"`
-	PolicySet        string                     `json:"policySet,omitempty"`
+	PlacementBinding  string                     `json:"placementBinding,omitempty"`
+	PlacementRule     string                     `json:"placementRule,omitempty"`
+	Placement         string                     `json:"placement,omitempty"`
+	ManagedClusterSet string                     `json:"managedClusterSet,omitempty"`
+	Decisions         []appsv1.PlacementDecision `json:"decisions,omitempty"`
+	PolicySet         string                     `json:"policySet,omitempty"`
 }
 
 // CompliancePerClusterStatus defines compliance per cluster status
@@ -130,6 +202,67 @@ type PolicyStatus struct {
 	// +kubebuilder:validation:Enum=Compliant;Pending;NonCompliant
 	ComplianceState ComplianceState       `json:"compliant,omitempty"` // used by replicated policy
 	Details         []*DetailsPerTemplate `json:"details,omitempty"`   // used by replicated policy
+
+	// The most recent changes to the compliance state of each cluster, newest first. This is only set when the
+	// compliance history is enabled on the propagator, and it's limited to the configured number of entries per
+	// cluster and in total.
+	ComplianceHistory []ComplianceTransition `json:"complianceHistory,omitempty"` // used by root policy
+
+	// The errors from the last attempt to replicate the policy to the placed clusters. Clusters that failed with the
+	// same error share an entry, and an entry is removed once its clusters are replicated to successfully.
+	PropagationErrors []PropagationError `json:"propagationErrors,omitempty"` // used by root policy
+
+	// The progress of the rollout to the decision groups of the Placement. This is only set when the
+	// decisionGroupRollout is set.
+	DecisionGroupRollout *DecisionGroupRolloutStatus `json:"decisionGroupRollout,omitempty"` // used by root policy
+
+	// How many of the clusters the policy is placed on are Compliant. This is not set when the policy isn't placed on
+	// any cluster.
+	ComplianceSummary *ComplianceSummary `json:"complianceSummary,omitempty"` // used by root policy
+
+	// The conditions reported by the agent on the managed cluster, such as the TeardownComplete condition once it has
+	// cleaned up the resources enforced by the policy after the deletion of the root policy was requested.
+	Conditions []metav1.Condition `json:"conditions,omitempty"` // used by replicated policy
+}
+
+// TeardownCompleteCondition is the type of the condition that the agent on a managed cluster sets to True on a
+// replicated policy once it has cleaned up the resources enforced by the policy after the propagator requested its
+// teardown.
+const TeardownCompleteCondition = "TeardownComplete"
+
+// ComplianceSummary defines how many of the clusters a root policy is placed on are Compliant
+type ComplianceSummary struct {
+	// The compliant and total counts in the format of compliant/total, such as 5/7
+	Summary string `json:"summary"`
+	// The number of clusters that are Compliant
+	Compliant int `json:"compliant"`
+	// The number of clusters the policy is placed on, including those that are Pending or have no compliance state
+	Total int `json:"total"`
+}
+
+// PropagationError defines an error replicating a policy to one or more clusters
+type PropagationError struct {
+	// A machine-readable reason for the error, such as Forbidden or NamespaceNotFound
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// The namespaces of the clusters that failed with the error. This is limited in length, so clusterCount is the
+	// total number of clusters.
+	ClusterNamespaces []string `json:"clusterNamespaces"`
+	ClusterCount      int      `json:"clusterCount"`
+}
+
+// ComplianceTransition defines a change to the compliance state of a policy on a cluster
+type ComplianceTransition struct {
+	ClusterName      string `json:"clusterName"`
+	ClusterNamespace string `json:"clusterNamespace"`
+	// The compliance state before the change. This is empty if the cluster had no compliance state.
+	PreviousComplianceState ComplianceState `json:"previousCompliant,omitempty"`
+	ComplianceState         ComplianceState `json:"compliant"`
+	// When the change was observed by the propagator
+	Timestamp metav1.Time `json:"timestamp"`
+	// The latest compliance message of the replicated policy on the cluster when the change was observed. This is
+	// truncated to keep the size of the root policy status bounded.
+	Message string `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -140,6 +273,7 @@ type PolicyStatus struct {
 // +kubebuilder:resource:path=policies,shortName=plc
 // +kubebuilder:printcolumn:name="Remediation action",type="string",JSONPath=".spec.remediationAction"
 // +kubebuilder:printcolumn:name="Compliance state",type="string",JSONPath=".status.compliant"
+// +kubebuilder:printcolumn:name="Compliant clusters",type="string",JSONPath=".status.complianceSummary.summary"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type Policy struct {
 	metav1.TypeMeta   `json:",inline"`
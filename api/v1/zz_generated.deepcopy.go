@@ -9,6 +9,7 @@
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	placementrulev1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
 )
@@ -28,6 +29,29 @@ func (in *BindingOverrides) DeepCopy() *BindingOverrides {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterOverride) DeepCopyInto(out *ClusterOverride) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	if in.PolicyTemplates != nil {
+		in, out := &in.PolicyTemplates, &out.PolicyTemplates
+		*out = make([]PolicyTemplateOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterOverride.
+func (in *ClusterOverride) DeepCopy() *ClusterOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceHistory) DeepCopyInto(out *ComplianceHistory) {
 	*out = *in
@@ -59,6 +83,73 @@ func (in *CompliancePerClusterStatus) DeepCopy() *CompliancePerClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceSummary) DeepCopyInto(out *ComplianceSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceSummary.
+func (in *ComplianceSummary) DeepCopy() *ComplianceSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceTransition) DeepCopyInto(out *ComplianceTransition) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceTransition.
+func (in *ComplianceTransition) DeepCopy() *ComplianceTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecisionGroupRollout) DeepCopyInto(out *DecisionGroupRollout) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecisionGroupRollout.
+func (in *DecisionGroupRollout) DeepCopy() *DecisionGroupRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(DecisionGroupRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecisionGroupRolloutStatus) DeepCopyInto(out *DecisionGroupRolloutStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecisionGroupRolloutStatus.
+func (in *DecisionGroupRolloutStatus) DeepCopy() *DecisionGroupRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DecisionGroupRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DetailsPerTemplate) DeepCopyInto(out *DetailsPerTemplate) {
 	*out = *in
@@ -108,13 +199,18 @@ func (in *PlacementBinding) DeepCopyInto(out *PlacementBinding) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.BindingOverrides = in.BindingOverrides
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	out.PlacementRef = in.PlacementRef
 	if in.Subjects != nil {
 		in, out := &in.Subjects, &out.Subjects
 		*out = make([]Subject, len(*in))
 		copy(*out, *in)
 	}
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementBinding.
@@ -170,6 +266,13 @@ func (in *PlacementBindingList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlacementBindingStatus) DeepCopyInto(out *PlacementBindingStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementBindingStatus.
@@ -311,6 +414,28 @@ func (in *PolicySpec) DeepCopyInto(out *PolicySpec) {
 		*out = make([]PolicyDependency, len(*in))
 		copy(*out, *in)
 	}
+	if in.RolloutStrategy != nil {
+		in, out := &in.RolloutStrategy, &out.RolloutStrategy
+		*out = new(RolloutStrategy)
+		**out = **in
+	}
+	if in.DecisionGroupRollout != nil {
+		in, out := &in.DecisionGroupRollout, &out.DecisionGroupRollout
+		*out = new(DecisionGroupRollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterOverrides != nil {
+		in, out := &in.ClusterOverrides, &out.ClusterOverrides
+		*out = make([]ClusterOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicySpec.
@@ -359,6 +484,37 @@ func (in *PolicyStatus) DeepCopyInto(out *PolicyStatus) {
 			}
 		}
 	}
+	if in.ComplianceHistory != nil {
+		in, out := &in.ComplianceHistory, &out.ComplianceHistory
+		*out = make([]ComplianceTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PropagationErrors != nil {
+		in, out := &in.PropagationErrors, &out.PropagationErrors
+		*out = make([]PropagationError, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DecisionGroupRollout != nil {
+		in, out := &in.DecisionGroupRollout, &out.DecisionGroupRollout
+		*out = new(DecisionGroupRolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ComplianceSummary != nil {
+		in, out := &in.ComplianceSummary, &out.ComplianceSummary
+		*out = new(ComplianceSummary)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyStatus.
@@ -392,6 +548,57 @@ func (in *PolicyTemplate) DeepCopy() *PolicyTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyTemplateOverride) DeepCopyInto(out *PolicyTemplateOverride) {
+	*out = *in
+	in.Patch.DeepCopyInto(&out.Patch)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyTemplateOverride.
+func (in *PolicyTemplateOverride) DeepCopy() *PolicyTemplateOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyTemplateOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PropagationError) DeepCopyInto(out *PropagationError) {
+	*out = *in
+	if in.ClusterNamespaces != nil {
+		in, out := &in.ClusterNamespaces, &out.ClusterNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropagationError.
+func (in *PropagationError) DeepCopy() *PropagationError {
+	if in == nil {
+		return nil
+	}
+	out := new(PropagationError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStrategy.
+func (in *RolloutStrategy) DeepCopy() *RolloutStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Subject) DeepCopyInto(out *Subject) {
 	*out = *in
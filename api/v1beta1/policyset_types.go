@@ -32,9 +32,10 @@ type PolicySetStatus struct {
 
 // PolicySetStatusPlacement defines a placement object for the status
 type PolicySetStatusPlacement struct {
-	PlacementBinding string `json:"placementBinding,omitempty"`
-	Placement        string `json:"placement,omitempty"`
-	PlacementRule    string `json:"placementRule,omitempty"`
+	PlacementBinding  string `json:"placementBinding,omitempty"`
+	Placement         string `json:"placement,omitempty"`
+	PlacementRule     string `json:"placementRule,omitempty"`
+	ManagedClusterSet string `json:"managedClusterSet,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,295 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package autobind
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	ControllerName string = "policy-auto-bind"
+	// AutoBindLabel is set on a root policy to the name of a Placement or PlacementRule in the same namespace. A
+	// PlacementBinding that binds the policy to it is then generated, unless the policy is already bound by another
+	// PlacementBinding.
+	AutoBindLabel string = common.APIGroup + "/auto-bind"
+	// placementNotFoundRequeue is how long to wait before checking again for a placement that doesn't exist yet.
+	placementNotFoundRequeue = 30 * time.Second
+)
+
+var log = ctrl.Log.WithName(ControllerName)
+
+//+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=policies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=placementbindings,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=apps.open-cluster-management.io,resources=placementrules,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.open-cluster-management.io,resources=placements,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AutoBindReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: int(r.MaxConcurrentReconciles)}).
+		Named(ControllerName).
+		For(&policiesv1.Policy{}).
+		// This covers both the generated PlacementBindings and the ones created manually for the same policy
+		Watches(
+			&source.Kind{Type: &policiesv1.PlacementBinding{}},
+			handler.EnqueueRequestsFromMapFunc(placementBindingMapper)).
+		Complete(r)
+}
+
+// placementBindingMapper enqueues the policies that are subjects of the PlacementBinding.
+func placementBindingMapper(object client.Object) []reconcile.Request {
+	//nolint:forcetypeassert
+	pb := object.(*policiesv1.PlacementBinding)
+
+	var result []reconcile.Request
+
+	for _, subject := range pb.Subjects {
+		if subject.APIGroup != policiesv1.SchemeGroupVersion.Group || subject.Kind != policiesv1.Kind {
+			continue
+		}
+
+		result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      subject.Name,
+			Namespace: pb.GetNamespace(),
+		}})
+	}
+
+	return result
+}
+
+// blank assignment to verify that AutoBindReconciler implements reconcile.Reconciler
+var _ reconcile.Reconciler = &AutoBindReconciler{}
+
+// AutoBindReconciler generates a PlacementBinding for each root policy with the AutoBindLabel, so that a policy can
+// be placed without writing a PlacementBinding for it.
+type AutoBindReconciler struct {
+	client.Client
+	MaxConcurrentReconciles uint
+	Recorder                record.EventRecorder
+	Scheme                  *runtime.Scheme
+}
+
+// PlacementBindingName returns the name of the PlacementBinding generated for the input root policy name.
+func PlacementBindingName(policyName string) string {
+	return policyName + "-auto-bind"
+}
+
+// Reconcile creates or updates the PlacementBinding generated for a root policy with the AutoBindLabel. The
+// PlacementBinding is owned by the root policy, so it's garbage collected when the root policy is deleted. It's
+// deleted when the label is removed or when the root policy is bound by a PlacementBinding that was created manually.
+func (r *AutoBindReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	log := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	log.V(1).Info("Reconciling the auto-bind PlacementBinding")
+
+	inClusterNs, err := common.IsInClusterNamespace(r.Client, request.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to determine if the policy is in a managed cluster namespace. Requeueing the request.")
+
+		return reconcile.Result{}, err
+	}
+
+	if inClusterNs {
+		log.V(2).Info("Ignoring the replicated policy")
+
+		return reconcile.Result{}, nil
+	}
+
+	rootPolicy := &policiesv1.Policy{}
+
+	err = r.Get(ctx, request.NamespacedName, rootPolicy)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			log.V(2).Info("The root policy was deleted. The owner reference takes care of the PlacementBinding.")
+
+			return reconcile.Result{}, nil
+		}
+
+		log.Error(err, "Failed to get the root policy")
+
+		return reconcile.Result{}, err
+	}
+
+	pbList := &policiesv1.PlacementBindingList{}
+
+	err = r.List(ctx, pbList, &client.ListOptions{Namespace: request.Namespace})
+	if err != nil {
+		log.Error(err, "Failed to list the PlacementBindings")
+
+		return reconcile.Result{}, err
+	}
+
+	var generated *policiesv1.PlacementBinding
+
+	manuallyBound := false
+
+	for i := range pbList.Items {
+		pb := &pbList.Items[i]
+
+		if pb.Name == PlacementBindingName(rootPolicy.Name) {
+			generated = pb
+
+			if metav1.IsControlledBy(pb, rootPolicy) {
+				continue
+			}
+		}
+
+		for _, subject := range pb.Subjects {
+			if common.SubjectMatchesPolicy(subject, rootPolicy.Name) {
+				manuallyBound = true
+			}
+		}
+	}
+
+	placementName := rootPolicy.GetLabels()[AutoBindLabel]
+
+	if placementName == "" || manuallyBound || rootPolicy.DeletionTimestamp != nil {
+		if generated == nil || !metav1.IsControlledBy(generated, rootPolicy) {
+			return reconcile.Result{}, nil
+		}
+
+		log.Info("Deleting the auto-bind PlacementBinding", "manuallyBound", manuallyBound)
+
+		err := r.Delete(ctx, generated)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete the auto-bind PlacementBinding")
+
+			return reconcile.Result{}, err
+		}
+
+		return reconcile.Result{}, nil
+	}
+
+	if generated != nil && !metav1.IsControlledBy(generated, rootPolicy) {
+		message := fmt.Sprintf(
+			"The PlacementBinding %s already exists and is not managed by this policy, so the policy is not "+
+				"automatically bound", generated.Name,
+		)
+
+		log.Info(message)
+		r.Recorder.Event(rootPolicy, "Warning", "AutoBindConflict", message)
+
+		return reconcile.Result{}, nil
+	}
+
+	placementRef, err := r.getPlacementRef(ctx, rootPolicy.Namespace, placementName)
+	if err != nil {
+		log.Error(err, "Failed to get the placement", "placementName", placementName)
+
+		return reconcile.Result{}, err
+	}
+
+	if placementRef == nil {
+		log.Info(
+			"The placement from the auto-bind label doesn't exist yet. Will requeue.", "placementName", placementName,
+		)
+
+		return reconcile.Result{RequeueAfter: placementNotFoundRequeue}, nil
+	}
+
+	subjects := []policiesv1.Subject{{
+		APIGroup: policiesv1.SchemeGroupVersion.Group,
+		Kind:     policiesv1.Kind,
+		Name:     rootPolicy.Name,
+	}}
+
+	if generated == nil {
+		pb := &policiesv1.PlacementBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      PlacementBindingName(rootPolicy.Name),
+				Namespace: rootPolicy.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(rootPolicy, policiesv1.GroupVersion.WithKind(policiesv1.Kind)),
+				},
+			},
+			PlacementRef: *placementRef,
+			Subjects:     subjects,
+		}
+
+		log.Info("Creating the auto-bind PlacementBinding", "placementName", placementName)
+
+		err = r.Create(ctx, pb)
+		if err != nil {
+			log.Error(err, "Failed to create the auto-bind PlacementBinding")
+
+			return reconcile.Result{}, err
+		}
+
+		return reconcile.Result{}, nil
+	}
+
+	if equality.Semantic.DeepEqual(generated.PlacementRef, *placementRef) &&
+		equality.Semantic.DeepEqual(generated.Subjects, subjects) {
+		log.V(2).Info("The auto-bind PlacementBinding is up to date. Doing nothing.")
+
+		return reconcile.Result{}, nil
+	}
+
+	log.Info("Updating the auto-bind PlacementBinding", "placementName", placementName)
+
+	generated.PlacementRef = *placementRef
+	generated.Subjects = subjects
+
+	err = r.Update(ctx, generated)
+	if err != nil {
+		log.Error(err, "Failed to update the auto-bind PlacementBinding")
+
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// getPlacementRef returns the placementRef for the Placement with the input name in the input namespace, or if there
+// isn't one, for the PlacementRule with that name. If neither exists, nil is returned.
+func (r *AutoBindReconciler) getPlacementRef(
+	ctx context.Context, namespace string, name string,
+) (*policiesv1.PlacementSubject, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+
+	if common.PlacementAPIAvailable() {
+		err := r.Get(ctx, key, &clusterv1beta1.Placement{})
+		if err == nil {
+			return &policiesv1.PlacementSubject{
+				APIGroup: clusterv1beta1.SchemeGroupVersion.Group, Kind: "Placement", Name: name,
+			}, nil
+		}
+
+		if !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	err := r.Get(ctx, key, &appsv1.PlacementRule{})
+	if err == nil {
+		return &policiesv1.PlacementSubject{
+			APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: name,
+		}, nil
+	}
+
+	if !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,160 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package autobind
+
+import (
+	"context"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func getReconciler(t *testing.T, objects ...client.Object) *AutoBindReconciler {
+	t.Helper()
+
+	scheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme, clusterv1beta1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatalf("failed to set up the scheme: %v", err)
+		}
+	}
+
+	return &AutoBindReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Recorder: record.NewFakeRecorder(10),
+		Scheme:   scheme,
+	}
+}
+
+func TestReconcileAutoBind(t *testing.T) {
+	policy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-policy",
+			Namespace: "policies",
+			UID:       "1234",
+			Labels:    map[string]string{AutoBindLabel: "my-placement"},
+		},
+	}
+
+	r := getReconciler(t, policy)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-policy"}}
+	pbKey := types.NamespacedName{Namespace: "policies", Name: PlacementBindingName("my-policy")}
+
+	// The placement doesn't exist yet
+	result, err := r.Reconcile(context.TODO(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.RequeueAfter != placementNotFoundRequeue {
+		t.Fatalf("expected a requeue after %v, got %v", placementNotFoundRequeue, result.RequeueAfter)
+	}
+
+	placement := &clusterv1beta1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "my-placement", Namespace: "policies"}}
+	if err := r.Create(context.TODO(), placement); err != nil {
+		t.Fatalf("failed to create the Placement: %v", err)
+	}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pb := &policiesv1.PlacementBinding{}
+	if err := r.Get(context.TODO(), pbKey, pb); err != nil {
+		t.Fatalf("expected the PlacementBinding to be generated: %v", err)
+	}
+
+	if pb.PlacementRef.Kind != "Placement" || pb.PlacementRef.Name != "my-placement" {
+		t.Fatalf("unexpected placementRef %v", pb.PlacementRef)
+	}
+
+	if !metav1.IsControlledBy(pb, policy) {
+		t.Fatal("expected the PlacementBinding to be owned by the policy")
+	}
+
+	// A manually created PlacementBinding for the same policy takes precedence
+	manual := &policiesv1.PlacementBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "policies"},
+		PlacementRef: policiesv1.PlacementSubject{
+			APIGroup: clusterv1beta1.SchemeGroupVersion.Group, Kind: "Placement", Name: "other-placement",
+		},
+		Subjects: []policiesv1.Subject{
+			{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "my-policy"},
+		},
+	}
+	if err := r.Create(context.TODO(), manual); err != nil {
+		t.Fatalf("failed to create the PlacementBinding: %v", err)
+	}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := r.Get(context.TODO(), pbKey, pb); !k8serrors.IsNotFound(err) {
+		t.Fatalf("expected the generated PlacementBinding to be deleted, got %v", err)
+	}
+
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: "policies", Name: "manual"}, manual); err != nil {
+		t.Fatalf("expected the manual PlacementBinding to be kept: %v", err)
+	}
+}
+
+func TestReconcileAutoBindConflict(t *testing.T) {
+	policy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-policy",
+			Namespace: "policies",
+			UID:       "1234",
+			Labels:    map[string]string{AutoBindLabel: "my-rule"},
+		},
+	}
+
+	// A PlacementBinding with the generated name that isn't owned by the policy
+	existing := &policiesv1.PlacementBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: PlacementBindingName("my-policy"), Namespace: "policies"},
+		PlacementRef: policiesv1.PlacementSubject{
+			APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "other-rule",
+		},
+		Subjects: []policiesv1.Subject{
+			{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "other-policy"},
+		},
+	}
+
+	rule := &appsv1.PlacementRule{ObjectMeta: metav1.ObjectMeta{Name: "my-rule", Namespace: "policies"}}
+
+	r := getReconciler(t, policy, existing, rule)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-policy"}}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pb := &policiesv1.PlacementBinding{}
+	if err := r.Get(context.TODO(), client.ObjectKeyFromObject(existing), pb); err != nil {
+		t.Fatalf("failed to get the PlacementBinding: %v", err)
+	}
+
+	if pb.PlacementRef.Name != "other-rule" || pb.Subjects[0].Name != "other-policy" {
+		t.Fatalf("expected the existing PlacementBinding to not be modified, got %v", pb)
+	}
+
+	//nolint:forcetypeassert
+	if events := r.Recorder.(*record.FakeRecorder).Events; len(events) != 1 {
+		t.Fatalf("expected a conflict event, got %d events", len(events))
+	}
+}
@@ -8,13 +8,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -26,35 +33,403 @@ const (
 	ClusterNameLabel      string = APIGroup + "/cluster-name"
 	ClusterNamespaceLabel string = APIGroup + "/cluster-namespace"
 	RootPolicyLabel       string = APIGroup + "/root-policy"
+	// ClusterNamespaceSignalLabel is the label on a namespace that marks it as the namespace of a managed cluster,
+	// including before the ManagedCluster is registered.
+	ClusterNamespaceSignalLabel string = "cluster.open-cluster-management.io/managedCluster"
+	// SourceHubLabel is set on the replicated policies to the identifier of the hub that created them when the
+	// propagator is configured with a hub identifier, such as when multiple regional hubs propagate policies to the
+	// same managed clusters.
+	SourceHubLabel string = APIGroup + "/source-hub"
+	// ReplicaNamespacePlaceholder is replaced by the cluster namespace in the replicated policy namespace template.
+	ReplicaNamespacePlaceholder string = "{cluster}"
 )
 
-var ErrInvalidLabelValue = errors.New("unexpected format of label value")
+var (
+	ErrInvalidLabelValue = errors.New("unexpected format of label value")
+	// ErrInvalidReplicaNamespace is returned when the namespace derived for a replicated policy is not a valid
+	// namespace name.
+	ErrInvalidReplicaNamespace = errors.New("the replicated policy namespace is invalid")
+	// ErrInvalidClusterSelector is returned when the clusterSelector of a PlacementBinding can't be converted to a label
+	// selector.
+	ErrInvalidClusterSelector = errors.New("the cluster selector is invalid")
+)
+
+// replicaNamespacePrefix and replicaNamespaceSuffix surround the cluster namespace to derive the namespace of the
+// replicated policies for a cluster. Both are empty by default so that the replicated policies are in the cluster
+// namespace.
+var replicaNamespacePrefix, replicaNamespaceSuffix string
+
+// hubID is the identifier of this hub that is set in the SourceHubLabel of the replicated policies. When it's empty,
+// the replicated policies aren't labeled and the label is ignored.
+var hubID string
+
+// clusterNamespaceLabelEnabled determines if the ClusterNamespaceSignalLabel label on a namespace is sufficient for it
+// to be considered a cluster namespace.
+var clusterNamespaceLabelEnabled bool
+
+// bindingClusterSelectorEnabled determines if the clusterSelector of a PlacementBinding is used to select the clusters
+// instead of its placementRef.
+var bindingClusterSelectorEnabled bool
+
+// placementAPIAvailable determines if the Placement API from the cluster.open-cluster-management.io API group is
+// installed. When it's not, PlacementBindings referencing a Placement don't resolve to any clusters.
+var placementAPIAvailable = true
+
+// managedClusterSetAPIAvailable determines if the v1beta2 ManagedClusterSet API from the
+// cluster.open-cluster-management.io API group is installed. When it's not, PlacementBindings referencing a
+// ManagedClusterSet don't resolve to any clusters.
+var managedClusterSetAPIAvailable = true
+
+// watchedRootNamespaces are the namespaces of the root policies that are handled. When it's empty, the root policies
+// in every namespace are handled.
+var watchedRootNamespaces map[string]bool
+
+// rootPolicyLabelKeys are the label keys, in priority order, checked for the root policy of a replicated policy.
+var rootPolicyLabelKeys = []string{RootPolicyLabel}
+
+// SetRootPolicyLabelKeys configures the label keys, in priority order, that are checked for the root policy of a
+// replicated policy. This allows replicated policies with an older or newer label key to be mapped to their root
+// policy during a migration. An empty input resets it to only the RootPolicyLabel. This must be called before the
+// controllers are started.
+func SetRootPolicyLabelKeys(keys []string) {
+	if len(keys) == 0 {
+		rootPolicyLabelKeys = []string{RootPolicyLabel}
+
+		return
+	}
+
+	rootPolicyLabelKeys = keys
+}
+
+// GetRootPolicyLabel returns the value of the first configured root policy label key that is set to a valid value. If
+// none of the label keys are set, an empty string is returned. If the label keys that are set all have invalid values,
+// the value of the first one is returned with an error.
+func GetRootPolicyLabel(obj client.Object) (string, error) {
+	var firstErr error
+	var firstInvalid string
+
+	for _, key := range rootPolicyLabelKeys {
+		value := obj.GetLabels()[key]
+		if value == "" {
+			continue
+		}
+
+		_, _, err := ParseRootPolicyLabel(value)
+		if err == nil {
+			return value, nil
+		}
+
+		if firstErr == nil {
+			firstErr = fmt.Errorf("invalid value set in %s: %w", key, err)
+			firstInvalid = value
+		}
+	}
+
+	return firstInvalid, firstErr
+}
+
+// SetHubID configures the identifier of this hub that is set in the SourceHubLabel of the replicated policies. The
+// replicated policies labeled with a different identifier are owned by another hub and are ignored. This must be
+// called before the controllers are started. An error is returned if the identifier isn't a valid label value.
+func SetHubID(id string) error {
+	if errs := validation.IsValidLabelValue(id); len(errs) > 0 {
+		return fmt.Errorf("the hub identifier %s is not a valid label value: %s", id, strings.Join(errs, ", "))
+	}
+
+	hubID = id
+
+	return nil
+}
+
+// HubID returns the configured identifier of this hub, or an empty string if none is configured.
+func HubID() string {
+	return hubID
+}
+
+// IsOwnedByOtherHub returns true if a hub identifier is configured and the input replicated policy is labeled as
+// created by a hub with a different identifier. A replicated policy without the SourceHubLabel, such as one created
+// before the hub identifier was configured, is owned by this hub.
+func IsOwnedByOtherHub(obj client.Object) bool {
+	if hubID == "" {
+		return false
+	}
+
+	owner := obj.GetLabels()[SourceHubLabel]
+
+	return owner != "" && owner != hubID
+}
+
+// SetClusterNamespaceLabelEnabled configures whether a namespace with the ClusterNamespaceSignalLabel label is
+// considered a cluster namespace before its ManagedCluster exists. This must be called before the controllers are
+// started.
+func SetClusterNamespaceLabelEnabled(enabled bool) {
+	clusterNamespaceLabelEnabled = enabled
+}
+
+// SetBindingClusterSelectorEnabled configures whether a PlacementBinding with a clusterSelector selects the
+// ManagedClusters matching it instead of the clusters of its placementRef. This must be called before the controllers
+// are started.
+func SetBindingClusterSelectorEnabled(enabled bool) {
+	bindingClusterSelectorEnabled = enabled
+}
+
+// UsesClusterSelector returns whether the input PlacementBinding selects the clusters with its clusterSelector rather
+// than its placementRef based on SetBindingClusterSelectorEnabled.
+func UsesClusterSelector(pb *policiesv1.PlacementBinding) bool {
+	return bindingClusterSelectorEnabled && pb.ClusterSelector != nil
+}
+
+// SetWatchedRootNamespaces configures the namespaces of the root policies that are handled, which allows several
+// propagators to share a hub. The replicated policies of these root policies are still handled in the cluster
+// namespaces. An empty input handles the root policies in every namespace. This must be called before the controllers
+// are started.
+func SetWatchedRootNamespaces(namespaces []string) {
+	if len(namespaces) == 0 {
+		watchedRootNamespaces = nil
+
+		return
+	}
+
+	watchedRootNamespaces = make(map[string]bool, len(namespaces))
+
+	for _, namespace := range namespaces {
+		watchedRootNamespaces[namespace] = true
+	}
+}
+
+// IsWatchedRootNamespace returns whether the root policies in the input namespace are handled based on
+// SetWatchedRootNamespaces.
+func IsWatchedRootNamespace(namespace string) bool {
+	return len(watchedRootNamespaces) == 0 || watchedRootNamespaces[namespace]
+}
+
+// IsWatchedPolicy returns whether the input policy is handled based on SetWatchedRootNamespaces. A policy with a valid
+// root policy label is handled when its root policy is, and any other policy is handled when its namespace is watched.
+func IsWatchedPolicy(policy client.Object) bool {
+	if len(watchedRootNamespaces) == 0 {
+		return true
+	}
+
+	rootPlcName, err := GetRootPolicyLabel(policy)
+	if err == nil && rootPlcName != "" {
+		_, rootNamespace, _ := ParseRootPolicyLabel(rootPlcName)
+
+		return watchedRootNamespaces[rootNamespace]
+	}
+
+	return watchedRootNamespaces[policy.GetNamespace()]
+}
+
+// DetectPlacementAPI determines if the Placement and PlacementDecision kinds are installed on the hub using the input
+// REST mapper. Hubs on older versions of Open Cluster Management may only have the PlacementRule kind, in which case a
+// single informational message is logged and resolving a Placement is skipped rather than failing the whole reconcile.
+// This must be called before the controllers are started.
+func DetectPlacementAPI(mapper meta.RESTMapper, log logr.Logger) error {
+	placementAPIAvailable = true
+
+	for _, kind := range []string{"Placement", "PlacementDecision"} {
+		_, err := mapper.RESTMapping(
+			schema.GroupKind{Group: clusterv1beta1.GroupName, Kind: kind}, clusterv1beta1.GroupVersion.Version,
+		)
+		if err == nil {
+			continue
+		}
+
+		if meta.IsNoMatchError(err) {
+			placementAPIAvailable = false
+
+			log.Info(
+				"The Placement API is not installed, so PlacementBindings referencing a Placement will be ignored",
+				"group", clusterv1beta1.GroupName, "missingKind", kind,
+			)
+
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// PlacementAPIAvailable returns whether the Placement API was found by DetectPlacementAPI.
+func PlacementAPIAvailable() bool {
+	return placementAPIAvailable
+}
+
+// DetectManagedClusterSetAPI determines if the v1beta2 ManagedClusterSet and ManagedClusterSetBinding kinds are
+// installed on the hub using the input REST mapper. When they're not, a single informational message is logged, and
+// the ManagedClusterSets aren't watched or resolved rather than failing the manager start and every reconcile. This
+// must be called before the controllers are started.
+func DetectManagedClusterSetAPI(mapper meta.RESTMapper, log logr.Logger) error {
+	managedClusterSetAPIAvailable = true
+
+	for _, kind := range []string{"ManagedClusterSet", "ManagedClusterSetBinding"} {
+		_, err := mapper.RESTMapping(
+			schema.GroupKind{Group: clusterv1beta2.GroupName, Kind: kind}, clusterv1beta2.GroupVersion.Version,
+		)
+		if err == nil {
+			continue
+		}
+
+		if meta.IsNoMatchError(err) {
+			managedClusterSetAPIAvailable = false
+
+			log.Info(
+				"The ManagedClusterSet API is not installed, so PlacementBindings referencing a ManagedClusterSet will "+
+					"be ignored",
+				"group", clusterv1beta2.GroupName, "version", clusterv1beta2.GroupVersion.Version, "missingKind", kind,
+			)
+
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// ManagedClusterSetAPIAvailable returns whether the ManagedClusterSet API was found by DetectManagedClusterSetAPI.
+func ManagedClusterSetAPIAvailable() bool {
+	return managedClusterSetAPIAvailable
+}
+
+// SetReplicaNamespaceTemplate configures the namespace of the replicated policies for each cluster, such as
+// "{cluster}-policies". The template must contain ReplicaNamespacePlaceholder exactly once, which is replaced by the
+// cluster namespace. An empty input resets it so that the replicated policies are in the cluster namespace. This must
+// be called before the controllers are started.
+func SetReplicaNamespaceTemplate(template string) error {
+	if template == "" {
+		template = ReplicaNamespacePlaceholder
+	}
+
+	if strings.Count(template, ReplicaNamespacePlaceholder) != 1 {
+		return fmt.Errorf(
+			"%w: the template %s must contain %s exactly once",
+			ErrInvalidReplicaNamespace, template, ReplicaNamespacePlaceholder,
+		)
+	}
+
+	prefix, suffix, _ := strings.Cut(template, ReplicaNamespacePlaceholder)
+
+	// Catch invalid characters in the prefix or suffix on startup rather than on every replication
+	if err := ValidateReplicaNamespace(prefix + "a" + suffix); err != nil {
+		return fmt.Errorf("the template %s is invalid: %w", template, err)
+	}
+
+	replicaNamespacePrefix = prefix
+	replicaNamespaceSuffix = suffix
+
+	return nil
+}
+
+// ReplicaNamespace returns the namespace of the replicated policies for the input cluster namespace based on
+// SetReplicaNamespaceTemplate. Use ValidateReplicaNamespace to verify that the returned namespace is valid.
+func ReplicaNamespace(clusterNamespace string) string {
+	return replicaNamespacePrefix + clusterNamespace + replicaNamespaceSuffix
+}
+
+// ClusterNamespaceForReplica returns the cluster namespace that the input replicated policy namespace was derived
+// from by ReplicaNamespace. If the namespace can't have been derived from a cluster namespace, ok is false.
+func ClusterNamespaceForReplica(namespace string) (clusterNamespace string, ok bool) {
+	if len(namespace) <= len(replicaNamespacePrefix)+len(replicaNamespaceSuffix) ||
+		!strings.HasPrefix(namespace, replicaNamespacePrefix) || !strings.HasSuffix(namespace, replicaNamespaceSuffix) {
+		return "", false
+	}
+
+	return namespace[len(replicaNamespacePrefix) : len(namespace)-len(replicaNamespaceSuffix)], true
+}
+
+// ValidateReplicaNamespace returns an error wrapping ErrInvalidReplicaNamespace if the input namespace is not a valid
+// RFC 1123 label, which is required of namespace names.
+func ValidateReplicaNamespace(namespace string) error {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) != 0 {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidReplicaNamespace, namespace, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
 
-// IsInClusterNamespace check if policy is in cluster namespace
+// IsInClusterNamespace check if policy is in cluster namespace. A namespace is a cluster namespace if a ManagedCluster
+// with the same name exists, which includes the local-cluster namespace on a self-managed hub. This is a single Get by
+// name, which is served from the informer cache when the input client is the manager's client, so it does not list
+// the ManagedClusters. If SetClusterNamespaceLabelEnabled was called with true, a namespace with the
+// ClusterNamespaceSignalLabel label set is also a cluster namespace, which allows policies to be staged in the
+// namespace of a cluster that is still being onboarded. If SetReplicaNamespaceTemplate was called, the input namespace
+// is a cluster namespace when it was derived from one, so that the replicated policies are found in their namespace.
 func IsInClusterNamespace(c client.Client, ns string) (bool, error) {
+	ns, ok := ClusterNamespaceForReplica(ns)
+	if !ok {
+		return false, nil
+	}
+
+	return isClusterNamespace(c, ns)
+}
+
+// isClusterNamespace returns true if the input namespace is the namespace of a ManagedCluster, or of a cluster being
+// onboarded when SetClusterNamespaceLabelEnabled was called with true, regardless of SetReplicaNamespaceTemplate.
+func isClusterNamespace(c client.Client, ns string) (bool, error) {
 	cluster := &clusterv1.ManagedCluster{}
 
 	err := c.Get(context.TODO(), types.NamespacedName{Name: ns}, cluster)
+	if err == nil {
+		return true, nil
+	}
+
+	if !k8serrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get the managed cluster %s: %w", ns, err)
+	}
+
+	if !clusterNamespaceLabelEnabled {
+		return false, nil
+	}
+
+	namespace := &corev1.Namespace{}
+
+	err = c.Get(context.TODO(), types.NamespacedName{Name: ns}, namespace)
 	if k8serrors.IsNotFound(err) {
 		return false, nil
 	}
 
 	if err != nil {
-		return false, fmt.Errorf("failed to get the managed cluster %s: %w", ns, err)
+		return false, fmt.Errorf("failed to get the namespace %s: %w", ns, err)
 	}
 
-	return true, nil
+	return namespace.GetLabels()[ClusterNamespaceSignalLabel] != "", nil
 }
 
-func IsReplicatedPolicy(c client.Client, policy client.Object) (bool, error) {
-	rootPlcName := policy.GetLabels()[RootPolicyLabel]
-	if rootPlcName == "" {
-		return false, nil
+// IsRootPolicy returns true if the input policy is a root policy, which is a policy that isn't in a cluster namespace
+// based on IsInClusterNamespace. When SetReplicaNamespaceTemplate was called, a policy with the root policy label in
+// the namespace of a cluster is also not a root policy, since it's a replicated policy left behind from before the
+// template was set, and it's deleted as an orphan by the reconcile of its root policy.
+func IsRootPolicy(c client.Client, policy client.Object) (bool, error) {
+	inClusterNs, err := IsInClusterNamespace(c, policy.GetNamespace())
+	if err != nil || inClusterNs {
+		return false, err
 	}
 
-	_, _, err := ParseRootPolicyLabel(rootPlcName)
+	if replicaNamespacePrefix == "" && replicaNamespaceSuffix == "" {
+		return true, nil
+	}
+
+	if rootPlcName, _ := GetRootPolicyLabel(policy); rootPlcName == "" {
+		return true, nil
+	}
+
+	leftoverReplica, err := isClusterNamespace(c, policy.GetNamespace())
+
+	return !leftoverReplica, err
+}
+
+func IsReplicatedPolicy(c client.Client, policy client.Object) (bool, error) {
+	rootPlcName, err := GetRootPolicyLabel(policy)
 	if err != nil {
-		return false, fmt.Errorf("invalid value set in %s: %w", RootPolicyLabel, err)
+		return false, err
+	}
+
+	if rootPlcName == "" {
+		return false, nil
 	}
 
 	return IsInClusterNamespace(c, policy.GetNamespace())
@@ -76,6 +451,32 @@ func IsPbForPoicy(pb *policiesv1.PlacementBinding) bool {
 	return found
 }
 
+// SubjectMatchesPolicy returns true if the input PlacementBinding subject selects the root policy with the input name
+// in the namespace of the PlacementBinding. The name of a subject with nameIsPattern set is matched as a path.Match
+// pattern, and an invalid pattern doesn't match any policy.
+func SubjectMatchesPolicy(subject policiesv1.Subject, policyName string) bool {
+	return SubjectMatchesObject(
+		subject, schema.GroupKind{Group: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind}, policyName,
+	)
+}
+
+// SubjectMatchesObject returns true if the input PlacementBinding subject selects the object with the input group,
+// kind, and name in the namespace of the PlacementBinding, such as an object of a kind propagated by an
+// ObjectReconciler. Pattern subjects are matched like by SubjectMatchesPolicy.
+func SubjectMatchesObject(subject policiesv1.Subject, groupKind schema.GroupKind, name string) bool {
+	if subject.APIGroup != groupKind.Group || subject.Kind != groupKind.Kind {
+		return false
+	}
+
+	if !subject.NameIsPattern {
+		return subject.Name == name
+	}
+
+	matched, err := path.Match(subject.Name, name)
+
+	return err == nil && matched
+}
+
 // IsPbForPoicySet compares group and kind with policyset group and kind for given pb
 func IsPbForPoicySet(pb *policiesv1.PlacementBinding) bool {
 	found := false
@@ -111,6 +512,13 @@ func GetClusterPlacementDecisions(
 	c client.Client, pb policiesv1.PlacementBinding, instance *policiesv1.Policy, log logr.Logger,
 ) ([]appsv1.PlacementDecision, error) {
 	log = log.WithValues("name", pb.PlacementRef.Name, "namespace", instance.GetNamespace())
+
+	if !placementAPIAvailable {
+		log.V(2).Info("The Placement API is not installed, so the Placement has no decisions")
+
+		return nil, nil
+	}
+
 	pl := &clusterv1beta1.Placement{}
 
 	err := c.Get(context.TODO(), types.NamespacedName{
@@ -154,6 +562,113 @@ func GetClusterPlacementDecisions(
 	return decisions, nil
 }
 
+// GetClusterSetPlacementDecisions return the placement decisions for all the clusters in a
+// ManagedClusterSet. The ManagedClusterSet must be bound to the namespace of the policy with a
+// ManagedClusterSetBinding, otherwise no decisions are returned.
+func GetClusterSetPlacementDecisions(
+	c client.Client, pb policiesv1.PlacementBinding, instance *policiesv1.Policy, log logr.Logger,
+) ([]appsv1.PlacementDecision, error) {
+	log = log.WithValues("name", pb.PlacementRef.Name, "namespace", instance.GetNamespace())
+
+	if !managedClusterSetAPIAvailable {
+		log.V(2).Info("The ManagedClusterSet API is not installed, so the ManagedClusterSet has no decisions")
+
+		return nil, nil
+	}
+
+	binding := &clusterv1beta2.ManagedClusterSetBinding{}
+
+	err := c.Get(context.TODO(), types.NamespacedName{
+		Namespace: instance.GetNamespace(),
+		Name:      pb.PlacementRef.Name,
+	}, binding)
+	if err != nil {
+		// no error when not found
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		log.Error(err, "Failed to get the ManagedClusterSetBinding")
+
+		return nil, err
+	}
+
+	clusterSet := &clusterv1beta2.ManagedClusterSet{}
+
+	err = c.Get(context.TODO(), types.NamespacedName{Name: binding.Spec.ClusterSet}, clusterSet)
+	if err != nil {
+		// no error when not found
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		log.Error(err, "Failed to get the ManagedClusterSet")
+
+		return nil, err
+	}
+
+	selector, err := clusterv1beta2.BuildClusterSelector(clusterSet)
+	if err != nil {
+		log.Error(err, "Failed to build the cluster selector of the ManagedClusterSet")
+
+		return nil, err
+	}
+
+	clusterList := &clusterv1.ManagedClusterList{}
+
+	err = c.List(context.TODO(), clusterList, &client.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Error(err, "Failed to list the ManagedClusters in the ManagedClusterSet")
+
+		return nil, err
+	}
+
+	decisions := make([]appsv1.PlacementDecision, 0, len(clusterList.Items))
+
+	for _, cluster := range clusterList.Items {
+		decisions = append(decisions, appsv1.PlacementDecision{
+			ClusterName:      cluster.GetName(),
+			ClusterNamespace: cluster.GetName(),
+		})
+	}
+
+	return decisions, nil
+}
+
+// GetClusterSelectorPlacementDecisions return the placement decisions for all the ManagedClusters matching the
+// clusterSelector of a PlacementBinding. An invalid selector is returned as an error wrapping
+// ErrInvalidClusterSelector.
+func GetClusterSelectorPlacementDecisions(
+	c client.Client, pb policiesv1.PlacementBinding, log logr.Logger,
+) ([]appsv1.PlacementDecision, error) {
+	selector, err := metav1.LabelSelectorAsSelector(pb.ClusterSelector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the clusterSelector of the placement binding %s/%s: %v",
+			ErrInvalidClusterSelector, pb.Namespace, pb.Name, err)
+	}
+
+	clusterList := &clusterv1.ManagedClusterList{}
+
+	err = c.List(context.TODO(), clusterList, &client.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Error(err, "Failed to list the ManagedClusters matching the cluster selector",
+			"name", pb.Name, "namespace", pb.Namespace)
+
+		return nil, err
+	}
+
+	decisions := make([]appsv1.PlacementDecision, 0, len(clusterList.Items))
+
+	for _, cluster := range clusterList.Items {
+		decisions = append(decisions, appsv1.PlacementDecision{
+			ClusterName:      cluster.GetName(),
+			ClusterNamespace: cluster.GetName(),
+		})
+	}
+
+	return decisions, nil
+}
+
 // GetApplicationPlacementDecisions return the placement decisions from an application
 // lifecycle placementrule
 func GetApplicationPlacementDecisions(
@@ -193,9 +708,26 @@ func GetNumWorkers(listLength int, concurrencyPerPolicy int) int {
 	return numWorkers
 }
 
+// ReplicatedPolicyName returns the name of the replicated policy of the root policy with the input namespace and
+// name. The replicated policy has this name in every cluster namespace it's replicated to.
+func ReplicatedPolicyName(rootNamespace, rootName string) string {
+	return rootNamespace + "." + rootName
+}
+
+// ParseReplicatedName returns the namespace and name of the root policy from the input replicated policy name. Since
+// namespaces follow RFC 1123 and can't have a `.` (but names can), the namespace is everything before the first `.`.
+// If the input is not in the `<namespace>.<name>` format, ok is false.
+func ParseReplicatedName(name string) (rootNamespace, rootName string, ok bool) {
+	rootNamespace, rootName, ok = strings.Cut(name, ".")
+	if !ok {
+		return "", "", false
+	}
+
+	return rootNamespace, rootName, true
+}
+
 func ParseRootPolicyLabel(rootPlc string) (name, namespace string, err error) {
-	// namespaces can't have a `.` (but names can) so this always correctly pulls the namespace out
-	namespace, name, found := strings.Cut(rootPlc, ".")
+	namespace, name, found := ParseReplicatedName(rootPlc)
 	if !found {
 		err = fmt.Errorf("required at least one `.` in value of label `%v`: %w",
 			RootPolicyLabel, ErrInvalidLabelValue)
@@ -214,7 +746,7 @@ func LabelsForRootPolicy(plc *policiesv1.Policy) map[string]string {
 // fullNameForPolicy returns the fully qualified name for given policy
 // full qualified name: ${namespace}.${name}
 func FullNameForPolicy(plc *policiesv1.Policy) string {
-	return plc.GetNamespace() + "." + plc.GetName()
+	return ReplicatedPolicyName(plc.GetNamespace(), plc.GetName())
 }
 
 // TypeConverter is a helper function to converter type struct a to b
@@ -1,6 +1,45 @@
 package common
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// noListClient fails every List call so that tests can verify that only keyed lookups are performed.
+type noListClient struct {
+	client.Client
+}
+
+func (c *noListClient) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
+	return errors.New("unexpected list call")
+}
+
+// erroringGetClient fails every Get call to simulate a transient API failure.
+type erroringGetClient struct {
+	client.Client
+}
+
+func (c *erroringGetClient) Get(_ context.Context, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	return errors.New("some get error")
+}
 
 func TestParseRootPolicyLabel(t *testing.T) {
 	tests := map[string]struct {
@@ -28,3 +67,610 @@ func TestParseRootPolicyLabel(t *testing.T) {
 		})
 	}
 }
+
+func TestSubjectMatchesPolicy(t *testing.T) {
+	policySubject := func(name string, pattern bool) policiesv1.Subject {
+		return policiesv1.Subject{
+			APIGroup:      policiesv1.SchemeGroupVersion.Group,
+			Kind:          policiesv1.Kind,
+			Name:          name,
+			NameIsPattern: pattern,
+		}
+	}
+
+	tests := map[string]struct {
+		subject  policiesv1.Subject
+		expected bool
+	}{
+		"exact name":                  {policySubject("team-a-certs", false), true},
+		"exact name is not a pattern": {policySubject("team-a-*", false), false},
+		"prefix pattern":              {policySubject("team-a-*", true), true},
+		"glob pattern":                {policySubject("team-?-cert?", true), true},
+		"pattern not matching":        {policySubject("team-b-*", true), false},
+		"invalid pattern":             {policySubject("team-[a", true), false},
+		"policy set kind": {
+			policiesv1.Subject{
+				APIGroup:      policiesv1.SchemeGroupVersion.Group,
+				Kind:          policiesv1.PolicySetKind,
+				Name:          "team-a-*",
+				NameIsPattern: true,
+			},
+			false,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if matched := SubjectMatchesPolicy(test.subject, "team-a-certs"); matched != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, matched)
+			}
+		})
+	}
+}
+
+func TestReplicatedPolicyNameRoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		rootNamespace string
+		rootName      string
+		expected      string
+	}{
+		"simple name":      {"policies", "my-policy", "policies.my-policy"},
+		"name with dots":   {"policies", "my.dotted.policy", "policies.my.dotted.policy"},
+		"single character": {"a", "b", "a.b"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			replicatedName := ReplicatedPolicyName(test.rootNamespace, test.rootName)
+			if replicatedName != test.expected {
+				t.Fatalf("expected the replicated policy name %s, got %s", test.expected, replicatedName)
+			}
+
+			rootNamespace, rootName, ok := ParseReplicatedName(replicatedName)
+			if !ok {
+				t.Fatalf("expected %s to be parsed", replicatedName)
+			}
+
+			if rootNamespace != test.rootNamespace || rootName != test.rootName {
+				t.Fatalf(
+					"expected the root policy %s/%s, got %s/%s",
+					test.rootNamespace, test.rootName, rootNamespace, rootName,
+				)
+			}
+		})
+	}
+}
+
+func TestParseReplicatedNameInvalid(t *testing.T) {
+	rootNamespace, rootName, ok := ParseReplicatedName("my-policy")
+	if ok || rootNamespace != "" || rootName != "" {
+		t.Fatalf("expected the name without a namespace prefix to be invalid, got %s/%s", rootNamespace, rootName)
+	}
+}
+
+func TestIsInClusterNamespace(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	err := clusterv1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	c := &noListClient{
+		fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+		).Build(),
+	}
+
+	tests := map[string]bool{
+		"managed1": true,
+		"managed2": false,
+		"policies": false,
+	}
+
+	for ns, expected := range tests {
+		t.Run(ns, func(t *testing.T) {
+			inClusterNs, err := IsInClusterNamespace(c, ns)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if inClusterNs != expected {
+				t.Fatalf("expected %v, got %v", expected, inClusterNs)
+			}
+		})
+	}
+}
+
+func TestPolicyMapperGetError(t *testing.T) {
+	c := &erroringGetClient{fake.NewClientBuilder().Build()}
+	mapper := PolicyMapper(c)
+
+	replicated := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "policies.my-policy",
+			Namespace: "managed1",
+			Labels:    map[string]string{RootPolicyLabel: "policies.my-policy"},
+		},
+	}
+
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-policy"}}}
+
+	requests := mapper(replicated)
+	if len(requests) != 1 || requests[0] != expected[0] {
+		t.Fatalf("expected the requests %v, got %v", expected, requests)
+	}
+
+	invalidLabel := replicated.DeepCopy()
+	invalidLabel.Labels[RootPolicyLabel] = "invalid"
+
+	if requests := mapper(invalidLabel); len(requests) != 0 {
+		t.Fatalf("expected no requests for an invalid label, got %v", requests)
+	}
+}
+
+func TestPolicyMapperLocalCluster(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	err := clusterv1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	// On a self-managed hub, the hub is also the managed cluster named local-cluster
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "local-cluster"}},
+	).Build()
+
+	inClusterNs, err := IsInClusterNamespace(c, "local-cluster")
+	if err != nil || !inClusterNs {
+		t.Fatalf("expected local-cluster to be a cluster namespace, got %v and %v", inClusterNs, err)
+	}
+
+	root := &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies"}}
+	replicated := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "policies.my-policy",
+			Namespace: "local-cluster",
+			Labels:    map[string]string{RootPolicyLabel: "policies.my-policy"},
+		},
+	}
+
+	expected := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-policy"}}
+
+	// Both the root policy and its replicated policy on the hub map to only the root policy, so the replicated
+	// policy is never reconciled as if it were a root policy
+	for _, policy := range []*policiesv1.Policy{root, replicated} {
+		requests := PolicyMapper(c)(policy)
+		if len(requests) != 1 || requests[0] != expected {
+			t.Fatalf("expected the requests %v for %s/%s, got %v", expected, policy.Namespace, policy.Name, requests)
+		}
+	}
+}
+
+func TestPolicyMapperSourceHub(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	err := clusterv1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := SetHubID("hub1"); err != nil {
+		t.Fatalf("failed to set the hub identifier: %v", err)
+	}
+
+	defer func() { _ = SetHubID("") }()
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	).Build()
+
+	tests := map[string]struct {
+		sourceHub        string
+		expectedRequests int
+	}{
+		"this hub":    {"hub1", 1},
+		"legacy":      {"", 1},
+		"another hub": {"hub2", 0},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			labels := map[string]string{RootPolicyLabel: "policies.my-policy"}
+			if test.sourceHub != "" {
+				labels[SourceHubLabel] = test.sourceHub
+			}
+
+			replicated := &policiesv1.Policy{
+				ObjectMeta: metav1.ObjectMeta{Name: "policies.my-policy", Namespace: "managed1", Labels: labels},
+			}
+
+			requests := PolicyMapper(c)(replicated)
+			if len(requests) != test.expectedRequests {
+				t.Fatalf("expected %d requests, got %v", test.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestPolicyMapperRootPolicyLabelKeys(t *testing.T) {
+	const newLabel = "example.com/root-policy"
+
+	SetRootPolicyLabelKeys([]string{newLabel, RootPolicyLabel})
+	defer SetRootPolicyLabelKeys(nil)
+
+	scheme := k8sruntime.NewScheme()
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	mapper := PolicyMapper(fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	).Build())
+
+	tests := map[string]struct {
+		labels   map[string]string
+		expected []reconcile.Request
+	}{
+		"only the old label": {
+			map[string]string{RootPolicyLabel: "policies.old"},
+			[]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "old"}}},
+		},
+		"only the new label": {
+			map[string]string{newLabel: "policies.new"},
+			[]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "new"}}},
+		},
+		"both labels uses the priority order": {
+			map[string]string{RootPolicyLabel: "policies.old", newLabel: "policies.new"},
+			[]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "new"}}},
+		},
+		"malformed new label falls back to the old label": {
+			map[string]string{RootPolicyLabel: "policies.old", newLabel: "malformed"},
+			[]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "old"}}},
+		},
+		"malformed old label uses the new label": {
+			map[string]string{RootPolicyLabel: "malformed", newLabel: "policies.new"},
+			[]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "new"}}},
+		},
+		"both labels malformed": {
+			map[string]string{RootPolicyLabel: "malformed", newLabel: "malformed"},
+			nil,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			replicated := &policiesv1.Policy{
+				ObjectMeta: metav1.ObjectMeta{Name: "replicated", Namespace: "managed1", Labels: test.labels},
+			}
+
+			requests := mapper(replicated)
+			if len(requests) != len(test.expected) {
+				t.Fatalf("expected the requests %v, got %v", test.expected, requests)
+			}
+
+			for i := range requests {
+				if requests[i] != test.expected[i] {
+					t.Fatalf("expected the requests %v, got %v", test.expected, requests)
+				}
+			}
+		})
+	}
+}
+
+func TestIsInClusterNamespaceLabel(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "onboarding1",
+			Labels: map[string]string{ClusterNamespaceSignalLabel: "onboarding1"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "policies"}},
+	).Build()
+
+	tests := map[string]struct {
+		labelEnabled bool
+		namespace    string
+		expected     bool
+	}{
+		"ManagedCluster with the label disabled":    {false, "managed1", true},
+		"labeled namespace with the label disabled": {false, "onboarding1", false},
+		"ManagedCluster with the label enabled":     {true, "managed1", true},
+		"labeled namespace with the label enabled":  {true, "onboarding1", true},
+		"unlabeled namespace":                       {true, "policies", false},
+		"missing namespace":                         {true, "onboarding2", false},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			SetClusterNamespaceLabelEnabled(test.labelEnabled)
+			defer SetClusterNamespaceLabelEnabled(false)
+
+			inClusterNs, err := IsInClusterNamespace(c, test.namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if inClusterNs != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, inClusterNs)
+			}
+		})
+	}
+}
+
+func TestPolicyMapperDeletedClusterNamespace(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	mapper := PolicyMapper(fake.NewClientBuilder().WithScheme(scheme).Build())
+
+	// The replicated policy is no longer in a cluster namespace, such as after the namespace was deleted
+	replicated := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "policies.my-policy",
+			Namespace: "onboarding1",
+			Labels:    map[string]string{RootPolicyLabel: "policies.my-policy"},
+		},
+	}
+
+	requests := mapper(replicated)
+
+	expectedRoot := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-policy"}}
+	if len(requests) != 2 || requests[1] != expectedRoot {
+		t.Fatalf("expected the root policy to be queued, got %v", requests)
+	}
+}
+
+func TestDetectPlacementAPI(t *testing.T) {
+	defer func() { placementAPIAvailable = true }()
+
+	// Only the legacy PlacementRule kind is installed
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("PlacementRule"), meta.RESTScopeNamespace)
+
+	if err := DetectPlacementAPI(mapper, logr.Discard()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if PlacementAPIAvailable() {
+		t.Fatal("expected the Placement API to be detected as unavailable")
+	}
+
+	// The scheme also lacks the Placement kind, so any request for it would fail
+	scheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	pb := policiesv1.PlacementBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-pb", Namespace: "policies"},
+		PlacementRef: policiesv1.PlacementSubject{
+			APIGroup: clusterv1beta1.GroupName, Kind: "Placement", Name: "my-placement",
+		},
+	}
+	policy := &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies"}}
+
+	decisions, err := GetClusterPlacementDecisions(c, pb, policy, logr.Discard())
+	if err != nil {
+		t.Fatalf("expected no error when the Placement API is not installed, got: %v", err)
+	}
+
+	if len(decisions) != 0 {
+		t.Fatalf("expected no decisions, got %v", decisions)
+	}
+
+	// Once both kinds are installed, the Placement API is detected as available
+	mapper.Add(clusterv1beta1.GroupVersion.WithKind("Placement"), meta.RESTScopeNamespace)
+	mapper.Add(clusterv1beta1.GroupVersion.WithKind("PlacementDecision"), meta.RESTScopeNamespace)
+
+	if err := DetectPlacementAPI(mapper, logr.Discard()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !PlacementAPIAvailable() {
+		t.Fatal("expected the Placement API to be detected as available")
+	}
+}
+
+func TestDetectManagedClusterSetAPI(t *testing.T) {
+	defer func() { managedClusterSetAPIAvailable = true }()
+
+	// Only the v1beta1 ManagedClusterSet kind is installed, such as on an older hub
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(clusterv1beta1.GroupVersion.WithKind("ManagedClusterSet"), meta.RESTScopeRoot)
+
+	if err := DetectManagedClusterSetAPI(mapper, logr.Discard()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ManagedClusterSetAPIAvailable() {
+		t.Fatal("expected the ManagedClusterSet API to be detected as unavailable")
+	}
+
+	// The scheme also lacks the v1beta2 kinds, so any request for them would fail
+	scheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	pb := policiesv1.PlacementBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-pb", Namespace: "policies"},
+		PlacementRef: policiesv1.PlacementSubject{
+			APIGroup: clusterv1beta1.GroupName, Kind: "ManagedClusterSet", Name: "my-set",
+		},
+	}
+	policy := &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies"}}
+
+	decisions, err := GetClusterSetPlacementDecisions(c, pb, policy, logr.Discard())
+	if err != nil {
+		t.Fatalf("expected no error when the ManagedClusterSet API is not installed, got: %v", err)
+	}
+
+	if len(decisions) != 0 {
+		t.Fatalf("expected no decisions, got %v", decisions)
+	}
+
+	// Once both kinds are installed, the ManagedClusterSet API is detected as available
+	mapper.Add(clusterv1beta2.GroupVersion.WithKind("ManagedClusterSet"), meta.RESTScopeRoot)
+	mapper.Add(clusterv1beta2.GroupVersion.WithKind("ManagedClusterSetBinding"), meta.RESTScopeNamespace)
+
+	if err := DetectManagedClusterSetAPI(mapper, logr.Discard()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !ManagedClusterSetAPIAvailable() {
+		t.Fatal("expected the ManagedClusterSet API to be detected as available")
+	}
+}
+
+func TestIsWatchedPolicy(t *testing.T) {
+	policy := func(namespace string, labels map[string]string) *policiesv1.Policy {
+		return &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: namespace, Labels: labels}}
+	}
+
+	tests := map[string]struct {
+		policy   *policiesv1.Policy
+		expected bool
+	}{
+		"root policy in a watched namespace":  {policy("team-a", nil), true},
+		"root policy in another namespace":    {policy("team-b", nil), false},
+		"replicated policy of a watched root": {policy("managed1", map[string]string{RootPolicyLabel: "team-a.p"}), true},
+		"replicated policy of another root":   {policy("managed1", map[string]string{RootPolicyLabel: "team-b.p"}), false},
+		"invalid root label in a watched ns":  {policy("team-a", map[string]string{RootPolicyLabel: "invalid"}), true},
+		"invalid root label in another ns":    {policy("managed1", map[string]string{RootPolicyLabel: "invalid"}), false},
+		"other root label in a watched ns":    {policy("team-a", map[string]string{RootPolicyLabel: "team-b.p"}), false},
+	}
+
+	SetWatchedRootNamespaces([]string{"team-a"})
+	defer SetWatchedRootNamespaces(nil)
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if watched := IsWatchedPolicy(test.policy); watched != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, watched)
+			}
+		})
+	}
+
+	if IsWatchedRootNamespace("team-b") || !IsWatchedRootNamespace("team-a") {
+		t.Fatal("expected only the team-a namespace to be watched")
+	}
+
+	SetWatchedRootNamespaces(nil)
+
+	if !IsWatchedPolicy(policy("team-b", nil)) || !IsWatchedRootNamespace("team-b") {
+		t.Fatal("expected every namespace to be watched when no namespaces are set")
+	}
+}
+
+func TestReplicaNamespaceTemplate(t *testing.T) {
+	for _, template := range []string{"policies", "{cluster}-{cluster}", "Policies-{cluster}", "{cluster}_policies"} {
+		if err := SetReplicaNamespaceTemplate(template); !errors.Is(err, ErrInvalidReplicaNamespace) {
+			t.Fatalf("expected the template %s to be invalid, got %v", template, err)
+		}
+	}
+
+	if err := SetReplicaNamespaceTemplate("policies-{cluster}"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defer func() { _ = SetReplicaNamespaceTemplate("") }()
+
+	if namespace := ReplicaNamespace("managed1"); namespace != "policies-managed1" {
+		t.Fatalf("expected policies-managed1, got %s", namespace)
+	}
+
+	scheme := k8sruntime.NewScheme()
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	).Build()
+
+	tests := map[string]bool{
+		"policies-managed1": true,
+		"managed1":          false,
+		"policies-managed2": false,
+		"policies-":         false,
+	}
+
+	for namespace, expected := range tests {
+		inClusterNs, err := IsInClusterNamespace(c, namespace)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if inClusterNs != expected {
+			t.Fatalf("expected %v for the namespace %s, got %v", expected, namespace, inClusterNs)
+		}
+	}
+
+	// A replicated policy left in the cluster namespace from before the template was set isn't a root policy
+	rootTests := map[string]struct {
+		namespace string
+		labels    map[string]string
+		expected  bool
+	}{
+		"root policy":                     {"policies", nil, true},
+		"root policy in the cluster ns":   {"managed1", nil, true},
+		"replicated policy":               {"policies-managed1", map[string]string{RootPolicyLabel: "policies.p"}, false},
+		"leftover replicated policy":      {"managed1", map[string]string{RootPolicyLabel: "policies.p"}, false},
+		"labeled policy outside clusters": {"policies", map[string]string{RootPolicyLabel: "policies.p"}, true},
+	}
+
+	for name, test := range rootTests {
+		policy := &policiesv1.Policy{
+			ObjectMeta: metav1.ObjectMeta{Name: "policies.p", Namespace: test.namespace, Labels: test.labels},
+		}
+
+		isRoot, err := IsRootPolicy(c, policy)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if isRoot != test.expected {
+			t.Fatalf("expected %v for the %s, got %v", test.expected, name, isRoot)
+		}
+	}
+
+	err := ValidateReplicaNamespace(ReplicaNamespace(strings.Repeat("a", 60)))
+	if !errors.Is(err, ErrInvalidReplicaNamespace) {
+		t.Fatalf("expected a namespace over 63 characters to be invalid, got %v", err)
+	}
+}
@@ -47,3 +47,6 @@ func (e *EnqueueRequestsFromMapFunc) mapAndEnqueue(q workqueue.RateLimitingInter
 }
 
 var NeverEnqueue = predicate.NewPredicateFuncs(func(o client.Object) bool { return false })
+
+// WatchedPolicyPredicate filters out the policies that are not handled based on SetWatchedRootNamespaces.
+var WatchedPolicyPredicate = predicate.NewPredicateFuncs(IsWatchedPolicy)
@@ -0,0 +1,70 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// OpenMetricsPath is the path on the metrics server that serves the metrics in the OpenMetrics format with their
+// exemplars to the scrapers that negotiate it. The built-in /metrics endpoint of the manager never serves exemplars.
+const OpenMetricsPath = "/metrics/openmetrics"
+
+// reconcileDuration is the wall-clock time of the reconciles of the governance controllers by outcome. Unlike the
+// controller-runtime reconcile metrics, this is specific to the policy controllers so that alerts on slow or failing
+// propagation aren't affected by other controllers in the same process.
+var reconcileDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "policy_reconcile_duration_seconds",
+		Help: "The time a policy controller reconcile takes to complete by controller and outcome",
+	},
+	[]string{"controller", "outcome"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileDuration)
+}
+
+// ObserveReconcile records the time since the input start time of a reconcile by the input controller. The outcome is
+// "error" if the input error is not nil and "success" otherwise.
+func ObserveReconcile(controllerName string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+
+	reconcileDuration.WithLabelValues(controllerName, outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObserveWithTraceExemplar records the input value on the input observer. When the span in the input context is
+// sampled, which is only the case when tracing is enabled, the value is recorded with an exemplar carrying the trace ID
+// so that a slow observation links to its trace.
+func ObserveWithTraceExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+	spanContext := trace.SpanContextFromContext(ctx)
+
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !ok || !spanContext.IsSampled() {
+		observer.Observe(value)
+
+		return
+	}
+
+	exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{"trace_id": spanContext.TraceID().String()})
+}
+
+// OpenMetricsHandler returns an HTTP handler that serves the metrics of the controller-runtime registry like the
+// built-in /metrics endpoint, except that the OpenMetrics format, which includes the exemplars, is served to the
+// scrapers that request it. The other scrapers get the text format without the exemplars.
+func OpenMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.HTTPErrorOnError,
+		EnableOpenMetrics: true,
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func TestObserveReconcile(t *testing.T) {
+	defer reconcileDuration.Reset()
+
+	start := time.Now()
+
+	ObserveReconcile("policy-propagator", start, nil)
+	ObserveReconcile("policy-propagator", start, nil)
+	ObserveReconcile("policy-propagator", start, errors.New("some error"))
+	ObserveReconcile("policy-metrics", start, nil)
+
+	if count := testutil.CollectAndCount(reconcileDuration); count != 3 {
+		t.Fatalf("expected 3 series, got %d", count)
+	}
+
+	for _, labels := range [][]string{
+		{"policy-propagator", "success"},
+		{"policy-propagator", "error"},
+		{"policy-metrics", "success"},
+	} {
+		if !reconcileDuration.DeleteLabelValues(labels...) {
+			t.Fatalf("expected a series with the labels %v", labels)
+		}
+	}
+}
+
+func TestObserveWithTraceExemplar(t *testing.T) {
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_exemplar_duration_seconds",
+		Help:    "A test histogram",
+		Buckets: []float64{1, 10},
+	})
+
+	metrics.Registry.MustRegister(histogram)
+	defer metrics.Registry.Unregister(histogram)
+
+	traceID := trace.TraceID{
+		0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36,
+	}
+	sampledCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	// Without tracing, there is no sampled span, so the fast observation has no exemplar
+	ObserveWithTraceExemplar(context.Background(), histogram, 0.5)
+	// The slow observation is linked to its trace
+	ObserveWithTraceExemplar(sampledCtx, histogram, 5)
+
+	if count := testutil.CollectAndCount(histogram); count != 1 {
+		t.Fatalf("expected 1 series, got %d", count)
+	}
+
+	scrape := func(accept string) string {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, OpenMetricsPath, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		recorder := httptest.NewRecorder()
+		OpenMetricsHandler().ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, recorder.Code)
+		}
+
+		body, err := io.ReadAll(recorder.Body)
+		if err != nil {
+			t.Fatalf("failed to read the response: %v", err)
+		}
+
+		return string(body)
+	}
+
+	openMetrics := scrape("application/openmetrics-text; version=1.0.0")
+
+	var slowBucket, fastBucket string
+
+	for _, line := range strings.Split(openMetrics, "\n") {
+		switch {
+		case strings.HasPrefix(line, `test_exemplar_duration_seconds_bucket{le="10.0"}`):
+			slowBucket = line
+		case strings.HasPrefix(line, `test_exemplar_duration_seconds_bucket{le="1.0"}`):
+			fastBucket = line
+		}
+	}
+
+	if !strings.Contains(slowBucket, `# {trace_id="`+traceID.String()+`"} 5`) {
+		t.Fatalf("expected the slow bucket to have an exemplar with the trace ID, got %q", slowBucket)
+	}
+
+	if fastBucket == "" || strings.Contains(fastBucket, "#") {
+		t.Fatalf("expected the fast bucket to have no exemplar, got %q", fastBucket)
+	}
+
+	// Scrapers that don't negotiate OpenMetrics get the text format without the exemplars
+	text := scrape("")
+
+	if !strings.Contains(text, `test_exemplar_duration_seconds_bucket{le="10"} 2`) || strings.Contains(text, "trace_id") {
+		t.Fatalf("expected the text format without exemplars, got:\n%s", text)
+	}
+}
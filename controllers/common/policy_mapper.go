@@ -21,19 +21,44 @@ func PolicyMapper(c client.Client) handler.MapFunc {
 
 		isReplicated, err := IsReplicatedPolicy(c, object)
 		if err != nil {
-			log.Error(err, "Failed to determine if this queued policy is a replicated policy")
+			rootPlcName, labelErr := GetRootPolicyLabel(object)
 
-			return nil
+			name, namespace, parseErr := ParseRootPolicyLabel(rootPlcName)
+			if labelErr != nil || parseErr != nil {
+				log.Error(err, "Failed to determine if this queued policy is a replicated policy")
+
+				return nil
+			}
+
+			// The lookup of the ManagedCluster failed, which is likely transient. Rather than dropping the event,
+			// assume the policy is replicated based on its label. The reconciler of the root policy performs its own
+			// lookups, so a persistent API failure results in the request being retried with a backoff.
+			log.Error(
+				err,
+				"Failed to determine if this queued policy is a replicated policy. Queuing the root policy from the "+
+					"label.",
+				"value", rootPlcName,
+			)
+
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
 		}
 
 		var name string
 		var namespace string
 
 		if isReplicated {
+			if IsOwnedByOtherHub(object) {
+				log.V(2).Info(
+					"Ignoring the replicated policy created by another hub", "hub", object.GetLabels()[SourceHubLabel],
+				)
+
+				return nil
+			}
+
 			log.V(2).Info("Found reconciliation request from replicated policy")
 
-			rootPlcName := object.GetLabels()[RootPolicyLabel]
 			// Skip error checking since IsReplicatedPolicy verified this already
+			rootPlcName, _ := GetRootPolicyLabel(object)
 			name, namespace, _ = ParseRootPolicyLabel(rootPlcName)
 		} else {
 			log.V(2).Info("Found reconciliation request from root policy")
@@ -47,6 +72,20 @@ func PolicyMapper(c client.Client) handler.MapFunc {
 			Namespace: namespace,
 		}}
 
+		// A policy with a valid root policy label that is not in a cluster namespace may be a replicated policy whose
+		// cluster namespace was just deleted, such as when a cluster being onboarded is removed before its
+		// ManagedCluster registers, so also queue the root policy so that its status is updated.
+		if !isReplicated {
+			rootPlcName, _ := GetRootPolicyLabel(object)
+			if rootPlcName != "" {
+				rootName, rootNamespace, _ := ParseRootPolicyLabel(rootPlcName)
+
+				return []reconcile.Request{
+					request, {NamespacedName: types.NamespacedName{Name: rootName, Namespace: rootNamespace}},
+				}
+			}
+		}
+
 		return []reconcile.Request{request}
 	}
 }
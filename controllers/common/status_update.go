@@ -0,0 +1,82 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// DefaultStatusFieldManager is the field manager of the policy status writes of the propagator when none is
+// configured, so that they can be told apart from the writes of the agents in the managed fields.
+const DefaultStatusFieldManager = "governance-policy-propagator-status"
+
+// StatusUpdateOptions configures how the propagator writes the status of the policies.
+type StatusUpdateOptions struct {
+	// FieldManager is the field manager of the status writes. If it's empty, DefaultStatusFieldManager is used.
+	FieldManager string
+	// ConflictRetries is how many times a status write that conflicts with another write is retried on the latest
+	// version of the policy. If it's zero, a conflict is returned right away.
+	ConflictRetries int
+	// ConflictBackoff is how long to wait before the first retry. It doubles with each retry.
+	ConflictBackoff time.Duration
+}
+
+// StatusFieldOwner returns the option to set the field manager of a status write to the input field manager, or to
+// DefaultStatusFieldManager if it's empty.
+func StatusFieldOwner(fieldManager string) client.FieldOwner {
+	if fieldManager == "" {
+		return client.FieldOwner(DefaultStatusFieldManager)
+	}
+
+	return client.FieldOwner(fieldManager)
+}
+
+// UpdatePolicyStatus calls mutate on the input policy and writes its status if mutate returns true. When the write
+// conflicts with another write, the latest version of the policy is read into the input policy and mutate is called
+// on it again before retrying, up to the configured number of retries. The conflict error is returned once the retries
+// are exhausted.
+func UpdatePolicyStatus(
+	ctx context.Context,
+	c client.Client,
+	policy *policiesv1.Policy,
+	opts StatusUpdateOptions,
+	mutate func(policy *policiesv1.Policy) bool,
+) error {
+	retries := opts.ConflictRetries
+	if retries < 0 {
+		retries = 0
+	}
+
+	backoff := wait.Backoff{
+		Steps:    retries + 1,
+		Duration: opts.ConflictBackoff,
+		Factor:   2,
+		Jitter:   0.1,
+	}
+	attempted := false
+
+	return retry.RetryOnConflict(backoff, func() error {
+		if attempted {
+			err := c.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, policy)
+			if err != nil {
+				return err
+			}
+		}
+
+		attempted = true
+
+		if !mutate(policy) {
+			return nil
+		}
+
+		return c.Status().Update(ctx, policy, StatusFieldOwner(opts.FieldManager))
+	})
+}
@@ -0,0 +1,168 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// conflictingStatusClient fails the first conflicts status updates with a conflict error, as if another writer had
+// updated the policy, and records the field managers of the status updates.
+type conflictingStatusClient struct {
+	client.Client
+	conflicts     int
+	updates       int
+	fieldManagers []string
+}
+
+func (c *conflictingStatusClient) Status() client.SubResourceWriter {
+	return &conflictingStatusWriter{SubResourceWriter: c.Client.Status(), client: c}
+}
+
+type conflictingStatusWriter struct {
+	client.SubResourceWriter
+	client *conflictingStatusClient
+}
+
+func (w *conflictingStatusWriter) Update(
+	ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption,
+) error {
+	w.client.updates++
+
+	updateOpts := &client.SubResourceUpdateOptions{}
+	updateOpts.ApplyOptions(opts)
+	w.client.fieldManagers = append(w.client.fieldManagers, updateOpts.FieldManager)
+
+	if w.client.updates <= w.client.conflicts {
+		return k8serrors.NewConflict(
+			schema.GroupResource{Group: policiesv1.GroupVersion.Group, Resource: "policies"},
+			obj.GetName(),
+			nil,
+		)
+	}
+
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func TestUpdatePolicyStatusConflict(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	tests := map[string]struct {
+		conflicts        int
+		opts             StatusUpdateOptions
+		expectedConflict bool
+		expectedUpdates  int
+		expectedManager  string
+	}{
+		"Conflict on the first attempt is retried": {
+			conflicts:       1,
+			opts:            StatusUpdateOptions{FieldManager: "status-writer", ConflictRetries: 3},
+			expectedUpdates: 2,
+			expectedManager: "status-writer",
+		},
+		"Default field manager": {
+			expectedUpdates: 1,
+			expectedManager: DefaultStatusFieldManager,
+		},
+		"Retries are exhausted": {
+			conflicts:        5,
+			opts:             StatusUpdateOptions{ConflictRetries: 2},
+			expectedConflict: true,
+			expectedUpdates:  3,
+			expectedManager:  DefaultStatusFieldManager,
+		},
+		"No retries": {
+			conflicts:        1,
+			expectedConflict: true,
+			expectedUpdates:  1,
+			expectedManager:  DefaultStatusFieldManager,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			policy := &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies"}}
+			c := &conflictingStatusClient{
+				Client:    fake.NewClientBuilder().WithScheme(testscheme).WithObjects(policy).Build(),
+				conflicts: test.conflicts,
+			}
+
+			mutations := 0
+
+			err := UpdatePolicyStatus(context.TODO(), c, policy, test.opts, func(policy *policiesv1.Policy) bool {
+				mutations++
+				policy.Status.ComplianceState = policiesv1.NonCompliant
+
+				return true
+			})
+
+			if test.expectedConflict != k8serrors.IsConflict(err) || (!test.expectedConflict && err != nil) {
+				t.Fatalf("Expected a conflict to be %v, got %v", test.expectedConflict, err)
+			}
+
+			if c.updates != test.expectedUpdates || mutations != test.expectedUpdates {
+				t.Fatalf(
+					"Expected %d updates of the status, got %d updates and %d mutations",
+					test.expectedUpdates, c.updates, mutations,
+				)
+			}
+
+			for _, fieldManager := range c.fieldManagers {
+				if fieldManager != test.expectedManager {
+					t.Fatalf("Expected the field manager %s, got %s", test.expectedManager, fieldManager)
+				}
+			}
+
+			written := &policiesv1.Policy{}
+
+			err = c.Get(context.TODO(), types.NamespacedName{Namespace: "policies", Name: "my-policy"}, written)
+			if err != nil {
+				t.Fatalf("Failed to get the policy: %v", err)
+			}
+
+			statusWritten := written.Status.ComplianceState == policiesv1.NonCompliant
+			if statusWritten == test.expectedConflict {
+				t.Fatalf("Expected the status to be written to be %v, got %v", !test.expectedConflict, statusWritten)
+			}
+		})
+	}
+}
+
+func TestUpdatePolicyStatusNoChange(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	policy := &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies"}}
+	c := &conflictingStatusClient{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(policy).Build(),
+	}
+
+	err := UpdatePolicyStatus(context.TODO(), c, policy, StatusUpdateOptions{}, func(*policiesv1.Policy) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if c.updates != 0 {
+		t.Fatalf("Expected no status update when the status is unchanged, got %d", c.updates)
+	}
+}
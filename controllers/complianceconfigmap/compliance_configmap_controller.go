@@ -0,0 +1,186 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package complianceconfigmap
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	ControllerName string = "policy-compliance-configmap"
+	// ConfigMapLabel is set on every ConfigMap managed by this controller. The manager cache is restricted to
+	// ConfigMaps with this label.
+	ConfigMapLabel string = common.APIGroup + "/compliance-configmap"
+	// ComplianceDataKey is the ConfigMap data key holding the JSON map of cluster name to compliance state.
+	ComplianceDataKey string = "compliance.json"
+)
+
+var log = ctrl.Log.WithName(ControllerName)
+
+//+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=policies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;delete
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ComplianceConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: int(r.MaxConcurrentReconciles)}).
+		Named(ControllerName).
+		For(&policiesv1.Policy{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
+
+// blank assignment to verify that ComplianceConfigMapReconciler implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ComplianceConfigMapReconciler{}
+
+// ComplianceConfigMapReconciler maintains a ConfigMap per root policy that summarizes the compliance state of each
+// cluster the policy is propagated to. This is meant for consumers that can't read Policy objects directly.
+type ComplianceConfigMapReconciler struct {
+	client.Client
+	MaxConcurrentReconciles uint
+	Scheme                  *runtime.Scheme
+}
+
+// ConfigMapName returns the name of the compliance summary ConfigMap for the input root policy name.
+func ConfigMapName(policyName string) string {
+	return policyName + "-compliance"
+}
+
+// Reconcile creates or updates the compliance summary ConfigMap of a root policy based on its status. The ConfigMap
+// is only written when its content changes, and it is deleted when the root policy is deleted.
+func (r *ComplianceConfigMapReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	log := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	log.V(1).Info("Reconciling the compliance ConfigMap")
+
+	inClusterNs, err := common.IsInClusterNamespace(r.Client, request.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to determine if the policy is in a managed cluster namespace. Requeueing the request.")
+
+		return reconcile.Result{}, err
+	}
+
+	if inClusterNs {
+		log.V(2).Info("Ignoring the replicated policy")
+
+		return reconcile.Result{}, nil
+	}
+
+	cmKey := client.ObjectKey{Namespace: request.Namespace, Name: ConfigMapName(request.Name)}
+
+	rootPolicy := &policiesv1.Policy{}
+
+	err = r.Get(ctx, request.NamespacedName, rootPolicy)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			log.Info("The root policy was not found, so it may have been deleted. Deleting the compliance ConfigMap.")
+
+			// The owner reference should take care of this, but delete it explicitly in case it was removed
+			err := r.Delete(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: cmKey.Namespace, Name: cmKey.Name},
+			})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				log.Error(err, "Failed to delete the compliance ConfigMap")
+
+				return reconcile.Result{}, err
+			}
+
+			return reconcile.Result{}, nil
+		}
+
+		log.Error(err, "Failed to get the root policy")
+
+		return reconcile.Result{}, err
+	}
+
+	data, err := complianceData(rootPolicy)
+	if err != nil {
+		log.Error(err, "Failed to build the compliance ConfigMap data")
+
+		return reconcile.Result{}, err
+	}
+
+	configMap := &corev1.ConfigMap{}
+
+	err = r.Get(ctx, cmKey, configMap)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Error(err, "Failed to get the compliance ConfigMap")
+
+			return reconcile.Result{}, err
+		}
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cmKey.Name,
+				Namespace: cmKey.Namespace,
+				Labels:    map[string]string{ConfigMapLabel: "true"},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(rootPolicy, policiesv1.GroupVersion.WithKind(policiesv1.Kind)),
+				},
+			},
+			Data: data,
+		}
+
+		log.Info("Creating the compliance ConfigMap", "configMap", cmKey.Name)
+
+		err = r.Create(ctx, configMap)
+		if err != nil {
+			log.Error(err, "Failed to create the compliance ConfigMap")
+
+			return reconcile.Result{}, err
+		}
+
+		return reconcile.Result{}, nil
+	}
+
+	if equality.Semantic.DeepEqual(configMap.Data, data) {
+		log.V(2).Info("The compliance ConfigMap is up to date. Doing nothing.")
+
+		return reconcile.Result{}, nil
+	}
+
+	log.Info("Updating the compliance ConfigMap", "configMap", cmKey.Name)
+
+	configMap.Data = data
+
+	err = r.Update(ctx, configMap)
+	if err != nil {
+		log.Error(err, "Failed to update the compliance ConfigMap")
+
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// complianceData returns the ConfigMap data for the input root policy, which is a JSON map of cluster name to
+// compliance state.
+func complianceData(rootPolicy *policiesv1.Policy) (map[string]string, error) {
+	clusterCompliance := make(map[string]policiesv1.ComplianceState, len(rootPolicy.Status.Status))
+
+	for _, status := range rootPolicy.Status.Status {
+		clusterCompliance[status.ClusterName] = status.ComplianceState
+	}
+
+	// Map keys are sorted when marshaled, so the output is stable between reconciles
+	complianceJSON, err := json.Marshal(clusterCompliance)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{ComplianceDataKey: string(complianceJSON)}, nil
+}
@@ -0,0 +1,128 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package complianceconfigmap
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func getReconciler(t *testing.T, policy *policiesv1.Policy) *ComplianceConfigMapReconciler {
+	t.Helper()
+
+	scheme := k8sruntime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}}
+
+	return &ComplianceConfigMapReconciler{
+		Client:                  fake.NewClientBuilder().WithScheme(scheme).WithObjects(policy, cluster).Build(),
+		MaxConcurrentReconciles: 1,
+		Scheme:                  scheme,
+	}
+}
+
+func TestReconcileComplianceConfigMap(t *testing.T) {
+	policy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies", UID: "1234"},
+		Status: policiesv1.PolicyStatus{
+			Status: []*policiesv1.CompliancePerClusterStatus{
+				{ComplianceState: policiesv1.NonCompliant, ClusterName: "managed2", ClusterNamespace: "managed2"},
+				{ComplianceState: policiesv1.Compliant, ClusterName: "managed1", ClusterNamespace: "managed1"},
+			},
+		},
+	}
+
+	r := getReconciler(t, policy)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-policy"}}
+	cmKey := types.NamespacedName{Namespace: "policies", Name: ConfigMapName("my-policy")}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected reconcile error: %v", err)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(context.TODO(), cmKey, configMap); err != nil {
+		t.Fatalf("expected the compliance ConfigMap to be created: %v", err)
+	}
+
+	expected := `{"managed1":"Compliant","managed2":"NonCompliant"}`
+	if configMap.Data[ComplianceDataKey] != expected {
+		t.Fatalf("expected data %s, got %s", expected, configMap.Data[ComplianceDataKey])
+	}
+
+	if configMap.Labels[ConfigMapLabel] != "true" {
+		t.Fatalf("expected the %s label to be set", ConfigMapLabel)
+	}
+
+	// A second reconcile with no status change must not write the ConfigMap
+	resourceVersion := configMap.ResourceVersion
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected reconcile error: %v", err)
+	}
+
+	if err := r.Get(context.TODO(), cmKey, configMap); err != nil {
+		t.Fatalf("failed to get the compliance ConfigMap: %v", err)
+	}
+
+	if configMap.ResourceVersion != resourceVersion {
+		t.Fatalf("expected no update of the compliance ConfigMap on a no-op reconcile")
+	}
+
+	if err := r.Delete(context.TODO(), policy); err != nil {
+		t.Fatalf("failed to delete the policy: %v", err)
+	}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected reconcile error: %v", err)
+	}
+
+	err := r.Get(context.TODO(), cmKey, configMap)
+	if !k8serrors.IsNotFound(err) {
+		t.Fatalf("expected the compliance ConfigMap to be deleted, got: %v", err)
+	}
+}
+
+func TestReconcileComplianceConfigMapReplicated(t *testing.T) {
+	policy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policies.my-policy", Namespace: "managed1"},
+	}
+
+	r := getReconciler(t, policy)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "managed1", Name: "policies.my-policy"}}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected reconcile error: %v", err)
+	}
+
+	cmKey := types.NamespacedName{Namespace: "managed1", Name: ConfigMapName("policies.my-policy")}
+
+	err := r.Get(context.TODO(), cmKey, &corev1.ConfigMap{})
+	if !k8serrors.IsNotFound(err) {
+		t.Fatalf("expected no compliance ConfigMap for a replicated policy, got: %v", err)
+	}
+}
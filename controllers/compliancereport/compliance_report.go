@@ -0,0 +1,304 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package compliancereport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	ControllerName string = "policy-compliance-report"
+	// ReportLabel is set on every compliance report ConfigMap so that the reports can be listed for rotation.
+	ReportLabel string = common.APIGroup + "/compliance-report"
+	// ReportHashAnnotation is set on every compliance report ConfigMap to the hash of its policies, so that a report
+	// isn't generated when nothing changed since the last one.
+	ReportHashAnnotation string = common.APIGroup + "/compliance-report-hash"
+	// ReportDataKey is the ConfigMap data key holding the JSON report.
+	ReportDataKey string = "report.json"
+	// ReportNamePrefix is the prefix of the compliance report ConfigMap names, which end with the UTC time the report
+	// was generated at, so that the names sort from the oldest to the newest report.
+	ReportNamePrefix string = "compliance-report-"
+	// The time format of the compliance report ConfigMap names.
+	reportNameTimeFormat = "20060102-150405"
+	// The number of policies to retrieve per List call when generating a report.
+	reportPageSize = 500
+)
+
+var log = ctrl.Log.WithName(ControllerName)
+
+//+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=policies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;delete
+
+// Report is a point-in-time snapshot of the compliance of every root policy on each cluster it's propagated to.
+type Report struct {
+	GeneratedAt metav1.Time    `json:"generatedAt"`
+	Policies    []PolicyReport `json:"policies"`
+}
+
+// PolicyReport is the compliance of a root policy in a Report.
+type PolicyReport struct {
+	Namespace       string                     `json:"namespace"`
+	Name            string                     `json:"name"`
+	Disabled        bool                       `json:"disabled,omitempty"`
+	ComplianceState policiesv1.ComplianceState `json:"compliant,omitempty"`
+	Clusters        []ClusterReport            `json:"clusters"`
+}
+
+// ClusterReport is the compliance of a replicated policy in a Report.
+type ClusterReport struct {
+	ClusterName      string                     `json:"clusterName"`
+	ClusterNamespace string                     `json:"clusterNamespace"`
+	ComplianceState  policiesv1.ComplianceState `json:"compliant,omitempty"`
+}
+
+// blank assignment to verify that ComplianceReporter implements manager.Runnable
+var _ manager.Runnable = &ComplianceReporter{}
+
+// ComplianceReporter periodically writes a Report to an immutable ConfigMap so that auditors have point-in-time
+// records of the compliance rather than the live status of the policies. A report is skipped when the compliance
+// didn't change since the last report, and only the newest reports are kept. It's a leader election runnable, so only
+// the leader writes the reports.
+type ComplianceReporter struct {
+	// APIReader should not be backed by the cache so that the policies can be retrieved in pages and the report
+	// ConfigMaps, which aren't cached, can be listed.
+	APIReader client.Reader
+	Client    client.Client
+	// Namespace is the namespace of the report ConfigMaps.
+	Namespace string
+	// Interval is how often a report is generated.
+	Interval time.Duration
+	// Retention is the number of reports to keep. The oldest reports are deleted when there are more.
+	Retention uint
+	// now returns the current time, and is overridden in the tests.
+	now func() time.Time
+}
+
+// Start generates a report when it starts and then once per interval until the context is canceled. Failures are
+// logged and the report is attempted again on the next interval.
+func (r *ComplianceReporter) Start(ctx context.Context) error {
+	for {
+		start := time.Now()
+
+		name, err := r.GenerateReport(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Error(err, "Failed to generate the compliance report")
+		} else if name != "" {
+			log.Info("Generated the compliance report", "configMap", name, "duration", time.Since(start))
+		}
+
+		select {
+		case <-time.After(time.Until(start.Add(r.Interval))):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// GenerateReport writes a report ConfigMap if the compliance changed since the last report and deletes the oldest
+// reports beyond the retention. It returns the name of the written ConfigMap, or an empty string if the report was
+// skipped.
+func (r *ComplianceReporter) GenerateReport(ctx context.Context) (string, error) {
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+
+	report, err := r.buildReport(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	report.GeneratedAt = metav1.NewTime(now().UTC().Truncate(time.Second))
+
+	hash, err := reportHash(report)
+	if err != nil {
+		return "", err
+	}
+
+	reports, err := r.listReports(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	name := ""
+
+	if len(reports) != 0 && reports[len(reports)-1].Annotations[ReportHashAnnotation] == hash {
+		log.V(1).Info(
+			"The compliance didn't change since the last report. Skipping the report.",
+			"lastReport", reports[len(reports)-1].Name,
+		)
+	} else {
+		reportJSON, err := json.Marshal(report)
+		if err != nil {
+			return "", err
+		}
+
+		immutable := true
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        ReportNamePrefix + report.GeneratedAt.Format(reportNameTimeFormat),
+				Namespace:   r.Namespace,
+				Labels:      map[string]string{ReportLabel: "true"},
+				Annotations: map[string]string{ReportHashAnnotation: hash},
+			},
+			Immutable: &immutable,
+			Data:      map[string]string{ReportDataKey: string(reportJSON)},
+		}
+
+		if err := r.Client.Create(ctx, configMap); err != nil {
+			return "", err
+		}
+
+		name = configMap.Name
+		reports = append(reports, *configMap)
+	}
+
+	// The retention is also applied when the report is skipped in case it was lowered since the last report
+	retention := int(r.Retention)
+	if retention < 1 {
+		retention = 1
+	}
+
+	for i := 0; i < len(reports)-retention; i++ {
+		log.V(1).Info("Deleting the compliance report beyond the retention", "configMap", reports[i].Name)
+
+		if err := r.Client.Delete(ctx, &reports[i]); client.IgnoreNotFound(err) != nil {
+			return name, err
+		}
+	}
+
+	return name, nil
+}
+
+// buildReport returns a Report of the root policies and their replicated policies, sorted by namespace and name and
+// by cluster name. The GeneratedAt field isn't set.
+func (r *ComplianceReporter) buildReport(ctx context.Context) (*Report, error) {
+	rootPolicies := map[string]*PolicyReport{}
+	replicatedPolicies := map[string][]ClusterReport{}
+	continueToken := ""
+
+	for {
+		policyList := &policiesv1.PolicyList{}
+
+		err := r.APIReader.List(ctx, policyList, client.Limit(reportPageSize), client.Continue(continueToken))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range policyList.Items {
+			policy := &policyList.Items[i]
+
+			if rootPlcName, _ := common.GetRootPolicyLabel(policy); rootPlcName != "" {
+				clusterName := policy.Labels[common.ClusterNameLabel]
+				clusterNamespace := policy.Labels[common.ClusterNamespaceLabel]
+
+				if clusterNamespace == "" {
+					clusterNamespace = policy.Namespace
+				}
+
+				if clusterName == "" {
+					clusterName = clusterNamespace
+				}
+
+				replicatedPolicies[rootPlcName] = append(replicatedPolicies[rootPlcName], ClusterReport{
+					ClusterName:      clusterName,
+					ClusterNamespace: clusterNamespace,
+					ComplianceState:  policy.Status.ComplianceState,
+				})
+
+				continue
+			}
+
+			if !common.IsWatchedRootNamespace(policy.Namespace) {
+				continue
+			}
+
+			rootPolicies[common.ReplicatedPolicyName(policy.Namespace, policy.Name)] = &PolicyReport{
+				Namespace:       policy.Namespace,
+				Name:            policy.Name,
+				Disabled:        policy.Spec.Disabled,
+				ComplianceState: policy.Status.ComplianceState,
+			}
+		}
+
+		continueToken = policyList.GetContinue()
+		if continueToken == "" {
+			break
+		}
+	}
+
+	report := &Report{Policies: make([]PolicyReport, 0, len(rootPolicies))}
+
+	for rootPlcName, policyReport := range rootPolicies {
+		// The replicated policies of a deleted root policy that weren't cleaned up yet are left out
+		policyReport.Clusters = replicatedPolicies[rootPlcName]
+		if policyReport.Clusters == nil {
+			policyReport.Clusters = []ClusterReport{}
+		}
+
+		sort.Slice(policyReport.Clusters, func(i, j int) bool {
+			return policyReport.Clusters[i].ClusterName < policyReport.Clusters[j].ClusterName
+		})
+
+		report.Policies = append(report.Policies, *policyReport)
+	}
+
+	sort.Slice(report.Policies, func(i, j int) bool {
+		if report.Policies[i].Namespace != report.Policies[j].Namespace {
+			return report.Policies[i].Namespace < report.Policies[j].Namespace
+		}
+
+		return report.Policies[i].Name < report.Policies[j].Name
+	})
+
+	return report, nil
+}
+
+// reportHash returns the hex encoded SHA256 hash of the policies of the report, so that two reports generated at
+// different times with the same compliance have the same hash.
+func reportHash(report *Report) (string, error) {
+	policiesJSON, err := json.Marshal(report.Policies)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(policiesJSON)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// listReports returns the report ConfigMaps in the report namespace, from the oldest to the newest.
+func (r *ComplianceReporter) listReports(ctx context.Context) ([]corev1.ConfigMap, error) {
+	configMapList := &corev1.ConfigMapList{}
+
+	err := r.APIReader.List(
+		ctx, configMapList, client.InNamespace(r.Namespace), client.MatchingLabels{ReportLabel: "true"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(configMapList.Items, func(i, j int) bool {
+		return configMapList.Items[i].Name < configMapList.Items[j].Name
+	})
+
+	return configMapList.Items, nil
+}
@@ -0,0 +1,192 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package compliancereport
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func fakePolicy(namespace, name string, compliance policiesv1.ComplianceState) *policiesv1.Policy {
+	return &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     policiesv1.PolicyStatus{ComplianceState: compliance},
+	}
+}
+
+func fakeReplicatedPolicy(cluster string, compliance policiesv1.ComplianceState) *policiesv1.Policy {
+	policy := fakePolicy(cluster, "policies.my-policy", compliance)
+	policy.Labels = map[string]string{
+		common.RootPolicyLabel:       "policies.my-policy",
+		common.ClusterNameLabel:      cluster,
+		common.ClusterNamespaceLabel: cluster,
+	}
+
+	return policy
+}
+
+func getReporter(t *testing.T, retention uint, objects ...client.Object) *ComplianceReporter {
+	t.Helper()
+
+	scheme := k8sruntime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	return &ComplianceReporter{
+		APIReader: c,
+		Client:    c,
+		Namespace: "open-cluster-management",
+		Interval:  time.Hour,
+		Retention: retention,
+		now: func() time.Time {
+			now = now.Add(time.Minute)
+
+			return now
+		},
+	}
+}
+
+func getReport(t *testing.T, r *ComplianceReporter, name string) *Report {
+	t.Helper()
+
+	configMap := &corev1.ConfigMap{}
+
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Namespace: r.Namespace, Name: name}, configMap)
+	if err != nil {
+		t.Fatalf("expected the compliance report %s to exist: %v", name, err)
+	}
+
+	if configMap.Immutable == nil || !*configMap.Immutable {
+		t.Fatalf("expected the compliance report %s to be immutable", name)
+	}
+
+	if configMap.Labels[ReportLabel] != "true" {
+		t.Fatalf("expected the %s label to be set", ReportLabel)
+	}
+
+	report := &Report{}
+	if err := json.Unmarshal([]byte(configMap.Data[ReportDataKey]), report); err != nil {
+		t.Fatalf("failed to unmarshal the compliance report: %v", err)
+	}
+
+	return report
+}
+
+func TestGenerateReport(t *testing.T) {
+	disabledPolicy := fakePolicy("policies", "disabled-policy", "")
+	disabledPolicy.Spec.Disabled = true
+
+	r := getReporter(t, 5,
+		fakePolicy("policies", "my-policy", policiesv1.NonCompliant),
+		disabledPolicy,
+		fakeReplicatedPolicy("managed2", policiesv1.NonCompliant),
+		fakeReplicatedPolicy("managed1", policiesv1.Compliant),
+	)
+
+	name, err := r.GenerateReport(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error generating the compliance report: %v", err)
+	}
+
+	if name != ReportNamePrefix+"20230601-120100" {
+		t.Fatalf("expected the compliance report to be named after the time it was generated, got %s", name)
+	}
+
+	report := getReport(t, r, name)
+
+	if !report.GeneratedAt.Equal(&metav1.Time{Time: time.Date(2023, 6, 1, 12, 1, 0, 0, time.UTC)}) {
+		t.Fatalf("unexpected generation time %v", report.GeneratedAt)
+	}
+
+	expected := []PolicyReport{
+		{Namespace: "policies", Name: "disabled-policy", Disabled: true, Clusters: []ClusterReport{}},
+		{
+			Namespace:       "policies",
+			Name:            "my-policy",
+			ComplianceState: policiesv1.NonCompliant,
+			Clusters: []ClusterReport{
+				{ClusterName: "managed1", ClusterNamespace: "managed1", ComplianceState: policiesv1.Compliant},
+				{ClusterName: "managed2", ClusterNamespace: "managed2", ComplianceState: policiesv1.NonCompliant},
+			},
+		},
+	}
+
+	expectedJSON, _ := json.Marshal(expected)
+	actualJSON, _ := json.Marshal(report.Policies)
+
+	if string(expectedJSON) != string(actualJSON) {
+		t.Fatalf("expected the policies %s, got %s", expectedJSON, actualJSON)
+	}
+
+	name, err = r.GenerateReport(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error generating the compliance report: %v", err)
+	}
+
+	if name != "" {
+		t.Fatalf("expected the compliance report to be skipped when nothing changed, got %s", name)
+	}
+}
+
+func TestGenerateReportRetention(t *testing.T) {
+	replicatedPolicy := fakeReplicatedPolicy("managed1", policiesv1.Compliant)
+	r := getReporter(t, 2, fakePolicy("policies", "my-policy", policiesv1.Compliant), replicatedPolicy)
+
+	names := make([]string, 0, 3)
+	compliance := []policiesv1.ComplianceState{policiesv1.Compliant, policiesv1.NonCompliant, policiesv1.Compliant}
+
+	for _, state := range compliance {
+		replicatedPolicy.Status.ComplianceState = state
+
+		if err := r.Client.Status().Update(context.TODO(), replicatedPolicy); err != nil {
+			t.Fatalf("failed to update the replicated policy status: %v", err)
+		}
+
+		name, err := r.GenerateReport(context.TODO())
+		if err != nil {
+			t.Fatalf("unexpected error generating the compliance report: %v", err)
+		}
+
+		if name == "" {
+			t.Fatalf("expected a compliance report after the compliance changed to %s", state)
+		}
+
+		names = append(names, name)
+	}
+
+	reports, err := r.listReports(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to list the compliance reports: %v", err)
+	}
+
+	if len(reports) != 2 || reports[0].Name != names[1] || reports[1].Name != names[2] {
+		t.Fatalf("expected only the compliance reports %v to be kept, got %d reports", names[1:], len(reports))
+	}
+
+	report := getReport(t, r, names[2])
+	if report.Policies[0].Clusters[0].ComplianceState != policiesv1.Compliant {
+		t.Fatalf("expected the newest compliance report to have the latest compliance, got %+v", report.Policies)
+	}
+}
@@ -257,7 +257,15 @@ func (r *EncryptionKeysReconciler) triggerTemplateUpdate(
 		}
 
 		// Find the root policy to patch with the annotation
-		rootPlcName := policy.GetLabels()[common.RootPolicyLabel]
+		// #nosec G601 -- no memory addresses are stored in collections
+		rootPlcName, err := common.GetRootPolicyLabel(&policy)
+		if err != nil {
+			log.Error(err, "Unable to parse name and namespace of root policy, ignoring this replicated policy",
+				"rootPlcName", rootPlcName)
+
+			continue
+		}
+
 		if rootPlcName == "" {
 			log.Info(
 				"The replicated policy does not have the root policy label set",
@@ -268,13 +276,8 @@ func (r *EncryptionKeysReconciler) triggerTemplateUpdate(
 			continue
 		}
 
-		name, namespace, err := common.ParseRootPolicyLabel(rootPlcName)
-		if err != nil {
-			log.Error(err, "Unable to parse name and namespace of root policy, ignoring this replicated policy",
-				"rootPlcName", rootPlcName)
-
-			continue
-		}
+		// Skip error checking since GetRootPolicyLabel verified this already
+		name, namespace, _ := common.ParseRootPolicyLabel(rootPlcName)
 
 		rootPolicy := &policyv1.Policy{
 			ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,259 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package placementbinding
+
+import (
+	"context"
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const ControllerName string = "placement-binding-validation"
+
+var log = ctrl.Log.WithName(ControllerName)
+
+//+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=placementbindings,verbs=get;list;watch
+//+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=placementbindings/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=apps.open-cluster-management.io,resources=placementrules,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.open-cluster-management.io,resources=managedclustersetbindings;placements,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PlacementBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	builder := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: int(r.MaxConcurrentReconciles)}).
+		Named(ControllerName).
+		For(&policiesv1.PlacementBinding{}).
+		Watches(
+			&source.Kind{Type: &appsv1.PlacementRule{}},
+			handler.EnqueueRequestsFromMapFunc(placementRefMapper(mgr.GetClient(), "PlacementRule")))
+
+	if common.ManagedClusterSetAPIAvailable() {
+		builder.Watches(
+			&source.Kind{Type: &clusterv1beta2.ManagedClusterSetBinding{}},
+			handler.EnqueueRequestsFromMapFunc(placementRefMapper(mgr.GetClient(), "ManagedClusterSet")))
+	}
+
+	if common.PlacementAPIAvailable() {
+		builder.Watches(
+			&source.Kind{Type: &clusterv1beta1.Placement{}},
+			handler.EnqueueRequestsFromMapFunc(placementRefMapper(mgr.GetClient(), "Placement")))
+	}
+
+	return builder.Complete(r)
+}
+
+// placementRefMapper enqueues the PlacementBindings in the namespace of the object whose placementRef has the input
+// kind and the name of the object. This way, the condition is updated when the referenced resource is created or
+// deleted.
+func placementRefMapper(c client.Client, kind string) handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		pbList := &policiesv1.PlacementBindingList{}
+		lopts := &client.ListOptions{Namespace: object.GetNamespace()}
+		opts := client.MatchingFields{"placementRef.name": object.GetName()}
+		opts.ApplyToList(lopts)
+
+		err := c.List(context.TODO(), pbList, lopts)
+		if err != nil {
+			log.Error(err, "Failed to list the PlacementBindings", "namespace", object.GetNamespace())
+
+			return nil
+		}
+
+		var result []reconcile.Request
+
+		for _, pb := range pbList.Items {
+			if pb.PlacementRef.Kind != kind {
+				continue
+			}
+
+			result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      pb.GetName(),
+				Namespace: pb.GetNamespace(),
+			}})
+		}
+
+		return result
+	}
+}
+
+// blank assignment to verify that PlacementBindingReconciler implements reconcile.Reconciler
+var _ reconcile.Reconciler = &PlacementBindingReconciler{}
+
+// PlacementBindingReconciler verifies that the resource referenced by the placementRef of a PlacementBinding exists.
+// Otherwise, a PlacementBinding with a typo in its placementRef silently never propagates its policies.
+type PlacementBindingReconciler struct {
+	client.Client
+	MaxConcurrentReconciles uint
+	Recorder                record.EventRecorder
+	Scheme                  *runtime.Scheme
+}
+
+// Reconcile sets the PlacementRefResolved condition on the PlacementBinding based on whether the resource referenced by
+// its placementRef exists. When the reference can't be resolved, a warning event is also emitted on the
+// PlacementBinding.
+func (r *PlacementBindingReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	log := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	log.V(1).Info("Reconciling the PlacementBinding")
+
+	pb := &policiesv1.PlacementBinding{}
+
+	err := r.Get(ctx, request.NamespacedName, pb)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			log.V(2).Info("The PlacementBinding was deleted. Doing nothing.")
+
+			return reconcile.Result{}, nil
+		}
+
+		log.Error(err, "Failed to get the PlacementBinding")
+
+		return reconcile.Result{}, err
+	}
+
+	condition, err := r.getPlacementRefCondition(ctx, pb)
+	if err != nil {
+		log.Error(err, "Failed to determine if the placementRef exists")
+
+		return reconcile.Result{}, err
+	}
+
+	existing := meta.FindStatusCondition(pb.Status.Conditions, condition.Type)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message {
+		log.V(2).Info("The PlacementRefResolved condition is up to date")
+
+		return reconcile.Result{}, nil
+	}
+
+	log.Info("Updating the PlacementRefResolved condition", "status", condition.Status, "reason", condition.Reason)
+
+	meta.SetStatusCondition(&pb.Status.Conditions, condition)
+
+	err = r.Status().Update(ctx, pb)
+	if err != nil {
+		log.Error(err, "Failed to update the PlacementBinding status. Will Requeue.")
+
+		return reconcile.Result{}, err
+	}
+
+	if condition.Status == metav1.ConditionFalse {
+		r.Recorder.Event(pb, "Warning", condition.Reason, condition.Message)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// getPlacementRefCondition returns the PlacementRefResolved condition for the input PlacementBinding. A
+// ManagedClusterSet is considered resolved when it's bound to the namespace of the PlacementBinding, since a
+// ManagedClusterSet that is not bound can't be used for placement. A PlacementBinding that selects the clusters with
+// its clusterSelector is resolved when the selector is valid, since the placementRef is not used.
+func (r *PlacementBindingReconciler) getPlacementRefCondition(
+	ctx context.Context, pb *policiesv1.PlacementBinding,
+) (metav1.Condition, error) {
+	if common.UsesClusterSelector(pb) {
+		if _, err := metav1.LabelSelectorAsSelector(pb.ClusterSelector); err != nil {
+			return metav1.Condition{
+				Type:    policiesv1.PlacementRefResolved,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ClusterSelectorInvalid",
+				Message: fmt.Sprintf("The clusterSelector is invalid: %v", err),
+			}, nil
+		}
+
+		return metav1.Condition{
+			Type:    policiesv1.PlacementRefResolved,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ClusterSelectorUsed",
+			Message: "The managed clusters are selected with the clusterSelector",
+		}, nil
+	}
+
+	ref := pb.PlacementRef
+	key := types.NamespacedName{Namespace: pb.Namespace, Name: ref.Name}
+
+	var obj client.Object
+
+	switch {
+	case ref.APIGroup == appsv1.SchemeGroupVersion.Group && ref.Kind == "PlacementRule":
+		obj = &appsv1.PlacementRule{}
+	case ref.APIGroup == clusterv1beta1.SchemeGroupVersion.Group && ref.Kind == "Placement":
+		if !common.PlacementAPIAvailable() {
+			return metav1.Condition{
+				Type:    policiesv1.PlacementRefResolved,
+				Status:  metav1.ConditionFalse,
+				Reason:  "PlacementRefInvalid",
+				Message: "The Placement API is not installed on the hub",
+			}, nil
+		}
+
+		obj = &clusterv1beta1.Placement{}
+	case ref.APIGroup == clusterv1beta2.GroupName && ref.Kind == "ManagedClusterSet":
+		if !common.ManagedClusterSetAPIAvailable() {
+			return metav1.Condition{
+				Type:    policiesv1.PlacementRefResolved,
+				Status:  metav1.ConditionFalse,
+				Reason:  "PlacementRefInvalid",
+				Message: "The ManagedClusterSet API is not installed on the hub",
+			}, nil
+		}
+
+		obj = &clusterv1beta2.ManagedClusterSetBinding{}
+	default:
+		return metav1.Condition{
+			Type:   policiesv1.PlacementRefResolved,
+			Status: metav1.ConditionFalse,
+			Reason: "PlacementRefInvalid",
+			Message: fmt.Sprintf(
+				"The placementRef kind %s in the API group %s is not supported", ref.Kind, ref.APIGroup,
+			),
+		}, nil
+	}
+
+	err := r.Get(ctx, key, obj)
+	if k8serrors.IsNotFound(err) {
+		message := fmt.Sprintf("The %s %s was not found in the namespace %s", ref.Kind, ref.Name, pb.Namespace)
+		if ref.Kind == "ManagedClusterSet" {
+			message = fmt.Sprintf(
+				"The ManagedClusterSet %s is not bound to the namespace %s with a ManagedClusterSetBinding",
+				ref.Name, pb.Namespace,
+			)
+		}
+
+		return metav1.Condition{
+			Type:    policiesv1.PlacementRefResolved,
+			Status:  metav1.ConditionFalse,
+			Reason:  "PlacementRefNotFound",
+			Message: message,
+		}, nil
+	}
+
+	if err != nil {
+		return metav1.Condition{}, err
+	}
+
+	return metav1.Condition{
+		Type:    policiesv1.PlacementRefResolved,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PlacementRefFound",
+		Message: fmt.Sprintf("The %s %s was found", ref.Kind, ref.Name),
+	}, nil
+}
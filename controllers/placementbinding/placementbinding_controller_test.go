@@ -0,0 +1,205 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package placementbinding
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func TestReconcilePlacementRefResolved(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1beta1.AddToScheme, clusterv1beta2.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatalf("failed to set up the scheme: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		ref            policiesv1.PlacementSubject
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		"PlacementRule found": {
+			policiesv1.PlacementSubject{
+				APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule",
+			},
+			metav1.ConditionTrue, "PlacementRefFound",
+		},
+		"PlacementRule typo": {
+			policiesv1.PlacementSubject{
+				APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rul",
+			},
+			metav1.ConditionFalse, "PlacementRefNotFound",
+		},
+		"Placement found": {
+			policiesv1.PlacementSubject{
+				APIGroup: clusterv1beta1.SchemeGroupVersion.Group, Kind: "Placement", Name: "my-placement",
+			},
+			metav1.ConditionTrue, "PlacementRefFound",
+		},
+		"Placement not found": {
+			policiesv1.PlacementSubject{
+				APIGroup: clusterv1beta1.SchemeGroupVersion.Group, Kind: "Placement", Name: "my-rule",
+			},
+			metav1.ConditionFalse, "PlacementRefNotFound",
+		},
+		"ManagedClusterSet bound": {
+			policiesv1.PlacementSubject{
+				APIGroup: clusterv1beta2.GroupName, Kind: "ManagedClusterSet", Name: "my-set",
+			},
+			metav1.ConditionTrue, "PlacementRefFound",
+		},
+		"ManagedClusterSet not bound": {
+			policiesv1.PlacementSubject{
+				APIGroup: clusterv1beta2.GroupName, Kind: "ManagedClusterSet", Name: "other-set",
+			},
+			metav1.ConditionFalse, "PlacementRefNotFound",
+		},
+		"unsupported kind": {
+			policiesv1.PlacementSubject{
+				APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "Placement", Name: "my-placement",
+			},
+			metav1.ConditionFalse, "PlacementRefInvalid",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			pb := &policiesv1.PlacementBinding{
+				ObjectMeta:   metav1.ObjectMeta{Name: "my-pb", Namespace: "policies"},
+				PlacementRef: test.ref,
+			}
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				pb,
+				&appsv1.PlacementRule{ObjectMeta: metav1.ObjectMeta{Name: "my-rule", Namespace: "policies"}},
+				&clusterv1beta1.Placement{ObjectMeta: metav1.ObjectMeta{Name: "my-placement", Namespace: "policies"}},
+				&clusterv1beta2.ManagedClusterSetBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "my-set", Namespace: "policies"},
+				},
+			).Build()
+
+			recorder := record.NewFakeRecorder(10)
+			r := &PlacementBindingReconciler{Client: c, Scheme: scheme, Recorder: recorder}
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-pb"}}
+
+			// Reconcile twice to verify that the event is only emitted when the condition changes
+			for i := 0; i < 2; i++ {
+				if _, err := r.Reconcile(context.TODO(), request); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			updated := &policiesv1.PlacementBinding{}
+			if err := c.Get(context.TODO(), request.NamespacedName, updated); err != nil {
+				t.Fatalf("failed to get the PlacementBinding: %v", err)
+			}
+
+			condition := meta.FindStatusCondition(updated.Status.Conditions, policiesv1.PlacementRefResolved)
+			if condition == nil {
+				t.Fatalf("expected the %s condition to be set", policiesv1.PlacementRefResolved)
+			}
+
+			if condition.Status != test.expectedStatus || condition.Reason != test.expectedReason {
+				t.Fatalf(
+					"expected status %s and reason %s, got %s and %s",
+					test.expectedStatus, test.expectedReason, condition.Status, condition.Reason,
+				)
+			}
+
+			expectedEvents := 0
+			if test.expectedStatus == metav1.ConditionFalse {
+				expectedEvents = 1
+			}
+
+			if len(recorder.Events) != expectedEvents {
+				t.Fatalf("expected %d events, got %d", expectedEvents, len(recorder.Events))
+			}
+		})
+	}
+}
+
+func TestReconcileClusterSelector(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	common.SetBindingClusterSelectorEnabled(true)
+	defer common.SetBindingClusterSelectorEnabled(false)
+
+	tests := map[string]struct {
+		selector       *metav1.LabelSelector
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		"valid selector": {
+			&metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			metav1.ConditionTrue, "ClusterSelectorUsed",
+		},
+		"invalid selector": {
+			&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: "Matches"},
+			}},
+			metav1.ConditionFalse, "ClusterSelectorInvalid",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			// The placementRef is not set since it's not used with a cluster selector
+			pb := &policiesv1.PlacementBinding{
+				ObjectMeta:      metav1.ObjectMeta{Name: "my-pb", Namespace: "policies"},
+				ClusterSelector: test.selector,
+			}
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pb).Build()
+			r := &PlacementBindingReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-pb"}}
+
+			if _, err := r.Reconcile(context.TODO(), request); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			updated := &policiesv1.PlacementBinding{}
+			if err := c.Get(context.TODO(), request.NamespacedName, updated); err != nil {
+				t.Fatalf("failed to get the PlacementBinding: %v", err)
+			}
+
+			condition := meta.FindStatusCondition(updated.Status.Conditions, policiesv1.PlacementRefResolved)
+			if condition == nil {
+				t.Fatalf("expected the %s condition to be set", policiesv1.PlacementRefResolved)
+			}
+
+			if condition.Status != test.expectedStatus || condition.Reason != test.expectedReason {
+				t.Fatalf(
+					"expected status %s and reason %s, got %s and %s",
+					test.expectedStatus, test.expectedReason, condition.Status, condition.Reason,
+				)
+			}
+		})
+	}
+}
@@ -4,25 +4,407 @@
 package policymetrics
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
-var policyStatusGauge = prometheus.NewGaugeVec(
-	prometheus.GaugeOpts{
-		Name: "policy_governance_info",
-		Help: "The compliance status of the named policy. 0 == Compliant. 1 == NonCompliant",
-	},
-	[]string{
+// The sentinel value of a policy-derived label when the policy doesn't have the label set.
+const missingLabelValue = "<null>"
+
+var (
+	statusGaugeLabels = []string{
 		"type",              // "root" or "propagated"
 		"policy",            // The name of the root policy
 		"policy_namespace",  // The namespace where the root policy is defined
 		"cluster_namespace", // The namespace where the policy was propagated
-	},
+	}
+	// policyLabelKeys maps the additional label names on policyStatusGauge to the policy label keys their values are
+	// taken from.
+	policyLabelKeys   = map[string]string{}
+	policyStatusGauge = newPolicyStatusGauge(nil, nil)
+	// policyControlInfo has a series per root policy and each of its categories, standards, and controls so that
+	// policy_governance_info can be aggregated by them with a PromQL join on the policy and policy_namespace labels.
+	// Each series has exactly one of the category, standard, or control labels set.
+	policyControlInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_governance_control_info",
+			Help: "A series with the value 1 per root policy and each category, standard, and control in its " +
+				"annotations",
+		},
+		[]string{"policy", "policy_namespace", "category", "standard", "control"},
+	)
+	// policyComplianceTransitions counts the changes of each policy between Compliant and NonCompliant so that
+	// flapping policies can be alerted on. It has the base labels of policyStatusGauge and the direction of the change.
+	policyComplianceTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_compliance_transitions_total",
+			Help: "The number of times the named policy changed between Compliant and NonCompliant",
+		},
+		append(append([]string{}, statusGaugeLabels...), "direction"), // "to_compliant" or "to_noncompliant"
+	)
+	// policyDisabledInfo has a series with the value 1 per disabled policy, since disabled policies don't have a
+	// policyStatusGauge series. It has the base labels of policyStatusGauge.
+	policyDisabledInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_disabled",
+			Help: "A series with the value 1 per disabled policy",
+		},
+		statusGaugeLabels,
+	)
+	// policyStuckPending has a series with the value 1 per policy that has had no compliance state, or a Pending one,
+	// for longer than the stuck pending threshold, such as when the agent isn't installed on the managed cluster. It has
+	// the base labels of policyStatusGauge.
+	policyStuckPending = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_stuck_pending",
+			Help: "A series with the value 1 per policy that has been pending for longer than the threshold",
+		},
+		statusGaugeLabels,
+	)
+	// pendingStates maps the policyStatusGauge base labels of a policy, as returned by seriesKey, to the pendingState
+	// of the policy while it has no compliance state or a Pending one.
+	pendingStates sync.Map
+	// lastComplianceStates maps the policyStatusGauge base labels of a policy, as returned by seriesKey, to the last
+	// Compliant or NonCompliant state observed for it.
+	lastComplianceStates sync.Map
+	// controlInfoAnnotations maps the labels on policyControlInfo to the policy annotations their values are taken
+	// from.
+	controlInfoAnnotations = map[string]string{
+		"category": "policy.open-cluster-management.io/categories",
+		"standard": "policy.open-cluster-management.io/standards",
+		"control":  "policy.open-cluster-management.io/controls",
+	}
 )
 
-func init() {
-	metrics.Registry.MustRegister(
-		policyStatusGauge,
+func newPolicyStatusGauge(extraLabelNames []string, constLabels prometheus.Labels) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "policy_governance_info",
+			Help:        "The compliance status of the named policy. 0 == Compliant. 1 == NonCompliant",
+			ConstLabels: constLabels,
+		},
+		append(append([]string{}, statusGaugeLabels...), extraLabelNames...),
 	)
 }
+
+var (
+	// metricsLock is held for reading by the reconciles while they update the series and for writing while the series
+	// are reset on losing leadership, so that the reset is atomic relative to the reconciles.
+	metricsLock sync.RWMutex
+	// standby is true when this replica isn't the leader, in which case no series are exported so that they aren't
+	// counted twice with those of the leader. It's only accessed while metricsLock is held.
+	standby bool
+)
+
+// setLeading determines if the series are exported based on whether this replica is the leader. On losing
+// leadership, all the series are deleted. On acquiring leadership, the series are exported again as the policies are
+// reconciled.
+func setLeading(leading bool) {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	standby = !leading
+
+	if standby {
+		policyStatusGauge.Reset()
+		policyControlInfo.Reset()
+		policyComplianceTransitions.Reset()
+		policyDisabledInfo.Reset()
+		policyStuckPending.Reset()
+
+		for _, states := range []*sync.Map{&lastComplianceStates, &pendingStates} {
+			states.Range(func(key, _ any) bool {
+				states.Delete(key)
+
+				return true
+			})
+		}
+	}
+}
+
+// RegisterStatusGauge registers the policy_governance_info metric with additional labels, along with the
+// policy_governance_control_info, policy_compliance_transitions_total, policy_disabled, and policy_stuck_pending
+// metrics. policyLabels maps a metric label name to the policy label key whose value is used for it, and staticLabels
+// maps a metric label name to a constant value. The Prometheus registry doesn't allow the label names of a metric to
+// change once registered, so this must be called exactly once before the MetricReconciler is started. Nothing is
+// registered if the MetricReconciler isn't used.
+func RegisterStatusGauge(policyLabels map[string]string, staticLabels map[string]string) error {
+	reserved := make(map[string]bool, len(statusGaugeLabels))
+	for _, label := range statusGaugeLabels {
+		reserved[label] = true
+	}
+
+	extraLabelNames := make([]string, 0, len(policyLabels))
+
+	for label := range policyLabels {
+		if reserved[label] || !model.LabelName(label).IsValid() {
+			return fmt.Errorf("the policy metric label %s is invalid or reserved", label)
+		}
+
+		extraLabelNames = append(extraLabelNames, label)
+	}
+
+	sort.Strings(extraLabelNames)
+
+	for label := range staticLabels {
+		if reserved[label] || policyLabels[label] != "" || !model.LabelName(label).IsValid() {
+			return fmt.Errorf("the static policy metric label %s is invalid or reserved", label)
+		}
+	}
+
+	gauge := newPolicyStatusGauge(extraLabelNames, staticLabels)
+	collectors := []prometheus.Collector{
+		gauge, policyControlInfo, policyComplianceTransitions, policyDisabledInfo, policyStuckPending,
+	}
+
+	for i, collector := range collectors {
+		if err := metrics.Registry.Register(collector); err != nil {
+			// Unregister the ones already registered so that this can be called again
+			for _, registered := range collectors[:i] {
+				metrics.Registry.Unregister(registered)
+			}
+
+			return err
+		}
+	}
+
+	policyStatusGauge = gauge
+
+	if policyLabels != nil {
+		policyLabelKeys = policyLabels
+	}
+
+	return nil
+}
+
+// rootLabels returns the policyStatusGauge labels of the root policy with the input namespace and name.
+func rootLabels(namespace string, name string) prometheus.Labels {
+	return prometheus.Labels{
+		"type":              "root",
+		"policy":            name,
+		"policy_namespace":  namespace,
+		"cluster_namespace": missingLabelValue,
+	}
+}
+
+// propagatedLabels returns the policyStatusGauge labels of the replicated policy with the input cluster namespace and
+// name. If the name is not in the <root namespace>.<root name> format, ok is false.
+func propagatedLabels(clusterNamespace string, name string) (labels prometheus.Labels, ok bool) {
+	rootNamespace, rootName, ok := common.ParseReplicatedName(name)
+	if !ok {
+		return nil, false
+	}
+
+	return prometheus.Labels{
+		"type":              "propagated",
+		"policy":            rootName,
+		"policy_namespace":  rootNamespace,
+		"cluster_namespace": clusterNamespace,
+	}, true
+}
+
+// deletePolicySeries deletes all the series of the policy with the input namespace and name and returns how many were
+// deleted. Since the policy may no longer exist, and whether its namespace is a cluster namespace may have changed
+// since its series were exported, the series are deleted as both a root and a replicated policy. This is idempotent,
+// so it's safe to call when the series were already deleted, such as when the policy was disabled before it was
+// deleted.
+func deletePolicySeries(namespace string, name string) int {
+	deleted := policyStatusGauge.DeletePartialMatch(rootLabels(namespace, name))
+	deleted += deleteControlInfo(name, namespace)
+	deleted += deleteComplianceTransitions(rootLabels(namespace, name))
+	deleted += policyDisabledInfo.DeletePartialMatch(rootLabels(namespace, name))
+	deleted += deleteStuckPending(rootLabels(namespace, name))
+
+	// A name that is not in the replicated policy format never had a replicated policy series exported
+	if labels, ok := propagatedLabels(namespace, name); ok {
+		deleted += policyStatusGauge.DeletePartialMatch(labels)
+		deleted += deleteComplianceTransitions(labels)
+		deleted += policyDisabledInfo.DeletePartialMatch(labels)
+		deleted += deleteStuckPending(labels)
+	}
+
+	return deleted
+}
+
+// deleteClusterSeries deletes all the replicated policy series with the input cluster namespace label and returns how
+// many were deleted. This is used when a ManagedCluster is deleted so that its series don't linger until each of its
+// replicated policies is deleted and reconciled.
+func deleteClusterSeries(clusterNamespace string) int {
+	clusterLabels := prometheus.Labels{"type": "propagated", "cluster_namespace": clusterNamespace}
+
+	deleted := policyStatusGauge.DeletePartialMatch(clusterLabels)
+	deleted += policyComplianceTransitions.DeletePartialMatch(clusterLabels)
+	deleted += policyDisabledInfo.DeletePartialMatch(clusterLabels)
+	deleted += policyStuckPending.DeletePartialMatch(clusterLabels)
+
+	// The keys are in the format of seriesKey, and the namespaces and names can't contain a slash
+	for _, states := range []*sync.Map{&lastComplianceStates, &pendingStates} {
+		states.Range(func(key, _ any) bool {
+			if key, ok := key.(string); ok &&
+				strings.HasPrefix(key, "propagated/") && strings.HasSuffix(key, "/"+clusterNamespace) {
+				states.Delete(key)
+			}
+
+			return true
+		})
+	}
+
+	return deleted
+}
+
+// withPolicyLabels returns a copy of the input labels with the configured policy-derived labels added from the input
+// policy labels. Missing policy labels are set to a sentinel value.
+func withPolicyLabels(promLabels prometheus.Labels, policyLabels map[string]string) prometheus.Labels {
+	fullLabels := make(prometheus.Labels, len(promLabels)+len(policyLabelKeys))
+
+	for label, value := range promLabels {
+		fullLabels[label] = value
+	}
+
+	for label, key := range policyLabelKeys {
+		value, ok := policyLabels[key]
+		if !ok || value == "" {
+			value = missingLabelValue
+		}
+
+		fullLabels[label] = value
+	}
+
+	return fullLabels
+}
+
+// setControlInfo replaces the policyControlInfo series of the root policy with a series per category, standard, and
+// control in the input policy annotations. The annotation values are comma-separated lists.
+func setControlInfo(policy string, namespace string, annotations map[string]string) {
+	deleteControlInfo(policy, namespace)
+
+	for label, annotation := range controlInfoAnnotations {
+		for _, value := range strings.Split(annotations[annotation], ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			infoLabels := prometheus.Labels{
+				"policy":           policy,
+				"policy_namespace": namespace,
+				"category":         "",
+				"standard":         "",
+				"control":          "",
+			}
+			infoLabels[label] = value
+
+			policyControlInfo.With(infoLabels).Set(1)
+		}
+	}
+}
+
+// deleteControlInfo deletes all the policyControlInfo series of the root policy and returns the number deleted.
+func deleteControlInfo(policy string, namespace string) int {
+	return policyControlInfo.DeletePartialMatch(prometheus.Labels{"policy": policy, "policy_namespace": namespace})
+}
+
+// seriesKey returns a key that uniquely identifies the policy with the input policyStatusGauge base labels.
+func seriesKey(promLabels prometheus.Labels) string {
+	return fmt.Sprintf(
+		"%s/%s/%s/%s",
+		promLabels["type"], promLabels["policy_namespace"], promLabels["policy"], promLabels["cluster_namespace"],
+	)
+}
+
+// recordComplianceTransition increments policyComplianceTransitions when the input compliance state of the policy
+// with the input policyStatusGauge base labels differs from the last one observed. Other states, such as Pending, are
+// not considered a change. The first state observed for a policy, such as after a restart, is only recorded, so a
+// transition is never counted twice.
+func recordComplianceTransition(promLabels prometheus.Labels, state policiesv1.ComplianceState) {
+	if state != policiesv1.Compliant && state != policiesv1.NonCompliant {
+		return
+	}
+
+	previous, loaded := lastComplianceStates.Swap(seriesKey(promLabels), state)
+	if !loaded || previous == state {
+		return
+	}
+
+	transitionLabels := make(prometheus.Labels, len(promLabels)+1)
+	for label, value := range promLabels {
+		transitionLabels[label] = value
+	}
+
+	transitionLabels["direction"] = "to_noncompliant"
+	if state == policiesv1.Compliant {
+		transitionLabels["direction"] = "to_compliant"
+	}
+
+	policyComplianceTransitions.With(transitionLabels).Inc()
+}
+
+// deleteComplianceTransitions deletes the policyComplianceTransitions series and the last observed compliance state
+// of the policy with the input policyStatusGauge base labels, and returns the number of series deleted.
+func deleteComplianceTransitions(promLabels prometheus.Labels) int {
+	lastComplianceStates.Delete(seriesKey(promLabels))
+
+	return policyComplianceTransitions.DeletePartialMatch(promLabels)
+}
+
+// pendingState is the state of a policy while it has no compliance state or a Pending one.
+type pendingState struct {
+	// since is when the policy was first observed as pending. After a restart or a change of leader, this is when it
+	// was first observed by this replica.
+	since time.Time
+	// stuck is set once the policy has been pending for longer than the threshold.
+	stuck bool
+}
+
+// recordPending tracks how long the policy with the input policyStatusGauge base labels has had the input compliance
+// state, when it's empty or Pending, and sets its policyStuckPending series once that is longer than the input
+// threshold. newlyStuck is true when the series was just set. When the policy isn't stuck yet, remaining is how long
+// until it would be. Any other compliance state, or a zero threshold, deletes the series and the tracked state.
+func recordPending(
+	promLabels prometheus.Labels, state policiesv1.ComplianceState, threshold time.Duration,
+) (newlyStuck bool, remaining time.Duration) {
+	if threshold <= 0 || (state != "" && state != policiesv1.Pending) {
+		deleteStuckPending(promLabels)
+
+		return false, 0
+	}
+
+	key := seriesKey(promLabels)
+
+	loaded, _ := pendingStates.LoadOrStore(key, pendingState{since: time.Now()})
+	pending := loaded.(pendingState) //nolint:forcetypeassert
+
+	remaining = threshold - time.Since(pending.since)
+	if remaining > 0 {
+		return false, remaining
+	}
+
+	policyStuckPending.With(promLabels).Set(1)
+
+	if pending.stuck {
+		return false, 0
+	}
+
+	pending.stuck = true
+	pendingStates.Store(key, pending)
+
+	return true, 0
+}
+
+// deleteStuckPending deletes the policyStuckPending series and the tracked pending state of the policy with the input
+// policyStatusGauge base labels, and returns the number of series deleted.
+func deleteStuckPending(promLabels prometheus.Labels) int {
+	pendingStates.Delete(seriesKey(promLabels))
+
+	return policyStuckPending.DeletePartialMatch(promLabels)
+}
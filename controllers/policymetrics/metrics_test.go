@@ -0,0 +1,151 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package policymetrics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestRegisterStatusGauge(t *testing.T) {
+	invalid := []map[string]string{
+		{"policy": "category"},
+		{"not-valid": "category"},
+	}
+
+	for _, policyLabels := range invalid {
+		if err := RegisterStatusGauge(policyLabels, nil); err == nil {
+			t.Fatalf("expected an error for the labels %v", policyLabels)
+		}
+	}
+
+	err := RegisterStatusGauge(map[string]string{"team": "example.com/team"}, map[string]string{"hub": "hub1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	baseLabels := prometheus.Labels{
+		"type":              "root",
+		"policy":            "my-policy",
+		"policy_namespace":  "policies",
+		"cluster_namespace": "<null>",
+	}
+
+	tests := map[string]struct {
+		policyLabels map[string]string
+		expectedTeam string
+	}{
+		"label set":     {map[string]string{"example.com/team": "security"}, "security"},
+		"label missing": {map[string]string{"other": "value"}, missingLabelValue},
+		"no labels":     {nil, missingLabelValue},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fullLabels := withPolicyLabels(baseLabels, test.policyLabels)
+
+			expected := prometheus.Labels{"team": test.expectedTeam}
+			for label, value := range baseLabels {
+				expected[label] = value
+			}
+
+			if !reflect.DeepEqual(fullLabels, expected) {
+				t.Fatalf("expected labels %v, got %v", expected, fullLabels)
+			}
+
+			if _, err := policyStatusGauge.GetMetricWith(fullLabels); err != nil {
+				t.Fatalf("expected the labels to match the gauge: %v", err)
+			}
+
+			if policyStatusGauge.DeletePartialMatch(baseLabels) != 1 {
+				t.Fatal("expected the series to be deleted by the base labels")
+			}
+		})
+	}
+}
+
+func TestSetControlInfo(t *testing.T) {
+	annotations := map[string]string{
+		"policy.open-cluster-management.io/categories": "CM Configuration Management, AC Access Control",
+		"policy.open-cluster-management.io/standards":  "NIST SP 800-53",
+		"policy.open-cluster-management.io/controls":   "CM-2 Baseline Configuration,",
+	}
+
+	setControlInfo("my-policy", "policies", annotations)
+	setControlInfo("other-policy", "policies", annotations)
+
+	if count := testutil.CollectAndCount(policyControlInfo); count != 8 {
+		t.Fatalf("expected 8 series, got %d", count)
+	}
+
+	series := prometheus.Labels{
+		"policy":           "my-policy",
+		"policy_namespace": "policies",
+		"category":         "AC Access Control",
+		"standard":         "",
+		"control":          "",
+	}
+	if value := testutil.ToFloat64(policyControlInfo.With(series)); value != 1 {
+		t.Fatalf("expected the series %v to have the value 1, got %v", series, value)
+	}
+
+	// Removing an annotation removes its series
+	delete(annotations, "policy.open-cluster-management.io/categories")
+	setControlInfo("my-policy", "policies", annotations)
+
+	if count := testutil.CollectAndCount(policyControlInfo); count != 6 {
+		t.Fatalf("expected 6 series after removing the categories, got %d", count)
+	}
+
+	if deleted := deleteControlInfo("my-policy", "policies"); deleted != 2 {
+		t.Fatalf("expected 2 series to be deleted, got %d", deleted)
+	}
+
+	deleteControlInfo("other-policy", "policies")
+}
+
+func TestRecordComplianceTransition(t *testing.T) {
+	labels := rootLabels("policies", "flapping-policy")
+	defer deleteComplianceTransitions(labels)
+
+	// The first state observed, such as after a restart, isn't a transition
+	states := []policiesv1.ComplianceState{
+		policiesv1.NonCompliant,
+		policiesv1.NonCompliant,
+		policiesv1.Compliant,
+		policiesv1.Pending,
+		policiesv1.NonCompliant,
+		policiesv1.Compliant,
+	}
+
+	for _, state := range states {
+		recordComplianceTransition(labels, state)
+	}
+
+	for direction, expected := range map[string]float64{"to_compliant": 2, "to_noncompliant": 1} {
+		transitionLabels := prometheus.Labels{"direction": direction}
+		for label, value := range labels {
+			transitionLabels[label] = value
+		}
+
+		if value := testutil.ToFloat64(policyComplianceTransitions.With(transitionLabels)); value != expected {
+			t.Fatalf("expected %v transitions %s, got %v", expected, direction, value)
+		}
+	}
+
+	if deleted := deleteComplianceTransitions(labels); deleted != 2 {
+		t.Fatalf("expected 2 series to be deleted, got %d", deleted)
+	}
+
+	// The state is forgotten along with the series
+	recordComplianceTransition(labels, policiesv1.NonCompliant)
+
+	if count := testutil.CollectAndCount(policyComplianceTransitions); count != 0 {
+		t.Fatalf("expected no transitions after the state was forgotten, got %d series", count)
+	}
+}
@@ -5,43 +5,112 @@ package policymetrics
 
 import (
 	"context"
-	"strings"
+	"fmt"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
-const ControllerName string = "policy-metrics"
+const (
+	ControllerName string = "policy-metrics"
+	// primePageSize is the number of policies listed per page when priming the series.
+	primePageSize = 500
+)
 
 var log = ctrl.Log.WithName(ControllerName)
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MetricReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Only the leader exports the series. This runs with leader election, so it's started when leadership is acquired,
+	// at which point the controller reconciles every policy, and it's stopped when leadership is lost. The series are
+	// primed from a list of the policies so that they're complete from the first scrape rather than only once the
+	// initial reconciles are done.
+	err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		setLeading(true)
+
+		if err := r.primeMetrics(ctx); err != nil {
+			// The reconciles still export the series, so this isn't fatal
+			log.Error(err, "Failed to prime the policy metrics")
+		}
+
+		<-ctx.Done()
+
+		log.Info("No longer the leader, so the policy metrics are reset")
+		setLeading(false)
+
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		// The work queue prevents the same item being reconciled concurrently:
 		// https://github.com/kubernetes-sigs/controller-runtime/issues/1416#issuecomment-899833144
 		WithOptions(controller.Options{MaxConcurrentReconciles: int(r.MaxConcurrentReconciles)}).
 		Named(ControllerName).
-		For(&policiesv1.Policy{}).
+		For(&policiesv1.Policy{}, builder.WithPredicates(common.WatchedPolicyPredicate)).
+		// The series of a deleted cluster are deleted directly rather than by enqueuing its replicated policies
+		Watches(&source.Kind{Type: &clusterv1.ManagedCluster{}}, handler.Funcs{DeleteFunc: r.handleClusterDeletion}).
 		Complete(r)
 }
 
+// handleClusterDeletion deletes the replicated policy series of the deleted ManagedCluster.
+func (r *MetricReconciler) handleClusterDeletion(evt event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+	metricsLock.RLock()
+	defer metricsLock.RUnlock()
+
+	if standby {
+		return
+	}
+
+	clusterNamespace := common.ReplicaNamespace(evt.Object.GetName())
+	seriesDeleted := deleteClusterSeries(clusterNamespace)
+
+	log.Info(
+		"The ManagedCluster was deleted, so its replicated policy series were deleted",
+		"cluster", evt.Object.GetName(), "series-deleted", seriesDeleted,
+	)
+}
+
 // blank assignment to verify that ReconcilePolicy implements reconcile.Reconciler
 var _ reconcile.Reconciler = &MetricReconciler{}
 
 // MetricReconciler reconciles the metrics for the Policy
 type MetricReconciler struct {
 	client.Client
+	// APIReader lists the policies from the API server in pages when priming the series. If it's not set, the
+	// client is used.
+	APIReader               client.Reader
 	MaxConcurrentReconciles uint
+	// ReportPropagatedMetrics determines if a series is exported for each replicated policy in addition to the root
+	// policy series. Disabling this greatly reduces the metric cardinality on large fleets.
+	ReportPropagatedMetrics bool
 	Scheme                  *runtime.Scheme
+	// StuckPendingThreshold is how long a policy can have no compliance state, or a Pending one, before its
+	// policy_stuck_pending series is set. If it's zero, the pending policies aren't tracked.
+	StuckPendingThreshold time.Duration
+	// Recorder emits an event on a policy when it becomes stuck pending. If it's not set, no events are emitted.
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=policies,verbs=get;list;watch;create;update;patch;delete
@@ -51,80 +120,157 @@ type MetricReconciler struct {
 // Reconcile reads the state of the cluster for the Policy object and ensures that the exported
 // policy metrics are accurate, updating them as necessary.
 func (r *MetricReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+
+	result, err := r.reconcile(ctx, request)
+
+	common.ObserveReconcile(ControllerName, start, err)
+
+	return result, err
+}
+
+func (r *MetricReconciler) reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
 	log := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	metricsLock.RLock()
+	defer metricsLock.RUnlock()
+
+	if standby {
+		log.V(2).Info("Not the leader, so the metric for the policy is not exported")
+
+		return reconcile.Result{}, nil
+	}
+
 	log.Info("Reconciling metric for the policy")
 
-	// Need to know if the policy is a root policy to create the correct prometheus labels
-	// Can't try to use a label on the policy, because the policy might have been deleted.
-	inClusterNs, err := common.IsInClusterNamespace(r.Client, request.Namespace)
+	pol := &policiesv1.Policy{}
+
+	err := r.Get(ctx, request.NamespacedName, pol)
 	if err != nil {
-		log.Error(err, "Failed to determine if the policy is a replicated policy")
+		if errors.IsNotFound(err) {
+			// Try to delete the series, but don't get hung up on errors. Log whether any were deleted.
+			seriesDeleted := deletePolicySeries(request.Namespace, request.Name)
+			log.Info("Policy not found. It must have been deleted.", "series-deleted", seriesDeleted)
+
+			return reconcile.Result{}, nil
+		}
+
+		log.Error(err, "Failed to get Policy")
 
 		return reconcile.Result{}, err
 	}
 
-	var promLabels map[string]string
+	if !common.IsWatchedPolicy(pol) {
+		log.V(2).Info("The policy is not in a watched namespace, so the metric for the policy is not exported")
 
-	if inClusterNs {
-		// propagated policies should look like <namespace>.<name>
-		// also note: k8s namespace names follow RFC 1123 (so no "." in it)
-		splitName := strings.SplitN(request.Name, ".", 2)
-		if len(splitName) < 2 {
-			// Don't do any metrics if the policy is invalid.
-			log.Info("Invalid policy in cluster namespace: missing root policy ns prefix")
+		return reconcile.Result{}, nil
+	}
 
-			return reconcile.Result{}, nil
-		}
+	requeueAfter, err := r.exportPolicy(log, pol)
 
-		promLabels = prometheus.Labels{
-			"type":              "propagated",
-			"policy":            splitName[1],
-			"policy_namespace":  splitName[0],
-			"cluster_namespace": request.Namespace,
+	return reconcile.Result{RequeueAfter: requeueAfter}, err
+}
+
+// exportPolicy sets the series of the input policy based on its current state. The returned duration is when the
+// policy should be reconciled again to determine if it's stuck pending. The caller must hold metricsLock.
+func (r *MetricReconciler) exportPolicy(log logr.Logger, pol *policiesv1.Policy) (time.Duration, error) {
+	// Need to know if the policy is a root policy to create the correct prometheus labels
+	inClusterNs, err := common.IsInClusterNamespace(r.Client, pol.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to determine if the policy is a replicated policy")
+
+		return 0, err
+	}
+
+	// A replicated policy can linger in the namespace of a ManagedCluster that was just deleted, so its series are
+	// deleted rather than set. Its namespace may no longer be considered a cluster namespace at that point.
+	if inClusterNs || isReplicatedPolicy(pol) {
+		clusterExists, err := r.managedClusterExists(pol.Namespace)
+		if err != nil {
+			log.Error(err, "Failed to determine if the ManagedCluster of the replicated policy exists")
+
+			return 0, err
 		}
-	} else {
-		promLabels = prometheus.Labels{
-			"type":              "root",
-			"policy":            request.Name,
-			"policy_namespace":  request.Namespace,
-			"cluster_namespace": "<null>", // this is basically a sentinel value
+
+		if !clusterExists {
+			seriesDeleted := deletePolicySeries(pol.Namespace, pol.Name)
+			log.V(1).Info(
+				"The ManagedCluster of the replicated policy doesn't exist, so its series were deleted",
+				"series-deleted", seriesDeleted,
+			)
+
+			return 0, nil
 		}
 	}
 
-	pol := &policiesv1.Policy{}
+	var promLabels prometheus.Labels
 
-	err = r.Get(ctx, request.NamespacedName, pol)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Try to delete the gauge, but don't get hung up on errors. Log whether it was deleted.
-			statusGaugeDeleted := policyStatusGauge.Delete(promLabels)
-			log.Info("Policy not found. It must have been deleted.", "status-gauge-deleted", statusGaugeDeleted)
+	if inClusterNs {
+		var ok bool
 
-			return reconcile.Result{}, nil
+		promLabels, ok = propagatedLabels(pol.Namespace, pol.Name)
+		if !ok {
+			// Don't do any metrics if the policy is invalid.
+			log.Info("Invalid policy in cluster namespace: missing root policy ns prefix")
+
+			return 0, nil
 		}
 
-		log.Error(err, "Failed to get Policy")
+		if !r.ReportPropagatedMetrics {
+			// Delete the series in case it was exported before the propagated metrics were disabled
+			statusGaugeDeleted := policyStatusGauge.DeletePartialMatch(promLabels) > 0
+			deleteComplianceTransitions(promLabels)
+			policyDisabledInfo.DeletePartialMatch(promLabels)
+			deleteStuckPending(promLabels)
+			log.V(2).Info(
+				"Skipping the metric for the replicated policy since propagated metrics are disabled",
+				"status-gauge-deleted", statusGaugeDeleted,
+			)
 
-		return reconcile.Result{}, err
+			return 0, nil
+		}
+	} else {
+		promLabels = rootLabels(pol.Namespace, pol.Name)
 	}
 
 	log.V(2).Info("Got active state", "pol.Spec.Disabled", pol.Spec.Disabled)
 
 	if pol.Spec.Disabled {
 		// The policy is no longer active, so delete its metric
-		statusGaugeDeleted := policyStatusGauge.Delete(promLabels)
+		statusGaugeDeleted := policyStatusGauge.DeletePartialMatch(promLabels) > 0
+		deleteComplianceTransitions(promLabels)
+		deleteStuckPending(promLabels)
 		log.V(1).Info("Metric removed for non-active policy", "status-gauge-deleted", statusGaugeDeleted)
 
-		return reconcile.Result{}, nil
+		// The disabled policies are counted instead
+		policyDisabledInfo.With(promLabels).Set(1)
+
+		if !inClusterNs {
+			deleteControlInfo(pol.Name, pol.Namespace)
+		}
+
+		return 0, nil
+	}
+
+	policyDisabledInfo.DeletePartialMatch(promLabels)
+
+	if !inClusterNs {
+		// The annotations may have changed, so the existing series are replaced
+		setControlInfo(pol.Name, pol.Namespace, pol.GetAnnotations())
 	}
 
 	log.V(2).Info("Got ComplianceState", "pol.Status.ComplianceState", pol.Status.ComplianceState)
 
-	statusMetric, err := policyStatusGauge.GetMetricWith(promLabels)
+	if len(policyLabelKeys) != 0 {
+		// The policy-derived label values may have changed, so remove the existing series before setting it again
+		policyStatusGauge.DeletePartialMatch(promLabels)
+	}
+
+	statusMetric, err := policyStatusGauge.GetMetricWith(withPolicyLabels(promLabels, pol.GetLabels()))
 	if err != nil {
 		log.Error(err, "Failed to get status metric from GaugeVec")
 
-		return reconcile.Result{}, err
+		return 0, err
 	}
 
 	if pol.Status.ComplianceState == policiesv1.Compliant {
@@ -133,5 +279,105 @@ func (r *MetricReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 		statusMetric.Set(1)
 	}
 
-	return reconcile.Result{}, nil
+	recordComplianceTransition(promLabels, pol.Status.ComplianceState)
+
+	newlyStuck, remaining := recordPending(promLabels, pol.Status.ComplianceState, r.StuckPendingThreshold)
+	if newlyStuck {
+		log.Info("The policy is stuck pending", "threshold", r.StuckPendingThreshold.String())
+
+		if r.Recorder != nil {
+			r.Recorder.Event(pol, "Warning", "PolicyStuckPending", fmt.Sprintf(
+				"The policy has not reported a compliance state for more than %s, which may mean the agent isn't "+
+					"running on the managed cluster or a resource kind in the policy isn't installed there",
+				r.StuckPendingThreshold,
+			))
+		}
+	}
+
+	return remaining, nil
+}
+
+// isReplicatedPolicy returns true if the input policy has a root policy label that matches its name, as is the case for
+// every replicated policy, regardless of its namespace.
+func isReplicatedPolicy(pol *policiesv1.Policy) bool {
+	rootPlcName, err := common.GetRootPolicyLabel(pol)
+
+	return err == nil && rootPlcName != "" && rootPlcName == pol.Name
+}
+
+// managedClusterExists returns true if the ManagedCluster of the input replicated policy namespace exists. This is a
+// single Get by name, which is served from the cache.
+func (r *MetricReconciler) managedClusterExists(namespace string) (bool, error) {
+	clusterName, ok := common.ClusterNamespaceForReplica(namespace)
+	if !ok {
+		return false, nil
+	}
+
+	err := r.Get(context.TODO(), types.NamespacedName{Name: clusterName}, &clusterv1.ManagedCluster{})
+	if err == nil {
+		return true, nil
+	}
+
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// primeMetrics exports the series of every policy from a paginated list of the policies. metricsLock is held for
+// writing for the duration so that the reconciles, which may be handling newer versions of the policies, wait until
+// it's done and then update the series as usual. Errors on individual policies are logged so that the others are still
+// exported.
+func (r *MetricReconciler) primeMetrics(ctx context.Context) error {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	if standby {
+		return nil
+	}
+
+	start := time.Now()
+	primed := 0
+	continueToken := ""
+
+	for {
+		policyList := &policiesv1.PolicyList{}
+
+		err := reader.List(ctx, policyList, client.Limit(primePageSize), client.Continue(continueToken))
+		if err != nil {
+			return err
+		}
+
+		for i := range policyList.Items {
+			pol := &policyList.Items[i]
+
+			if !common.IsWatchedPolicy(pol) {
+				continue
+			}
+
+			log := log.WithValues("Request.Namespace", pol.Namespace, "Request.Name", pol.Name)
+
+			// The reconciles requeue the pending policies, so the returned duration isn't needed
+			if _, err := r.exportPolicy(log, pol); err != nil {
+				continue
+			}
+
+			primed++
+		}
+
+		continueToken = policyList.GetContinue()
+		if continueToken == "" {
+			break
+		}
+	}
+
+	log.Info("Primed the policy metrics", "policies", primed, "duration", time.Since(start).String())
+
+	return nil
 }
@@ -0,0 +1,487 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package policymetrics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func newMetricReconciler(t *testing.T, objs ...client.Object) *MetricReconciler {
+	t.Helper()
+
+	scheme := k8sruntime.NewScheme()
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	return &MetricReconciler{
+		Client:                  fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		ReportPropagatedMetrics: true,
+		Scheme:                  scheme,
+	}
+}
+
+func reconcileMetric(t *testing.T, r *MetricReconciler, namespace string, name string) {
+	t.Helper()
+
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error reconciling %s/%s: %v", namespace, name, err)
+	}
+}
+
+func TestReconcileDisabledThenDeleted(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+
+	rootPolicy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies"},
+		Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.NonCompliant},
+	}
+	replicatedPolicy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policies.my-policy", Namespace: "managed1"},
+		Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.NonCompliant},
+	}
+
+	r := newMetricReconciler(
+		t, rootPolicy, replicatedPolicy, &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	)
+	policies := []*policiesv1.Policy{rootPolicy, replicatedPolicy}
+
+	for _, policy := range policies {
+		reconcileMetric(t, r, policy.Namespace, policy.Name)
+	}
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 2 {
+		t.Fatalf("expected 2 series, got %d", count)
+	}
+
+	for _, policy := range policies {
+		policy.Spec.Disabled = true
+
+		if err := r.Update(context.TODO(), policy); err != nil {
+			t.Fatalf("failed to disable the policy: %v", err)
+		}
+
+		reconcileMetric(t, r, policy.Namespace, policy.Name)
+	}
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 0 {
+		t.Fatalf("expected the series to be deleted when the policies are disabled, got %d", count)
+	}
+
+	for _, policy := range policies {
+		if err := r.Delete(context.TODO(), policy); err != nil {
+			t.Fatalf("failed to delete the policy: %v", err)
+		}
+
+		// Reconciling the deletion twice is idempotent
+		reconcileMetric(t, r, policy.Namespace, policy.Name)
+		reconcileMetric(t, r, policy.Namespace, policy.Name)
+	}
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 0 {
+		t.Fatalf("expected no series after the policies were deleted, got %d", count)
+	}
+}
+
+func TestReconcileDisabledToggle(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+	defer policyDisabledInfo.Reset()
+
+	rootPolicy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "toggled-policy", Namespace: "policies"},
+		Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.Compliant},
+	}
+
+	r := newMetricReconciler(t, rootPolicy)
+
+	for _, disabled := range []bool{true, true, false, true} {
+		rootPolicy.Spec.Disabled = disabled
+
+		if err := r.Update(context.TODO(), rootPolicy); err != nil {
+			t.Fatalf("failed to update the policy: %v", err)
+		}
+
+		reconcileMetric(t, r, rootPolicy.Namespace, rootPolicy.Name)
+
+		expected := 0
+		if disabled {
+			expected = 1
+		}
+
+		if count := testutil.CollectAndCount(policyDisabledInfo); count != expected {
+			t.Fatalf("expected %d disabled series when disabled=%v, got %d", expected, disabled, count)
+		}
+
+		if count := testutil.CollectAndCount(policyStatusGauge); count != 1-expected {
+			t.Fatalf("expected %d status series when disabled=%v, got %d", 1-expected, disabled, count)
+		}
+	}
+
+	if err := r.Delete(context.TODO(), rootPolicy); err != nil {
+		t.Fatalf("failed to delete the policy: %v", err)
+	}
+
+	reconcileMetric(t, r, rootPolicy.Namespace, rootPolicy.Name)
+
+	if count := testutil.CollectAndCount(policyDisabledInfo); count != 0 {
+		t.Fatalf("expected no disabled series after the policy was deleted, got %d", count)
+	}
+}
+
+func TestReconcileStuckPending(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+	defer policyStuckPending.Reset()
+
+	const threshold = 100 * time.Millisecond
+
+	replicatedPolicy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policies.pending-policy", Namespace: "managed1"},
+	}
+
+	r := newMetricReconciler(
+		t, replicatedPolicy, &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	)
+	r.StuckPendingThreshold = threshold
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	request := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(replicatedPolicy)}
+
+	result, err := r.Reconcile(context.TODO(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.RequeueAfter <= 0 || result.RequeueAfter > threshold {
+		t.Fatalf("expected a requeue within the threshold, got %v", result.RequeueAfter)
+	}
+
+	if count := testutil.CollectAndCount(policyStuckPending); count != 0 {
+		t.Fatalf("expected no stuck pending series before the threshold, got %d", count)
+	}
+
+	time.Sleep(result.RequeueAfter)
+
+	// Reconciling again once stuck only emits a single event
+	for i := 0; i < 2; i++ {
+		reconcileMetric(t, r, replicatedPolicy.Namespace, replicatedPolicy.Name)
+
+		if count := testutil.CollectAndCount(policyStuckPending); count != 1 {
+			t.Fatalf("expected a stuck pending series after the threshold, got %d", count)
+		}
+	}
+
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected a single event, got %d", len(recorder.Events))
+	}
+
+	if event := <-recorder.Events; !strings.Contains(event, "PolicyStuckPending") {
+		t.Fatalf("expected a PolicyStuckPending event, got %s", event)
+	}
+
+	replicatedPolicy.Status.ComplianceState = policiesv1.Compliant
+
+	if err := r.Update(context.TODO(), replicatedPolicy); err != nil {
+		t.Fatalf("failed to update the policy: %v", err)
+	}
+
+	reconcileMetric(t, r, replicatedPolicy.Namespace, replicatedPolicy.Name)
+
+	if count := testutil.CollectAndCount(policyStuckPending); count != 0 {
+		t.Fatalf("expected the stuck pending series to be deleted once compliant, got %d", count)
+	}
+
+	if _, ok := pendingStates.Load("propagated/policies/pending-policy/managed1"); ok {
+		t.Fatal("expected the pending state to be forgotten once compliant")
+	}
+}
+
+func TestReconcileDeletedWithInvalidName(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+
+	replicatedPolicy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policies.my-policy", Namespace: "managed1"},
+		Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.Compliant},
+	}
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}}
+
+	r := newMetricReconciler(t, replicatedPolicy, managedCluster)
+
+	reconcileMetric(t, r, replicatedPolicy.Namespace, replicatedPolicy.Name)
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 1 {
+		t.Fatalf("expected 1 series, got %d", count)
+	}
+
+	// A policy without the root policy namespace prefix that no longer exists is ignored
+	reconcileMetric(t, r, "managed1", "invalid")
+
+	// The ManagedCluster is deleted before the replicated policy, so its namespace is no longer a cluster namespace
+	for _, obj := range []client.Object{managedCluster, replicatedPolicy} {
+		if err := r.Delete(context.TODO(), obj); err != nil {
+			t.Fatalf("failed to delete the object: %v", err)
+		}
+	}
+
+	reconcileMetric(t, r, replicatedPolicy.Namespace, replicatedPolicy.Name)
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 0 {
+		t.Fatalf("expected the replicated policy series to be deleted, got %d", count)
+	}
+}
+
+func TestHandleClusterDeletion(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+
+	objs := []client.Object{
+		&policiesv1.Policy{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "policies"},
+			Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.NonCompliant},
+		},
+	}
+
+	for _, cluster := range []string{"managed1", "managed2"} {
+		objs = append(objs,
+			&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: cluster}},
+			&policiesv1.Policy{
+				ObjectMeta: metav1.ObjectMeta{Name: "policies.my-policy", Namespace: cluster},
+				Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.NonCompliant},
+			},
+		)
+	}
+
+	r := newMetricReconciler(t, objs...)
+
+	for _, obj := range objs {
+		if _, ok := obj.(*policiesv1.Policy); ok {
+			reconcileMetric(t, r, obj.GetNamespace(), obj.GetName())
+		}
+	}
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 3 {
+		t.Fatalf("expected 3 series, got %d", count)
+	}
+
+	// The series are deleted when the ManagedCluster is deleted, before its replicated policy is reconciled
+	r.handleClusterDeletion(
+		event.DeleteEvent{Object: &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}}}, nil,
+	)
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 2 {
+		t.Fatalf("expected 2 series after the cluster was deleted, got %d", count)
+	}
+
+	if deleted := policyStatusGauge.DeletePartialMatch(prometheus.Labels{"cluster_namespace": "managed1"}); deleted != 0 {
+		t.Fatalf("expected the series of the deleted cluster to be deleted, found %d", deleted)
+	}
+
+	if deleted := policyStatusGauge.DeletePartialMatch(prometheus.Labels{"cluster_namespace": "managed2"}); deleted != 1 {
+		t.Fatalf("expected the series of the other cluster to be kept, found %d", deleted)
+	}
+
+	deleteControlInfo("my-policy", "policies")
+}
+
+func TestReconcileReplicaOfDeletedCluster(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+
+	replicatedPolicy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "policies.my-policy",
+			Namespace: "managed1",
+			Labels:    map[string]string{common.RootPolicyLabel: "policies.my-policy"},
+		},
+		Status: policiesv1.PolicyStatus{ComplianceState: policiesv1.NonCompliant},
+	}
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}}
+
+	r := newMetricReconciler(t, replicatedPolicy, cluster)
+
+	reconcileMetric(t, r, "managed1", "policies.my-policy")
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 1 {
+		t.Fatalf("expected 1 series, got %d", count)
+	}
+
+	// The replicated policy lingers after its ManagedCluster is deleted, so its series is deleted rather than being
+	// exported as a root policy
+	if err := r.Delete(context.TODO(), cluster); err != nil {
+		t.Fatalf("failed to delete the ManagedCluster: %v", err)
+	}
+
+	reconcileMetric(t, r, "managed1", "policies.my-policy")
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 0 {
+		t.Fatalf("expected no series after the cluster was deleted, got %d", count)
+	}
+}
+
+func TestReconcileStandby(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+	defer setLeading(true)
+
+	rootPolicy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-policy",
+			Namespace:   "policies",
+			Annotations: map[string]string{"policy.open-cluster-management.io/standards": "NIST SP 800-53"},
+		},
+		Status: policiesv1.PolicyStatus{ComplianceState: policiesv1.NonCompliant},
+	}
+
+	r := newMetricReconciler(t, rootPolicy)
+
+	reconcileMetric(t, r, rootPolicy.Namespace, rootPolicy.Name)
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 1 {
+		t.Fatalf("expected 1 series, got %d", count)
+	}
+
+	// Losing leadership deletes every series, and no series are exported while on standby
+	setLeading(false)
+
+	if count := testutil.CollectAndCount(policyStatusGauge) + testutil.CollectAndCount(policyControlInfo); count != 0 {
+		t.Fatalf("expected no series after losing leadership, got %d", count)
+	}
+
+	reconcileMetric(t, r, rootPolicy.Namespace, rootPolicy.Name)
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 0 {
+		t.Fatalf("expected no series while on standby, got %d", count)
+	}
+
+	setLeading(true)
+	reconcileMetric(t, r, rootPolicy.Namespace, rootPolicy.Name)
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 1 {
+		t.Fatalf("expected the series to be exported again after acquiring leadership, got %d", count)
+	}
+
+	deleteControlInfo(rootPolicy.Name, rootPolicy.Namespace)
+}
+
+// pagedReader lists the policies of the embedded client in pages of pageSize, ignoring the requested limit, so that
+// the pagination is exercised with the fake client.
+type pagedReader struct {
+	client.Client
+	pageSize int
+	lists    int
+}
+
+func (r *pagedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.lists++
+
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	//nolint:forcetypeassert
+	policyList := list.(*policiesv1.PolicyList)
+
+	if err := r.Client.List(ctx, policyList); err != nil {
+		return err
+	}
+
+	start, _ := strconv.Atoi(listOpts.Continue)
+	end := start + r.pageSize
+
+	if end < len(policyList.Items) {
+		policyList.Continue = strconv.Itoa(end)
+	} else {
+		end = len(policyList.Items)
+	}
+
+	policyList.Items = policyList.Items[start:end]
+
+	return nil
+}
+
+func TestPrimeMetrics(t *testing.T) {
+	policyStatusGauge.Reset()
+	defer policyStatusGauge.Reset()
+
+	objs := []client.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}}}
+
+	for i := 0; i < 5; i++ {
+		objs = append(objs,
+			&policiesv1.Policy{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("policy%d", i), Namespace: "policies"},
+				Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.NonCompliant},
+			},
+			&policiesv1.Policy{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("policies.policy%d", i), Namespace: "managed1"},
+				Status:     policiesv1.PolicyStatus{ComplianceState: policiesv1.Compliant},
+			},
+		)
+	}
+
+	r := newMetricReconciler(t, objs...)
+	reader := &pagedReader{Client: r.Client, pageSize: 3}
+	r.APIReader = reader
+
+	if err := r.primeMetrics(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reader.lists != 4 {
+		t.Fatalf("expected the 10 policies to be listed in 4 pages, got %d", reader.lists)
+	}
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 10 {
+		t.Fatalf("expected a series per policy, got %d", count)
+	}
+
+	// Priming again, such as after acquiring leadership again, doesn't duplicate the series
+	if err := r.primeMetrics(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 10 {
+		t.Fatalf("expected a series per policy after priming again, got %d", count)
+	}
+
+	// Nothing is exported while on standby
+	setLeading(false)
+	defer setLeading(true)
+
+	if err := r.primeMetrics(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if count := testutil.CollectAndCount(policyStatusGauge); count != 0 {
+		t.Fatalf("expected no series while on standby, got %d", count)
+	}
+}
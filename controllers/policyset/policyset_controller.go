@@ -302,7 +302,9 @@ func showCompliance(compliancesFound []string, unknown []string, pending []strin
 func getDecisions(c client.Client, pb policyv1.PlacementBinding,
 	instance *policyv1.Policy,
 ) ([]appsv1.PlacementDecision, error) {
-	if pb.PlacementRef.APIGroup == appsv1.SchemeGroupVersion.Group &&
+	if common.UsesClusterSelector(&pb) {
+		return common.GetClusterSelectorPlacementDecisions(c, pb, log)
+	} else if pb.PlacementRef.APIGroup == appsv1.SchemeGroupVersion.Group &&
 		pb.PlacementRef.Kind == "PlacementRule" {
 		d, err := common.GetApplicationPlacementDecisions(c, pb, instance, log)
 		if err != nil {
@@ -317,6 +319,14 @@ func getDecisions(c client.Client, pb policyv1.PlacementBinding,
 			return nil, err
 		}
 
+		return d, nil
+	} else if pb.PlacementRef.APIGroup == clusterv1beta1.SchemeGroupVersion.Group &&
+		pb.PlacementRef.Kind == "ManagedClusterSet" {
+		d, err := common.GetClusterSetPlacementDecisions(c, pb, instance, log)
+		if err != nil {
+			return nil, err
+		}
+
 		return d, nil
 	}
 
@@ -325,7 +335,7 @@ func getDecisions(c client.Client, pb policyv1.PlacementBinding,
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *PolicySetReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		Named(ControllerName).
 		For(
 			&policyv1beta1.PolicySet{},
@@ -340,11 +350,15 @@ func (r *PolicySetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			builder.WithPredicates(pbPredicateFuncs)).
 		Watches(
 			&source.Kind{Type: &appsv1.PlacementRule{}},
-			handler.EnqueueRequestsFromMapFunc(placementRuleMapper(mgr.GetClient()))).
-		Watches(
+			handler.EnqueueRequestsFromMapFunc(placementRuleMapper(mgr.GetClient())))
+
+	if common.PlacementAPIAvailable() {
+		builder.Watches(
 			&source.Kind{Type: &clusterv1beta1.PlacementDecision{}},
-			handler.EnqueueRequestsFromMapFunc(placementDecisionMapper(mgr.GetClient()))).
-		Complete(r)
+			handler.EnqueueRequestsFromMapFunc(placementDecisionMapper(mgr.GetClient())))
+	}
+
+	return builder.Complete(r)
 }
 
 // Helper function to filter out compliance statuses that are not in scope
@@ -392,8 +406,9 @@ func clusterInList(list []string, cluster string) bool {
 // Helper function to convert policy placement to policyset placement
 func plcPlacementToSetPlacement(plcPlacement policyv1.Placement) policyv1beta1.PolicySetStatusPlacement {
 	return policyv1beta1.PolicySetStatusPlacement{
-		PlacementBinding: plcPlacement.PlacementBinding,
-		Placement:        plcPlacement.Placement,
-		PlacementRule:    plcPlacement.PlacementRule,
+		PlacementBinding:  plcPlacement.PlacementBinding,
+		Placement:         plcPlacement.Placement,
+		PlacementRule:     plcPlacement.PlacementRule,
+		ManagedClusterSet: plcPlacement.ManagedClusterSet,
 	}
 }
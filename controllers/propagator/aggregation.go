@@ -2,25 +2,36 @@ package propagator
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
+// maxComplianceMessageLength is the length that the messages in the compliance history are truncated to.
+const maxComplianceMessageLength = 256
+
 // calculatePerClusterStatus lists up all policies replicated from the input policy, and stores
 // their compliance states in the result list. Additionally, clusters in the failedClusters input
-// will be marked as NonCompliant in the result. The result is sorted by cluster name. An error
-// will be returned if lookup of the replicated policies fails, and the retries also fail.
+// will be marked as NonCompliant in the result. The result is sorted by cluster name. The latest
+// compliance messages of the replicated policies are also returned, keyed by cluster namespace.
+// An error will be returned if lookup of the replicated policies fails, and the retries also fail.
 func (r *PolicyReconciler) calculatePerClusterStatus(
 	instance *policiesv1.Policy, allDecisions, failedClusters decisionSet,
-) ([]*policiesv1.CompliancePerClusterStatus, error) {
+) ([]*policiesv1.CompliancePerClusterStatus, map[string]string, error) {
 	if instance.Spec.Disabled {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	status := make([]*policiesv1.CompliancePerClusterStatus, 0, len(allDecisions))
+	messages := make(map[string]string, len(allDecisions))
 
 	// Update the status based on the processed decisions
 	for decision := range allDecisions {
@@ -32,14 +43,16 @@ func (r *PolicyReconciler) calculatePerClusterStatus(
 
 		rPlc := &policiesv1.Policy{}
 		key := types.NamespacedName{
-			Namespace: decision.ClusterNamespace, Name: instance.Namespace + "." + instance.Name,
+			Namespace: decision.ClusterNamespace, Name: common.FullNameForPolicy(instance),
 		}
 
 		err := r.Get(context.TODO(), key, rPlc)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
+		messages[decision.ClusterNamespace] = ComplianceMessage(rPlc)
+
 		status = append(status, &policiesv1.CompliancePerClusterStatus{
 			ComplianceState:  rPlc.Status.ComplianceState,
 			ClusterName:      decision.ClusterName,
@@ -66,7 +79,35 @@ func (r *PolicyReconciler) calculatePerClusterStatus(
 		return status[i].ClusterName < status[j].ClusterName
 	})
 
-	return status, nil
+	return status, messages, nil
+}
+
+// ComplianceMessage returns the latest compliance message of each policy template of the input replicated policy,
+// joined and truncated to be kept in the compliance history of the root policy.
+func ComplianceMessage(replicatedPolicy *policiesv1.Policy) string {
+	messages := make([]string, 0, len(replicatedPolicy.Status.Details))
+
+	for _, details := range replicatedPolicy.Status.Details {
+		if details == nil || len(details.History) == 0 || details.History[0].Message == "" {
+			continue
+		}
+
+		messages = append(messages, details.History[0].Message)
+	}
+
+	message := strings.Join(messages, "; ")
+	if len(message) > maxComplianceMessageLength {
+		end := maxComplianceMessageLength - 3
+
+		// Don't split a multibyte character
+		for end > 0 && !utf8.RuneStart(message[end]) {
+			end--
+		}
+
+		message = message[:end] + "..."
+	}
+
+	return message
 }
 
 // CalculateRootCompliance uses the input per-cluster statuses to determine what a root policy's
@@ -105,3 +146,110 @@ func CalculateRootCompliance(clusters []*policiesv1.CompliancePerClusterStatus)
 	// Returns compliant if, and only if, *all* cluster statuses are Compliant
 	return policiesv1.Compliant
 }
+
+// CalculateComplianceSummary uses the input per-cluster statuses to count how many clusters are Compliant. Clusters
+// that are Pending or have no compliance state only count toward the total. If there are no clusters, nil is returned
+// so that the summary is removed from the status.
+func CalculateComplianceSummary(clusters []*policiesv1.CompliancePerClusterStatus) *policiesv1.ComplianceSummary {
+	if len(clusters) == 0 {
+		return nil
+	}
+
+	compliant := 0
+
+	for _, status := range clusters {
+		if status.ComplianceState == policiesv1.Compliant {
+			compliant++
+		}
+	}
+
+	return &policiesv1.ComplianceSummary{
+		Summary:   fmt.Sprintf("%d/%d", compliant, len(clusters)),
+		Compliant: compliant,
+		Total:     len(clusters),
+	}
+}
+
+// RecordComplianceHistory adds an entry to the front of the compliance history of the input root policy for each
+// cluster whose compliance state changed from the input previous per-cluster statuses. The entry has the message of the
+// cluster from the input messages, keyed by cluster namespace. A change is not recorded again if it's already the
+// newest entry for the cluster, such as when both the propagator and the root policy status controller observe it. The
+// history is then trimmed to the input depth for each cluster, if it's positive, and to the input limit in total so
+// that the size of the status stays bounded for large fleets. If the limit is not positive, the history is removed.
+func RecordComplianceHistory(
+	rootPolicy *policiesv1.Policy,
+	previous []*policiesv1.CompliancePerClusterStatus,
+	messages map[string]string,
+	depth int,
+	limit int,
+	now time.Time,
+) {
+	if limit <= 0 {
+		rootPolicy.Status.ComplianceHistory = nil
+
+		return
+	}
+
+	previousStates := make(map[string]policiesv1.ComplianceState, len(previous))
+
+	for _, status := range previous {
+		previousStates[status.ClusterNamespace] = status.ComplianceState
+	}
+
+	// The history is newest first, so the first entry for a cluster is its latest recorded state
+	latestRecorded := map[string]policiesv1.ComplianceState{}
+
+	for _, transition := range rootPolicy.Status.ComplianceHistory {
+		if _, ok := latestRecorded[transition.ClusterNamespace]; !ok {
+			latestRecorded[transition.ClusterNamespace] = transition.ComplianceState
+		}
+	}
+
+	var transitions []policiesv1.ComplianceTransition
+
+	for _, status := range rootPolicy.Status.Status {
+		if status.ComplianceState == "" || status.ComplianceState == previousStates[status.ClusterNamespace] {
+			continue
+		}
+
+		if latest, ok := latestRecorded[status.ClusterNamespace]; ok && latest == status.ComplianceState {
+			continue
+		}
+
+		transitions = append(transitions, policiesv1.ComplianceTransition{
+			ClusterName:             status.ClusterName,
+			ClusterNamespace:        status.ClusterNamespace,
+			PreviousComplianceState: previousStates[status.ClusterNamespace],
+			ComplianceState:         status.ComplianceState,
+			Timestamp:               metav1.NewTime(now),
+			Message:                 messages[status.ClusterNamespace],
+		})
+	}
+
+	history := append(transitions, rootPolicy.Status.ComplianceHistory...)
+
+	if depth > 0 {
+		perCluster := map[string]int{}
+		trimmed := make([]policiesv1.ComplianceTransition, 0, len(history))
+
+		for _, transition := range history {
+			perCluster[transition.ClusterNamespace]++
+
+			if perCluster[transition.ClusterNamespace] <= depth {
+				trimmed = append(trimmed, transition)
+			}
+		}
+
+		history = trimmed
+	}
+
+	if len(history) > limit {
+		history = history[:limit]
+	}
+
+	if len(transitions) == 0 && len(history) == len(rootPolicy.Status.ComplianceHistory) {
+		return
+	}
+
+	rootPolicy.Status.ComplianceHistory = history
+}
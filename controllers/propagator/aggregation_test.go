@@ -2,7 +2,9 @@ package propagator
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 )
@@ -77,3 +79,197 @@ func TestCalculateRootCompliance(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculateComplianceSummary(t *testing.T) {
+	tests := map[string]struct {
+		input []*policiesv1.CompliancePerClusterStatus
+		want  *policiesv1.ComplianceSummary
+	}{
+		"no clusters": {
+			input: nil,
+			want:  nil,
+		},
+		"all compliant": {
+			input: []*policiesv1.CompliancePerClusterStatus{
+				fakeCPCS("articuno", "Compliant"), fakeCPCS("zapdos", "Compliant"),
+			},
+			want: &policiesv1.ComplianceSummary{Summary: "2/2", Compliant: 2, Total: 2},
+		},
+		"pending and unknown count toward the total": {
+			input: []*policiesv1.CompliancePerClusterStatus{
+				fakeCPCS("articuno", "Compliant"),
+				fakeCPCS("zapdos", "NonCompliant"),
+				fakeCPCS("moltres", "Pending"),
+				fakeCPCS("thud", ""),
+			},
+			want: &policiesv1.ComplianceSummary{Summary: "1/4", Compliant: 1, Total: 4},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			got := CalculateComplianceSummary(test.input)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("expected: %v, got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRecordComplianceHistory(t *testing.T) {
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	now := time.Now()
+
+	type entry struct {
+		cluster  string
+		previous policiesv1.ComplianceState
+		current  policiesv1.ComplianceState
+	}
+
+	record := func(previous, current []*policiesv1.CompliancePerClusterStatus, expected []entry) {
+		t.Helper()
+
+		rootPolicy.Status.Status = current
+		RecordComplianceHistory(&rootPolicy, previous, nil, 0, 3, now)
+
+		actual := make([]entry, 0, len(rootPolicy.Status.ComplianceHistory))
+		for _, transition := range rootPolicy.Status.ComplianceHistory {
+			actual = append(actual, entry{
+				transition.ClusterName, transition.PreviousComplianceState, transition.ComplianceState,
+			})
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected the history %v, got %v", expected, actual)
+		}
+	}
+
+	// The initial compliance states are recorded, except for clusters with no compliance state
+	record(
+		nil,
+		[]*policiesv1.CompliancePerClusterStatus{fakeCPCS("articuno", "Compliant"), fakeCPCS("zapdos", "")},
+		[]entry{{"articuno", "", "Compliant"}},
+	)
+
+	// The same transition observed by another controller is not recorded again
+	record(
+		nil,
+		[]*policiesv1.CompliancePerClusterStatus{fakeCPCS("articuno", "Compliant"), fakeCPCS("zapdos", "")},
+		[]entry{{"articuno", "", "Compliant"}},
+	)
+
+	// Only the clusters whose compliance state changed are recorded
+	record(
+		[]*policiesv1.CompliancePerClusterStatus{fakeCPCS("articuno", "Compliant"), fakeCPCS("zapdos", "")},
+		[]*policiesv1.CompliancePerClusterStatus{fakeCPCS("articuno", "NonCompliant"), fakeCPCS("zapdos", "")},
+		[]entry{{"articuno", "Compliant", "NonCompliant"}, {"articuno", "", "Compliant"}},
+	)
+
+	// The history is limited to the newest entries
+	record(
+		[]*policiesv1.CompliancePerClusterStatus{fakeCPCS("articuno", "NonCompliant"), fakeCPCS("zapdos", "")},
+		[]*policiesv1.CompliancePerClusterStatus{
+			fakeCPCS("articuno", "Compliant"), fakeCPCS("zapdos", "NonCompliant"),
+		},
+		[]entry{
+			{"articuno", "NonCompliant", "Compliant"},
+			{"zapdos", "", "NonCompliant"},
+			{"articuno", "Compliant", "NonCompliant"},
+		},
+	)
+
+	// The history is removed when it's disabled
+	RecordComplianceHistory(&rootPolicy, nil, nil, 0, 0, now)
+
+	if rootPolicy.Status.ComplianceHistory != nil {
+		t.Fatalf("expected the history to be removed, got %v", rootPolicy.Status.ComplianceHistory)
+	}
+}
+
+func TestRecordComplianceHistoryDepth(t *testing.T) {
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	now := time.Now()
+	previous := []*policiesv1.CompliancePerClusterStatus{}
+
+	// Each cluster alternates its compliance state, with the message of the replicated policy at the time
+	for _, state := range []string{"Compliant", "NonCompliant", "Compliant", "NonCompliant"} {
+		rootPolicy.Status.Status = []*policiesv1.CompliancePerClusterStatus{
+			fakeCPCS("articuno", state), fakeCPCS("zapdos", state),
+		}
+		messages := map[string]string{"articuno": state + " on articuno"}
+
+		RecordComplianceHistory(&rootPolicy, previous, messages, 2, 10, now)
+
+		previous = rootPolicy.Status.DeepCopy().Status
+	}
+
+	history := rootPolicy.Status.ComplianceHistory
+
+	// Only the newest two changes of each cluster are kept
+	if len(history) != 4 {
+		t.Fatalf("expected 4 entries in the history, got %d", len(history))
+	}
+
+	for i, cluster := range []string{"articuno", "zapdos", "articuno", "zapdos"} {
+		if history[i].ClusterNamespace != cluster {
+			t.Fatalf("expected entry %d to be for %s, got %s", i, cluster, history[i].ClusterNamespace)
+		}
+	}
+
+	if history[0].ComplianceState != "NonCompliant" || history[0].Message != "NonCompliant on articuno" {
+		t.Fatalf("expected the newest entry to have the latest state and message, got %+v", history[0])
+	}
+
+	if history[2].Message != "Compliant on articuno" || history[1].Message != "" {
+		t.Fatalf("expected the messages observed with each change, got %+v", history)
+	}
+
+	// The total limit still applies when the depth allows more entries
+	RecordComplianceHistory(&rootPolicy, previous, nil, 2, 3, now)
+
+	if len(rootPolicy.Status.ComplianceHistory) != 3 {
+		t.Fatalf("expected the history to be limited to 3 entries, got %d", len(rootPolicy.Status.ComplianceHistory))
+	}
+}
+
+func TestComplianceMessage(t *testing.T) {
+	detailsWith := func(messages ...string) []*policiesv1.DetailsPerTemplate {
+		details := []*policiesv1.DetailsPerTemplate{}
+
+		for _, message := range messages {
+			details = append(details, &policiesv1.DetailsPerTemplate{
+				History: []policiesv1.ComplianceHistory{{Message: message}, {Message: "older"}},
+			})
+		}
+
+		return details
+	}
+
+	tests := map[string]struct {
+		details  []*policiesv1.DetailsPerTemplate
+		expected string
+	}{
+		"No details":         {nil, ""},
+		"One template":       {detailsWith("NonCompliant; violation"), "NonCompliant; violation"},
+		"Multiple templates": {detailsWith("Compliant", "NonCompliant"), "Compliant; NonCompliant"},
+		"Truncated": {
+			detailsWith(strings.Repeat("a", maxComplianceMessageLength+1)),
+			strings.Repeat("a", maxComplianceMessageLength-3) + "...",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			replicatedPolicy := fakeRootPolicy("policies.my-policy", "cluster1")
+			replicatedPolicy.Status.Details = test.details
+
+			if message := ComplianceMessage(&replicatedPolicy); message != test.expected {
+				t.Fatalf("expected the message %q, got %q", test.expected, message)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// PolicyDebugPath is the path prefix on the metrics server that returns the state the propagator last acted on for a
+// root policy. The namespace and name of the root policy follow the prefix, such as /debug/policy/policies/my-policy.
+const PolicyDebugPath = "/debug/policy/"
+
+var (
+	// debugStates maps the namespaced name of a root policy to the *policyDebugState of its last reconcile.
+	debugStates sync.Map
+	// renderErrors maps a renderKey to the error message of the last failed hub template resolution for the cluster.
+	renderErrors sync.Map
+)
+
+// renderKey identifies the replicated policy of a root policy on a cluster.
+type renderKey struct {
+	root    types.NamespacedName
+	cluster string
+}
+
+// policyDebugState is the response of the PolicyDebugHandler.
+type policyDebugState struct {
+	Generation   int64       `json:"generation"`
+	ReconciledAt metav1.Time `json:"reconciledAt"`
+	// TargetError is why some placement bindings didn't place the policy on any cluster.
+	TargetError string `json:"targetError,omitempty"`
+	// Placements are the resolved placement bindings with the clusters each one selected.
+	Placements []*policiesv1.Placement `json:"placements"`
+	// Clusters are the clusters the policy was replicated to.
+	Clusters []clusterDebugState `json:"clusters"`
+}
+
+type clusterDebugState struct {
+	ClusterName      string `json:"clusterName"`
+	ClusterNamespace string `json:"clusterNamespace"`
+	// PendingDeletion is set when the cluster is no longer selected but the replicated policy is kept until the
+	// replica deletion grace period passes.
+	PendingDeletion bool `json:"pendingDeletion,omitempty"`
+	// Error is why the replicated policy couldn't be created or updated.
+	Error string `json:"error,omitempty"`
+	// RenderError is why the hub templates couldn't be resolved for the cluster.
+	RenderError string `json:"renderError,omitempty"`
+}
+
+// recordRenderError keeps the input hub template resolution error of the replicated policy of the root policy on the
+// cluster. A nil error forgets the previous one.
+func (r *PolicyReconciler) recordRenderError(
+	rootPlc *policiesv1.Policy, decision appsv1.PlacementDecision, err error,
+) {
+	if !r.RecordDebugState {
+		return
+	}
+
+	key := renderKey{
+		root:    types.NamespacedName{Namespace: rootPlc.Namespace, Name: rootPlc.Name},
+		cluster: decision.ClusterName,
+	}
+
+	if err == nil {
+		renderErrors.Delete(key)
+
+		return
+	}
+
+	renderErrors.Store(key, err.Error())
+}
+
+// recordDebugState keeps the state that the reconcile of the root policy acted on so that it can be returned by the
+// PolicyDebugHandler. The render errors of the clusters that are no longer selected are forgotten.
+func (r *PolicyReconciler) recordDebugState(
+	instance *policiesv1.Policy,
+	placements []*policiesv1.Placement,
+	allDecisions decisionSet,
+	pendingDeletion decisionSet,
+	clusterErrs clusterErrors,
+	targetErr error,
+) {
+	if !r.RecordDebugState {
+		return
+	}
+
+	root := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	state := &policyDebugState{
+		Generation:   instance.Generation,
+		ReconciledAt: metav1.NewTime(time.Now()),
+		Placements:   make([]*policiesv1.Placement, 0, len(placements)),
+		Clusters:     make([]clusterDebugState, 0, len(allDecisions)),
+	}
+
+	if targetErr != nil {
+		state.TargetError = targetErr.Error()
+	}
+
+	for _, placement := range placements {
+		state.Placements = append(state.Placements, placement.DeepCopy())
+	}
+
+	clusters := map[string]bool{}
+
+	for decision := range allDecisions {
+		clusters[decision.ClusterName] = true
+
+		cluster := clusterDebugState{
+			ClusterName:      decision.ClusterName,
+			ClusterNamespace: decision.ClusterNamespace,
+			PendingDeletion:  pendingDeletion[decision],
+		}
+
+		if err := clusterErrs[decision]; err != nil {
+			cluster.Error = err.Error()
+		}
+
+		if renderErr, ok := renderErrors.Load(renderKey{root: root, cluster: decision.ClusterName}); ok {
+			cluster.RenderError = renderErr.(string) //nolint:forcetypeassert
+		}
+
+		state.Clusters = append(state.Clusters, cluster)
+	}
+
+	sort.Slice(state.Clusters, func(i, j int) bool {
+		return state.Clusters[i].ClusterName < state.Clusters[j].ClusterName
+	})
+
+	renderErrors.Range(func(key, _ any) bool {
+		if key, ok := key.(renderKey); ok && key.root == root && !clusters[key.cluster] {
+			renderErrors.Delete(key)
+		}
+
+		return true
+	})
+
+	debugStates.Store(root, state)
+}
+
+// forgetDebugState removes the state kept for a root policy that was deleted.
+func forgetDebugState(root types.NamespacedName) {
+	debugStates.Delete(root)
+
+	renderErrors.Range(func(key, _ any) bool {
+		if key, ok := key.(renderKey); ok && key.root == root {
+			renderErrors.Delete(key)
+		}
+
+		return true
+	})
+}
+
+// PolicyDebugHandler returns a read-only HTTP handler that responds with the placements, clusters, and hub template
+// render errors that the last reconcile of a root policy acted on. This is read from the memory of the propagator
+// rather than the API server, so it's only available for the root policies reconciled since the propagator started,
+// and only when the PolicyReconciler is configured with RecordDebugState. Callers must provide a bearer token of a
+// user who is allowed to get policies at the cluster scope.
+func PolicyDebugHandler(authClient kubernetes.Interface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		log := log.WithName("policy-debug")
+
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		authStatus, err := authorize(req.Context(), authClient, req, "get")
+		if err != nil {
+			log.Error(err, "Failed to authorize the request")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if authStatus != http.StatusOK {
+			w.WriteHeader(authStatus)
+
+			return
+		}
+
+		namespace, name, found := strings.Cut(strings.TrimPrefix(req.URL.Path, PolicyDebugPath), "/")
+		if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		state, ok := debugStates.Load(types.NamespacedName{Namespace: namespace, Name: name})
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(state)
+	})
+}
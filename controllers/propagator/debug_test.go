@@ -0,0 +1,107 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestPolicyDebugHandler(t *testing.T) {
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	rootPolicy.Generation = 3
+	root := types.NamespacedName{Namespace: rootPolicy.Namespace, Name: rootPolicy.Name}
+
+	defer forgetDebugState(root)
+
+	managed1 := appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"}
+	managed2 := appsv1.PlacementDecision{ClusterName: "managed2", ClusterNamespace: "managed2"}
+	managed3 := appsv1.PlacementDecision{ClusterName: "managed3", ClusterNamespace: "managed3"}
+
+	reconciler := &PolicyReconciler{RecordDebugState: true}
+
+	reconciler.recordRenderError(&rootPolicy, managed1, errors.New("template: missing key"))
+	// The render error of a cluster that is no longer selected is forgotten
+	reconciler.recordRenderError(&rootPolicy, managed3, errors.New("template: missing key"))
+	reconciler.recordDebugState(
+		&rootPolicy,
+		[]*policiesv1.Placement{{
+			PlacementBinding: "pb1",
+			Decisions:        []appsv1.PlacementDecision{managed1, managed2},
+		}},
+		decisionSet{managed1: true, managed2: true},
+		decisionSet{managed2: true},
+		clusterErrors{managed1: errors.New("failed to update the replicated policy")},
+		nil,
+	)
+
+	if _, ok := renderErrors.Load(renderKey{root: root, cluster: "managed3"}); ok {
+		t.Fatal("expected the render error of the cluster that is no longer selected to be forgotten")
+	}
+
+	tests := map[string]struct {
+		path           string
+		allowed        bool
+		method         string
+		expectedStatus int
+	}{
+		"authorized":     {PolicyDebugPath + "policies/my-policy", true, http.MethodGet, http.StatusOK},
+		"not authorized": {PolicyDebugPath + "policies/my-policy", false, http.MethodGet, http.StatusForbidden},
+		"wrong method":   {PolicyDebugPath + "policies/my-policy", true, http.MethodPost, http.StatusMethodNotAllowed},
+		"not reconciled": {PolicyDebugPath + "policies/other", true, http.MethodGet, http.StatusNotFound},
+		"missing name":   {PolicyDebugPath + "policies", true, http.MethodGet, http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, nil)
+			req.Header.Set("Authorization", "Bearer valid-token")
+
+			recorder := httptest.NewRecorder()
+			PolicyDebugHandler(fakeAuthClient(test.allowed)).ServeHTTP(recorder, req)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			state := policyDebugState{}
+			if err := json.NewDecoder(recorder.Body).Decode(&state); err != nil {
+				t.Fatalf("failed to decode the response: %v", err)
+			}
+
+			if state.Generation != 3 || len(state.Placements) != 1 || len(state.Clusters) != 2 {
+				t.Fatalf("unexpected debug state: %+v", state)
+			}
+
+			expected := []clusterDebugState{
+				{
+					ClusterName:      "managed1",
+					ClusterNamespace: "managed1",
+					Error:            "failed to update the replicated policy",
+					RenderError:      "template: missing key",
+				},
+				{ClusterName: "managed2", ClusterNamespace: "managed2", PendingDeletion: true},
+			}
+
+			for i := range expected {
+				if state.Clusters[i] != expected[i] {
+					t.Fatalf("expected the cluster state %+v, got %+v", expected[i], state.Clusters[i])
+				}
+			}
+		})
+	}
+}
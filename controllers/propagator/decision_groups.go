@@ -0,0 +1,214 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+// DecisionGroupIndexLabel is set on a PlacementDecision to the index of the decision group of its clusters when the
+// Placement has decision groups.
+const DecisionGroupIndexLabel = "cluster.open-cluster-management.io/decision-group-index"
+
+// getDecisionGroups returns the index of the decision group of each cluster name selected by the Placements in the
+// input placements. When a cluster is in multiple decision groups, such as when it's selected by multiple
+// Placements, the lowest index is used.
+func (r *PolicyReconciler) getDecisionGroups(
+	namespace string, placements []*policiesv1.Placement,
+) (map[string]int, error) {
+	groups := map[string]int{}
+
+	if !common.PlacementAPIAvailable() {
+		return groups, nil
+	}
+
+	for _, placement := range placements {
+		if placement.Placement == "" {
+			continue
+		}
+
+		decisionList := &clusterv1beta1.PlacementDecisionList{}
+
+		err := r.List(
+			context.TODO(),
+			decisionList,
+			client.InNamespace(namespace),
+			client.MatchingLabels{"cluster.open-cluster-management.io/placement": placement.Placement},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, placementDecision := range decisionList.Items {
+			// PlacementDecisions without the label are from a Placement without decision groups
+			index, err := strconv.Atoi(placementDecision.GetLabels()[DecisionGroupIndexLabel])
+			if err != nil || index < 0 {
+				index = 0
+			}
+
+			for _, decision := range placementDecision.Status.Decisions {
+				if existing, ok := groups[decision.ClusterName]; !ok || index < existing {
+					groups[decision.ClusterName] = index
+				}
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// applyDecisionGroupRollout limits the input cluster decisions to the decision groups that the policy is rolled out
+// to, and returns them along with the rollout status to set on the root policy. The groups are rolled out in order
+// of their index, and groups without any clusters are skipped. The policy is rolled out to the next group once it's
+// Compliant on every cluster of the current group according to the root policy status, or once the configured
+// timeout has passed since the rollout to the current group started. The groups before the current group of the
+// previous rollout status are done, so the rollout never goes back to a group that it proceeded from, such as after a
+// timeout, unless the previous rollout status is for another generation of the root policy, in which case the new
+// generation is rolled out from the first group. Clusters in the later groups which already have the replicated
+// policy always keep it, but it's left as is so that they stay on the previous spec until their group is reached.
+// They are returned as the held decisions.
+func (r *PolicyReconciler) applyDecisionGroupRollout(
+	instance *policiesv1.Policy,
+	placements []*policiesv1.Placement,
+	decisions []clusterDecision,
+	now time.Time,
+) (selected []clusterDecision, held []clusterDecision, status *policiesv1.DecisionGroupRolloutStatus, err error) {
+	if instance.Spec.DecisionGroupRollout == nil {
+		return decisions, nil, nil, nil
+	}
+
+	groups, err := r.getDecisionGroups(instance.Namespace, placements)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	decisionsByGroup := map[int][]clusterDecision{}
+
+	for _, decision := range decisions {
+		index := groups[decision.Cluster.ClusterName]
+		decisionsByGroup[index] = append(decisionsByGroup[index], decision)
+	}
+
+	if len(decisionsByGroup) == 0 {
+		return decisions, nil, nil, nil
+	}
+
+	indexes := make([]int, 0, len(decisionsByGroup))
+	for index := range decisionsByGroup {
+		indexes = append(indexes, index)
+	}
+
+	sort.Ints(indexes)
+
+	compliant := make(map[string]bool, len(instance.Status.Status))
+	propagated := make(map[string]bool, len(instance.Status.Status))
+
+	for _, clusterStatus := range instance.Status.Status {
+		propagated[clusterStatus.ClusterNamespace] = true
+
+		if clusterStatus.ComplianceState == policiesv1.Compliant {
+			compliant[clusterStatus.ClusterNamespace] = true
+		}
+	}
+
+	previous := instance.Status.DecisionGroupRollout
+
+	// A rollout status without a generation is from before it was recorded, so it's for the current generation
+	if previous != nil && previous.Generation != 0 && previous.Generation != instance.Generation {
+		log.Info(
+			"Rolling out the new generation of the policy from the first decision group",
+			"policyName", instance.Name,
+			"policyNamespace", instance.Namespace,
+			"previousGeneration", previous.Generation,
+			"generation", instance.Generation,
+		)
+
+		previous = nil
+	}
+
+	timeout := instance.Spec.DecisionGroupRollout.Timeout
+	status = &policiesv1.DecisionGroupRolloutStatus{
+		CurrentGroup: int32(indexes[len(indexes)-1]),
+		Generation:   instance.Generation,
+		Completed:    true,
+	}
+
+	for _, index := range indexes {
+		if previous != nil && int32(index) < previous.CurrentGroup {
+			continue
+		}
+
+		groupDone := true
+
+		for _, decision := range decisionsByGroup[index] {
+			if !compliant[decision.Cluster.ClusterNamespace] {
+				groupDone = false
+
+				break
+			}
+		}
+
+		if !groupDone && timeout != nil && previous != nil && previous.CurrentGroup == int32(index) &&
+			now.Sub(previous.StartTime.Time) >= timeout.Duration {
+			log.Info(
+				"The policy is not Compliant on every cluster of the decision group before the timeout. Proceeding "+
+					"to the next decision group.",
+				"policyName", instance.Name, "policyNamespace", instance.Namespace, "decisionGroup", index,
+			)
+
+			groupDone = true
+		}
+
+		if !groupDone {
+			status.CurrentGroup = int32(index)
+			status.Completed = false
+
+			break
+		}
+	}
+
+	status.StartTime = metav1.NewTime(now)
+	if previous != nil && previous.CurrentGroup == status.CurrentGroup {
+		status.StartTime = previous.StartTime
+	}
+
+	selected = make([]clusterDecision, 0, len(decisions))
+
+	for _, decision := range decisions {
+		if groups[decision.Cluster.ClusterName] <= int(status.CurrentGroup) {
+			selected = append(selected, decision)
+		} else if propagated[decision.Cluster.ClusterNamespace] {
+			held = append(held, decision)
+		}
+	}
+
+	return selected, held, status, nil
+}
+
+// decisionGroupTimeoutRemaining returns how long until the rollout to the current decision group of the root policy
+// times out, or 0 if the rollout is completed or has no timeout.
+func decisionGroupTimeoutRemaining(instance *policiesv1.Policy, now time.Time) time.Duration {
+	rollout := instance.Status.DecisionGroupRollout
+
+	if instance.Spec.DecisionGroupRollout == nil || instance.Spec.DecisionGroupRollout.Timeout == nil ||
+		rollout == nil || rollout.Completed {
+		return 0
+	}
+
+	remaining := rollout.StartTime.Add(instance.Spec.DecisionGroupRollout.Timeout.Duration).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
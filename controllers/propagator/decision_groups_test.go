@@ -0,0 +1,319 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestApplyDecisionGroupRollout(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, clusterv1beta1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	clusters := fakePlacementDecisions(5)
+
+	// Group 1 is empty. cluster1 is in group 0, cluster2 and cluster3 are in group 2, and cluster4 and cluster5 are
+	// in group 3.
+	placementDecision := func(name string, group string, indexes ...int) *clusterv1beta1.PlacementDecision {
+		decision := &clusterv1beta1.PlacementDecision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					"cluster.open-cluster-management.io/placement": "my-placement",
+					DecisionGroupIndexLabel:                        group,
+				},
+			},
+		}
+
+		for _, i := range indexes {
+			decision.Status.Decisions = append(
+				decision.Status.Decisions, clusterv1beta1.ClusterDecision{ClusterName: clusters[i].ClusterName},
+			)
+		}
+
+		return decision
+	}
+
+	r := &PolicyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+			placementDecision("my-placement-decision-0", "0", 0),
+			placementDecision("my-placement-decision-1", "1"),
+			placementDecision("my-placement-decision-2", "2", 1, 2),
+			placementDecision("my-placement-decision-3", "3", 3, 4),
+		).Build(),
+	}
+
+	placements := []*policiesv1.Placement{{Placement: "my-placement"}}
+
+	decisions := make([]clusterDecision, 0, len(clusters))
+	for _, cluster := range clusters {
+		decisions = append(decisions, clusterDecision{Cluster: cluster})
+	}
+
+	now := time.Now()
+	started := metav1.NewTime(now.Add(-10 * time.Minute))
+
+	tests := map[string]struct {
+		timeout           *metav1.Duration
+		previous          *policiesv1.DecisionGroupRolloutStatus
+		compliantIndexes  []int
+		propagatedIndexes []int
+		expectedIndexes   []int
+		expectedHeld      []int
+		expectedGroup     int32
+		expectedCompleted bool
+		expectedStart     metav1.Time
+	}{
+		"Starts with the first group": {
+			expectedIndexes: []int{0},
+			expectedStart:   metav1.NewTime(now),
+		},
+		"Skips empty groups": {
+			previous:          &policiesv1.DecisionGroupRolloutStatus{StartTime: started},
+			compliantIndexes:  []int{0},
+			propagatedIndexes: []int{0},
+			expectedIndexes:   []int{0, 1, 2},
+			expectedGroup:     2,
+			expectedStart:     metav1.NewTime(now),
+		},
+		"Waits for every cluster of the group to be compliant": {
+			previous:          &policiesv1.DecisionGroupRolloutStatus{CurrentGroup: 2, StartTime: started},
+			compliantIndexes:  []int{0, 1},
+			propagatedIndexes: []int{0, 1, 2},
+			expectedIndexes:   []int{0, 1, 2},
+			expectedGroup:     2,
+			expectedStart:     started,
+		},
+		"Proceeds after the timeout": {
+			timeout:           &metav1.Duration{Duration: 5 * time.Minute},
+			previous:          &policiesv1.DecisionGroupRolloutStatus{CurrentGroup: 2, StartTime: started},
+			compliantIndexes:  []int{0, 1},
+			propagatedIndexes: []int{0, 1, 2},
+			expectedIndexes:   []int{0, 1, 2, 3, 4},
+			expectedGroup:     3,
+			expectedStart:     metav1.NewTime(now),
+		},
+		"Waits before the timeout": {
+			timeout:           &metav1.Duration{Duration: time.Hour},
+			previous:          &policiesv1.DecisionGroupRolloutStatus{CurrentGroup: 2, StartTime: started},
+			compliantIndexes:  []int{0, 1},
+			propagatedIndexes: []int{0, 1, 2},
+			expectedIndexes:   []int{0, 1, 2},
+			expectedGroup:     2,
+			expectedStart:     started,
+		},
+		"Holds propagated clusters in later groups": {
+			propagatedIndexes: []int{4},
+			expectedIndexes:   []int{0},
+			expectedHeld:      []int{4},
+			expectedStart:     metav1.NewTime(now),
+		},
+		"Restarts for a new generation": {
+			previous: &policiesv1.DecisionGroupRolloutStatus{
+				CurrentGroup: 3, Generation: 1, StartTime: started, Completed: true,
+			},
+			propagatedIndexes: []int{0, 1, 2, 3, 4},
+			expectedIndexes:   []int{0},
+			expectedHeld:      []int{1, 2, 3, 4},
+			expectedStart:     metav1.NewTime(now),
+		},
+		"Continues a rollout without a generation": {
+			previous:          &policiesv1.DecisionGroupRolloutStatus{CurrentGroup: 2, StartTime: started},
+			compliantIndexes:  []int{0},
+			propagatedIndexes: []int{0, 1, 2, 3},
+			expectedIndexes:   []int{0, 1, 2},
+			expectedHeld:      []int{3},
+			expectedGroup:     2,
+			expectedStart:     started,
+		},
+		"Completes when every group is compliant": {
+			previous:          &policiesv1.DecisionGroupRolloutStatus{CurrentGroup: 3, StartTime: started},
+			compliantIndexes:  []int{0, 1, 2, 3, 4},
+			propagatedIndexes: []int{0, 1, 2, 3, 4},
+			expectedIndexes:   []int{0, 1, 2, 3, 4},
+			expectedGroup:     3,
+			expectedCompleted: true,
+			expectedStart:     started,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			policy := fakeRootPolicy("test-policy", "default")
+			policy.Generation = 2
+			policy.Spec.DecisionGroupRollout = &policiesv1.DecisionGroupRollout{Timeout: test.timeout}
+			policy.Status.DecisionGroupRollout = test.previous
+
+			for _, i := range test.propagatedIndexes {
+				clusterStatus := &policiesv1.CompliancePerClusterStatus{
+					ClusterName:      clusters[i].ClusterName,
+					ClusterNamespace: clusters[i].ClusterNamespace,
+					ComplianceState:  policiesv1.NonCompliant,
+				}
+
+				for _, j := range test.compliantIndexes {
+					if i == j {
+						clusterStatus.ComplianceState = policiesv1.Compliant
+					}
+				}
+
+				policy.Status.Status = append(policy.Status.Status, clusterStatus)
+			}
+
+			selected, held, status, err := r.applyDecisionGroupRollout(&policy, placements, decisions, now)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := make([]clusterDecision, 0, len(test.expectedIndexes))
+			for _, i := range test.expectedIndexes {
+				expected = append(expected, clusterDecision{Cluster: clusters[i]})
+			}
+
+			assert.ElementsMatch(t, expected, selected)
+
+			expectedHeld := make([]clusterDecision, 0, len(test.expectedHeld))
+			for _, i := range test.expectedHeld {
+				expectedHeld = append(expectedHeld, clusterDecision{Cluster: clusters[i]})
+			}
+
+			assert.ElementsMatch(t, expectedHeld, held)
+
+			if status.Generation != policy.Generation {
+				t.Fatalf("Expected the generation %d, got %d", policy.Generation, status.Generation)
+			}
+
+			if status.CurrentGroup != test.expectedGroup || status.Completed != test.expectedCompleted {
+				t.Fatalf(
+					"Expected the current group %d and completed %v, got %d and %v",
+					test.expectedGroup, test.expectedCompleted, status.CurrentGroup, status.Completed,
+				)
+			}
+
+			if !status.StartTime.Equal(&test.expectedStart) {
+				t.Fatalf("Expected the start time %v, got %v", test.expectedStart, status.StartTime)
+			}
+		})
+	}
+
+	t.Run("Proceeds past timed out groups across reconciles", func(t *testing.T) {
+		policy := fakeRootPolicy("test-policy", "default")
+		policy.Generation = 1
+		policy.Spec.DecisionGroupRollout = &policiesv1.DecisionGroupRollout{
+			Timeout: &metav1.Duration{Duration: 5 * time.Minute},
+		}
+
+		// Only cluster1 in group 0 ever becomes Compliant
+		steps := []struct {
+			elapsed           time.Duration
+			expectedGroup     int32
+			expectedCompleted bool
+		}{
+			{0, 0, false},
+			{time.Minute, 2, false},
+			{7 * time.Minute, 3, false},
+			{8 * time.Minute, 3, false},
+			{9 * time.Minute, 3, false},
+			{13 * time.Minute, 3, true},
+			{14 * time.Minute, 3, true},
+		}
+
+		for _, step := range steps {
+			selected, _, status, err := r.applyDecisionGroupRollout(
+				&policy, placements, decisions, now.Add(step.elapsed),
+			)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if status.CurrentGroup != step.expectedGroup || status.Completed != step.expectedCompleted {
+				t.Fatalf(
+					"Expected the current group %d and completed %v after %v, got %d and %v",
+					step.expectedGroup, step.expectedCompleted, step.elapsed, status.CurrentGroup, status.Completed,
+				)
+			}
+
+			policy.Status.DecisionGroupRollout = status
+			policy.Status.Status = nil
+
+			for _, decision := range selected {
+				complianceState := policiesv1.NonCompliant
+				if decision.Cluster.ClusterName == clusters[0].ClusterName {
+					complianceState = policiesv1.Compliant
+				}
+
+				policy.Status.Status = append(policy.Status.Status, &policiesv1.CompliancePerClusterStatus{
+					ClusterName:      decision.Cluster.ClusterName,
+					ClusterNamespace: decision.Cluster.ClusterNamespace,
+					ComplianceState:  complianceState,
+				})
+			}
+		}
+
+		// A new generation of the policy is rolled out from the first group again, and the replicated policies of
+		// the later groups are held on the previous generation
+		policy.Generation++
+
+		for _, clusterStatus := range policy.Status.Status {
+			clusterStatus.ComplianceState = policiesv1.NonCompliant
+		}
+
+		selected, held, status, err := r.applyDecisionGroupRollout(&policy, placements, decisions, now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if status.CurrentGroup != 0 || status.Completed || len(selected) != 1 || len(held) != 4 {
+			t.Fatalf(
+				"Expected the rollout to restart from the first group, got the group %d, completed %v, %d selected "+
+					"and %d held clusters",
+				status.CurrentGroup, status.Completed, len(selected), len(held),
+			)
+		}
+	})
+}
+
+func TestDecisionGroupTimeoutRemaining(t *testing.T) {
+	now := time.Now()
+	policy := fakeRootPolicy("test-policy", "default")
+
+	if remaining := decisionGroupTimeoutRemaining(&policy, now); remaining != 0 {
+		t.Fatalf("Expected no timeout without a decision group rollout, got %v", remaining)
+	}
+
+	policy.Spec.DecisionGroupRollout = &policiesv1.DecisionGroupRollout{
+		Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+	}
+	policy.Status.DecisionGroupRollout = &policiesv1.DecisionGroupRolloutStatus{
+		StartTime: metav1.NewTime(now.Add(-4 * time.Minute)),
+	}
+
+	if remaining := decisionGroupTimeoutRemaining(&policy, now); remaining != 6*time.Minute {
+		t.Fatalf("Expected 6m remaining, got %v", remaining)
+	}
+
+	policy.Status.DecisionGroupRollout.Completed = true
+
+	if remaining := decisionGroupTimeoutRemaining(&policy, now); remaining != 0 {
+		t.Fatalf("Expected no timeout when the rollout is completed, got %v", remaining)
+	}
+}
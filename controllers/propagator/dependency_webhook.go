@@ -0,0 +1,209 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	// DependencyWebhookPath is the path on the webhook server of the DependencyValidator.
+	DependencyWebhookPath = "/validate-policy-dependencies"
+	// ForceDependencyOverrideAnnotation on a root policy set to true allows it to be disabled or deleted even though
+	// other policies depend on it.
+	ForceDependencyOverrideAnnotation = "policy.open-cluster-management.io/force-dependency-override"
+)
+
+// DependencyValidator is an admission handler for the updates and deletions of root policies. Disabling or deleting a
+// root policy that is in the dependencies or extraDependencies of another enabled root policy, directly or through a
+// PolicySet, is denied, or only warned about when WarnOnly is true, since the dependent policies would otherwise wait
+// on it forever. The ForceDependencyOverrideAnnotation bypasses the check.
+type DependencyValidator struct {
+	client.Client
+	// WarnOnly determines if the request is allowed with a warning naming the dependent policies rather than denied.
+	WarnOnly bool
+}
+
+// blank assignment to verify that DependencyValidator implements admission.Handler
+var _ admission.Handler = &DependencyValidator{}
+
+// Handle denies or warns about the disabling or deletion of a root policy that other root policies depend on.
+func (v *DependencyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Group != policiesv1.GroupVersion.Group || req.Kind.Kind != policiesv1.Kind {
+		return admission.Allowed("")
+	}
+
+	policy := &policiesv1.Policy{}
+
+	switch req.Operation {
+	case admissionv1.Delete:
+		if err := json.Unmarshal(req.OldObject.Raw, policy); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	case admissionv1.Update:
+		oldPolicy := &policiesv1.Policy{}
+
+		if err := json.Unmarshal(req.OldObject.Raw, oldPolicy); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		if err := json.Unmarshal(req.Object.Raw, policy); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		// Only the policy being disabled can wedge its dependents
+		if oldPolicy.Spec.Disabled || !policy.Spec.Disabled {
+			return admission.Allowed("")
+		}
+	default:
+		return admission.Allowed("")
+	}
+
+	// The replicated policies are managed by the propagator and follow their root policy
+	if rootPlcName, _ := common.GetRootPolicyLabel(policy); rootPlcName != "" {
+		return admission.Allowed("")
+	}
+
+	if strings.EqualFold(policy.GetAnnotations()[ForceDependencyOverrideAnnotation], "true") {
+		return admission.Allowed("")
+	}
+
+	dependents, err := v.dependentPolicies(ctx, policy)
+	if err != nil {
+		log.Error(err, "Failed to determine the dependent policies", "namespace", req.Namespace, "name", req.Name)
+
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if len(dependents) == 0 {
+		return admission.Allowed("")
+	}
+
+	action := "deleted"
+	if req.Operation == admissionv1.Update {
+		action = "disabled"
+	}
+
+	message := fmt.Sprintf(
+		"the policy %s/%s is a dependency of the policies %s, which won't be applied while it's %s; set the %s "+
+			"annotation to true to allow it",
+		policy.Namespace, policy.Name, strings.Join(dependents, ", "), action, ForceDependencyOverrideAnnotation,
+	)
+
+	if v.WarnOnly {
+		return admission.Allowed("").WithWarnings(message)
+	}
+
+	return admission.Denied(message)
+}
+
+// dependentPolicies returns the sorted namespaced names of the enabled root policies that depend on the input root
+// policy, directly or through a PolicySet.
+func (v *DependencyValidator) dependentPolicies(ctx context.Context, policy *policiesv1.Policy) ([]string, error) {
+	policyList := &policiesv1.PolicyList{}
+
+	if err := v.List(ctx, policyList); err != nil {
+		return nil, err
+	}
+
+	// Cache whether each PolicySet contains the policy since policies often share the same PolicySet dependencies
+	policySetMembership := map[types.NamespacedName]bool{}
+
+	dependsOn := func(dependent *policiesv1.Policy, dep policiesv1.PolicyDependency) (bool, error) {
+		namespace := dep.Namespace
+		if namespace == "" {
+			namespace = dependent.Namespace
+		}
+
+		if depIsPolicy(dep) {
+			// The replicated policy name format is also accepted since that is what the dependencies are converted to
+			return (namespace == policy.Namespace && dep.Name == policy.Name) ||
+				dep.Name == common.ReplicatedPolicyName(policy.Namespace, policy.Name), nil
+		}
+
+		if !depIsPolicySet(dep) || namespace != policy.Namespace {
+			return false, nil
+		}
+
+		policySetKey := types.NamespacedName{Namespace: namespace, Name: dep.Name}
+
+		member, ok := policySetMembership[policySetKey]
+		if ok {
+			return member, nil
+		}
+
+		policySet := &policiesv1beta1.PolicySet{}
+
+		err := v.Get(ctx, policySetKey, policySet)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+
+		for _, plc := range policySet.Spec.Policies {
+			if string(plc) == policy.Name {
+				member = true
+
+				break
+			}
+		}
+
+		policySetMembership[policySetKey] = member
+
+		return member, nil
+	}
+
+	var dependents []string
+
+	for i := range policyList.Items {
+		dependent := &policyList.Items[i]
+
+		if dependent.Spec.Disabled || dependent.DeletionTimestamp != nil ||
+			(dependent.Namespace == policy.Namespace && dependent.Name == policy.Name) {
+			continue
+		}
+
+		if rootPlcName, _ := common.GetRootPolicyLabel(dependent); rootPlcName != "" {
+			continue
+		}
+
+		deps := append([]policiesv1.PolicyDependency{}, dependent.Spec.Dependencies...)
+
+		for _, template := range dependent.Spec.PolicyTemplates {
+			if template != nil {
+				deps = append(deps, template.ExtraDependencies...)
+			}
+		}
+
+		for _, dep := range deps {
+			isDependent, err := dependsOn(dependent, dep)
+			if err != nil {
+				return nil, err
+			}
+
+			if isDependent {
+				dependents = append(dependents, dependent.Namespace+"/"+dependent.Name)
+
+				break
+			}
+		}
+	}
+
+	sort.Strings(dependents)
+
+	return dependents, nil
+}
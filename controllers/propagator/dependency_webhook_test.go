@@ -0,0 +1,239 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func policyDependency(kind, name, namespace string) policiesv1.PolicyDependency {
+	apiVersion := policiesv1.GroupVersion.String()
+	if kind == policiesv1.PolicySetKind {
+		apiVersion = policiesv1beta1.GroupVersion.String()
+	}
+
+	return policiesv1.PolicyDependency{
+		TypeMeta:   metav1.TypeMeta{Kind: kind, APIVersion: apiVersion},
+		Name:       name,
+		Namespace:  namespace,
+		Compliance: policiesv1.Compliant,
+	}
+}
+
+func dependencyAdmissionRequest(
+	t *testing.T, operation admissionv1.Operation, oldPolicy, policy *policiesv1.Policy,
+) admission.Request {
+	t.Helper()
+
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "policy.open-cluster-management.io", Version: "v1", Kind: "Policy"},
+		Operation: operation,
+		Namespace: oldPolicy.Namespace,
+		Name:      oldPolicy.Name,
+	}}
+
+	oldRaw, err := json.Marshal(oldPolicy)
+	if err != nil {
+		t.Fatalf("Failed to marshal the policy: %v", err)
+	}
+
+	req.OldObject.Raw = oldRaw
+
+	if policy != nil {
+		raw, err := json.Marshal(policy)
+		if err != nil {
+			t.Fatalf("Failed to marshal the policy: %v", err)
+		}
+
+		req.Object.Raw = raw
+	}
+
+	return req
+}
+
+func TestDependencyValidator(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	if err := policiesv1beta1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	directDependent := fakeRootPolicy("direct", "policies")
+	directDependent.Spec.Dependencies = []policiesv1.PolicyDependency{
+		policyDependency(policiesv1.Kind, "base", ""),
+	}
+
+	extraDependent := fakeRootPolicy("extra", "other")
+	extraDependent.Spec.PolicyTemplates = []*policiesv1.PolicyTemplate{{
+		ExtraDependencies: []policiesv1.PolicyDependency{policyDependency(policiesv1.Kind, "policies.base", "")},
+	}}
+
+	policySet := &policiesv1beta1.PolicySet{
+		ObjectMeta: metav1.ObjectMeta{Name: "base-set", Namespace: "policies"},
+		Spec:       policiesv1beta1.PolicySetSpec{Policies: []policiesv1beta1.NonEmptyString{"base", "other"}},
+	}
+	setDependent := fakeRootPolicy("set", "policies")
+	setDependent.Spec.Dependencies = []policiesv1.PolicyDependency{
+		policyDependency(policiesv1.PolicySetKind, "base-set", ""),
+	}
+
+	disabledDependent := fakeRootPolicy("disabled", "policies")
+	disabledDependent.Spec.Disabled = true
+	disabledDependent.Spec.Dependencies = directDependent.Spec.Dependencies
+
+	replicatedDependent := fakeRootPolicy("policies.direct", "cluster1")
+	replicatedDependent.Labels = map[string]string{common.RootPolicyLabel: "policies.direct"}
+	replicatedDependent.Spec.Dependencies = []policiesv1.PolicyDependency{
+		policyDependency(policiesv1.Kind, "policies.base", ""),
+	}
+
+	otherNamespace := fakeRootPolicy("unrelated", "other")
+	otherNamespace.Spec.Dependencies = []policiesv1.PolicyDependency{
+		policyDependency(policiesv1.Kind, "base", ""),
+	}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&directDependent, &extraDependent, policySet, &setDependent, &disabledDependent, &replicatedDependent,
+		&otherNamespace,
+	).Build()
+
+	expectedDependents := "other/extra, policies/direct, policies/set"
+
+	tests := map[string]struct {
+		operation       admissionv1.Operation
+		update          func(policy *policiesv1.Policy)
+		name            string
+		warnOnly        bool
+		expectedAllowed bool
+		expectedMessage string
+	}{
+		"Deletion is blocked": {
+			operation:       admissionv1.Delete,
+			name:            "base",
+			expectedMessage: expectedDependents,
+		},
+		"Disabling is blocked": {
+			operation:       admissionv1.Update,
+			update:          func(policy *policiesv1.Policy) { policy.Spec.Disabled = true },
+			name:            "base",
+			expectedMessage: "while it's disabled",
+		},
+		"Deletion is only warned about": {
+			operation:       admissionv1.Delete,
+			name:            "base",
+			warnOnly:        true,
+			expectedAllowed: true,
+			expectedMessage: expectedDependents,
+		},
+		"Forced deletion": {
+			operation: admissionv1.Delete,
+			update: func(policy *policiesv1.Policy) {
+				policy.Annotations = map[string]string{ForceDependencyOverrideAnnotation: "true"}
+			},
+			name:            "base",
+			expectedAllowed: true,
+		},
+		"Forced disabling": {
+			operation: admissionv1.Update,
+			update: func(policy *policiesv1.Policy) {
+				policy.Spec.Disabled = true
+				policy.Annotations = map[string]string{ForceDependencyOverrideAnnotation: "true"}
+			},
+			name:            "base",
+			expectedAllowed: true,
+		},
+		"No dependents": {
+			operation:       admissionv1.Delete,
+			name:            "standalone",
+			expectedAllowed: true,
+		},
+		"Update that doesn't disable": {
+			operation:       admissionv1.Update,
+			update:          func(policy *policiesv1.Policy) { policy.Spec.RemediationAction = policiesv1.Enforce },
+			name:            "base",
+			expectedAllowed: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			oldPolicy := fakeRootPolicy(test.name, "policies")
+
+			var policy *policiesv1.Policy
+
+			if test.update != nil {
+				updated := oldPolicy.DeepCopy()
+				test.update(updated)
+
+				if test.operation == admissionv1.Delete {
+					oldPolicy = *updated
+				} else {
+					policy = updated
+				}
+			}
+
+			validator := &DependencyValidator{Client: c, WarnOnly: test.warnOnly}
+			resp := validator.Handle(
+				context.TODO(), dependencyAdmissionRequest(t, test.operation, &oldPolicy, policy),
+			)
+
+			if resp.Allowed != test.expectedAllowed {
+				t.Fatalf("Expected the request to be allowed to be %v, got %+v", test.expectedAllowed, resp.Result)
+			}
+
+			message := strings.Join(resp.Warnings, "")
+			if resp.Result != nil {
+				message += string(resp.Result.Reason)
+			}
+
+			if test.expectedMessage == "" && message != "" {
+				t.Fatalf("Expected no message, got %q", message)
+			}
+
+			if !strings.Contains(message, test.expectedMessage) {
+				t.Fatalf("Expected the message to contain %q, got %q", test.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestDependencyValidatorReplicatedPolicy(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	dependent := fakeRootPolicy("dependent", "policies")
+	dependent.Spec.Dependencies = []policiesv1.PolicyDependency{policyDependency(policiesv1.Kind, "base", "")}
+
+	replicated := fakeRootPolicy("policies.base", "cluster1")
+	replicated.Labels = map[string]string{common.RootPolicyLabel: "policies.base"}
+
+	validator := &DependencyValidator{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects([]client.Object{&dependent}...).Build(),
+	}
+
+	resp := validator.Handle(context.TODO(), dependencyAdmissionRequest(t, admissionv1.Delete, &replicated, nil))
+	if !resp.Allowed {
+		t.Fatalf("Expected the deletion of a replicated policy to be allowed, got %+v", resp.Result)
+	}
+}
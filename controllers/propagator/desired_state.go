@@ -0,0 +1,160 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+var (
+	// desiredStates maps the namespaced name of a root policy to the fingerprint, as returned by
+	// desiredStateFingerprint, of the inputs of its last reconcile that fully propagated it without a requeue.
+	desiredStates sync.Map
+	// clusterEpoch is incremented on every ManagedCluster and ManagedClusterSet event that enqueues root policies, since
+	// the clusters can change the decisions, cluster overrides, and pinned generations of any root policy.
+	clusterEpoch atomic.Uint64
+)
+
+// desiredStateFingerprint returns a hash of the inputs of the propagation of the input root policy: its generation,
+// labels, and annotations, the resource versions of the placement bindings, placement rules, placement decisions,
+// policy sets, and cluster set bindings in its namespace, the resource versions of its replicated policies, and the
+// clusterEpoch. These are all read from the cache, so this doesn't make any API requests. An empty string is returned
+// when the root policy has hub templates since they can change the replicated policies without changing any of these
+// inputs.
+func (r *PolicyReconciler) desiredStateFingerprint(ctx context.Context, instance *policiesv1.Policy) (string, error) {
+	if policyHasTemplates(instance) {
+		return "", nil
+	}
+
+	root, err := json.Marshal(map[string]interface{}{
+		"generation":  instance.Generation,
+		"labels":      instance.Labels,
+		"annotations": instance.Annotations,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	inputs := []string{string(root), fmt.Sprintf("clusterEpoch=%d", clusterEpoch.Load())}
+
+	namespacedLists := []client.ObjectList{
+		&policiesv1.PlacementBindingList{},
+		&appsv1.PlacementRuleList{},
+		&policiesv1beta1.PolicySetList{},
+	}
+
+	if common.ManagedClusterSetAPIAvailable() {
+		namespacedLists = append(namespacedLists, &clusterv1beta2.ManagedClusterSetBindingList{})
+	}
+
+	if common.PlacementAPIAvailable() {
+		namespacedLists = append(namespacedLists, &clusterv1beta1.PlacementDecisionList{})
+	}
+
+	for _, list := range namespacedLists {
+		listInputs, err := r.resourceVersions(ctx, list, client.InNamespace(instance.Namespace))
+		if err != nil {
+			return "", err
+		}
+
+		inputs = append(inputs, listInputs...)
+	}
+
+	replicaInputs, err := r.resourceVersions(
+		ctx, &policiesv1.PolicyList{}, client.MatchingLabels(common.LabelsForRootPolicy(instance)),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	inputs = append(inputs, replicaInputs...)
+
+	hash := sha256.New()
+
+	for _, input := range inputs {
+		_, _ = fmt.Fprintln(hash, input)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// resourceVersions lists the objects of the input list type and returns their kind, namespace, name, and resource
+// version, sorted so that they don't depend on the order of the list.
+func (r *PolicyReconciler) resourceVersions(
+	ctx context.Context, list client.ObjectList, opts ...client.ListOption,
+) ([]string, error) {
+	if err := r.List(ctx, list, opts...); err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(items))
+
+	for _, item := range items {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, fmt.Sprintf(
+			"%T %s/%s=%s", list, obj.GetNamespace(), obj.GetName(), obj.GetResourceVersion(),
+		))
+	}
+
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// desiredStateUnchanged returns true if the input fingerprint of the inputs of the root policy matches the one of its
+// last reconcile that fully propagated it.
+func desiredStateUnchanged(rootPolicy types.NamespacedName, fingerprint string) bool {
+	if fingerprint == "" {
+		return false
+	}
+
+	previous, ok := desiredStates.Load(rootPolicy)
+
+	return ok && previous == fingerprint
+}
+
+// recordDesiredState keeps the input fingerprint of the inputs of the root policy if its reconcile fully propagated
+// it without a requeue. Otherwise, the previous one is forgotten so that the next reconcile isn't skipped.
+func recordDesiredState(rootPolicy types.NamespacedName, fingerprint string, requeueAfter time.Duration, err error) {
+	if fingerprint == "" || requeueAfter > 0 || err != nil {
+		desiredStates.Delete(rootPolicy)
+
+		return
+	}
+
+	desiredStates.Store(rootPolicy, fingerprint)
+}
+
+// forgetDesiredState removes the fingerprint of the root policy so that its next reconcile isn't skipped, such as when
+// it's deleted or when all root policies are reconciled to correct changes that were missed.
+func forgetDesiredState(rootPolicy types.NamespacedName) {
+	desiredStates.Delete(rootPolicy)
+}
@@ -0,0 +1,245 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+)
+
+// countingClient counts the requests made through the client.
+type countingClient struct {
+	client.Client
+	calls atomic.Int64
+}
+
+func (c *countingClient) Get(
+	ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption,
+) error {
+	c.calls.Add(1)
+
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *countingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	c.calls.Add(1)
+
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *countingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.calls.Add(1)
+
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *countingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.calls.Add(1)
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *countingClient) Patch(
+	ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption,
+) error {
+	c.calls.Add(1)
+
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// desiredStateReconciler returns a reconciler for a root policy bound to a placement rule that selects the input
+// number of clusters.
+func desiredStateReconciler(tb testing.TB, clusters int, cacheEnabled bool) (*PolicyReconciler, *countingClient) {
+	tb.Helper()
+
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		corev1.AddToScheme,
+		policiesv1.AddToScheme,
+		policiesv1beta1.AddToScheme,
+		appsv1.AddToScheme,
+		clusterv1.AddToScheme,
+		clusterv1beta1.AddToScheme,
+		clusterv1beta2.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			tb.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	decisions := fakePlacementDecisions(clusters)
+	objects := []client.Object{}
+
+	for _, decision := range decisions {
+		objects = append(objects, &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: decision.ClusterName}})
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	pr := fakePlacementRule("my-rule", "policies", decisions)
+	pb := fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "my-policy"}},
+	)
+
+	objects = append(objects, &rootPolicy, &pr, &pb)
+
+	c := &countingClient{Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build()}
+	watcher := &fakeDynamicWatcher{watched: map[k8sdepwatches.ObjectIdentifier][]k8sdepwatches.ObjectIdentifier{}}
+
+	return &PolicyReconciler{
+		Client:            c,
+		Scheme:            testscheme,
+		Recorder:          record.NewFakeRecorder(100),
+		DynamicWatcher:    watcher,
+		RootPolicyLocks:   &sync.Map{},
+		DesiredStateCache: cacheEnabled,
+	}, c
+}
+
+func TestReconcileDesiredStateCache(t *testing.T) {
+	root := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+	defer forgetDesiredState(root)
+
+	reconciler, c := desiredStateReconciler(t, 2, true)
+	request := reconcile.Request{NamespacedName: root}
+
+	// reconcileSkipped reconciles the root policy and returns true if it was skipped by the desired state cache
+	reconcileSkipped := func() bool {
+		t.Helper()
+
+		before := testutil.ToFloat64(propagationSkippedMetric)
+
+		if _, err := reconciler.Reconcile(context.TODO(), request); err != nil {
+			t.Fatalf("Unexpected error reconciling the policy: %v", err)
+		}
+
+		return testutil.ToFloat64(propagationSkippedMetric) > before
+	}
+
+	// The first reconcile creates the replicated policies, which changes the inputs of the second one
+	if reconcileSkipped() || reconcileSkipped() {
+		t.Fatal("Expected the reconciles that changed the replicated policies to not be skipped")
+	}
+
+	if !reconcileSkipped() {
+		t.Fatal("Expected the reconcile with unchanged inputs to be skipped")
+	}
+
+	// A change to a placement binding in the namespace invalidates the cache
+	pb := &policiesv1.PlacementBinding{}
+
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "policies", Name: "my-pb"}, pb); err != nil {
+		t.Fatalf("Failed to get the placement binding: %v", err)
+	}
+
+	pb.Labels = map[string]string{"changed": "true"}
+	if err := c.Update(context.TODO(), pb); err != nil {
+		t.Fatalf("Failed to update the placement binding: %v", err)
+	}
+
+	if reconcileSkipped() {
+		t.Fatal("Expected the reconcile after the placement binding changed to not be skipped")
+	}
+
+	// A change to a replicated policy, such as by a user on the hub, invalidates the cache and the change is reverted
+	replicaName := types.NamespacedName{Namespace: "cluster1", Name: "policies.my-policy"}
+	replica := &policiesv1.Policy{}
+
+	if err := c.Get(context.TODO(), replicaName, replica); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	replica.Spec.Disabled = true
+	if err := c.Update(context.TODO(), replica); err != nil {
+		t.Fatalf("Failed to update the replicated policy: %v", err)
+	}
+
+	if reconcileSkipped() {
+		t.Fatal("Expected the reconcile after the replicated policy changed to not be skipped")
+	}
+
+	if err := c.Get(context.TODO(), replicaName, replica); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	if replica.Spec.Disabled {
+		t.Fatal("Expected the change to the replicated policy to be reverted")
+	}
+
+	// A ManagedCluster event invalidates the cache of all root policies
+	reconcileSkipped()
+
+	if !reconcileSkipped() {
+		t.Fatal("Expected the reconcile with unchanged inputs to be skipped")
+	}
+
+	clusterEpoch.Add(1)
+
+	if reconcileSkipped() {
+		t.Fatal("Expected the reconcile after a cluster changed to not be skipped")
+	}
+
+	// The resync and reconcile-all requests always do the full reconcile
+	forgetDesiredState(root)
+
+	if reconcileSkipped() {
+		t.Fatal("Expected the reconcile after the desired state was forgotten to not be skipped")
+	}
+}
+
+func BenchmarkReconcileSteadyState(b *testing.B) {
+	for name, cacheEnabled := range map[string]bool{"cache disabled": false, "cache enabled": true} {
+		cacheEnabled := cacheEnabled
+
+		b.Run(name, func(b *testing.B) {
+			root := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+			defer forgetDesiredState(root)
+
+			reconciler, c := desiredStateReconciler(b, 50, cacheEnabled)
+			request := reconcile.Request{NamespacedName: root}
+
+			// Reach the steady state where the replicated policies are up to date
+			for i := 0; i < 2; i++ {
+				if _, err := reconciler.Reconcile(context.TODO(), request); err != nil {
+					b.Fatalf("Unexpected error reconciling the policy: %v", err)
+				}
+			}
+
+			c.calls.Store(0)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := reconciler.Reconcile(context.TODO(), request); err != nil {
+					b.Fatalf("Unexpected error reconciling the policy: %v", err)
+				}
+			}
+
+			b.ReportMetric(float64(c.calls.Load())/float64(b.N), "client-calls/op")
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+const (
+	// MaintenanceWindowAnnotation is set on a root policy to the maintenance windows during which changes to its
+	// replicated policies are applied. It overrides the maintenance windows configured on the propagator.
+	MaintenanceWindowAnnotation = "policy.open-cluster-management.io/maintenance-window"
+	// MaintenanceWindowTimezoneAnnotation is set on a root policy to the IANA time zone, such as America/New_York, in
+	// which its maintenance windows are evaluated. It overrides the time zone configured on the propagator.
+	MaintenanceWindowTimezoneAnnotation = "policy.open-cluster-management.io/maintenance-window-timezone"
+)
+
+var ErrInvalidMaintenanceWindow = errors.New("invalid maintenance window")
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// maintenanceWindow is a time range on the selected days of the week. When end is not after start, the window ends on
+// the following day.
+type maintenanceWindow struct {
+	days  [7]bool
+	start time.Duration
+	end   time.Duration
+}
+
+// MaintenanceSchedule is a set of weekly maintenance windows evaluated in a time zone. Changes to replicated policies
+// are only applied while one of the maintenance windows is open.
+type MaintenanceSchedule struct {
+	windows  []maintenanceWindow
+	location *time.Location
+}
+
+// ParseMaintenanceSchedule parses the semicolon separated maintenance windows in the input schedule, which are
+// evaluated in the input location. Each window is in the format `[days] HH:MM-HH:MM`, where the optional days are a
+// comma separated list of days or ranges of days such as `Mon-Fri,Sun`, and every day is selected when they are
+// omitted. A window that ends at or before its start time ends on the following day, such as `Sat 22:00-02:00`. An
+// empty schedule returns nil, meaning changes are always applied.
+func ParseMaintenanceSchedule(schedule string, location *time.Location) (*MaintenanceSchedule, error) {
+	if strings.TrimSpace(schedule) == "" {
+		return nil, nil
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	parsed := &MaintenanceSchedule{location: location}
+
+	for _, rawWindow := range strings.Split(schedule, ";") {
+		window, err := parseMaintenanceWindow(strings.TrimSpace(rawWindow))
+		if err != nil {
+			return nil, err
+		}
+
+		parsed.windows = append(parsed.windows, window)
+	}
+
+	return parsed, nil
+}
+
+func parseMaintenanceWindow(rawWindow string) (maintenanceWindow, error) {
+	window := maintenanceWindow{}
+	fields := strings.Fields(rawWindow)
+
+	var rawDays, rawTimes string
+
+	switch len(fields) {
+	case 1:
+		rawTimes = fields[0]
+
+		for i := range window.days {
+			window.days[i] = true
+		}
+	case 2:
+		rawDays, rawTimes = fields[0], fields[1]
+	default:
+		return window, fmt.Errorf("%w: %q is not in the format [days] HH:MM-HH:MM", ErrInvalidMaintenanceWindow, rawWindow)
+	}
+
+	if rawDays != "" {
+		for _, dayRange := range strings.Split(rawDays, ",") {
+			first, last, isRange := strings.Cut(dayRange, "-")
+			if !isRange {
+				last = first
+			}
+
+			firstDay, ok := weekdays[strings.ToLower(first)]
+			if !ok {
+				return window, fmt.Errorf("%w: %q is not a day of the week", ErrInvalidMaintenanceWindow, first)
+			}
+
+			lastDay, ok := weekdays[strings.ToLower(last)]
+			if !ok {
+				return window, fmt.Errorf("%w: %q is not a day of the week", ErrInvalidMaintenanceWindow, last)
+			}
+
+			// Ranges such as Fri-Mon wrap around the end of the week
+			for day := firstDay; ; day = (day + 1) % 7 {
+				window.days[day] = true
+
+				if day == lastDay {
+					break
+				}
+			}
+		}
+	}
+
+	rawStart, rawEnd, found := strings.Cut(rawTimes, "-")
+	if !found {
+		return window, fmt.Errorf("%w: %q is not in the format HH:MM-HH:MM", ErrInvalidMaintenanceWindow, rawTimes)
+	}
+
+	var err error
+
+	window.start, err = parseTimeOfDay(rawStart)
+	if err != nil {
+		return window, err
+	}
+
+	window.end, err = parseTimeOfDay(rawEnd)
+	if err != nil {
+		return window, err
+	}
+
+	return window, nil
+}
+
+// parseTimeOfDay returns the duration since midnight of the input time in the HH:MM format.
+func parseTimeOfDay(rawTime string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", rawTime)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a time in the HH:MM format", ErrInvalidMaintenanceWindow, rawTime)
+	}
+
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// timeOnDay returns the input time of day on the day of the input date in the input location. This is computed with
+// time.Date rather than by adding the duration to midnight so that the result is correct on days with a daylight saving
+// time transition.
+func timeOnDay(date time.Time, timeOfDay time.Duration, location *time.Location) time.Time {
+	return time.Date(
+		date.Year(), date.Month(), date.Day(),
+		int(timeOfDay/time.Hour), int((timeOfDay%time.Hour)/time.Minute), 0, 0, location,
+	)
+}
+
+// untilOpen returns how long until one of the maintenance windows is open from the input time. Zero is returned if a
+// maintenance window is currently open.
+func (s *MaintenanceSchedule) untilOpen(now time.Time) time.Duration {
+	now = now.In(s.location)
+
+	var next time.Time
+
+	for _, window := range s.windows {
+		// A window that started on the previous day may still be open
+		for dayOffset := -1; dayOffset <= 7; dayOffset++ {
+			date := now.AddDate(0, 0, dayOffset)
+			if !window.days[date.Weekday()] {
+				continue
+			}
+
+			start := timeOnDay(date, window.start, s.location)
+
+			end := timeOnDay(date, window.end, s.location)
+			if !end.After(start) {
+				end = timeOnDay(date.AddDate(0, 0, 1), window.end, s.location)
+			}
+
+			if !now.Before(start) && now.Before(end) {
+				return 0
+			}
+
+			if start.After(now) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+
+	return next.Sub(now)
+}
+
+// getMaintenanceSchedule returns the maintenance schedule of the input root policy. The maintenance window annotations
+// on the root policy take precedence over the maintenance schedule configured on the reconciler. A nil schedule means
+// that changes are always applied.
+func (r *PolicyReconciler) getMaintenanceSchedule(rootPolicy *policiesv1.Policy) (*MaintenanceSchedule, error) {
+	annotations := rootPolicy.GetAnnotations()
+
+	rawSchedule, hasSchedule := annotations[MaintenanceWindowAnnotation]
+	rawTimezone, hasTimezone := annotations[MaintenanceWindowTimezoneAnnotation]
+
+	if !hasSchedule && !hasTimezone {
+		return r.MaintenanceSchedule, nil
+	}
+
+	location := time.UTC
+	if r.MaintenanceSchedule != nil {
+		location = r.MaintenanceSchedule.location
+	}
+
+	if hasTimezone {
+		var err error
+
+		location, err = time.LoadLocation(rawTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: the time zone %q is invalid: %w", ErrInvalidMaintenanceWindow, rawTimezone, err)
+		}
+	}
+
+	if !hasSchedule {
+		if r.MaintenanceSchedule == nil {
+			return nil, nil
+		}
+
+		return &MaintenanceSchedule{windows: r.MaintenanceSchedule.windows, location: location}, nil
+	}
+
+	return ParseMaintenanceSchedule(rawSchedule, location)
+}
@@ -0,0 +1,137 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceScheduleUntilOpen(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load the time zone: %v", err)
+	}
+
+	// 2023-06-14 is a Wednesday
+	wednesdayNoonUTC := time.Date(2023, time.June, 14, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		schedule string
+		location *time.Location
+		now      time.Time
+		expected time.Duration
+	}{
+		"inside a daily window": {"09:00-17:00", time.UTC, wednesdayNoonUTC, 0},
+		"before a daily window": {"13:30-17:00", time.UTC, wednesdayNoonUTC, 90 * time.Minute},
+		"after a daily window":  {"09:00-11:00", time.UTC, wednesdayNoonUTC, 21 * time.Hour},
+		"overnight window from the previous day": {
+			"Tue 22:00-13:00", time.UTC, wednesdayNoonUTC, 0,
+		},
+		"weekend window": {"Sat,Sun 00:00-23:59", time.UTC, wednesdayNoonUTC, 2*24*time.Hour + 12*time.Hour},
+		"range wrapping the end of the week": {
+			"Fri-Mon 10:00-11:00", time.UTC, wednesdayNoonUTC, 2*24*time.Hour - 2*time.Hour,
+		},
+		"earliest of multiple windows": {
+			"Thu 09:00-10:00; Wed 18:00-19:00", time.UTC, wednesdayNoonUTC, 6 * time.Hour,
+		},
+		// Noon UTC is 08:00 in New York during daylight saving time
+		"evaluated in the time zone": {"Wed 09:00-17:00", newYork, wednesdayNoonUTC, time.Hour},
+		"daylight saving time transition": {
+			// 2023-03-12 02:00 in New York is skipped, so 01:00 to 03:00 is only an hour
+			"Sun 03:00-04:00", newYork, time.Date(2023, time.March, 12, 1, 0, 0, 0, newYork), time.Hour,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			schedule, err := ParseMaintenanceSchedule(test.schedule, test.location)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if untilOpen := schedule.untilOpen(test.now); untilOpen != test.expected {
+				t.Fatalf("expected the window to open in %v, got %v", test.expected, untilOpen)
+			}
+		})
+	}
+}
+
+func TestParseMaintenanceScheduleInvalid(t *testing.T) {
+	for _, schedule := range []string{"9:00", "Mon-Fri", "Someday 09:00-10:00", "09:00-25:00", "Mon 09:00 10:00"} {
+		_, err := ParseMaintenanceSchedule(schedule, time.UTC)
+		if !errors.Is(err, ErrInvalidMaintenanceWindow) {
+			t.Fatalf("expected the schedule %q to be invalid, got %v", schedule, err)
+		}
+	}
+
+	schedule, err := ParseMaintenanceSchedule("", time.UTC)
+	if schedule != nil || err != nil {
+		t.Fatalf("expected no schedule and no error for an empty schedule, got %v and %v", schedule, err)
+	}
+}
+
+func TestGetMaintenanceSchedule(t *testing.T) {
+	globalSchedule, err := ParseMaintenanceSchedule("Sat 00:00-06:00", time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := &PolicyReconciler{MaintenanceSchedule: globalSchedule}
+	wednesdayNoonUTC := time.Date(2023, time.June, 14, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		annotations map[string]string
+		// A negative value means no schedule is expected
+		expected time.Duration
+	}{
+		"global schedule":      {nil, 2*24*time.Hour + 12*time.Hour},
+		"annotation override":  {map[string]string{MaintenanceWindowAnnotation: "Wed 13:00-14:00"}, time.Hour},
+		"annotation disables":  {map[string]string{MaintenanceWindowAnnotation: ""}, -1},
+		"time zone annotation": {map[string]string{MaintenanceWindowTimezoneAnnotation: "Asia/Tokyo"}, 51 * time.Hour},
+		"both annotations used": {
+			map[string]string{
+				MaintenanceWindowAnnotation:         "Wed 13:00-14:00",
+				MaintenanceWindowTimezoneAnnotation: "Asia/Tokyo",
+			},
+			// Noon UTC is 21:00 in Tokyo, so the window opens on the following Wednesday
+			7*24*time.Hour - 8*time.Hour,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			policy := fakeRootPolicy("my-policy", "policies")
+			policy.SetAnnotations(test.annotations)
+
+			schedule, err := r.getMaintenanceSchedule(&policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.expected < 0 {
+				if schedule != nil {
+					t.Fatal("expected no maintenance schedule")
+				}
+
+				return
+			}
+
+			if untilOpen := schedule.untilOpen(wednesdayNoonUTC); untilOpen != test.expected {
+				t.Fatalf("expected the window to open in %v, got %v", test.expected, untilOpen)
+			}
+		})
+	}
+
+	policy := fakeRootPolicy("my-policy", "policies")
+	policy.SetAnnotations(map[string]string{MaintenanceWindowTimezoneAnnotation: "Not/AZone"})
+
+	if _, err := r.getMaintenanceSchedule(&policy); !errors.Is(err, ErrInvalidMaintenanceWindow) {
+		t.Fatalf("expected an invalid time zone error, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+// we only want to watch for changes that can affect the membership of a cluster in a ManagedClusterSet
+var managedClusterPredicateFuncs = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !reflect.DeepEqual(e.ObjectNew.GetLabels(), e.ObjectOld.GetLabels()) ||
+			e.ObjectNew.GetAnnotations()[PinnedGenerationsAnnotation] !=
+				e.ObjectOld.GetAnnotations()[PinnedGenerationsAnnotation] ||
+			e.ObjectNew.GetAnnotations()[SuppressedReplicasAnnotation] !=
+				e.ObjectOld.GetAnnotations()[SuppressedReplicasAnnotation]
+	},
+	CreateFunc: func(e event.CreateEvent) bool {
+		return true
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return true
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
+
+// isPbForClusterSet returns true if the placement binding references a ManagedClusterSet
+func isPbForClusterSet(pb *policiesv1.PlacementBinding) bool {
+	return pb.PlacementRef.APIGroup == clusterv1beta1.SchemeGroupVersion.Group &&
+		pb.PlacementRef.Kind == "ManagedClusterSet"
+}
+
+// clusterSetPlacementBindingRequests returns the reconcile requests for the policies bound by the input placement
+// bindings that reference a ManagedClusterSet.
+func clusterSetPlacementBindingRequests(c client.Client, pbList *policiesv1.PlacementBindingList) []reconcile.Request {
+	var result []reconcile.Request
+
+	pbMapper := placementBindingMapper(c)
+
+	for i := range pbList.Items {
+		if !isPbForClusterSet(&pbList.Items[i]) {
+			continue
+		}
+
+		result = append(result, pbMapper(&pbList.Items[i])...)
+	}
+
+	return result
+}
+
+// clusterSelectorPlacementBindingRequests returns the reconcile requests for the policies bound by the input placement
+// bindings that select the clusters with their clusterSelector.
+func clusterSelectorPlacementBindingRequests(
+	c client.Client, pbList *policiesv1.PlacementBindingList,
+) []reconcile.Request {
+	var result []reconcile.Request
+
+	pbMapper := placementBindingMapper(c)
+
+	for i := range pbList.Items {
+		if !common.UsesClusterSelector(&pbList.Items[i]) {
+			continue
+		}
+
+		result = append(result, pbMapper(&pbList.Items[i])...)
+	}
+
+	return result
+}
+
+// managedClusterMapper enqueues the policies bound to any ManagedClusterSet when a ManagedCluster is added, removed,
+// or relabeled, since that can change which clusters are members of a ManagedClusterSet. The policies bound by a
+// placement binding with a clusterSelector and the root policies with a cluster selector are also enqueued, since that
+// can change which clusters are selected, as well as the root policies replicated to the cluster, since the cluster
+// may pin them to a generation.
+func managedClusterMapper(c client.Client) handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		log := log.WithValues("managedClusterName", object.GetName())
+
+		log.V(2).Info("Reconcile request for a ManagedCluster")
+
+		// The cluster may change the replicated policies of any root policy
+		clusterEpoch.Add(1)
+
+		pbList := &policiesv1.PlacementBindingList{}
+
+		err := c.List(context.TODO(), pbList)
+		if err != nil {
+			log.Error(err, "Failed to list the PlacementBindings")
+
+			return nil
+		}
+
+		result := clusterSetPlacementBindingRequests(c, pbList)
+		result = append(result, clusterSelectorPlacementBindingRequests(c, pbList)...)
+		result = append(result, replicatedPolicyRequests(c, object.GetName())...)
+		result = append(result, suppressedReplicaRequests(c, object)...)
+
+		return append(result, clusterSelectorPolicyRequests(c)...)
+	}
+}
+
+// clusterSelectorPolicyRequests returns the reconcile requests for the root policies with a cluster selector, since a
+// change to the labels of a ManagedCluster can change whether it's selected.
+func clusterSelectorPolicyRequests(c client.Client) []reconcile.Request {
+	policyList := &policiesv1.PolicyList{}
+
+	err := c.List(context.TODO(), policyList)
+	if err != nil {
+		log.Error(err, "Failed to list the policies")
+
+		return nil
+	}
+
+	var result []reconcile.Request
+
+	for _, policy := range policyList.Items {
+		// The cluster selector is removed from replicated policies, so only root policies have it set
+		if policy.Spec.ClusterSelector == nil {
+			continue
+		}
+
+		result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: policy.Namespace,
+			Name:      policy.Name,
+		}})
+	}
+
+	return result
+}
+
+// replicatedPolicyRequests returns the reconcile requests for the root policies replicated to the cluster with the
+// input name, since pinning the cluster to a generation of a root policy changes its replicated policy.
+func replicatedPolicyRequests(c client.Client, clusterName string) []reconcile.Request {
+	replicatedPlcList := &policiesv1.PolicyList{}
+
+	err := c.List(context.TODO(), replicatedPlcList, client.MatchingLabels{common.ClusterNameLabel: clusterName})
+	if err != nil {
+		log.Error(err, "Failed to list the replicated policies", "cluster", clusterName)
+
+		return nil
+	}
+
+	var result []reconcile.Request
+
+	for _, replicatedPlc := range withoutOtherHubReplicas(replicatedPlcList.Items) {
+		name, namespace, err := common.ParseRootPolicyLabel(replicatedPlc.Labels[common.RootPolicyLabel])
+		if err != nil {
+			continue
+		}
+
+		result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: namespace,
+			Name:      name,
+		}})
+	}
+
+	return result
+}
+
+// managedClusterSetMapper enqueues the policies bound to a ManagedClusterSet when the ManagedClusterSet changes.
+func managedClusterSetMapper(c client.Client) handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		log := log.WithValues("managedClusterSetName", object.GetName())
+
+		log.V(2).Info("Reconcile request for a ManagedClusterSet")
+
+		// The cluster set may change the replicated policies of any root policy bound to it
+		clusterEpoch.Add(1)
+
+		pbList := &policiesv1.PlacementBindingList{}
+
+		err := c.List(context.TODO(), pbList, client.MatchingFields{"placementRef.name": object.GetName()})
+		if err != nil {
+			log.Error(err, "Failed to list the PlacementBindings")
+
+			return nil
+		}
+
+		return clusterSetPlacementBindingRequests(c, pbList)
+	}
+}
+
+// managedClusterSetBindingMapper enqueues the policies bound to a ManagedClusterSet in the namespace of the
+// ManagedClusterSetBinding when the ManagedClusterSetBinding changes.
+func managedClusterSetBindingMapper(c client.Client) handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		log := log.WithValues("managedClusterSetBindingName", object.GetName(), "namespace", object.GetNamespace())
+
+		log.V(2).Info("Reconcile request for a ManagedClusterSetBinding")
+
+		pbList := &policiesv1.PlacementBindingList{}
+		lopts := &client.ListOptions{Namespace: object.GetNamespace()}
+		opts := client.MatchingFields{"placementRef.name": object.GetName()}
+		opts.ApplyToList(lopts)
+
+		err := c.List(context.TODO(), pbList, lopts)
+		if err != nil {
+			log.Error(err, "Failed to list the PlacementBindings")
+
+			return nil
+		}
+
+		return clusterSetPlacementBindingRequests(c, pbList)
+	}
+}
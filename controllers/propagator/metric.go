@@ -4,8 +4,19 @@
 package propagator
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
 var (
@@ -22,6 +33,79 @@ var (
 		},
 		[]string{"name", "namespace"},
 	)
+	replicaDriftMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_replica_drift",
+			Help: "The absolute difference between the number of clusters a root policy should be replicated to and " +
+				"the number of replicated policies. A nonzero value for a sustained period indicates stuck propagation.",
+		},
+		[]string{"name", "namespace"},
+	)
+	replicaSizeExceededMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_replica_size_exceeded_total",
+			Help: "The number of times a replicated policy of the root policy wasn't written because its rendered spec " +
+				"exceeded the maximum size",
+		},
+		[]string{"name", "namespace"},
+	)
+	templateKindRejectedMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_template_kind_rejected_total",
+			Help: "The number of times a replicated policy of the root policy wasn't written because a policy " +
+				"template, or an object embedded in one, had a kind that isn't allowed",
+		},
+		[]string{"name", "namespace", "kind"},
+	)
+	replicasAdoptedMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_replicas_adopted_total",
+			Help: "The number of existing replicated policies of the root policy that the propagator took ownership " +
+				"of since they were missing the labels it sets on the replicated policies it creates",
+		},
+		[]string{"name", "namespace"},
+	)
+	clusterQuarantinedMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_cluster_quarantined",
+			Help: "Set to 1 for each cluster namespace that replicated policies aren't written to since the writes " +
+				"failed too many times in a row. The series is deleted once a write to the cluster namespace succeeds.",
+		},
+		[]string{"cluster_namespace"},
+	)
+	propagationSkippedMetric = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "policy_propagation_skipped_total",
+			Help: "The number of root policy reconciles that were skipped since none of the inputs of the policy " +
+				"changed since it was last propagated",
+		},
+	)
+	targetResolutionErrorMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_target_resolution_errors_total",
+			Help: "The number of times the clusters of a root policy couldn't be fully resolved, by reason. The " +
+				"binding_invalid reason is a misconfigured placement binding, placement_not_found and no_decisions " +
+				"mean a placement binding doesn't place the policy on any cluster, and transient is an API error.",
+		},
+		[]string{"reason"},
+	)
+	placementDecisionCountMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_placement_decision_count",
+			Help: "The number of clusters that the placement of a placement binding that binds a root policy currently " +
+				"resolves to. A zero value means the placement, rather than the policy, selects no clusters. The " +
+				"placement label is empty for placement bindings that select the clusters with a clusterSelector.",
+		},
+		[]string{"placement_binding", "namespace", "placement"},
+	)
+	oldestPendingPropagationMetric = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "policy_oldest_pending_propagation_seconds",
+			Help: "The longest time that a root policy has gone without being fully propagated, across all root " +
+				"policies. A sustained high value indicates a stuck controller or a persistently failing cluster.",
+		},
+		func() float64 { return oldestPendingPropagationSeconds(time.Now()) },
+	)
 	roothandlerMeasure = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name: "ocm_handle_root_policy_duration_seconds_bucket",
 		Help: "Time the handleRootPolicy function takes to complete.",
@@ -32,4 +116,168 @@ func init() {
 	metrics.Registry.MustRegister(roothandlerMeasure)
 	metrics.Registry.MustRegister(propagationFailureMetric)
 	metrics.Registry.MustRegister(hubTemplateActiveWatchesMetric)
+	metrics.Registry.MustRegister(replicaDriftMetric)
+	metrics.Registry.MustRegister(replicaSizeExceededMetric)
+	metrics.Registry.MustRegister(templateKindRejectedMetric)
+	metrics.Registry.MustRegister(replicasAdoptedMetric)
+	metrics.Registry.MustRegister(clusterQuarantinedMetric)
+	metrics.Registry.MustRegister(propagationSkippedMetric)
+	metrics.Registry.MustRegister(targetResolutionErrorMetric)
+	metrics.Registry.MustRegister(placementDecisionCountMetric)
+	metrics.Registry.MustRegister(oldestPendingPropagationMetric)
+}
+
+// placementDecisionCountPlacements maps the namespaced name of a placement binding to the placement label of its
+// policy_placement_decision_count series, so that the series is replaced when the placementRef changes.
+var placementDecisionCountPlacements sync.Map
+
+// recordPlacementDecisionCount sets the policy_placement_decision_count metric of the input placement binding to the
+// input number of clusters. The series of the placement that the placement binding previously referenced is deleted.
+func recordPlacementDecisionCount(pb *policiesv1.PlacementBinding, count int) {
+	placement := pb.PlacementRef.Name
+	if common.UsesClusterSelector(pb) {
+		placement = ""
+	}
+
+	key := types.NamespacedName{Namespace: pb.Namespace, Name: pb.Name}
+
+	if previous, loaded := placementDecisionCountPlacements.Swap(key, placement); loaded && previous != placement {
+		//nolint:forcetypeassert
+		placementDecisionCountMetric.DeleteLabelValues(pb.Name, pb.Namespace, previous.(string))
+	}
+
+	placementDecisionCountMetric.WithLabelValues(pb.Name, pb.Namespace, placement).Set(float64(count))
+}
+
+// forgetStalePlacementBindings deletes the policy_placement_decision_count series of the placement bindings in the
+// input namespace that were deleted, since they aren't in the input list, or that no longer bind a root policy, such
+// as when their subjects changed or the root policies they bound were deleted.
+func (r *PolicyReconciler) forgetStalePlacementBindings(
+	ctx context.Context, namespace string, pbList *policiesv1.PlacementBindingList,
+) error {
+	existing := make(map[string]*policiesv1.PlacementBinding, len(pbList.Items))
+
+	for i := range pbList.Items {
+		existing[pbList.Items[i].Name] = &pbList.Items[i]
+	}
+
+	var tracked []types.NamespacedName
+
+	placementDecisionCountPlacements.Range(func(key, _ any) bool {
+		//nolint:forcetypeassert
+		if pbKey := key.(types.NamespacedName); pbKey.Namespace == namespace {
+			tracked = append(tracked, pbKey)
+		}
+
+		return true
+	})
+
+	if len(tracked) == 0 {
+		return nil
+	}
+
+	policyList := &policiesv1.PolicyList{}
+
+	if err := r.List(ctx, policyList, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	rootPolicies := make(map[string]bool, len(policyList.Items))
+
+	for i := range policyList.Items {
+		if rootPlcName, _ := common.GetRootPolicyLabel(&policyList.Items[i]); rootPlcName == "" {
+			rootPolicies[policyList.Items[i].Name] = true
+		}
+	}
+
+	for _, pbKey := range tracked {
+		if pb, ok := existing[pbKey.Name]; ok {
+			bound, err := r.bindsRootPolicy(ctx, pb, rootPolicies)
+			if err != nil {
+				return err
+			}
+
+			if bound {
+				continue
+			}
+		}
+
+		if placement, loaded := placementDecisionCountPlacements.LoadAndDelete(pbKey); loaded {
+			//nolint:forcetypeassert
+			placementDecisionCountMetric.DeleteLabelValues(pbKey.Name, pbKey.Namespace, placement.(string))
+		}
+	}
+
+	return nil
+}
+
+// bindsRootPolicy returns true if a subject of the input placement binding matches one of the input root policy names
+// in its namespace, directly or through a PolicySet.
+func (r *PolicyReconciler) bindsRootPolicy(
+	ctx context.Context, pb *policiesv1.PlacementBinding, rootPolicies map[string]bool,
+) (bool, error) {
+	for _, subject := range pb.Subjects {
+		for rootPolicy := range rootPolicies {
+			if common.SubjectMatchesPolicy(subject, rootPolicy) {
+				return true, nil
+			}
+		}
+
+		if subject.APIGroup != policiesv1.SchemeGroupVersion.Group || subject.Kind != policiesv1.PolicySetKind {
+			continue
+		}
+
+		policySet := &policiesv1beta1.PolicySet{}
+
+		err := r.Get(ctx, types.NamespacedName{Namespace: pb.Namespace, Name: subject.Name}, policySet)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+
+			return false, err
+		}
+
+		for _, plc := range policySet.Spec.Policies {
+			if rootPolicies[string(plc)] {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// pendingPropagationSince maps the namespaced name of a root policy to the time its propagation first failed since it
+// was last fully propagated.
+var pendingPropagationSince sync.Map
+
+// recordPendingPropagation records that the root policy couldn't be fully propagated. The time of the first failure is
+// kept until forgetPendingPropagation is called.
+func recordPendingPropagation(rootPolicy types.NamespacedName, now time.Time) {
+	pendingPropagationSince.LoadOrStore(rootPolicy, now)
+}
+
+// forgetPendingPropagation removes the root policy from the oldest pending propagation metric, either because its
+// replicated policies match the desired state or because it was deleted.
+func forgetPendingPropagation(rootPolicy types.NamespacedName) {
+	pendingPropagationSince.Delete(rootPolicy)
+}
+
+// oldestPendingPropagationSeconds returns the number of seconds since the propagation of the root policy that has been
+// pending the longest first failed. This is computed when the metric is collected so that it keeps increasing while
+// the root policy isn't reconciled.
+func oldestPendingPropagationSeconds(now time.Time) float64 {
+	var oldest time.Duration
+
+	pendingPropagationSince.Range(func(_, value any) bool {
+		//nolint:forcetypeassert
+		if pending := now.Sub(value.(time.Time)); pending > oldest {
+			oldest = pending
+		}
+
+		return true
+	})
+
+	return oldest.Seconds()
 }
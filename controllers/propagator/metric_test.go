@@ -0,0 +1,175 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestOldestPendingPropagationSeconds(t *testing.T) {
+	now := time.Now()
+	policyA := types.NamespacedName{Namespace: "policies", Name: "policy-a"}
+	policyB := types.NamespacedName{Namespace: "policies", Name: "policy-b"}
+
+	defer forgetPendingPropagation(policyA)
+	defer forgetPendingPropagation(policyB)
+
+	if oldest := oldestPendingPropagationSeconds(now); oldest != 0 {
+		t.Fatalf("expected no pending propagation, got %v", oldest)
+	}
+
+	recordPendingPropagation(policyA, now.Add(-time.Minute))
+	recordPendingPropagation(policyB, now.Add(-10*time.Second))
+	// Failing again doesn't reset the time of the first failure
+	recordPendingPropagation(policyA, now)
+
+	if oldest := oldestPendingPropagationSeconds(now); oldest != 60 {
+		t.Fatalf("expected the oldest pending propagation to be 60 seconds, got %v", oldest)
+	}
+
+	forgetPendingPropagation(policyA)
+
+	if oldest := oldestPendingPropagationSeconds(now); oldest != 10 {
+		t.Fatalf("expected the oldest pending propagation to be 10 seconds, got %v", oldest)
+	}
+}
+
+func TestPlacementDecisionCountMetric(t *testing.T) {
+	placementDecisionCountMetric.Reset()
+	placementDecisionCountPlacements = sync.Map{}
+
+	defer placementDecisionCountMetric.Reset()
+
+	testPolicy := fakeRootPolicy("test-policy", "default")
+	prA := fakePlacementRule("pr-a", "default", fakePlacementDecisions(2))
+	prEmpty := fakePlacementRule("pr-empty", "default", nil)
+	placementRef := policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule"}
+	subjects := []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: testPolicy.Name},
+	}
+
+	pbA := fakePlacementBinding("pb-a", "default", placementRef, subjects)
+	pbA.PlacementRef.Name = prA.Name
+	pbMissing := fakePlacementBinding("pb-missing", "default", placementRef, subjects)
+	pbMissing.PlacementRef.Name = "pr-missing"
+
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, appsv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	reconciler := &PolicyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&testPolicy, &prA, &prEmpty).Build(),
+	}
+
+	pbList := &policiesv1.PlacementBindingList{Items: []policiesv1.PlacementBinding{pbA, pbMissing}}
+
+	_, _, err := reconciler.getAllClusterDecisions(&testPolicy, pbList)
+	if !errors.Is(err, ErrPlacementNotFound) {
+		t.Fatalf("Expected the missing placement rule to be reported, got %v", err)
+	}
+
+	expectCount := func(pb, placement string, expected float64) {
+		t.Helper()
+
+		value := testutil.ToFloat64(placementDecisionCountMetric.WithLabelValues(pb, "default", placement))
+		if value != expected {
+			t.Fatalf("Expected %s/%s to resolve to %v clusters, got %v", pb, placement, expected, value)
+		}
+	}
+
+	expectCount("pb-a", "pr-a", 2)
+	// The missing placement resolves to no clusters
+	expectCount("pb-missing", "pr-missing", 0)
+
+	// Changing the placementRef replaces the series of the previous placement
+	pbList.Items[0].PlacementRef.Name = prEmpty.Name
+
+	_, _, _ = reconciler.getAllClusterDecisions(&testPolicy, pbList)
+
+	expectCount("pb-a", "pr-empty", 0)
+
+	if count := testutil.CollectAndCount(placementDecisionCountMetric); count != 2 {
+		t.Fatalf("Expected the series of pr-a to be deleted, got %d series", count)
+	}
+
+	// Deleting a placement binding deletes its series
+	pbList.Items = pbList.Items[:1]
+
+	if err := reconciler.forgetStalePlacementBindings(context.TODO(), "default", pbList); err != nil {
+		t.Fatalf("Unexpected error deleting the stale series: %v", err)
+	}
+
+	if count := testutil.CollectAndCount(placementDecisionCountMetric); count != 1 {
+		t.Fatalf("Expected only the series of pb-a to remain, got %d series", count)
+	}
+
+	// A placement binding whose subjects no longer match a root policy has its series deleted
+	pbList.Items[0].Subjects = []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "other-policy"},
+	}
+
+	if err := reconciler.forgetStalePlacementBindings(context.TODO(), "default", pbList); err != nil {
+		t.Fatalf("Unexpected error deleting the stale series: %v", err)
+	}
+
+	if count := testutil.CollectAndCount(placementDecisionCountMetric); count != 0 {
+		t.Fatalf("Expected the series of pb-a to be deleted, got %d series", count)
+	}
+}
+
+func TestPlacementDecisionCountMetricRootPolicyDeleted(t *testing.T) {
+	placementDecisionCountMetric.Reset()
+	placementDecisionCountPlacements = sync.Map{}
+
+	defer placementDecisionCountMetric.Reset()
+
+	root := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+	defer forgetDesiredState(root)
+
+	reconciler, c := desiredStateReconciler(t, 2, false)
+	request := reconcile.Request{NamespacedName: root}
+
+	if _, err := reconciler.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("Unexpected error reconciling the policy: %v", err)
+	}
+
+	value := testutil.ToFloat64(placementDecisionCountMetric.WithLabelValues("my-pb", "policies", "my-rule"))
+	if value != 2 {
+		t.Fatalf("Expected the placement binding to resolve to 2 clusters, got %v", value)
+	}
+
+	rootPolicy := &policiesv1.Policy{}
+
+	if err := c.Get(context.TODO(), root, rootPolicy); err != nil {
+		t.Fatalf("Failed to get the root policy: %v", err)
+	}
+
+	if err := c.Delete(context.TODO(), rootPolicy); err != nil {
+		t.Fatalf("Failed to delete the root policy: %v", err)
+	}
+
+	if _, err := reconciler.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("Unexpected error reconciling the deleted policy: %v", err)
+	}
+
+	// The placement binding still exists, but it no longer binds a root policy
+	if count := testutil.CollectAndCount(placementDecisionCountMetric); count != 0 {
+		t.Fatalf("Expected the series of the placement binding to be deleted, got %d series", count)
+	}
+}
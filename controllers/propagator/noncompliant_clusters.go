@@ -0,0 +1,183 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	// NonCompliantClustersPath is the path prefix on the metrics server that returns the NonCompliant clusters of a
+	// root policy. The full path is NonCompliantClustersPath followed by <namespace>/<name> of the root policy.
+	NonCompliantClustersPath = "/noncompliant-clusters/"
+	// defaultNonCompliantClustersLimit is the number of replicated policies read per page when the limit query
+	// parameter isn't set.
+	defaultNonCompliantClustersLimit = 500
+)
+
+// NonCompliantCluster is a cluster that a root policy is NonCompliant on.
+type NonCompliantCluster struct {
+	ClusterName      string                     `json:"clusterName"`
+	ClusterNamespace string                     `json:"clusterNamespace"`
+	ComplianceState  policiesv1.ComplianceState `json:"compliant"`
+	// Message is the latest message of each NonCompliant policy template of the replicated policy, separated by
+	// semicolons.
+	Message string `json:"message,omitempty"`
+}
+
+// NonCompliantClusters is a page of the NonCompliant clusters of a root policy.
+type NonCompliantClusters struct {
+	Clusters []NonCompliantCluster `json:"clusters"`
+	// Continue is set when there are more replicated policies to read. It's passed as the continue query parameter, or
+	// to ListNonCompliantClusters, to get the next page.
+	Continue string `json:"continue,omitempty"`
+}
+
+// ListNonCompliantClusters returns the clusters that the input root policy is NonCompliant on from a single List of
+// up to limit of its replicated policies by the root policy label, starting from the input continue token. Since the
+// compliance of a replicated policy isn't a supported field selector, the NonCompliant replicated policies are selected
+// from each page, so a page can have fewer clusters than the limit, or none, even when Continue is set. The input
+// reader should read from the API server rather than the cache so that the pages are consistent.
+func ListNonCompliantClusters(
+	ctx context.Context, c client.Reader, rootPolicy types.NamespacedName, limit int64, continueToken string,
+) (*NonCompliantClusters, error) {
+	replicatedPlcList := &policiesv1.PolicyList{}
+
+	err := c.List(
+		ctx,
+		replicatedPlcList,
+		client.MatchingLabels{
+			common.RootPolicyLabel: common.ReplicatedPolicyName(rootPolicy.Namespace, rootPolicy.Name),
+		},
+		client.Limit(limit),
+		client.Continue(continueToken),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NonCompliantClusters{
+		Clusters: []NonCompliantCluster{},
+		Continue: replicatedPlcList.Continue,
+	}
+
+	replicatedPlcs := withoutOtherHubReplicas(replicatedPlcList.Items)
+
+	for i := range replicatedPlcs {
+		replicatedPlc := &replicatedPlcs[i]
+
+		if replicatedPlc.Status.ComplianceState != policiesv1.NonCompliant {
+			continue
+		}
+
+		result.Clusters = append(result.Clusters, NonCompliantCluster{
+			ClusterName:      replicatedPlc.GetLabels()[common.ClusterNameLabel],
+			ClusterNamespace: replicatedPlc.GetNamespace(),
+			ComplianceState:  replicatedPlc.Status.ComplianceState,
+			Message:          nonCompliantMessage(replicatedPlc),
+		})
+	}
+
+	return result, nil
+}
+
+// nonCompliantMessage returns the latest message of each NonCompliant policy template of the input replicated policy,
+// separated by semicolons.
+func nonCompliantMessage(replicatedPlc *policiesv1.Policy) string {
+	messages := make([]string, 0, len(replicatedPlc.Status.Details))
+
+	for _, details := range replicatedPlc.Status.Details {
+		if details == nil || details.ComplianceState != policiesv1.NonCompliant || len(details.History) == 0 {
+			continue
+		}
+
+		messages = append(messages, details.History[0].Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// NonCompliantClustersHandler returns a read-only HTTP handler that responds with a JSON page of the NonCompliant
+// clusters of the root policy in the request path, as returned by ListNonCompliantClusters. The limit and continue
+// query parameters page through the replicated policies. Callers must provide a bearer token of a user who is allowed
+// to list policies at the cluster scope.
+func NonCompliantClustersHandler(c client.Reader, authClient kubernetes.Interface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		log := log.WithName("noncompliant-clusters")
+
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		authStatus, err := authorize(req.Context(), authClient, req, "list")
+		if err != nil {
+			log.Error(err, "Failed to authorize the request")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if authStatus != http.StatusOK {
+			w.WriteHeader(authStatus)
+
+			return
+		}
+
+		namespace, name, found := strings.Cut(strings.TrimPrefix(req.URL.Path, NonCompliantClustersPath), "/")
+		if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		limit := int64(defaultNonCompliantClustersLimit)
+
+		if rawLimit := req.URL.Query().Get("limit"); rawLimit != "" {
+			limit, err = strconv.ParseInt(rawLimit, 10, 64)
+			if err != nil || limit < 1 {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		clusters, err := ListNonCompliantClusters(
+			req.Context(),
+			c,
+			types.NamespacedName{Namespace: namespace, Name: name},
+			limit,
+			req.URL.Query().Get("continue"),
+		)
+		if k8serrors.IsResourceExpired(err) {
+			// The continue token is too old to resume from, so the caller must start over
+			w.WriteHeader(http.StatusGone)
+
+			return
+		}
+
+		if err != nil {
+			log.Error(err, "Failed to list the NonCompliant clusters", "namespace", namespace, "name", name)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(clusters)
+	})
+}
@@ -0,0 +1,228 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+// pagedReader pages the policy lists of the wrapped reader by namespace since the fake client doesn't support the
+// limit and continue list options. The continue token is the index of the first policy of the next page.
+type pagedReader struct {
+	client.Reader
+	lists int
+}
+
+func (r *pagedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.lists++
+
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	if err := r.Reader.List(ctx, list, opts...); err != nil {
+		return err
+	}
+
+	//nolint:forcetypeassert
+	policyList := list.(*policiesv1.PolicyList)
+
+	sort.Slice(policyList.Items, func(i, j int) bool {
+		return policyList.Items[i].Namespace < policyList.Items[j].Namespace
+	})
+
+	start := 0
+	if listOpts.Continue != "" {
+		start, _ = strconv.Atoi(listOpts.Continue)
+	}
+
+	end := len(policyList.Items)
+	if listOpts.Limit > 0 && start+int(listOpts.Limit) < end {
+		end = start + int(listOpts.Limit)
+		policyList.Continue = strconv.Itoa(end)
+	}
+
+	policyList.Items = policyList.Items[start:end]
+
+	return nil
+}
+
+func fakeReplicatedPolicyWithCompliance(
+	cluster string, compliance policiesv1.ComplianceState, messages ...string,
+) *policiesv1.Policy {
+	replicatedPolicy := fakeRootPolicy("policies.my-policy", cluster)
+	replicatedPolicy.Labels = map[string]string{
+		common.RootPolicyLabel:  "policies.my-policy",
+		common.ClusterNameLabel: cluster,
+	}
+	replicatedPolicy.Status.ComplianceState = compliance
+
+	for _, message := range messages {
+		replicatedPolicy.Status.Details = append(replicatedPolicy.Status.Details, &policiesv1.DetailsPerTemplate{
+			ComplianceState: compliance,
+			History: []policiesv1.ComplianceHistory{
+				{Message: message},
+				{Message: "an older message"},
+			},
+		})
+	}
+
+	return &replicatedPolicy
+}
+
+func TestNonCompliantClustersHandler(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	otherPolicy := fakeReplicatedPolicyWithCompliance("managed1", policiesv1.NonCompliant, "other policy")
+	otherPolicy.Name = "policies.other-policy"
+	otherPolicy.Labels[common.RootPolicyLabel] = "policies.other-policy"
+
+	reader := &pagedReader{Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		fakeReplicatedPolicyWithCompliance("managed1", policiesv1.NonCompliant, "a pod is missing"),
+		fakeReplicatedPolicyWithCompliance("managed2", policiesv1.Compliant, "everything is fine"),
+		fakeReplicatedPolicyWithCompliance(
+			"managed3", policiesv1.NonCompliant, "a role is missing", "a role binding is missing",
+		),
+		fakeReplicatedPolicyWithCompliance("managed4", policiesv1.NonCompliant),
+		fakeReplicatedPolicyWithCompliance("managed5", policiesv1.Pending),
+		otherPolicy,
+	).Build()}
+
+	tests := map[string]struct {
+		token          string
+		allowed        bool
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		"authorized":     {"valid-token", true, http.MethodGet, "policies/my-policy", http.StatusOK},
+		"not authorized": {"valid-token", false, http.MethodGet, "policies/my-policy", http.StatusForbidden},
+		"no token":       {"", true, http.MethodGet, "policies/my-policy", http.StatusUnauthorized},
+		"wrong method":   {"valid-token", true, http.MethodPost, "policies/my-policy", http.StatusMethodNotAllowed},
+		"no name":        {"valid-token", true, http.MethodGet, "policies", http.StatusBadRequest},
+		"invalid limit":  {"valid-token", true, http.MethodGet, "policies/my-policy?limit=0", http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, NonCompliantClustersPath+test.path, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			NonCompliantClustersHandler(reader, fakeAuthClient(test.allowed)).ServeHTTP(recorder, req)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			clusters := NonCompliantClusters{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &clusters); err != nil {
+				t.Fatalf("failed to unmarshal the response: %v", err)
+			}
+
+			expected := []NonCompliantCluster{
+				{"managed1", "managed1", policiesv1.NonCompliant, "a pod is missing"},
+				{"managed3", "managed3", policiesv1.NonCompliant, "a role is missing; a role binding is missing"},
+				{"managed4", "managed4", policiesv1.NonCompliant, ""},
+			}
+
+			if len(clusters.Clusters) != len(expected) || clusters.Continue != "" {
+				t.Fatalf("expected %d NonCompliant clusters in a single page, got %+v", len(expected), clusters)
+			}
+
+			for i := range expected {
+				if clusters.Clusters[i] != expected[i] {
+					t.Fatalf("expected %+v, got %+v", expected[i], clusters.Clusters[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListNonCompliantClustersPagination(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	objects := make([]client.Object, 0, 5)
+
+	for i := 1; i <= 5; i++ {
+		compliance := policiesv1.NonCompliant
+		if i == 2 {
+			compliance = policiesv1.Compliant
+		}
+
+		objects = append(objects, fakeReplicatedPolicyWithCompliance("managed"+strconv.Itoa(i), compliance))
+	}
+
+	reader := &pagedReader{Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()}
+	rootPolicy := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+
+	var clusterNamespaces []string
+
+	continueToken := ""
+
+	for {
+		page, err := ListNonCompliantClusters(context.TODO(), reader, rootPolicy, 2, continueToken)
+		if err != nil {
+			t.Fatalf("Unexpected error listing the NonCompliant clusters: %v", err)
+		}
+
+		if len(page.Clusters) > 2 {
+			t.Fatalf("Expected at most 2 clusters per page, got %d", len(page.Clusters))
+		}
+
+		for _, cluster := range page.Clusters {
+			clusterNamespaces = append(clusterNamespaces, cluster.ClusterNamespace)
+		}
+
+		if page.Continue == "" {
+			break
+		}
+
+		continueToken = page.Continue
+	}
+
+	if reader.lists != 3 {
+		t.Fatalf("Expected 3 pages of replicated policies, got %d", reader.lists)
+	}
+
+	expected := []string{"managed1", "managed3", "managed4", "managed5"}
+
+	if len(clusterNamespaces) != len(expected) {
+		t.Fatalf("Expected the NonCompliant clusters %v, got %v", expected, clusterNamespaces)
+	}
+
+	for i := range expected {
+		if clusterNamespaces[i] != expected[i] {
+			t.Fatalf("Expected the NonCompliant clusters %v, got %v", expected, clusterNamespaces)
+		}
+	}
+}
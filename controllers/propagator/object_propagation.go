@@ -0,0 +1,631 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const ObjectControllerName string = "object-propagator"
+
+var ErrInvalidPropagatedKind = errors.New("invalid propagated kind")
+
+// ParsePropagatedKinds parses the input kinds in the `Kind.version.group` format, such as
+// `ConfigurationPolicy.v1.policy.open-cluster-management.io`, into the kinds that the ObjectReconciler propagates.
+func ParsePropagatedKinds(rawKinds []string) ([]schema.GroupVersionKind, error) {
+	gvks := make([]schema.GroupVersionKind, 0, len(rawKinds))
+
+	for _, rawKind := range rawKinds {
+		gvk, _ := schema.ParseKindArg(rawKind)
+		if gvk == nil || gvk.Kind == "" || gvk.Version == "" || gvk.Group == "" {
+			return nil, fmt.Errorf("%w: %q is not in the format Kind.version.group", ErrInvalidPropagatedKind, rawKind)
+		}
+
+		if gvk.Group == policiesv1.SchemeGroupVersion.Group &&
+			(gvk.Kind == policiesv1.Kind || gvk.Kind == policiesv1.PolicySetKind) {
+			return nil, fmt.Errorf("%w: %s is already propagated by the policy propagator", ErrInvalidPropagatedKind, rawKind)
+		}
+
+		gvks = append(gvks, *gvk)
+	}
+
+	return gvks, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ObjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(r.GVK)
+
+	builder := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: int(r.MaxConcurrentReconciles)}).
+		Named(ObjectControllerName+"-"+strings.ToLower(r.GVK.Kind)).
+		For(object, builder.WithPredicates(common.NeverEnqueue)).
+		// The root label on the copies in the cluster namespaces follows the same convention as replicated policies,
+		// so the policy mapper queues the root object for both.
+		Watches(
+			&source.Kind{Type: object},
+			handler.EnqueueRequestsFromMapFunc(common.PolicyMapper(mgr.GetClient()))).
+		Watches(
+			&source.Kind{Type: &policiesv1.PlacementBinding{}},
+			handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+				//nolint:forcetypeassert
+				return r.subjectRequests(obj.(*policiesv1.PlacementBinding))
+			})).
+		Watches(
+			&source.Kind{Type: &appsv1.PlacementRule{}},
+			handler.EnqueueRequestsFromMapFunc(r.placementRefMapper(
+				appsv1.SchemeGroupVersion.Group, "PlacementRule", func(obj client.Object) string {
+					return obj.GetName()
+				},
+			)))
+
+	if common.PlacementAPIAvailable() {
+		builder.Watches(
+			&source.Kind{Type: &clusterv1beta1.PlacementDecision{}},
+			handler.EnqueueRequestsFromMapFunc(r.placementRefMapper(
+				clusterv1beta1.SchemeGroupVersion.Group, "Placement", func(obj client.Object) string {
+					return obj.GetLabels()["cluster.open-cluster-management.io/placement"]
+				},
+			)))
+	}
+
+	return builder.Complete(r)
+}
+
+// subjectRequests returns a reconcile request for each object of the propagated kind selected by a subject of the
+// input PlacementBinding. A subject with nameIsPattern set selects every object of the propagated kind in the
+// namespace with a matching name.
+func (r *ObjectReconciler) subjectRequests(pb *policiesv1.PlacementBinding) []reconcile.Request {
+	var result []reconcile.Request
+
+	for _, subject := range pb.Subjects {
+		if subject.APIGroup != r.GVK.Group || subject.Kind != r.GVK.Kind {
+			continue
+		}
+
+		if !subject.NameIsPattern {
+			result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      subject.Name,
+				Namespace: pb.GetNamespace(),
+			}})
+
+			continue
+		}
+
+		objects := &unstructured.UnstructuredList{}
+		objects.SetGroupVersionKind(r.GVK.GroupVersion().WithKind(r.GVK.Kind + "List"))
+
+		err := r.List(context.TODO(), objects, client.InNamespace(pb.GetNamespace()))
+		if err != nil {
+			log.Error(err, "Failed to list the objects matching the placement binding subject",
+				"kind", r.GVK.Kind, "pattern", subject.Name)
+
+			continue
+		}
+
+		for _, object := range objects.Items {
+			if common.SubjectMatchesObject(subject, r.GVK.GroupKind(), object.GetName()) {
+				result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      object.GetName(),
+					Namespace: pb.GetNamespace(),
+				}})
+			}
+		}
+	}
+
+	return result
+}
+
+// placementRefMapper queues the propagated objects bound by the PlacementBindings whose placementRef has the input
+// API group and kind and the name returned by placementName for the mapped object.
+func (r *ObjectReconciler) placementRefMapper(
+	group string, kind string, placementName func(client.Object) string,
+) handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		name := placementName(object)
+		if name == "" {
+			return nil
+		}
+
+		pbList := &policiesv1.PlacementBindingList{}
+		lopts := &client.ListOptions{Namespace: object.GetNamespace()}
+		opts := client.MatchingFields{"placementRef.name": name}
+		opts.ApplyToList(lopts)
+
+		err := r.List(context.TODO(), pbList, lopts)
+		if err != nil {
+			log.Error(err, "Failed to list the PlacementBindings", "namespace", object.GetNamespace())
+
+			return nil
+		}
+
+		var result []reconcile.Request
+
+		for i := range pbList.Items {
+			if pbList.Items[i].PlacementRef.APIGroup != group || pbList.Items[i].PlacementRef.Kind != kind {
+				continue
+			}
+
+			result = append(result, r.subjectRequests(&pbList.Items[i])...)
+		}
+
+		return result
+	}
+}
+
+// blank assignment to verify that ObjectReconciler implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ObjectReconciler{}
+
+// ObjectReconciler propagates objects of a kind other than Policy, such as a ConfigurationPolicy, that are directly
+// bound by a PlacementBinding. A copy of the root object is placed in the namespace of each selected managed cluster
+// in the same way as replicated policies, without wrapping it in a Policy. The clusters are resolved and the copies
+// are written with the configuration of the PolicyReconciler, such as its AllowedTemplateKinds, ClusterQuarantine,
+// DriftIgnoredPaths, and AgentOwnedPaths, whose paths are relative to the spec of the copies.
+type ObjectReconciler struct {
+	*PolicyReconciler
+	// GVK is the kind of the propagated objects.
+	GVK                     schema.GroupVersionKind
+	MaxConcurrentReconciles uint
+}
+
+// Reconcile creates, updates, or deletes the copies of the root object in the cluster namespaces based on the
+// PlacementBindings that bind it. A copy that was modified outside of the propagator is reverted. When the root object
+// has a kind that isn't in the AllowedTemplateKinds, all of its copies are deleted.
+func (r *ObjectReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	log := log.WithValues(
+		"kind", r.GVK.Kind, "Request.Namespace", request.Namespace, "Request.Name", request.Name,
+	)
+
+	// Requests can also come from the placement bindings and placements in any namespace
+	if !common.IsWatchedRootNamespace(request.Namespace) {
+		log.V(2).Info("The root object is not in a watched namespace. Ignoring it.")
+
+		return reconcile.Result{}, nil
+	}
+
+	log.V(1).Info("Reconciling the propagated object")
+
+	root := &unstructured.Unstructured{}
+	root.SetGroupVersionKind(r.GVK)
+
+	err := r.Get(ctx, request.NamespacedName, root)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Error(err, "Failed to get the root object")
+
+			return reconcile.Result{}, err
+		}
+
+		log.Info("The root object was deleted. Cleaning up its copies.")
+
+		templateKindRejectedMetric.DeletePartialMatch(
+			prometheus.Labels{"name": request.Name, "namespace": request.Namespace},
+		)
+
+		return reconcile.Result{}, r.cleanUpCopies(ctx, request.NamespacedName, nil)
+	}
+
+	var decisions []clusterDecision
+
+	if root.GetDeletionTimestamp() == nil && r.kindAllowed(root) {
+		pbList := &policiesv1.PlacementBindingList{}
+
+		err := r.List(ctx, pbList, client.InNamespace(root.GetNamespace()))
+		if err != nil {
+			log.Error(err, "Failed to list the placement bindings of the root object")
+
+			return reconcile.Result{}, err
+		}
+
+		decisions, _, err = r.resolveClusterDecisions(standInPolicy(root), pbList)
+		if err != nil && !isNoTargetsError(err) {
+			log.Error(err, "Failed to get the placement decisions of the root object")
+
+			return reconcile.Result{}, err
+		}
+	}
+
+	clusterNamespaces, requeueAfter, err := r.handleCopies(ctx, root, decisions)
+	if err != nil {
+		log.Error(err, "Failed to propagate the root object")
+	}
+
+	// The copies in the cluster namespaces that failed are kept since the cluster is still placed
+	err = errors.Join(err, r.cleanUpCopies(ctx, request.NamespacedName, clusterNamespaces))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// kindAllowed returns true if the input root object and the objects embedded in it have kinds in the
+// AllowedTemplateKinds. Otherwise, a warning event is emitted on the root object and the
+// policy_template_kind_rejected_total metric is incremented.
+func (r *ObjectReconciler) kindAllowed(root *unstructured.Unstructured) bool {
+	if r.AllowedTemplateKinds == nil {
+		return true
+	}
+
+	gvk, err := disallowedObjectKind(r.AllowedTemplateKinds, root)
+	if err == nil && gvk.Empty() {
+		return true
+	}
+
+	metricKind, kindErr := templateKindRejection(gvk, err)
+
+	log.Info(
+		"Not propagating the root object since it has a kind that isn't allowed",
+		"kind", r.GVK.Kind, "namespace", root.GetNamespace(), "name", root.GetName(), "reason", kindErr.Error(),
+	)
+
+	templateKindRejectedMetric.WithLabelValues(root.GetName(), root.GetNamespace(), metricKind).Inc()
+
+	r.Recorder.Event(root, "Warning", "PolicyPropagation",
+		fmt.Sprintf("%s %s/%s can't be propagated: %s", r.GVK.Kind, root.GetNamespace(), root.GetName(), kindErr.Error()))
+
+	return false
+}
+
+// standInPolicy returns a root policy with the namespace and name of the input root object and with its kind in the
+// type metadata, so that resolveClusterDecisions resolves the clusters of the root object from the subjects that
+// select it, in the same way as for a root policy.
+func standInPolicy(root *unstructured.Unstructured) *policiesv1.Policy {
+	instance := &policiesv1.Policy{}
+	instance.SetGroupVersionKind(root.GroupVersionKind())
+	instance.SetNamespace(root.GetNamespace())
+	instance.SetName(root.GetName())
+
+	return instance
+}
+
+// objectCopyHandler is the decisionHandler that writes the copies of a root object, so that they're written by
+// handleDecisionWrapper with the same propagation timeout and cluster quarantine as the replicated policies.
+type objectCopyHandler struct {
+	reconciler *ObjectReconciler
+	root       *unstructured.Unstructured
+}
+
+func (h *objectCopyHandler) handleDecision(
+	ctx context.Context, _ *policiesv1.Policy, decision clusterDecision,
+) (map[k8sdepwatches.ObjectIdentifier]bool, error) {
+	return nil, h.reconciler.handleCopy(ctx, h.root, decision)
+}
+
+// handleCopies writes the copies of the input root object for the input cluster decisions. It returns the cluster
+// namespaces of the decisions, the delay until the next probe of the quarantined clusters that were skipped, and an
+// error naming the cluster namespaces that a copy couldn't be written to.
+func (r *ObjectReconciler) handleCopies(
+	ctx context.Context, root *unstructured.Unstructured, decisions []clusterDecision,
+) (map[string]bool, time.Duration, error) {
+	clusterNamespaces := make(map[string]bool, len(decisions))
+
+	if len(decisions) == 0 {
+		return clusterNamespaces, 0, nil
+	}
+
+	decisionsChan := make(chan clusterDecision, len(decisions))
+	resultsChan := make(chan decisionResult, len(decisions))
+	handler := &objectCopyHandler{reconciler: r, root: root}
+	instance := standInPolicy(root)
+
+	var deadline time.Time
+	if r.PropagationTimeout > 0 {
+		deadline = time.Now().Add(r.PropagationTimeout)
+	}
+
+	for i := 0; i < common.GetNumWorkers(len(decisions), concurrencyPerPolicy); i++ {
+		go handleDecisionWrapper(ctx, handler, instance, decisionsChan, resultsChan, deadline, r.ClusterQuarantine)
+	}
+
+	for _, decision := range decisions {
+		clusterNamespaces[decision.Cluster.ClusterNamespace] = true
+		decisionsChan <- decision
+	}
+
+	close(decisionsChan)
+
+	var failed []string
+
+	var requeueAfter time.Duration
+
+	for range decisions {
+		result := <-resultsChan
+
+		// The root object is requeued for the next probe of a quarantined cluster rather than retried with a backoff
+		if errors.Is(result.Err, errClusterQuarantined) {
+			probeDelay := r.ClusterQuarantine.probeDelay(result.Identifier.ClusterNamespace, time.Now())
+			if requeueAfter == 0 || probeDelay < requeueAfter {
+				requeueAfter = probeDelay
+			}
+
+			continue
+		}
+
+		if result.Err != nil {
+			log.Info(
+				"Failed to propagate the root object to the cluster namespace",
+				"kind", r.GVK.Kind,
+				"namespace", root.GetNamespace(),
+				"name", root.GetName(),
+				"clusterNamespace", result.Identifier.ClusterNamespace,
+				"error", result.Err.Error(),
+			)
+
+			failed = append(failed, result.Identifier.ClusterNamespace)
+		}
+	}
+
+	if len(failed) != 0 {
+		sort.Strings(failed)
+
+		return clusterNamespaces, 0, errors.New("failed to handle cluster namespaces:" + strings.Join(failed, ","))
+	}
+
+	return clusterNamespaces, requeueAfter, nil
+}
+
+// objectSpec returns the content of the input object without its metadata and status, which is what the
+// SpecHashAnnotation of a propagated copy is calculated from.
+func objectSpec(obj *unstructured.Unstructured) map[string]interface{} {
+	spec := make(map[string]interface{}, len(obj.Object))
+
+	for key, value := range obj.Object {
+		switch key {
+		case "apiVersion", "kind", "metadata", "status":
+			continue
+		}
+
+		spec[key] = value
+	}
+
+	return spec
+}
+
+// canonicalObjectSpec returns a copy of the content of the input object, as returned by objectSpec, without the fields
+// of its spec at the input paths. It's what the copies are compared by.
+func canonicalObjectSpec(obj *unstructured.Unstructured, ignoredPaths [][]string) map[string]interface{} {
+	content := runtime.DeepCopyJSON(objectSpec(obj))
+
+	if spec, ok := content["spec"]; ok {
+		for _, path := range ignoredPaths {
+			removeSpecPath(spec, path)
+		}
+	}
+
+	return content
+}
+
+// buildObjectCopy returns the desired copy of the input root object for the input cluster decision. It's named and
+// labeled in the same way as a replicated policy, and the enforce remediationAction binding override is applied to
+// the spec.remediationAction field when the root object has it. The SpecHashAnnotation is calculated without the
+// fields at the input agent-owned paths.
+func buildObjectCopy(
+	root *unstructured.Unstructured, decision clusterDecision, agentOwnedPaths [][]string,
+) (*unstructured.Unstructured, error) {
+	desired := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(objectSpec(root))}
+	desired.SetGroupVersionKind(root.GroupVersionKind())
+	desired.SetName(common.ReplicatedPolicyName(root.GetNamespace(), root.GetName()))
+	desired.SetNamespace(decision.Cluster.ClusterNamespace)
+
+	if strings.EqualFold(decision.PolicyOverrides.RemediationAction, string(policiesv1.Enforce)) {
+		if _, found, _ := unstructured.NestedString(desired.Object, "spec", "remediationAction"); found {
+			err := unstructured.SetNestedField(
+				desired.Object, strings.ToLower(string(policiesv1.Enforce)), "spec", "remediationAction",
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	labels := root.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	labels[common.RootPolicyLabel] = common.ReplicatedPolicyName(root.GetNamespace(), root.GetName())
+	labels[common.ClusterNameLabel] = decision.Cluster.ClusterName
+	labels[common.ClusterNamespaceLabel] = decision.Cluster.ClusterNamespace
+
+	if hubID := common.HubID(); hubID != "" {
+		labels[common.SourceHubLabel] = hubID
+	}
+
+	desired.SetLabels(labels)
+
+	hash, err := specHash(canonicalObjectSpec(desired, agentOwnedPaths))
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := root.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[SpecHashAnnotation] = hash
+	desired.SetAnnotations(annotations)
+
+	return desired, nil
+}
+
+// handleCopy creates or updates the copy of the input root object for the input cluster decision. Like a replicated
+// policy, an existing object that isn't a copy of the root object for the cluster, or that was created by another hub,
+// is never overwritten, the fields at the AgentOwnedPaths of an existing copy are kept, and an existing copy is only
+// updated when it differs from the desired copy without the fields at the DriftIgnoredPaths.
+func (r *ObjectReconciler) handleCopy(
+	ctx context.Context, root *unstructured.Unstructured, decision clusterDecision,
+) error {
+	clusterNamespace := decision.Cluster.ClusterNamespace
+
+	if err := common.ValidateReplicaNamespace(clusterNamespace); err != nil {
+		return err
+	}
+
+	desired, err := buildObjectCopy(root, decision, r.AgentOwnedPaths)
+	if err != nil {
+		return err
+	}
+
+	log := log.WithValues("kind", r.GVK.Kind, "namespace", clusterNamespace, "name", desired.GetName())
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(r.GVK)
+
+	err = r.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: desired.GetName()}, existing)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+
+		log.Info("Creating the propagated object")
+
+		err = r.Create(ctx, desired)
+		if err != nil {
+			// The cluster namespace may not exist yet when the cluster is being onboarded
+			if k8serrors.IsNotFound(err) {
+				return fmt.Errorf("%w: %s", errNamespaceNotFound, clusterNamespace)
+			}
+
+			return err
+		}
+
+		r.Recorder.Event(
+			root, "Normal", "PolicyPropagation",
+			fmt.Sprintf("%s %s was propagated to the cluster namespace %s", r.GVK.Kind, root.GetName(), clusterNamespace),
+		)
+
+		return nil
+	}
+
+	if err := r.checkCopyOwner(root, existing, decision); err != nil {
+		return err
+	}
+
+	for _, path := range r.AgentOwnedPaths {
+		copySpecPath(desired.Object["spec"], existing.Object["spec"], path)
+	}
+
+	ignoredPaths := r.ignoredSpecPaths()
+
+	if equality.Semantic.DeepEqual(existing.GetLabels(), desired.GetLabels()) &&
+		equality.Semantic.DeepEqual(existing.GetAnnotations(), desired.GetAnnotations()) &&
+		equality.Semantic.DeepEqual(canonicalObjectSpec(existing, ignoredPaths), canonicalObjectSpec(desired, ignoredPaths)) {
+		log.V(2).Info("The propagated object is up to date")
+
+		return nil
+	}
+
+	if existing.GetAnnotations()[SpecHashAnnotation] == desired.GetAnnotations()[SpecHashAnnotation] {
+		log.Info("The propagated object was modified outside of the propagator. Reverting it.")
+	} else {
+		log.Info("Updating the propagated object")
+	}
+
+	desired.SetResourceVersion(existing.GetResourceVersion())
+
+	// Keep the status since only the managed cluster sets it
+	if status, ok := existing.Object["status"]; ok {
+		desired.Object["status"] = status
+	}
+
+	return r.Update(ctx, desired)
+}
+
+// checkCopyOwner returns an error wrapping errReplicaNameConflict, and emits a warning event on the root object, if
+// the input existing object with the name of the copy of the root object for the input cluster decision isn't a copy
+// of the root object for that cluster, or was created by another hub.
+func (r *ObjectReconciler) checkCopyOwner(
+	root *unstructured.Unstructured, existing *unstructured.Unstructured, decision clusterDecision,
+) error {
+	owner, _ := common.GetRootPolicyLabel(existing)
+	copyCluster := existing.GetLabels()[common.ClusterNameLabel]
+
+	var reason string
+
+	if owner != common.ReplicatedPolicyName(root.GetNamespace(), root.GetName()) ||
+		(copyCluster != "" && copyCluster != decision.Cluster.ClusterName) {
+		reason = fmt.Sprintf("it has the root policy label %q and the cluster name label %q", owner, copyCluster)
+	} else if common.IsOwnedByOtherHub(existing) {
+		reason = fmt.Sprintf("it was created by the hub %q", existing.GetLabels()[common.SourceHubLabel])
+	} else {
+		return nil
+	}
+
+	log.Info(
+		"An object that isn't a copy of the root object already has the name of the copy",
+		"kind", r.GVK.Kind, "namespace", existing.GetNamespace(), "name", existing.GetName(), "reason", reason,
+	)
+
+	r.Recorder.Event(root, "Warning", "PolicyPropagation",
+		fmt.Sprintf("%s %s/%s can't be propagated to cluster %s/%s since the %s %s/%s already exists and %s",
+			r.GVK.Kind, root.GetNamespace(), root.GetName(), decision.Cluster.ClusterNamespace,
+			decision.Cluster.ClusterName, r.GVK.Kind, existing.GetNamespace(), existing.GetName(), reason))
+
+	return fmt.Errorf(
+		"%w: the %s %s/%s already exists and %s",
+		errReplicaNameConflict, r.GVK.Kind, existing.GetNamespace(), existing.GetName(), reason,
+	)
+}
+
+// cleanUpCopies deletes the copies of the root object with the input namespace and name that are not in one of the
+// input cluster namespaces. The copies created by another hub are kept.
+func (r *ObjectReconciler) cleanUpCopies(
+	ctx context.Context, rootObject types.NamespacedName, clusterNamespaces map[string]bool,
+) error {
+	copies := &unstructured.UnstructuredList{}
+	copies.SetGroupVersionKind(r.GVK.GroupVersion().WithKind(r.GVK.Kind + "List"))
+
+	err := r.List(ctx, copies, client.MatchingLabels{
+		common.RootPolicyLabel: common.ReplicatedPolicyName(rootObject.Namespace, rootObject.Name),
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range copies.Items {
+		if clusterNamespaces[copies.Items[i].GetNamespace()] || common.IsOwnedByOtherHub(&copies.Items[i]) {
+			continue
+		}
+
+		log.Info(
+			"Deleting the propagated object that is no longer placed",
+			"kind", r.GVK.Kind, "namespace", copies.Items[i].GetNamespace(), "name", copies.Items[i].GetName(),
+		)
+
+		err := r.Delete(ctx, &copies.Items[i])
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,428 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+var configPolicyGVK = schema.GroupVersionKind{
+	Group: policiesv1.SchemeGroupVersion.Group, Version: "v1", Kind: "ConfigurationPolicy",
+}
+
+func fakeConfigPolicy(name, namespace string, severity string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"remediationAction": "inform", "severity": severity},
+	}}
+	obj.SetGroupVersionKind(configPolicyGVK)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+
+	return obj
+}
+
+// objectCopyDecision returns the cluster decision of the cluster with the input name and namespace.
+func objectCopyDecision(cluster string) clusterDecision {
+	return clusterDecision{Cluster: appsv1.PlacementDecision{ClusterName: cluster, ClusterNamespace: cluster}}
+}
+
+func TestParsePropagatedKinds(t *testing.T) {
+	gvks, err := ParsePropagatedKinds([]string{"ConfigurationPolicy.v1.policy.open-cluster-management.io"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(gvks) != 1 || gvks[0] != configPolicyGVK {
+		t.Fatalf("expected the ConfigurationPolicy kind, got %v", gvks)
+	}
+
+	for _, rawKind := range []string{"ConfigurationPolicy", "Policy.v1.policy.open-cluster-management.io"} {
+		if _, err := ParsePropagatedKinds([]string{rawKind}); !errors.Is(err, ErrInvalidPropagatedKind) {
+			t.Fatalf("expected the kind %q to be invalid, got %v", rawKind, err)
+		}
+	}
+}
+
+func TestObjectReconcile(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	if err := appsv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configPolicyGVK, meta.RESTScopeNamespace)
+	mapper.Add(policiesv1.SchemeGroupVersion.WithKind("PlacementBinding"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("PlacementRule"), meta.RESTScopeNamespace)
+
+	root := fakeConfigPolicy("my-config", "policies", "low")
+
+	pr := fakePlacementRule("my-rule", "policies", []appsv1.PlacementDecision{
+		{ClusterName: "cluster1", ClusterNamespace: "cluster1"},
+		{ClusterName: "cluster2", ClusterNamespace: "cluster2"},
+	})
+
+	pb := fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{{APIGroup: configPolicyGVK.Group, Kind: configPolicyGVK.Kind, Name: "my-config"}},
+	)
+
+	// A copy in a cluster namespace that is no longer placed and a copy that was modified outside of the propagator
+	stale, err := buildObjectCopy(root, objectCopyDecision("cluster3"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	drifted, err := buildObjectCopy(root, objectCopyDecision("cluster2"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	drifted.Object["spec"] = map[string]interface{}{"remediationAction": "enforce", "severity": "low"}
+
+	c := fake.NewClientBuilder().
+		WithScheme(testscheme).
+		WithRESTMapper(mapper).
+		WithObjects(root, &pr, &pb, stale, drifted).
+		Build()
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	r := &ObjectReconciler{
+		PolicyReconciler: &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10), Scheme: testscheme},
+		GVK:              configPolicyGVK,
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-config"}}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	copies := &unstructured.UnstructuredList{}
+	copies.SetGroupVersionKind(configPolicyGVK.GroupVersion().WithKind("ConfigurationPolicyList"))
+
+	err = c.List(context.TODO(), copies, client.MatchingLabels{common.RootPolicyLabel: "policies.my-config"})
+	if err != nil {
+		t.Fatalf("failed to list the copies: %v", err)
+	}
+
+	if len(copies.Items) != 2 {
+		t.Fatalf("expected a copy in each placed cluster namespace, got %d copies", len(copies.Items))
+	}
+
+	for _, copied := range copies.Items {
+		if copied.GetNamespace() != "cluster1" && copied.GetNamespace() != "cluster2" {
+			t.Fatalf("unexpected copy in the namespace %s", copied.GetNamespace())
+		}
+
+		action, _, _ := unstructured.NestedString(copied.Object, "spec", "remediationAction")
+		if action != "inform" {
+			t.Fatalf("expected the copy in %s to match the root object, got %s", copied.GetNamespace(), action)
+		}
+
+		if copied.GetLabels()[common.ClusterNamespaceLabel] != copied.GetNamespace() {
+			t.Fatalf("expected the cluster namespace label to be set on the copy in %s", copied.GetNamespace())
+		}
+	}
+
+	if err := c.Delete(context.TODO(), root); err != nil {
+		t.Fatalf("failed to delete the root object: %v", err)
+	}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = c.List(context.TODO(), copies, client.MatchingLabels{common.RootPolicyLabel: "policies.my-config"})
+	if err != nil {
+		t.Fatalf("failed to list the copies: %v", err)
+	}
+
+	if len(copies.Items) != 0 {
+		t.Fatalf("expected the copies to be deleted with the root object, got %d copies", len(copies.Items))
+	}
+}
+
+func TestObjectReconcileKindNotAllowed(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	if err := appsv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	t.Cleanup(templateKindRejectedMetric.Reset)
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configPolicyGVK, meta.RESTScopeNamespace)
+	mapper.Add(policiesv1.SchemeGroupVersion.WithKind("PlacementBinding"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("PlacementRule"), meta.RESTScopeNamespace)
+
+	root := fakeConfigPolicy("my-config", "policies", "low")
+	err := unstructured.SetNestedSlice(root.Object, []interface{}{
+		map[string]interface{}{
+			"complianceType": "musthave",
+			"objectDefinition": map[string]interface{}{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "ClusterRoleBinding",
+				"metadata":   map[string]interface{}{"name": "cluster-admins"},
+			},
+		},
+	}, "spec", "object-templates")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pr := fakePlacementRule("my-rule", "policies", []appsv1.PlacementDecision{
+		{ClusterName: "cluster1", ClusterNamespace: "cluster1"},
+	})
+
+	pb := fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{{APIGroup: configPolicyGVK.Group, Kind: configPolicyGVK.Kind, Name: "my-config"}},
+	)
+
+	// A copy written before the ClusterRoleBinding kind was disallowed
+	existing, err := buildObjectCopy(root, objectCopyDecision("cluster1"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(testscheme).
+		WithRESTMapper(mapper).
+		WithObjects(root, &pr, &pb, existing).
+		Build()
+
+	allowed, err := ParseAllowedTemplateKinds([]string{"ConfigurationPolicy.v1.policy.open-cluster-management.io"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	recorder := record.NewFakeRecorder(10)
+	r := &ObjectReconciler{
+		PolicyReconciler: &PolicyReconciler{
+			Client: c, AllowedTemplateKinds: allowed, Recorder: recorder, Scheme: testscheme,
+		},
+		GVK: configPolicyGVK,
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-config"}}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	copies := &unstructured.UnstructuredList{}
+	copies.SetGroupVersionKind(configPolicyGVK.GroupVersion().WithKind("ConfigurationPolicyList"))
+
+	err = c.List(context.TODO(), copies, client.MatchingLabels{common.RootPolicyLabel: "policies.my-config"})
+	if err != nil {
+		t.Fatalf("failed to list the copies: %v", err)
+	}
+
+	if len(copies.Items) != 0 {
+		t.Fatalf("expected the copies with a kind that isn't allowed to be deleted, got %d copies", len(copies.Items))
+	}
+
+	rejected := testutil.ToFloat64(
+		templateKindRejectedMetric.WithLabelValues("my-config", "policies", "ClusterRoleBinding.rbac.authorization.k8s.io"),
+	)
+	if rejected != 1 {
+		t.Fatalf("expected the rejection to be counted once, got %v", rejected)
+	}
+
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected a warning event on the root object, got %d events", len(recorder.Events))
+	}
+
+	r.AllowedTemplateKinds = append(r.AllowedTemplateKinds, schema.GroupVersionKind{
+		Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding",
+	})
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = c.List(context.TODO(), copies, client.MatchingLabels{common.RootPolicyLabel: "policies.my-config"})
+	if err != nil {
+		t.Fatalf("failed to list the copies: %v", err)
+	}
+
+	if len(copies.Items) != 1 {
+		t.Fatalf("expected the copy to be created once the kind is allowed, got %d copies", len(copies.Items))
+	}
+}
+
+func TestObjectReconcileReplicaWritePath(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	if err := appsv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configPolicyGVK, meta.RESTScopeNamespace)
+	mapper.Add(policiesv1.SchemeGroupVersion.WithKind("PlacementBinding"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("PlacementRule"), meta.RESTScopeNamespace)
+
+	root := fakeConfigPolicy("my-config", "policies", "low")
+
+	pr := fakePlacementRule("my-rule", "policies", []appsv1.PlacementDecision{
+		{ClusterName: "cluster1", ClusterNamespace: "cluster1"},
+		{ClusterName: "cluster2", ClusterNamespace: "cluster2"},
+	})
+
+	// The pattern subject selects the root object and the binding override enforces it
+	pb := fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{{
+			APIGroup: configPolicyGVK.Group, Kind: configPolicyGVK.Kind, Name: "my-*", NameIsPattern: true,
+		}},
+	)
+	pb.BindingOverrides.RemediationAction = "Enforce"
+
+	// An object in cluster2 with the name of the copy that belongs to another root object
+	conflicting, err := buildObjectCopy(
+		fakeConfigPolicy("my-config", "other", "high"), objectCopyDecision("cluster2"), nil,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conflicting.SetName("policies.my-config")
+
+	c := fake.NewClientBuilder().
+		WithScheme(testscheme).
+		WithRESTMapper(mapper).
+		WithObjects(root, &pr, &pb, conflicting).
+		Build()
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	r := &ObjectReconciler{
+		PolicyReconciler: &PolicyReconciler{
+			Client:          c,
+			Recorder:        record.NewFakeRecorder(10),
+			Scheme:          testscheme,
+			AgentOwnedPaths: [][]string{{"severity"}},
+		},
+		GVK: configPolicyGVK,
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "my-config"}}
+
+	if _, err := r.Reconcile(context.TODO(), request); err == nil || !strings.Contains(err.Error(), "cluster2") {
+		t.Fatalf("expected the name conflict in cluster2 to fail the reconcile, got %v", err)
+	}
+
+	if err := r.handleCopy(context.TODO(), root, objectCopyDecision("cluster2")); !errors.Is(err, errReplicaNameConflict) {
+		t.Fatalf("expected a name conflict for the copy in cluster2, got %v", err)
+	}
+
+	getCopy := func(namespace string) *unstructured.Unstructured {
+		t.Helper()
+
+		copied := &unstructured.Unstructured{}
+		copied.SetGroupVersionKind(configPolicyGVK)
+
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "policies.my-config"}, copied)
+		if err != nil {
+			t.Fatalf("failed to get the copy in %s: %v", namespace, err)
+		}
+
+		return copied
+	}
+
+	if owner := getCopy("cluster2").GetLabels()[common.RootPolicyLabel]; owner != "other.my-config" {
+		t.Fatalf("expected the object of the other root object to not be overwritten, got the owner %q", owner)
+	}
+
+	copied := getCopy("cluster1")
+
+	if action, _, _ := unstructured.NestedString(copied.Object, "spec", "remediationAction"); action != "enforce" {
+		t.Fatalf("expected the binding override to enforce the copy, got %s", action)
+	}
+
+	// The agent-owned field is kept when the copy is updated for a change to the root object
+	if err := unstructured.SetNestedField(copied.Object, "critical", "spec", "severity"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Update(context.TODO(), copied); err != nil {
+		t.Fatalf("failed to update the copy: %v", err)
+	}
+
+	if err := c.Get(context.TODO(), request.NamespacedName, root); err != nil {
+		t.Fatalf("failed to get the root object: %v", err)
+	}
+
+	if err := unstructured.SetNestedField(root.Object, "musthave", "spec", "complianceType"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Update(context.TODO(), root); err != nil {
+		t.Fatalf("failed to update the root object: %v", err)
+	}
+
+	if _, err := r.Reconcile(context.TODO(), request); err == nil {
+		t.Fatal("expected the name conflict in cluster2 to still fail the reconcile")
+	}
+
+	copied = getCopy("cluster1")
+
+	complianceType, _, _ := unstructured.NestedString(copied.Object, "spec", "complianceType")
+	if complianceType != "musthave" {
+		t.Fatalf("expected the change to the root object to be propagated, got %q", complianceType)
+	}
+
+	if severity, _, _ := unstructured.NestedString(copied.Object, "spec", "severity"); severity != "critical" {
+		t.Fatalf("expected the agent-owned severity to be kept, got %q", severity)
+	}
+
+	// An object in an unwatched namespace is ignored
+	common.SetWatchedRootNamespaces([]string{"other"})
+	defer common.SetWatchedRootNamespaces(nil)
+
+	if err := c.Delete(context.TODO(), root); err != nil {
+		t.Fatalf("failed to delete the root object: %v", err)
+	}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	getCopy("cluster1")
+}
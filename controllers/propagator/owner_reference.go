@@ -0,0 +1,95 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// OwnerReferenceStrategy determines how a replicated policy refers back to its root policy.
+type OwnerReferenceStrategy string
+
+const (
+	// OwnerReferenceNone doesn't set an owner on the replicated policies. This is the default.
+	OwnerReferenceNone OwnerReferenceStrategy = "none"
+	// OwnerReferenceCrossNamespaceAnnotation sets the OwnerAnnotation and OwnerUIDAnnotation on the replicated
+	// policies. The Kubernetes garbage collector doesn't honor owner references across namespaces, so the replicated
+	// policies are still deleted by the propagator when the root policy is deleted.
+	OwnerReferenceCrossNamespaceAnnotation OwnerReferenceStrategy = "cross-namespace-annotation"
+	// OwnerReferenceSameNamespace sets an owner reference to the root policy on the replicated policies in the same
+	// namespace as the root policy, and falls back to the OwnerReferenceCrossNamespaceAnnotation strategy for the
+	// others.
+	OwnerReferenceSameNamespace OwnerReferenceStrategy = "same-namespace-ownerref"
+)
+
+const (
+	// OwnerAnnotation is set to the namespace and name of the root policy on the replicated policies with the
+	// OwnerReferenceCrossNamespaceAnnotation strategy.
+	OwnerAnnotation = "policy.open-cluster-management.io/owner"
+	// OwnerUIDAnnotation is set to the UID of the root policy on the replicated policies with the
+	// OwnerReferenceCrossNamespaceAnnotation strategy, so that a replicated policy of a previous root policy with the
+	// same name can be told apart.
+	OwnerUIDAnnotation = "policy.open-cluster-management.io/owner-uid"
+)
+
+// ErrInvalidOwnerReferenceStrategy is returned by ParseOwnerReferenceStrategy for an unknown strategy.
+var ErrInvalidOwnerReferenceStrategy = errors.New("invalid owner reference strategy")
+
+// ParseOwnerReferenceStrategy returns the owner reference strategy with the input name. An empty name is the
+// OwnerReferenceNone strategy.
+func ParseOwnerReferenceStrategy(rawStrategy string) (OwnerReferenceStrategy, error) {
+	switch strategy := OwnerReferenceStrategy(rawStrategy); strategy {
+	case "", OwnerReferenceNone:
+		return OwnerReferenceNone, nil
+	case OwnerReferenceCrossNamespaceAnnotation, OwnerReferenceSameNamespace:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf(
+			"%w: %q must be one of %s, %s, or %s", ErrInvalidOwnerReferenceStrategy, rawStrategy,
+			OwnerReferenceNone, OwnerReferenceCrossNamespaceAnnotation, OwnerReferenceSameNamespace,
+		)
+	}
+}
+
+// setReplicaOwner sets the owner reference or the owner annotations of the input replicated policy to the input root
+// policy according to the OwnerReferenceStrategy. Any owner annotations copied from the root policy are removed.
+func (r *PolicyReconciler) setReplicaOwner(root *policiesv1.Policy, replicated *policiesv1.Policy) {
+	replicated.SetOwnerReferences(nil)
+
+	annotations := replicated.GetAnnotations()
+	delete(annotations, OwnerAnnotation)
+	delete(annotations, OwnerUIDAnnotation)
+
+	switch r.OwnerReferenceStrategy {
+	case OwnerReferenceSameNamespace:
+		if replicated.Namespace == root.Namespace {
+			isController := true
+
+			replicated.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: policiesv1.GroupVersion.String(),
+				Kind:       policiesv1.Kind,
+				Name:       root.Name,
+				UID:        root.UID,
+				Controller: &isController,
+			}})
+
+			break
+		}
+
+		fallthrough
+	case OwnerReferenceCrossNamespaceAnnotation:
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[OwnerAnnotation] = root.Namespace + "/" + root.Name
+		annotations[OwnerUIDAnnotation] = string(root.UID)
+	}
+
+	replicated.SetAnnotations(annotations)
+}
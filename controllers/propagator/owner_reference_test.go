@@ -0,0 +1,194 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestParseOwnerReferenceStrategy(t *testing.T) {
+	tests := map[string]OwnerReferenceStrategy{
+		"":                           OwnerReferenceNone,
+		"none":                       OwnerReferenceNone,
+		"cross-namespace-annotation": OwnerReferenceCrossNamespaceAnnotation,
+		"same-namespace-ownerref":    OwnerReferenceSameNamespace,
+	}
+
+	for rawStrategy, expected := range tests {
+		strategy, err := ParseOwnerReferenceStrategy(rawStrategy)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", rawStrategy, err)
+		}
+
+		if strategy != expected {
+			t.Fatalf("Expected %q to be parsed as %s, got %s", rawStrategy, expected, strategy)
+		}
+	}
+
+	if _, err := ParseOwnerReferenceStrategy("ownerref"); !errors.Is(err, ErrInvalidOwnerReferenceStrategy) {
+		t.Fatalf("Expected an invalid strategy error, got %v", err)
+	}
+}
+
+func TestBuildReplicatedPolicyOwner(t *testing.T) {
+	tests := map[string]struct {
+		strategy          OwnerReferenceStrategy
+		clusterNamespace  string
+		expectedOwnerRef  bool
+		expectedOwnerAnno bool
+	}{
+		"None": {
+			strategy:         OwnerReferenceNone,
+			clusterNamespace: "cluster1",
+		},
+		"Cross-namespace annotation": {
+			strategy:          OwnerReferenceCrossNamespaceAnnotation,
+			clusterNamespace:  "cluster1",
+			expectedOwnerAnno: true,
+		},
+		"Same-namespace owner reference in another namespace": {
+			strategy:          OwnerReferenceSameNamespace,
+			clusterNamespace:  "cluster1",
+			expectedOwnerAnno: true,
+		},
+		"Same-namespace owner reference in the same namespace": {
+			strategy:         OwnerReferenceSameNamespace,
+			clusterNamespace: "policies",
+			expectedOwnerRef: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			rootPolicy := fakeRootPolicy("my-policy", "policies")
+			rootPolicy.UID = types.UID("a1b2c3")
+			// Owner annotations set on the root policy are never copied
+			rootPolicy.Annotations = map[string]string{OwnerAnnotation: "other/policy", OwnerUIDAnnotation: "d4e5f6"}
+
+			reconciler := &PolicyReconciler{OwnerReferenceStrategy: test.strategy}
+			decision := appsv1.PlacementDecision{ClusterName: "cluster1", ClusterNamespace: test.clusterNamespace}
+
+			replicated, err := reconciler.buildReplicatedPolicy(&rootPolicy, clusterDecision{Cluster: decision})
+			if err != nil {
+				t.Fatalf("Unexpected error building the replicated policy: %v", err)
+			}
+
+			ownerRefs := replicated.GetOwnerReferences()
+
+			if !test.expectedOwnerRef && len(ownerRefs) != 0 {
+				t.Fatalf("Expected no owner references, got %v", ownerRefs)
+			}
+
+			if test.expectedOwnerRef {
+				if len(ownerRefs) != 1 || ownerRefs[0].UID != rootPolicy.UID ||
+					ownerRefs[0].Kind != policiesv1.Kind || ownerRefs[0].Name != "my-policy" {
+					t.Fatalf("Expected an owner reference to the root policy, got %v", ownerRefs)
+				}
+			}
+
+			owner, hasOwner := replicated.Annotations[OwnerAnnotation]
+			ownerUID, hasOwnerUID := replicated.Annotations[OwnerUIDAnnotation]
+
+			if !test.expectedOwnerAnno && (hasOwner || hasOwnerUID) {
+				t.Fatalf("Expected no owner annotations, got %q and %q", owner, ownerUID)
+			}
+
+			if test.expectedOwnerAnno && (owner != "policies/my-policy" || ownerUID != "a1b2c3") {
+				t.Fatalf("Expected the owner annotations to be set to the root policy, got %q and %q", owner, ownerUID)
+			}
+		})
+	}
+}
+
+func TestOwnerReferenceStrategyChange(t *testing.T) {
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	rootPolicy.UID = types.UID("a1b2c3")
+	decision := clusterDecision{Cluster: appsv1.PlacementDecision{ClusterName: "local", ClusterNamespace: "policies"}}
+
+	existing, err := (&PolicyReconciler{}).buildReplicatedPolicy(&rootPolicy, decision)
+	if err != nil {
+		t.Fatalf("Unexpected error building the replicated policy: %v", err)
+	}
+
+	for _, strategy := range []OwnerReferenceStrategy{
+		OwnerReferenceCrossNamespaceAnnotation, OwnerReferenceSameNamespace,
+	} {
+		desired, err := (&PolicyReconciler{OwnerReferenceStrategy: strategy}).buildReplicatedPolicy(
+			&rootPolicy, decision,
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error building the replicated policy: %v", err)
+		}
+
+		if equivalentReplicatedPolicies(desired, existing, nil) {
+			t.Fatalf("Expected the replicated policy to be updated when switching to the %s strategy", strategy)
+		}
+	}
+}
+
+func TestCleanUpPolicyOwnerReferenceStrategies(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	for _, strategy := range []OwnerReferenceStrategy{
+		OwnerReferenceNone, OwnerReferenceCrossNamespaceAnnotation, OwnerReferenceSameNamespace,
+	} {
+		rootPolicy := fakeRootPolicy("my-policy", "policies")
+		rootPolicy.UID = types.UID("a1b2c3")
+		reconciler := &PolicyReconciler{
+			OwnerReferenceStrategy: strategy,
+			DynamicWatcher: &fakeDynamicWatcher{
+				watched: map[k8sdepwatches.ObjectIdentifier][]k8sdepwatches.ObjectIdentifier{},
+			},
+		}
+
+		decisions := append(
+			fakePlacementDecisions(2), appsv1.PlacementDecision{ClusterName: "local", ClusterNamespace: "policies"},
+		)
+		objects := make([]client.Object, 0, len(decisions))
+
+		for _, decision := range decisions {
+			replicated, err := reconciler.buildReplicatedPolicy(&rootPolicy, clusterDecision{Cluster: decision})
+			if err != nil {
+				t.Fatalf("Unexpected error building the replicated policy: %v", err)
+			}
+
+			objects = append(objects, replicated)
+		}
+
+		reconciler.Client = fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build()
+
+		if err := reconciler.cleanUpPolicy(&rootPolicy); err != nil {
+			t.Fatalf("Unexpected error cleaning up the replicated policies with the %s strategy: %v", strategy, err)
+		}
+
+		remaining := &policiesv1.PolicyList{}
+		if err := reconciler.List(context.TODO(), remaining); err != nil {
+			t.Fatalf("Failed to list the policies: %v", err)
+		}
+
+		if len(remaining.Items) != 0 {
+			t.Fatalf(
+				"Expected the replicated policies to be deleted with the %s strategy, %d remain",
+				strategy, len(remaining.Items),
+			)
+		}
+	}
+}
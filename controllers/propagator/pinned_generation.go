@@ -0,0 +1,194 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	// PinnedGenerationsAnnotation is set on a ManagedCluster to pin the replicated policies on the cluster to a
+	// metadata.generation of their root policy. The value is a comma separated list of entries in the format
+	// `<root policy namespace>.<root policy name>=<generation>`.
+	PinnedGenerationsAnnotation = "policy.open-cluster-management.io/pinned-generations"
+	// RootGenerationAnnotation is set on a replicated policy built from a pinned generation of the root policy to
+	// that generation.
+	RootGenerationAnnotation = "policy.open-cluster-management.io/root-policy-generation"
+	// generationHistoryLimit is the number of generations of each root policy kept to replicate a pinned generation.
+	generationHistoryLimit = 10
+)
+
+var ErrInvalidPinnedGeneration = errors.New("invalid pinned generation")
+
+// generationHistory maps the namespaced name of a root policy to a *policyGenerations with its most recent
+// generations observed by the propagator.
+var generationHistory sync.Map
+
+type policyGenerations struct {
+	lock     sync.RWMutex
+	policies map[int64]*policiesv1.Policy
+}
+
+// recordGeneration keeps a copy of the input root policy at its current generation so that it can be replicated to
+// the clusters pinned to that generation after the root policy changes. Only the most recent generations, up to
+// generationHistoryLimit, are kept.
+func recordGeneration(instance *policiesv1.Policy) {
+	if instance.Generation == 0 {
+		return
+	}
+
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	history, _ := generationHistory.LoadOrStore(key, &policyGenerations{policies: map[int64]*policiesv1.Policy{}})
+	generations := history.(*policyGenerations) //nolint:forcetypeassert
+
+	generations.lock.Lock()
+	defer generations.lock.Unlock()
+
+	if _, ok := generations.policies[instance.Generation]; ok {
+		return
+	}
+
+	recorded := instance.DeepCopy()
+	recorded.Status = policiesv1.PolicyStatus{}
+	generations.policies[instance.Generation] = recorded
+
+	if len(generations.policies) <= generationHistoryLimit {
+		return
+	}
+
+	recordedGenerations := make([]int64, 0, len(generations.policies))
+	for generation := range generations.policies {
+		recordedGenerations = append(recordedGenerations, generation)
+	}
+
+	sort.Slice(recordedGenerations, func(i, j int) bool { return recordedGenerations[i] < recordedGenerations[j] })
+
+	for _, generation := range recordedGenerations[:len(recordedGenerations)-generationHistoryLimit] {
+		delete(generations.policies, generation)
+	}
+}
+
+// getGeneration returns the root policy with the input namespaced name at the input generation, or nil if that
+// generation isn't kept.
+func getGeneration(key types.NamespacedName, generation int64) *policiesv1.Policy {
+	history, ok := generationHistory.Load(key)
+	if !ok {
+		return nil
+	}
+
+	generations := history.(*policyGenerations) //nolint:forcetypeassert
+
+	generations.lock.RLock()
+	defer generations.lock.RUnlock()
+
+	if policy, ok := generations.policies[generation]; ok {
+		return policy.DeepCopy()
+	}
+
+	return nil
+}
+
+// forgetGenerations removes the generations kept for the root policy with the input namespaced name.
+func forgetGenerations(key types.NamespacedName) {
+	generationHistory.Delete(key)
+}
+
+// parsePinnedGeneration returns the generation that the input value of the pinned-generations annotation pins the
+// root policy with the input replicated policy name to, or 0 if it's not pinned.
+func parsePinnedGeneration(annotation string, replicatedName string) (int64, error) {
+	for _, entry := range strings.Split(annotation, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || strings.TrimSpace(name) != replicatedName {
+			continue
+		}
+
+		generation, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || generation < 1 {
+			return 0, fmt.Errorf(
+				"%w: the generation %q for the policy %s is not a positive integer",
+				ErrInvalidPinnedGeneration, value, replicatedName,
+			)
+		}
+
+		return generation, nil
+	}
+
+	return 0, nil
+}
+
+// getPinnedGeneration returns the generation of the root policy that the ManagedCluster with the input name pins it
+// to with the pinned-generations annotation, or 0 if it's not pinned.
+func (r *PolicyReconciler) getPinnedGeneration(rootPlc *policiesv1.Policy, clusterName string) (int64, error) {
+	managedCluster := &clusterv1.ManagedCluster{}
+
+	err := r.Get(context.TODO(), types.NamespacedName{Name: clusterName}, managedCluster)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	annotation := managedCluster.GetAnnotations()[PinnedGenerationsAnnotation]
+	if annotation == "" {
+		return 0, nil
+	}
+
+	generation, err := parsePinnedGeneration(annotation, common.FullNameForPolicy(rootPlc))
+	if err != nil {
+		return 0, fmt.Errorf("the %s annotation on the ManagedCluster %s is invalid: %w",
+			PinnedGenerationsAnnotation, clusterName, err)
+	}
+
+	return generation, nil
+}
+
+// setRootGenerationAnnotation sets the RootGenerationAnnotation on the input replicated policy built from the input
+// pinned generation of its root policy. The replicated policies of clusters that aren't pinned don't have it, so that
+// a new generation of the root policy only rewrites them when the replicated policy changes.
+func setRootGenerationAnnotation(replicated *policiesv1.Policy, generation int64) {
+	annotations := replicated.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[RootGenerationAnnotation] = strconv.FormatInt(generation, 10)
+	replicated.SetAnnotations(annotations)
+}
+
+// recordPinnedGenerationUnavailable logs and records an event on the root policy when the input cluster is pinned to a
+// generation of the root policy that is no longer available, so the current generation is replicated instead.
+func (r *PolicyReconciler) recordPinnedGenerationUnavailable(
+	rootPlc *policiesv1.Policy, decision appsv1.PlacementDecision, generation int64,
+) {
+	log.Info(
+		"The pinned generation of the root policy is not available, so the current generation is replicated",
+		"policyName", rootPlc.Name,
+		"policyNamespace", rootPlc.Namespace,
+		"cluster", decision.ClusterName,
+		"pinnedGeneration", generation,
+		"generation", rootPlc.Generation,
+	)
+
+	r.Recorder.Event(rootPlc, "Warning", "PolicyPropagation",
+		fmt.Sprintf(
+			"Policy %s/%s is pinned to generation %d on cluster %s, but that generation is not available, so the "+
+				"current generation %d was replicated instead",
+			rootPlc.Namespace, rootPlc.Name, generation, decision.ClusterName, rootPlc.Generation,
+		))
+}
@@ -0,0 +1,216 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func TestParsePinnedGeneration(t *testing.T) {
+	tests := map[string]struct {
+		annotation  string
+		expected    int64
+		expectedErr bool
+	}{
+		"Pinned":                     {"default.other=3, default.my-policy=2", 2, false},
+		"Not pinned":                 {"default.other=3", 0, false},
+		"Invalid other policy":       {"default.other=abc,default.my-policy=2", 2, false},
+		"Invalid generation":         {"default.my-policy=abc", 0, true},
+		"Generation is not positive": {"default.my-policy=0", 0, true},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			generation, err := parsePinnedGeneration(test.annotation, "default.my-policy")
+			if test.expectedErr {
+				if !errors.Is(err, ErrInvalidPinnedGeneration) {
+					t.Fatalf("Expected an error wrapping ErrInvalidPinnedGeneration, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if generation != test.expected {
+				t.Fatalf("Expected the generation %d, got %d", test.expected, generation)
+			}
+		})
+	}
+}
+
+func TestRecordGeneration(t *testing.T) {
+	rootPolicy := fakeRootPolicy("my-policy", "history")
+	key := types.NamespacedName{Namespace: "history", Name: "my-policy"}
+
+	defer forgetGenerations(key)
+
+	for generation := int64(1); generation <= generationHistoryLimit+2; generation++ {
+		rootPolicy.Generation = generation
+		recordGeneration(&rootPolicy)
+	}
+
+	for _, generation := range []int64{1, 2} {
+		if getGeneration(key, generation) != nil {
+			t.Fatalf("Expected the generation %d to no longer be kept", generation)
+		}
+	}
+
+	for generation := int64(3); generation <= generationHistoryLimit+2; generation++ {
+		if policy := getGeneration(key, generation); policy == nil || policy.Generation != generation {
+			t.Fatalf("Expected the generation %d to be kept, got %v", generation, policy)
+		}
+	}
+}
+
+func TestHandleDecisionPinnedGeneration(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "managed1",
+		Annotations: map[string]string{PinnedGenerationsAnnotation: "default.my-policy=1"},
+	}}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	rootPolicy.Generation = 1
+	rootPolicy.Spec.RemediationAction = policiesv1.Inform
+
+	defer forgetGenerations(types.NamespacedName{Namespace: "default", Name: "my-policy"})
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(managedCluster).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+	key := types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(&rootPolicy)}
+
+	// handle replicates the root policy at its current generation and verifies the generation and remediation action
+	// of the replicated policy
+	handle := func(expectedGeneration string, expectedAction policiesv1.RemediationAction) {
+		t.Helper()
+
+		recordGeneration(&rootPolicy)
+
+		if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+			t.Fatalf("Unexpected error handling the decision: %v", err)
+		}
+
+		replicatedPolicy := &policiesv1.Policy{}
+
+		if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+			t.Fatalf("Failed to get the replicated policy: %v", err)
+		}
+
+		if replicatedPolicy.Annotations[RootGenerationAnnotation] != expectedGeneration {
+			t.Fatalf(
+				"Expected the replicated policy to be at the generation %s, got %s",
+				expectedGeneration, replicatedPolicy.Annotations[RootGenerationAnnotation],
+			)
+		}
+
+		if replicatedPolicy.Spec.RemediationAction != expectedAction {
+			t.Fatalf(
+				"Expected the remediation action %s, got %s", expectedAction, replicatedPolicy.Spec.RemediationAction,
+			)
+		}
+	}
+
+	// The cluster is pinned to the current generation, so the replicated policy isn't annotated
+	handle("", policiesv1.Inform)
+
+	// The replicated policy stays at the pinned generation after the root policy changes
+	rootPolicy.Generation = 2
+	rootPolicy.Spec.RemediationAction = policiesv1.Enforce
+
+	handle("1", policiesv1.Inform)
+
+	// A replicated policy at the pinned generation is still compared and patched
+	replicatedPolicy := &policiesv1.Policy{}
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	replicatedPolicy.Spec.RemediationAction = policiesv1.Enforce
+	if err := c.Update(context.TODO(), replicatedPolicy); err != nil {
+		t.Fatalf("Failed to update the replicated policy: %v", err)
+	}
+
+	handle("1", policiesv1.Inform)
+
+	// Unpinning the cluster replicates the current generation
+	managedCluster.Annotations = nil
+	if err := c.Update(context.TODO(), managedCluster); err != nil {
+		t.Fatalf("Failed to update the ManagedCluster: %v", err)
+	}
+
+	handle("", policiesv1.Enforce)
+
+	// A new generation of the root policy that doesn't change the replicated policy doesn't rewrite it
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	resourceVersion := replicatedPolicy.ResourceVersion
+	rootPolicy.Generation = 3
+
+	handle("", policiesv1.Enforce)
+
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	if replicatedPolicy.ResourceVersion != resourceVersion {
+		t.Fatalf("Expected the replicated policy not to be updated")
+	}
+
+	// Pinning the cluster to a previous generation replicates it from the history
+	managedCluster.Annotations = map[string]string{PinnedGenerationsAnnotation: "default.my-policy=1"}
+	if err := c.Update(context.TODO(), managedCluster); err != nil {
+		t.Fatalf("Failed to update the ManagedCluster: %v", err)
+	}
+
+	handle("1", policiesv1.Inform)
+
+	// Pinning the cluster to an unavailable generation falls back to the current generation
+	managedCluster.Annotations = map[string]string{PinnedGenerationsAnnotation: "default.my-policy=7"}
+	if err := c.Update(context.TODO(), managedCluster); err != nil {
+		t.Fatalf("Failed to update the ManagedCluster: %v", err)
+	}
+
+	handle("", policiesv1.Enforce)
+
+	//nolint:forcetypeassert
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+	expected := "Warning PolicyPropagation Policy default/my-policy is pinned to generation 7 on cluster managed1, " +
+		"but that generation is not available, so the current generation 3 was replicated instead"
+
+	for len(recorder.Events) != 0 {
+		if <-recorder.Events == expected {
+			return
+		}
+	}
+
+	t.Fatalf("Expected the event %q", expected)
+}
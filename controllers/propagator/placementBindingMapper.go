@@ -13,6 +13,7 @@ import (
 
 	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
 func placementBindingMapper(c client.Client) handler.MapFunc {
@@ -32,11 +33,7 @@ func placementBindingMapper(c client.Client) handler.MapFunc {
 					log.V(2).Info("Found reconciliation request from policy placement binding",
 						"policyName", subject.Name)
 
-					request := reconcile.Request{NamespacedName: types.NamespacedName{
-						Name:      subject.Name,
-						Namespace: object.GetNamespace(),
-					}}
-					result = append(result, request)
+					result = append(result, policySubjectRequests(c, object.GetNamespace(), subject)...)
 				} else if subject.Kind == policiesv1.PolicySetKind {
 					policySetNamespacedName := types.NamespacedName{
 						Name:      subject.Name,
@@ -64,6 +61,66 @@ func placementBindingMapper(c client.Client) handler.MapFunc {
 			}
 		}
 
+		// Also queue the root policies that were previously bound by the PlacementBinding according to their status.
+		// This way, the replicated policies are cleaned up when the PlacementBinding is deleted or its subjects change
+		// even if the subject can no longer be resolved to the root policy, such as a deleted PolicySet.
+		policyList := &policiesv1.PolicyList{}
+
+		err := c.List(context.TODO(), policyList, &client.ListOptions{Namespace: object.GetNamespace()})
+		if err != nil {
+			log.Error(err, "Failed to list the policies to find the ones previously bound by the placement binding")
+
+			return result
+		}
+
+		for _, policy := range policyList.Items {
+			for _, placement := range policy.Status.Placement {
+				if placement.PlacementBinding != object.GetName() {
+					continue
+				}
+
+				log.V(2).Info("Found reconciliation request from a previously bound policy", "policyName", policy.Name)
+
+				result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      policy.GetName(),
+					Namespace: policy.GetNamespace(),
+				}})
+
+				break
+			}
+		}
+
 		return result
 	}
 }
+
+// policySubjectRequests returns the reconcile requests of the root policies in the input namespace that are selected
+// by the input Policy subject of a PlacementBinding. A subject with nameIsPattern set selects every root policy in the
+// namespace with a matching name.
+func policySubjectRequests(c client.Client, namespace string, subject policiesv1.Subject) []reconcile.Request {
+	if !subject.NameIsPattern {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: subject.Name, Namespace: namespace}}}
+	}
+
+	policyList := &policiesv1.PolicyList{}
+
+	err := c.List(context.TODO(), policyList, &client.ListOptions{Namespace: namespace})
+	if err != nil {
+		log.Error(err, "Failed to list the policies matching the placement binding subject", "pattern", subject.Name)
+
+		return nil
+	}
+
+	var result []reconcile.Request
+
+	for _, policy := range policyList.Items {
+		if common.SubjectMatchesPolicy(subject, policy.GetName()) {
+			result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      policy.GetName(),
+				Namespace: namespace,
+			}})
+		}
+	}
+
+	return result
+}
@@ -45,11 +45,7 @@ func placementRuleMapper(c client.Client) handler.MapFunc {
 							log.V(2).Info("Found reconciliation request from policy placement rule", "policyName",
 								subject.Name)
 							// generate reconcile request for policy referenced by pb
-							request := reconcile.Request{NamespacedName: types.NamespacedName{
-								Name:      subject.Name,
-								Namespace: object.GetNamespace(),
-							}}
-							result = append(result, request)
+							result = append(result, policySubjectRequests(c, object.GetNamespace(), subject)...)
 						} else if subject.Kind == policiesv1.PolicySetKind {
 							policySetNamespacedName := types.NamespacedName{
 								Name:      subject.Name,
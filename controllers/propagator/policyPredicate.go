@@ -4,15 +4,22 @@
 package propagator
 
 import (
+	"strings"
+
 	"k8s.io/apimachinery/pkg/api/equality"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
-// policyPredicates filters out updates to policies that are pure status updates.
-func policyPredicates() predicate.Funcs {
+// policyPredicates filters out updates to policies that are pure status updates, and updates to root policies that
+// only change labels or annotations that don't affect the propagation. The labels and annotations in the
+// policy.open-cluster-management.io group, such as the trigger-update and maintenance window annotations, always affect
+// the propagation. The others only do when they're copied to the replicated policies, so their changes are ignored
+// when the root policy sets copyPolicyMetadata to false, or for all root policies when ignoreCopiedMetadata is true.
+func policyPredicates(ignoreCopiedMetadata bool) predicate.Funcs {
 	return predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			//nolint:forcetypeassert
@@ -20,10 +27,60 @@ func policyPredicates() predicate.Funcs {
 			//nolint:forcetypeassert
 			updatedPolicy := e.ObjectNew.(*policiesv1.Policy)
 
-			// Ignore pure status updates since those are handled by a separate controller
-			return oldPolicy.Generation != updatedPolicy.Generation ||
-				!equality.Semantic.DeepEqual(oldPolicy.ObjectMeta.Labels, updatedPolicy.ObjectMeta.Labels) ||
-				!equality.Semantic.DeepEqual(oldPolicy.ObjectMeta.Annotations, updatedPolicy.ObjectMeta.Annotations)
+			if oldPolicy.Generation != updatedPolicy.Generation ||
+				oldPolicy.GetDeletionTimestamp().IsZero() != updatedPolicy.GetDeletionTimestamp().IsZero() {
+				return true
+			}
+
+			// Ignore pure status updates since those are handled by a separate controller, except for the cluster
+			// compliance of a root policy being rolled out by decision group, since that determines when the policy
+			// is rolled out to the next group, and the conditions of a replicated policy being torn down, since that
+			// acknowledges the teardown
+			if (updatedPolicy.Spec.DecisionGroupRollout != nil &&
+				!equality.Semantic.DeepEqual(oldPolicy.Status.Status, updatedPolicy.Status.Status)) ||
+				(updatedPolicy.GetAnnotations()[TeardownRequestedAnnotation] != "" &&
+					!equality.Semantic.DeepEqual(oldPolicy.Status.Conditions, updatedPolicy.Status.Conditions)) {
+				return true
+			}
+
+			// Any change to the metadata of a replicated policy is drift to correct
+			if rootPlcName, _ := common.GetRootPolicyLabel(updatedPolicy); rootPlcName != "" {
+				return !equality.Semantic.DeepEqual(oldPolicy.GetLabels(), updatedPolicy.GetLabels()) ||
+					!equality.Semantic.DeepEqual(oldPolicy.GetAnnotations(), updatedPolicy.GetAnnotations())
+			}
+
+			ignoreCopied := ignoreCopiedMetadata ||
+				(updatedPolicy.Spec.CopyPolicyMetadata != nil && !*updatedPolicy.Spec.CopyPolicyMetadata)
+
+			return metadataChanged(oldPolicy.GetLabels(), updatedPolicy.GetLabels(), ignoreCopied) ||
+				metadataChanged(oldPolicy.GetAnnotations(), updatedPolicy.GetAnnotations(), ignoreCopied)
 		},
 	}
 }
+
+// metadataChanged returns true if the input labels or annotations of a root policy differ in a key that affects the
+// propagation. When ignoreCopied is true, only the keys in the policy.open-cluster-management.io group are compared.
+// The TemplateValidationErrorAnnotation is never compared since the propagator sets it itself.
+func metadataChanged(oldMetadata, newMetadata map[string]string, ignoreCopied bool) bool {
+	relevant := func(key string) bool {
+		if key == TemplateValidationErrorAnnotation {
+			return false
+		}
+
+		return !ignoreCopied || strings.HasPrefix(key, policiesv1.GroupVersion.Group+"/")
+	}
+
+	for key, value := range newMetadata {
+		if oldValue, ok := oldMetadata[key]; relevant(key) && (!ok || oldValue != value) {
+			return true
+		}
+	}
+
+	for key := range oldMetadata {
+		if _, ok := newMetadata[key]; relevant(key) && !ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func TestPolicyPredicatesUpdate(t *testing.T) {
+	copyMetadata := false
+
+	tests := map[string]struct {
+		update               func(policy *policiesv1.Policy)
+		replicated           bool
+		copyPolicyMetadata   *bool
+		ignoreCopiedMetadata bool
+		expected             bool
+	}{
+		"Spec change": {
+			update:               func(policy *policiesv1.Policy) { policy.Generation++ },
+			ignoreCopiedMetadata: true,
+			expected:             true,
+		},
+		"Trigger-update annotation change": {
+			update: func(policy *policiesv1.Policy) {
+				policy.Annotations[TriggerUpdateAnnotation] = "2"
+			},
+			ignoreCopiedMetadata: true,
+			expected:             true,
+		},
+		"Maintenance window annotation added": {
+			update: func(policy *policiesv1.Policy) {
+				policy.Annotations[MaintenanceWindowAnnotation] = "0 2 * * 6 4h"
+			},
+			ignoreCopiedMetadata: true,
+			expected:             true,
+		},
+		"Irrelevant label change": {
+			update:               func(policy *policiesv1.Policy) { policy.Labels["team"] = "sre" },
+			ignoreCopiedMetadata: true,
+			expected:             false,
+		},
+		"Irrelevant annotation removed": {
+			update:               func(policy *policiesv1.Policy) { delete(policy.Annotations, "owner") },
+			ignoreCopiedMetadata: true,
+			expected:             false,
+		},
+		"Irrelevant label change without copying the metadata": {
+			update:             func(policy *policiesv1.Policy) { policy.Labels["team"] = "sre" },
+			copyPolicyMetadata: &copyMetadata,
+			expected:           false,
+		},
+		"Copied label change": {
+			update:   func(policy *policiesv1.Policy) { policy.Labels["team"] = "sre" },
+			expected: true,
+		},
+		"Template validation error annotation change": {
+			update: func(policy *policiesv1.Policy) {
+				policy.Annotations[TemplateValidationErrorAnnotation] = "the policy template is invalid"
+			},
+			expected: false,
+		},
+		"Replicated policy label change": {
+			update:               func(policy *policiesv1.Policy) { policy.Labels["team"] = "sre" },
+			replicated:           true,
+			ignoreCopiedMetadata: true,
+			expected:             true,
+		},
+		"Status change": {
+			update: func(policy *policiesv1.Policy) {
+				policy.Status.ComplianceState = policiesv1.NonCompliant
+			},
+			expected: false,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			oldPolicy := fakeRootPolicy("my-policy", "policies")
+			oldPolicy.Generation = 1
+			oldPolicy.Labels = map[string]string{"team": "dev"}
+			oldPolicy.Annotations = map[string]string{TriggerUpdateAnnotation: "1", "owner": "someone"}
+			oldPolicy.Spec.CopyPolicyMetadata = test.copyPolicyMetadata
+
+			if test.replicated {
+				oldPolicy.Namespace = "cluster1"
+				oldPolicy.Name = "policies.my-policy"
+				oldPolicy.Labels[common.RootPolicyLabel] = "policies.my-policy"
+			}
+
+			updatedPolicy := oldPolicy.DeepCopy()
+			test.update(updatedPolicy)
+
+			reconcile := policyPredicates(test.ignoreCopiedMetadata).Update(
+				event.UpdateEvent{ObjectOld: &oldPolicy, ObjectNew: updatedPolicy},
+			)
+			if reconcile != test.expected {
+				t.Fatalf("Expected the update to be reconciled to be %v, got %v", test.expected, reconcile)
+			}
+		})
+	}
+}
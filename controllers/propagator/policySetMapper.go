@@ -12,6 +12,9 @@ import (
 	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
 )
 
+// policySetMapper enqueues the member policies of a PolicySet. On an update, the members of both the old and new
+// PolicySet are enqueued, so the removed members have their replicated policies deleted and the added members are
+// propagated to the clusters of the PlacementBindings of the PolicySet.
 func policySetMapper(_ client.Client) handler.MapFunc {
 	return func(object client.Object) []reconcile.Request {
 		log := log.WithValues("policySetName", object.GetName(), "namespace", object.GetNamespace())
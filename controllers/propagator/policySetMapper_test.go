@@ -0,0 +1,170 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+)
+
+func TestPolicySetMapperMembershipChange(t *testing.T) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	// The removed members are queued along with the added ones so that their replicated policies are deleted
+	handler.EnqueueRequestsFromMapFunc(policySetMapper(nil)).Update(event.UpdateEvent{
+		ObjectOld: fakePolicySet("my-set", "policies", "kept", "removed"),
+		ObjectNew: fakePolicySet("my-set", "policies", "kept", "added"),
+	}, queue)
+
+	requests := []string{}
+
+	for queue.Len() > 0 {
+		item, _ := queue.Get()
+		requests = append(requests, item.(reconcile.Request).String()) //nolint:forcetypeassert
+		queue.Done(item)
+	}
+
+	assert.ElementsMatch(t, []string{"policies/kept", "policies/removed", "policies/added"}, requests)
+}
+
+func TestGetAllClusterDecisionsPolicySetMembers(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, policiesv1beta1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	clusters := fakePlacementDecisions(3)
+	prA := fakePlacementRule("pr-a", "default", []appsv1.PlacementDecision{clusters[0], clusters[1]})
+	prB := fakePlacementRule("pr-b", "default", []appsv1.PlacementDecision{clusters[1], clusters[2]})
+
+	pbFor := func(name string, rule string, set string) policiesv1.PlacementBinding {
+		return fakePlacementBinding(
+			name,
+			"default",
+			policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: rule},
+			[]policiesv1.Subject{
+				{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.PolicySetKind, Name: set},
+			},
+		)
+	}
+
+	pbList := &policiesv1.PlacementBindingList{
+		Items: []policiesv1.PlacementBinding{pbFor("pb-a", "pr-a", "set-a"), pbFor("pb-b", "pr-b", "set-b")},
+	}
+
+	// policy1 is in both bound sets, and policy2 is not in any of them yet
+	setA := fakePolicySet("set-a", "default", "policy1")
+	setB := fakePolicySet("set-b", "default", "policy1")
+	policy1 := fakeRootPolicy("policy1", "default")
+	policy2 := fakeRootPolicy("policy2", "default")
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&prA, &prB, setA, setB, &policy1, &policy2,
+	).Build()
+	reconciler := &PolicyReconciler{Client: c}
+
+	decidedClusters := func(policy *policiesv1.Policy) []string {
+		t.Helper()
+
+		decisions, _, err := reconciler.getAllClusterDecisions(policy, pbList)
+		if err != nil && !isNoTargetsError(err) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		clusterNames := []string{}
+		for _, decision := range decisions {
+			clusterNames = append(clusterNames, decision.Cluster.ClusterName)
+		}
+
+		return clusterNames
+	}
+
+	updateSet := func(set *policiesv1beta1.PolicySet, policies ...string) {
+		t.Helper()
+
+		set.Spec.Policies = nil
+		for _, policy := range policies {
+			set.Spec.Policies = append(set.Spec.Policies, policiesv1beta1.NonEmptyString(policy))
+		}
+
+		if err := c.Update(context.TODO(), set); err != nil {
+			t.Fatalf("Failed to update the PolicySet: %v", err)
+		}
+	}
+
+	// The cluster selected by both sets is only decided once
+	assert.ElementsMatch(t, []string{"cluster1", "cluster2", "cluster3"}, decidedClusters(&policy1))
+	assert.ElementsMatch(t, []string{}, decidedClusters(&policy2))
+
+	_, placements, err := reconciler.getAllClusterDecisions(&policy1, pbList)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	placementSets := []string{}
+	for _, placement := range placements {
+		placementSets = append(placementSets, placement.PolicySet)
+	}
+
+	assert.ElementsMatch(t, []string{"set-a", "set-b"}, placementSets)
+
+	// Removing policy1 from set-b removes the clusters only selected by it, and adding policy2 to set-a propagates it
+	updateSet(setB)
+	updateSet(setA, "policy1", "policy2")
+
+	assert.ElementsMatch(t, []string{"cluster1", "cluster2"}, decidedClusters(&policy1))
+	assert.ElementsMatch(t, []string{"cluster1", "cluster2"}, decidedClusters(&policy2))
+
+	// The clusters no longer decided for policy1 have their replicated policies deleted
+	policy1.Status.Status = []*policiesv1.CompliancePerClusterStatus{
+		{ClusterName: "cluster1", ClusterNamespace: "cluster1"},
+		{ClusterName: "cluster2", ClusterNamespace: "cluster2"},
+		{ClusterName: "cluster3", ClusterNamespace: "cluster3"},
+	}
+
+	replicated := fakeRootPolicy(policy1.Namespace+"."+policy1.Name, "cluster3")
+	if err := c.Create(context.TODO(), &replicated); err != nil {
+		t.Fatalf("Failed to create the replicated policy: %v", err)
+	}
+
+	allDecisions := decisionSet{}
+
+	decisions, _, err := reconciler.getAllClusterDecisions(&policy1, pbList)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, decision := range decisions {
+		allDecisions[decision.Cluster] = true
+	}
+
+	if _, _, err := reconciler.cleanUpOrphanedRplPolicies(&policy1, allDecisions); err != nil {
+		t.Fatalf("Unexpected error cleaning up the replicated policies: %v", err)
+	}
+
+	err = c.Get(context.TODO(), types.NamespacedName{Namespace: "cluster3", Name: "default.policy1"}, &replicated)
+	if err == nil || client.IgnoreNotFound(err) != nil {
+		t.Fatalf("Expected the replicated policy on the removed cluster to be deleted, got %v", err)
+	}
+}
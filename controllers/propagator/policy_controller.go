@@ -5,18 +5,26 @@ package propagator
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
 
 	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
@@ -35,13 +43,27 @@ var log = ctrl.Log.WithName(ControllerName)
 //+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=policies/finalizers,verbs=update
 //+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=placementbindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=policy.open-cluster-management.io,resources=policysets,verbs=get;list;watch
-//+kubebuilder:rbac:groups=cluster.open-cluster-management.io,resources=managedclusters;placementdecisions;placements,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.open-cluster-management.io,resources=managedclusters;managedclustersetbindings;managedclustersets;placementdecisions;placements,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apps.open-cluster-management.io,resources=placementrules,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *PolicyReconciler) SetupWithManager(mgr ctrl.Manager, additionalSources ...source.Source) error {
+	// When a priority window is configured, the requests enqueued within it are ordered by the priority of their root
+	// policies
+	enqueue := func(eventHandler handler.EventHandler) handler.EventHandler {
+		return eventHandler
+	}
+
+	if r.PriorityWindow > 0 {
+		batcher := newPriorityBatcher(mgr.GetClient(), r.PriorityWindow)
+
+		enqueue = func(eventHandler handler.EventHandler) handler.EventHandler {
+			return priorityEventHandler{handler: eventHandler, batcher: batcher}
+		}
+	}
+
 	builder := ctrl.NewControllerManagedBy(mgr).
 		Named(ControllerName).
 		For(
@@ -52,26 +74,43 @@ func (r *PolicyReconciler) SetupWithManager(mgr ctrl.Manager, additionalSources
 		// particular way, so we will define that in a separate "Watches"
 		Watches(
 			&source.Kind{Type: &policiesv1.Policy{}},
-			handler.EnqueueRequestsFromMapFunc(common.PolicyMapper(mgr.GetClient())),
-			builder.WithPredicates(policyPredicates())).
+			enqueue(handler.EnqueueRequestsFromMapFunc(common.PolicyMapper(mgr.GetClient()))),
+			builder.WithPredicates(common.WatchedPolicyPredicate, policyPredicates(r.IgnoreCopiedMetadataChanges))).
 		Watches(
 			&source.Kind{Type: &policiesv1beta1.PolicySet{}},
-			handler.EnqueueRequestsFromMapFunc(policySetMapper(mgr.GetClient())),
+			enqueue(handler.EnqueueRequestsFromMapFunc(policySetMapper(mgr.GetClient()))),
 			builder.WithPredicates(policySetPredicateFuncs)).
 		Watches(
 			&source.Kind{Type: &policiesv1.PlacementBinding{}},
-			handler.EnqueueRequestsFromMapFunc(placementBindingMapper(mgr.GetClient())),
+			enqueue(handler.EnqueueRequestsFromMapFunc(placementBindingMapper(mgr.GetClient()))),
 			builder.WithPredicates(pbPredicateFuncs)).
 		Watches(
 			&source.Kind{Type: &appsv1.PlacementRule{}},
-			handler.EnqueueRequestsFromMapFunc(placementRuleMapper(mgr.GetClient()))).
+			enqueue(handler.EnqueueRequestsFromMapFunc(placementRuleMapper(mgr.GetClient())))).
 		Watches(
+			&source.Kind{Type: &clusterv1.ManagedCluster{}},
+			enqueue(handler.EnqueueRequestsFromMapFunc(managedClusterMapper(mgr.GetClient()))),
+			builder.WithPredicates(managedClusterPredicateFuncs))
+
+	if common.ManagedClusterSetAPIAvailable() {
+		builder.Watches(
+			&source.Kind{Type: &clusterv1beta2.ManagedClusterSet{}},
+			enqueue(handler.EnqueueRequestsFromMapFunc(managedClusterSetMapper(mgr.GetClient()))),
+		).Watches(
+			&source.Kind{Type: &clusterv1beta2.ManagedClusterSetBinding{}},
+			enqueue(handler.EnqueueRequestsFromMapFunc(managedClusterSetBindingMapper(mgr.GetClient()))),
+		)
+	}
+
+	if common.PlacementAPIAvailable() {
+		builder.Watches(
 			&source.Kind{Type: &clusterv1beta1.PlacementDecision{}},
-			handler.EnqueueRequestsFromMapFunc(placementDecisionMapper(mgr.GetClient())),
+			enqueue(handler.EnqueueRequestsFromMapFunc(placementDecisionMapper(mgr.GetClient()))),
 		)
+	}
 
 	for _, source := range additionalSources {
-		builder.Watches(source, &handler.EnqueueRequestForObject{})
+		builder.Watches(source, enqueue(&handler.EnqueueRequestForObject{}))
 	}
 
 	return builder.Complete(r)
@@ -87,6 +126,75 @@ type PolicyReconciler struct {
 	Recorder        record.EventRecorder
 	DynamicWatcher  k8sdepwatches.DynamicWatcher
 	RootPolicyLocks *sync.Map
+	// ReplicaDeletionGracePeriod is how long a cluster must no longer be selected by the placement before its
+	// replicated policy is deleted. If it's zero, the replicated policy is deleted immediately.
+	ReplicaDeletionGracePeriod time.Duration
+	// MaintenanceSchedule limits when changes to replicated policies are applied. If it's nil, changes are always
+	// applied. The maintenance window annotations on a root policy take precedence.
+	MaintenanceSchedule *MaintenanceSchedule
+	// ComplianceHistoryLimit is the number of compliance state changes kept in the status of each root policy. If
+	// it's zero, the compliance history is not recorded.
+	ComplianceHistoryLimit int
+	// ComplianceHistoryDepth is the number of compliance state changes kept in the compliance history for each
+	// cluster. If it's zero, the compliance history is only limited by ComplianceHistoryLimit.
+	ComplianceHistoryDepth int
+	// DriftIgnoredPaths are the paths of replicated policy spec fields, as returned by ParseSpecPaths, that are ignored
+	// when comparing the desired and actual replicated policies. Changes to these fields made on the replicated
+	// policies are not reverted.
+	DriftIgnoredPaths [][]string
+	// AgentOwnedPaths are the paths of replicated policy spec fields, as returned by ParseSpecPaths, that are owned by
+	// the agents on the managed clusters. They are set when the replicated policy is created, and then their values on
+	// the replicated policy are kept, even when the propagator updates the other fields.
+	AgentOwnedPaths [][]string
+	// PriorityWindow is how long the reconcile requests are held so that those enqueued together are reconciled in
+	// order of the priority of their root policies. If it's zero, the requests are not reordered.
+	PriorityWindow time.Duration
+	// MaxReplicaSpecSize is the maximum size in bytes of the JSON encoded spec of a replicated policy after its hub
+	// templates are resolved. A replicated policy that exceeds it isn't written. If it's zero, the size isn't limited.
+	MaxReplicaSpecSize int
+	// PropagationTimeout is how long the replicated policies of a root policy can take to be written in a reconcile.
+	// The clusters that aren't done by then are reported as timed out in the status of the root policy and are retried
+	// in the next reconcile, so that one unreachable cluster doesn't block the others. If it's zero, there is no timeout.
+	PropagationTimeout time.Duration
+	// ReplicaTeardownTimeout is how long the deletion of a root policy waits for the agents on the managed clusters to
+	// acknowledge the teardown of its replicated policies before they're deleted anyway. If it's zero, the replicated
+	// policies are deleted as soon as the root policy is deleted.
+	ReplicaTeardownTimeout time.Duration
+	// ClusterQuarantine stops writing replicated policies to the cluster namespaces that the writes keep failing for,
+	// so that they don't slow down the propagation to the others. If it's nil, the clusters are never quarantined.
+	ClusterQuarantine *ClusterQuarantine
+	// StatusFieldManager is the field manager of the root policy status writes. If it's empty,
+	// common.DefaultStatusFieldManager is used.
+	StatusFieldManager string
+	// OwnerReferenceStrategy determines how the replicated policies refer back to their root policy. The replicated
+	// policies are deleted by the propagator when the root policy is deleted regardless of the strategy.
+	OwnerReferenceStrategy OwnerReferenceStrategy
+	// AllowedTemplateKinds are the kinds, as returned by ParseAllowedTemplateKinds, that the policy templates of the
+	// replicated policies and the objects embedded in their ConfigurationPolicies may have. A replicated policy with
+	// another kind isn't written. If it's nil, all kinds are allowed.
+	AllowedTemplateKinds []schema.GroupVersionKind
+	// TemplateValidator validates the policy templates of the replicated policies against the schemas of their CRDs on
+	// the hub before they're written. If it's nil, the policy templates aren't validated.
+	TemplateValidator *TemplateValidator
+	// IgnoreCopiedMetadataChanges determines if changes to the labels and annotations of a root policy that are only
+	// copied to its replicated policies, rather than read by the propagator, don't trigger a reconcile. The replicated
+	// policies get the changes in the next reconcile of the root policy for another reason.
+	IgnoreCopiedMetadataChanges bool
+	// DesiredStateCache determines if the reconcile of a root policy is skipped when none of its inputs changed since
+	// its last reconcile that fully propagated it. The inputs are read from the cache, so this avoids resolving the
+	// placements and reading and writing the replicated policies on steady-state fleets.
+	DesiredStateCache bool
+	// RecordDebugState determines if the placements, clusters, and render errors of the last reconcile of each root
+	// policy are kept in memory for the PolicyDebugHandler.
+	RecordDebugState bool
+	// ExportReplicationSpec determines if, instead of writing the replicated policies, the propagator exports the root
+	// policy, its placed clusters, and their binding overrides in a ConfigMap next to the root policy, so that a
+	// regional hub can replicate the policy to the clusters itself. Existing replicated policies of the placed clusters
+	// are kept until they're deleted explicitly.
+	ExportReplicationSpec bool
+	// APIReader reads the replication spec ConfigMaps from the API server since they aren't in the manager cache. It's
+	// required when ExportReplicationSpec is set.
+	APIReader client.Reader
 }
 
 // Reconcile reads that state of the cluster for a Policy object and makes changes based on the state read
@@ -95,8 +203,25 @@ type PolicyReconciler struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *PolicyReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+
+	result, err := r.reconcile(ctx, request)
+
+	common.ObserveReconcile(ControllerName, start, err)
+
+	return result, err
+}
+
+func (r *PolicyReconciler) reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
 	log := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 
+	// Requests can also come from the placement bindings and placements in any namespace
+	if !common.IsWatchedRootNamespace(request.Namespace) {
+		log.V(2).Info("The policy is not in a watched namespace. Ignoring it.")
+
+		return reconcile.Result{}, nil
+	}
+
 	log.V(3).Info("Acquiring the lock for the root policy")
 
 	lock, _ := r.RootPolicyLocks.LoadOrStore(request.NamespacedName, &sync.Mutex{})
@@ -122,9 +247,30 @@ func (r *PolicyReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 		if k8serrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected.
-			log.Info("Policy not found, so it may have been deleted. Deleting the replicated policies.")
+			requeueAfter, err := r.orphanGracePeriodRemaining(request.NamespacedName)
+			if err != nil {
+				log.Error(err, "Failed to list the replicated policies of the missing root policy")
+
+				return reconcile.Result{}, err
+			}
+
+			// Don't delete recently created replicated policies in case the root policy is being recreated and
+			// isn't in the cache yet
+			if requeueAfter > 0 {
+				log.Info(
+					"Policy not found but it has recently created replicated policies. Requeueing the request.",
+					"requeueAfter", requeueAfter.String(),
+				)
+
+				return reconcile.Result{RequeueAfter: requeueAfter}, nil
+			}
+
+			log.Info(
+				"Policy not found, so it may have been deleted. Deleting the replicated policies.",
+				"rootPolicy", common.ReplicatedPolicyName(request.Namespace, request.Name),
+			)
 
-			err := r.cleanUpPolicy(&policiesv1.Policy{
+			err = r.cleanUpPolicy(&policiesv1.Policy{
 				TypeMeta: metav1.TypeMeta{
 					Kind:       policiesv1.Kind,
 					APIVersion: policiesv1.GroupVersion.Group + "/" + policiesv1.GroupVersion.Version,
@@ -140,6 +286,34 @@ func (r *PolicyReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 				return reconcile.Result{}, err
 			}
 
+			forgetReconcileTime(request.NamespacedName)
+			namespaceRetryAttempts.Delete(request.NamespacedName)
+			forgetPendingPropagation(request.NamespacedName)
+			forgetGenerations(request.NamespacedName)
+			forgetDebugState(request.NamespacedName)
+			forgetDesiredState(request.NamespacedName)
+			replicaDriftMetric.DeleteLabelValues(request.Name, request.Namespace)
+
+			// The placement bindings that only bound this root policy no longer have a placement decision count
+			pbList := &policiesv1.PlacementBindingList{}
+
+			err = r.List(ctx, pbList, client.InNamespace(request.Namespace))
+			if err == nil {
+				err = r.forgetStalePlacementBindings(ctx, request.Namespace, pbList)
+			}
+
+			if err != nil {
+				log.Error(err, "Failed to delete the metric series of the stale placement bindings")
+			}
+
+			if r.ExportReplicationSpec {
+				if err := r.deleteReplicationSpec(ctx, request.NamespacedName); err != nil {
+					log.Error(err, "Failed to delete the replication spec ConfigMap")
+
+					return reconcile.Result{}, err
+				}
+			}
+
 			return reconcile.Result{}, nil
 		}
 
@@ -149,24 +323,157 @@ func (r *PolicyReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 		return reconcile.Result{}, err
 	}
 
-	inClusterNs, err := common.IsInClusterNamespace(r.Client, instance.Namespace)
+	isRoot, err := common.IsRootPolicy(r.Client, instance)
 	if err != nil {
 		log.Error(err, "Failed to determine if the policy is in a managed cluster namespace. Requeueing the request.")
 
 		return reconcile.Result{}, err
 	}
 
-	if !inClusterNs {
-		err := r.handleRootPolicy(instance)
+	if isRoot {
+		if instance.GetDeletionTimestamp() != nil {
+			if !controllerutil.ContainsFinalizer(instance, ReplicaTeardownFinalizer) {
+				log.V(1).Info("The policy is being deleted, waiting for it to be removed")
+
+				return reconcile.Result{}, nil
+			}
+
+			requeueAfter, err := r.handleRootPolicyDeletion(ctx, instance, time.Now())
+			if err != nil {
+				log.Error(err, "Failed to tear down the replicated policies")
+
+				return reconcile.Result{}, err
+			}
+
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+
+		err := r.ensureTeardownFinalizer(ctx, instance)
+		if err != nil {
+			log.Error(err, "Failed to add the replica teardown finalizer to the policy")
+
+			return reconcile.Result{}, err
+		}
+
+		schedule, err := r.getMaintenanceSchedule(instance)
+		if err != nil {
+			log.Error(err, "The maintenance window on the policy is invalid, so changes won't be propagated")
+
+			r.Recorder.Event(instance, "Warning", "PolicyPropagation", err.Error())
+
+			return reconcile.Result{}, nil
+		}
+
+		if schedule != nil {
+			if untilOpen := schedule.untilOpen(time.Now()); untilOpen > 0 {
+				log.Info(
+					"Outside of the maintenance window, so changes won't be propagated until it opens",
+					"requeueAfter", untilOpen.String(),
+				)
+
+				return reconcile.Result{RequeueAfter: untilOpen}, nil
+			}
+		}
+
+		var fingerprint string
+
+		if r.DesiredStateCache {
+			fingerprint, err = r.desiredStateFingerprint(ctx, instance)
+			if err != nil {
+				// The policy is still reconciled, so this isn't fatal
+				log.Error(err, "Failed to determine the inputs of the policy")
+			}
+
+			if desiredStateUnchanged(request.NamespacedName, fingerprint) {
+				log.V(1).Info("The inputs of the policy are unchanged since it was last propagated, skipping it")
+
+				propagationSkippedMetric.Inc()
+
+				return reconcile.Result{}, nil
+			}
+		}
+
+		propagateCtx, span := tracer.Start(ctx, "propagate-policy", trace.WithAttributes(
+			attribute.String("policy.name", instance.Name),
+			attribute.String("policy.namespace", instance.Namespace),
+		))
+
+		requeueAfter, err := r.handleRootPolicy(propagateCtx, instance)
+
+		endSpan(span, err)
+
+		recordDesiredState(request.NamespacedName, fingerprint, requeueAfter, err)
+
+		recordReconcileTime(request.NamespacedName)
+
+		if err != nil {
+			recordPendingPropagation(request.NamespacedName, time.Now())
+		} else {
+			forgetPendingPropagation(request.NamespacedName)
+		}
+
+		var pendingErr *namespacesPendingError
+		if errors.As(err, &pendingErr) {
+			retryDelay := nextNamespaceRetryDelay(request.NamespacedName)
+			if requeueAfter == 0 || retryDelay < requeueAfter {
+				requeueAfter = retryDelay
+			}
+
+			log.Info(
+				"Some cluster namespaces don't exist yet. Requeueing the request.",
+				"namespaces", pendingErr.namespaces,
+				"requeueAfter", requeueAfter.String(),
+			)
+
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+
+		namespaceRetryAttempts.Delete(request.NamespacedName)
+
+		// A misconfigured placement binding isn't retried since the PlacementBinding watch queues the policy again
+		// when it's fixed
+		if errors.Is(err, ErrBindingInvalid) {
+			log.Info("The policy can't be propagated due to an invalid placement binding", "reason", err.Error())
+
+			propagationFailureMetric.WithLabelValues(instance.GetName(), instance.GetNamespace()).Inc()
+			r.Recorder.Event(instance, "Warning", "PlacementBindingInvalid", err.Error())
+
+			return reconcile.Result{}, nil
+		}
+
 		if err != nil {
 			log.Error(err, "Failure during root policy handling")
 
 			propagationFailureMetric.WithLabelValues(instance.GetName(), instance.GetNamespace()).Inc()
+
+			return reconcile.Result{}, err
+		}
+
+		if requeueAfter > 0 {
+			log.V(1).Info(
+				"Requeueing the request to delete the replicated policies pending deletion",
+				"requeueAfter", requeueAfter.String(),
+			)
 		}
 
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	inClusterNs, err := common.IsInClusterNamespace(r.Client, instance.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to determine if the policy is in a managed cluster namespace. Requeueing the request.")
+
 		return reconcile.Result{}, err
 	}
 
+	// A replicated policy left in a cluster namespace from before the replica namespace template was set is deleted
+	// as an orphan by the reconcile of its root policy
+	if !inClusterNs {
+		log.V(2).Info("Ignoring the replicated policy left in the cluster namespace")
+
+		return reconcile.Result{}, nil
+	}
+
 	log = log.WithValues("name", instance.GetName(), "namespace", instance.GetNamespace())
 
 	log.Info("The policy was found in the cluster namespace but doesn't belong to any root policy, deleting it")
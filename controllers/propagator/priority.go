@@ -0,0 +1,145 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// PriorityAnnotation is set on a root policy to an integer priority. When the propagator is configured with a priority
+// window, the root policies that are enqueued together are reconciled in order of their priority, highest first. The
+// default priority is 0.
+const PriorityAnnotation = "policy.open-cluster-management.io/priority"
+
+// policyPriority returns the priority of the input root policy from the PriorityAnnotation. A missing or invalid
+// value is the default priority of 0.
+func policyPriority(policy client.Object) int64 {
+	priority, err := strconv.ParseInt(policy.GetAnnotations()[PriorityAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return priority
+}
+
+// priorityBatcher holds the reconcile requests enqueued within a window and then adds them to the work queue in order
+// of the priority of their root policies, so that when many root policies are enqueued at once, such as when a
+// cluster is onboarded, the higher priority ones are reconciled first. This is best-effort since the requests
+// enqueued in different windows, or already in the work queue, are not reordered, and the requests are handled
+// concurrently by the controller workers.
+type priorityBatcher struct {
+	client    client.Reader
+	window    time.Duration
+	lock      sync.Mutex
+	pending   map[reconcile.Request]bool
+	scheduled bool
+}
+
+func newPriorityBatcher(c client.Reader, window time.Duration) *priorityBatcher {
+	return &priorityBatcher{client: c, window: window, pending: map[reconcile.Request]bool{}}
+}
+
+// add holds the input request until the end of the current window, starting a window if none is in progress.
+func (b *priorityBatcher) add(queue workqueue.Interface, request reconcile.Request) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.pending[request] = true
+
+	if b.scheduled {
+		return
+	}
+
+	b.scheduled = true
+
+	time.AfterFunc(b.window, func() { b.flush(queue) })
+}
+
+// flush adds the pending requests to the input work queue, highest priority first. Requests with the same priority
+// are added in order of their namespace and name.
+func (b *priorityBatcher) flush(queue workqueue.Interface) {
+	b.lock.Lock()
+	pending := b.pending
+	b.pending = map[reconcile.Request]bool{}
+	b.scheduled = false
+	b.lock.Unlock()
+
+	requests := make([]reconcile.Request, 0, len(pending))
+	priorities := make(map[reconcile.Request]int64, len(pending))
+
+	for request := range pending {
+		requests = append(requests, request)
+
+		policy := &policiesv1.Policy{}
+
+		// A policy that can't be retrieved, such as one that was deleted, has the default priority
+		if err := b.client.Get(context.TODO(), request.NamespacedName, policy); err == nil {
+			priorities[request] = policyPriority(policy)
+		}
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		if priorities[requests[i]] != priorities[requests[j]] {
+			return priorities[requests[i]] > priorities[requests[j]]
+		}
+
+		return requests[i].String() < requests[j].String()
+	})
+
+	for _, request := range requests {
+		queue.Add(request)
+	}
+}
+
+// priorityQueue is a work queue that passes the reconcile requests added to it through the priorityBatcher. The
+// requests added after a delay or rate limited, such as retries, are added directly.
+type priorityQueue struct {
+	workqueue.RateLimitingInterface
+	batcher *priorityBatcher
+}
+
+func (q priorityQueue) Add(item interface{}) {
+	request, ok := item.(reconcile.Request)
+	if !ok {
+		q.RateLimitingInterface.Add(item)
+
+		return
+	}
+
+	q.batcher.add(q.RateLimitingInterface, request)
+}
+
+// priorityEventHandler wraps an event handler so that the reconcile requests it enqueues go through the
+// priorityBatcher.
+type priorityEventHandler struct {
+	handler handler.EventHandler
+	batcher *priorityBatcher
+}
+
+func (h priorityEventHandler) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.handler.Create(evt, priorityQueue{q, h.batcher})
+}
+
+func (h priorityEventHandler) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.handler.Update(evt, priorityQueue{q, h.batcher})
+}
+
+func (h priorityEventHandler) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.handler.Delete(evt, priorityQueue{q, h.batcher})
+}
+
+func (h priorityEventHandler) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.handler.Generic(evt, priorityQueue{q, h.batcher})
+}
@@ -0,0 +1,87 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	priorities := map[string]string{"rbac": "100", "namespaces": "50", "invalid": "high", "default": ""}
+	objs := make([]client.Object, 0, len(priorities))
+
+	for name, priority := range priorities {
+		policy := fakeRootPolicy(name, "policies")
+		if priority != "" {
+			policy.SetAnnotations(map[string]string{PriorityAnnotation: priority})
+		}
+
+		objs = append(objs, &policy)
+	}
+
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: name}}
+	}
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).Build()
+	q := priorityQueue{RateLimitingInterface: queue, batcher: newPriorityBatcher(c, 50*time.Millisecond)}
+
+	// The deleted policy has the default priority
+	for _, name := range []string{"invalid", "default", "namespaces", "deleted", "rbac", "namespaces"} {
+		q.Add(request(name))
+	}
+
+	if queue.Len() != 0 {
+		t.Fatalf("Expected the requests to be held until the end of the window, got %d", queue.Len())
+	}
+
+	for start := time.Now(); queue.Len() != 5; {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Expected 5 requests in the work queue, got %d", queue.Len())
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, expected := range []string{"rbac", "namespaces", "default", "deleted", "invalid"} {
+		item, _ := queue.Get()
+		queue.Done(item)
+
+		if item != request(expected) {
+			t.Fatalf("Expected the request for %s, got %v", expected, item)
+		}
+	}
+}
+
+func TestPolicyPriority(t *testing.T) {
+	tests := map[string]int64{"": 0, "10": 10, "-5": -5, "high": 0}
+
+	for annotation, expected := range tests {
+		policy := &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{PriorityAnnotation: annotation},
+		}}
+
+		if priority := policyPriority(policy); priority != expected {
+			t.Fatalf("Expected the priority %d for %q, got %d", expected, annotation, priority)
+		}
+	}
+}
@@ -11,19 +11,27 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	templates "github.com/stolostron/go-template-utils/v3/pkg/templates"
 	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -39,10 +47,140 @@ const (
 	concurrencyPerPolicyDefault = 5
 )
 
+// The minimum age of a replicated policy before it's considered orphaned when its root policy isn't found. This avoids
+// deleting valid replicated policies when the root policy is briefly not found, such as when it's recreated.
+const orphanedReplicaGracePeriod = 5 * time.Second
+
+// The bounds of the delay before retrying to replicate a policy to cluster namespaces that don't exist yet, such as
+// when clusters are being onboarded.
+const (
+	namespaceRetryBaseDelay = 2 * time.Second
+	namespaceRetryMaxDelay  = 2 * time.Minute
+)
+
+// The errors returned when resolving the clusters that a root policy is placed on. ErrBindingInvalid is a user
+// misconfiguration that isn't retried until the PlacementBinding changes. ErrPlacementNotFound and ErrNoDecisions mean
+// that a PlacementBinding doesn't place the policy on any cluster, so they are returned along with the clusters from
+// the other PlacementBindings, which are still propagated to.
+var (
+	ErrBindingInvalid    = errors.New("the placement binding is invalid")
+	ErrPlacementNotFound = errors.New("the placement was not found")
+	ErrNoDecisions       = errors.New("the policy is bound but no clusters are selected")
+)
+
+// isNoTargetsError returns true if the input error from resolving the clusters of a root policy only indicates that
+// some of its PlacementBindings don't place it on any cluster, which means the returned clusters are still valid.
+func isNoTargetsError(err error) bool {
+	if err == nil || errors.Is(err, ErrBindingInvalid) {
+		return false
+	}
+
+	var joinedErr interface{ Unwrap() []error }
+	if errors.As(err, &joinedErr) {
+		for _, wrappedErr := range joinedErr.Unwrap() {
+			if !isNoTargetsError(wrappedErr) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return errors.Is(err, ErrPlacementNotFound) || errors.Is(err, ErrNoDecisions)
+}
+
+// targetErrorReason returns the reason label of the policy_target_resolution_errors_total metric for the input error
+// from resolving the clusters of a root policy.
+func targetErrorReason(err error) string {
+	switch {
+	case errors.Is(err, ErrBindingInvalid):
+		return "binding_invalid"
+	case !isNoTargetsError(err):
+		return "transient"
+	case errors.Is(err, ErrPlacementNotFound):
+		return "placement_not_found"
+	default:
+		return "no_decisions"
+	}
+}
+
+// errNamespaceNotFound is returned when a replicated policy can't be created because its cluster namespace doesn't
+// exist yet.
+var errNamespaceNotFound = errors.New("the cluster namespace doesn't exist")
+
+// errReplicaNameConflict is returned when a replicated policy can't be written because a policy with its name in the
+// cluster namespace isn't a replica of the same root policy.
+var errReplicaNameConflict = errors.New("the replicated policy name is already used by another policy")
+
+// errReplicaTooLarge is returned when a replicated policy isn't written because its rendered spec exceeds the
+// configured maximum size.
+var errReplicaTooLarge = errors.New("the replicated policy spec exceeds the maximum size")
+
+// errPropagationTimeout is returned when a replicated policy isn't written before the propagation timeout of the root
+// policy passes, such as when the API requests for its cluster namespace hang.
+var errPropagationTimeout = errors.New("the propagation timed out before the replicated policy was written")
+
+// namespaceRetryAttempts maps the namespaced name of a root policy to the number of consecutive reconciles that
+// couldn't replicate the policy because cluster namespaces didn't exist yet.
+var namespaceRetryAttempts sync.Map
+
+// templateRefIndex maps the namespaced name of a root policy to the set of objects referenced by its hub templates
+// that the dynamic watcher is watching for it. A change to any of these objects reconciles the root policy.
+var templateRefIndex sync.Map
+
+// namespacesPendingError is returned by handleRootPolicy when the only clusters that the policy couldn't be replicated
+// to are those whose namespaces don't exist yet.
+type namespacesPendingError struct {
+	namespaces []string
+}
+
+func (e *namespacesPendingError) Error() string {
+	return "the cluster namespaces don't exist yet:" + strings.Join(e.namespaces, ",")
+}
+
+// namespaceRetryDelay returns the delay before retrying to replicate a policy to cluster namespaces that don't exist
+// yet for the input attempt, starting at 1. The delay doubles with each attempt up to namespaceRetryMaxDelay, and a
+// random jitter of up to half of the delay is subtracted so that simultaneously onboarded clusters don't cause the
+// policies to be retried in lockstep.
+func namespaceRetryDelay(attempt int) time.Duration {
+	delay := namespaceRetryBaseDelay
+
+	for i := 1; i < attempt && delay < namespaceRetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > namespaceRetryMaxDelay {
+		delay = namespaceRetryMaxDelay
+	}
+
+	return wait.Jitter(delay/2, 1)
+}
+
+// nextNamespaceRetryDelay records another attempt to replicate the root policy to cluster namespaces that don't exist
+// yet and returns the delay before the next attempt.
+func nextNamespaceRetryDelay(rootPolicy types.NamespacedName) time.Duration {
+	attempt := 1
+
+	if previous, ok := namespaceRetryAttempts.Load(rootPolicy); ok {
+		//nolint:forcetypeassert
+		attempt = previous.(int) + 1
+	}
+
+	namespaceRetryAttempts.Store(rootPolicy, attempt)
+
+	return namespaceRetryDelay(attempt)
+}
+
 const (
 	startDelim              = "{{hub"
 	stopDelim               = "hub}}"
 	TriggerUpdateAnnotation = "policy.open-cluster-management.io/trigger-update"
+	// LastTriggerUpdateAnnotation is set on a replicated policy to the value of the TriggerUpdateAnnotation on the root
+	// policy when the replicated policy was last written.
+	LastTriggerUpdateAnnotation = "policy.open-cluster-management.io/last-trigger-update"
+	// PendingDeletionAnnotation is set on a replicated policy to the RFC 3339 time its cluster was first found to no
+	// longer be selected by the placement when the replica deletion grace period is enabled.
+	PendingDeletionAnnotation = "policy.open-cluster-management.io/pending-deletion"
 )
 
 var (
@@ -124,6 +262,115 @@ func plcDeletionWrapper(
 	}
 }
 
+// orphanGracePeriodRemaining returns how long to wait before the replicated policies of the input root policy, which
+// was not found, are considered orphaned and can be deleted. Zero is returned if all the replicated policies are older
+// than orphanedReplicaGracePeriod.
+func (r *PolicyReconciler) orphanGracePeriodRemaining(rootPolicy types.NamespacedName) (time.Duration, error) {
+	replicatedPlcList := &policiesv1.PolicyList{}
+
+	err := r.List(
+		context.TODO(),
+		replicatedPlcList,
+		client.MatchingLabels{
+			common.RootPolicyLabel: common.ReplicatedPolicyName(rootPolicy.Namespace, rootPolicy.Name),
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var remaining time.Duration
+
+	for _, replicatedPlc := range withoutOtherHubReplicas(replicatedPlcList.Items) {
+		plcRemaining := orphanedReplicaGracePeriod - time.Since(replicatedPlc.CreationTimestamp.Time)
+		if plcRemaining > remaining {
+			remaining = plcRemaining
+		}
+	}
+
+	return remaining, nil
+}
+
+// withoutOtherHubReplicas returns the input replicated policies without those created by another hub, which must not
+// be deleted or counted by this hub.
+func withoutOtherHubReplicas(replicatedPlcs []policiesv1.Policy) []policiesv1.Policy {
+	if common.HubID() == "" {
+		return replicatedPlcs
+	}
+
+	owned := make([]policiesv1.Policy, 0, len(replicatedPlcs))
+
+	for i := range replicatedPlcs {
+		if !common.IsOwnedByOtherHub(&replicatedPlcs[i]) {
+			owned = append(owned, replicatedPlcs[i])
+		}
+	}
+
+	return owned
+}
+
+// deleteDuplicateReplicas deletes the extra replicated policies of the input root policy in a namespace that has more
+// than one, such as those left behind by past bugs. The replicated policy with the canonical <namespace>.<name> name
+// is kept, and all the others in the namespace are deleted. This is safe to run on every reconcile since a namespace
+// normally only has the canonical replicated policy.
+func (r *PolicyReconciler) deleteDuplicateReplicas(instance *policiesv1.Policy) error {
+	log := log.WithValues("policyName", instance.GetName(), "policyNamespace", instance.GetNamespace())
+
+	replicatedPlcList := &policiesv1.PolicyList{}
+
+	err := r.List(
+		context.TODO(), replicatedPlcList, client.MatchingLabels(common.LabelsForRootPolicy(instance)),
+	)
+	if err != nil {
+		return err
+	}
+
+	replicasByNamespace := map[string][]*policiesv1.Policy{}
+	replicatedPlcs := withoutOtherHubReplicas(replicatedPlcList.Items)
+
+	for i := range replicatedPlcs {
+		replicatedPlc := &replicatedPlcs[i]
+
+		// The root policy's own namespace is never a cluster namespace
+		if replicatedPlc.Namespace == instance.Namespace {
+			continue
+		}
+
+		replicasByNamespace[replicatedPlc.Namespace] = append(replicasByNamespace[replicatedPlc.Namespace], replicatedPlc)
+	}
+
+	canonicalName := common.FullNameForPolicy(instance)
+
+	var deletionErrs []error
+
+	for namespace, replicas := range replicasByNamespace {
+		if len(replicas) < 2 {
+			continue
+		}
+
+		for _, replica := range replicas {
+			if replica.Name == canonicalName {
+				continue
+			}
+
+			log.Info(
+				"Deleting a duplicate replicated policy in the cluster namespace",
+				"namespace", namespace, "name", replica.Name, "canonicalName", canonicalName,
+			)
+
+			err := r.Delete(context.TODO(), replica)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				deletionErrs = append(
+					deletionErrs,
+					fmt.Errorf("failed to delete the duplicate replicated policy %s/%s: %w", namespace, replica.Name, err),
+				)
+			}
+		}
+	}
+
+	return errors.Join(deletionErrs...)
+}
+
 // cleanUpPolicy will delete all replicated policies associated with provided policy.
 func (r *PolicyReconciler) cleanUpPolicy(instance *policiesv1.Policy) error {
 	log := log.WithValues("policyName", instance.GetName(), "policyNamespace", instance.GetNamespace())
@@ -151,6 +398,8 @@ func (r *PolicyReconciler) cleanUpPolicy(instance *policiesv1.Policy) error {
 		return err
 	}
 
+	templateRefIndex.Delete(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})
+
 	err = r.List(
 		context.TODO(), replicatedPlcList, client.MatchingLabels(common.LabelsForRootPolicy(instance)),
 	)
@@ -160,6 +409,8 @@ func (r *PolicyReconciler) cleanUpPolicy(instance *policiesv1.Policy) error {
 		return err
 	}
 
+	replicatedPlcList.Items = withoutOtherHubReplicas(replicatedPlcList.Items)
+
 	if len(replicatedPlcList.Items) == 0 {
 		log.V(2).Info("No replicated policies to delete.")
 
@@ -212,6 +463,11 @@ func (r *PolicyReconciler) cleanUpPolicy(instance *policiesv1.Policy) error {
 	}
 
 	propagationFailureMetric.DeleteLabelValues(instance.GetName(), instance.GetNamespace())
+	replicaSizeExceededMetric.DeleteLabelValues(instance.GetName(), instance.GetNamespace())
+	replicasAdoptedMetric.DeleteLabelValues(instance.GetName(), instance.GetNamespace())
+	templateKindRejectedMetric.DeletePartialMatch(
+		prometheus.Labels{"name": instance.GetName(), "namespace": instance.GetNamespace()},
+	)
 
 	return nil
 }
@@ -224,7 +480,7 @@ type clusterDecision struct {
 }
 
 type decisionHandler interface {
-	handleDecision(instance *policiesv1.Policy, decision clusterDecision) (
+	handleDecision(ctx context.Context, instance *policiesv1.Policy, decision clusterDecision) (
 		templateRefObjs map[k8sdepwatches.ObjectIdentifier]bool, err error,
 	)
 }
@@ -242,16 +498,24 @@ type decisionResult struct {
 
 // handleDecisionWrapper wraps the handleDecision method for concurrency. decisionHandler is an
 // object with the handleDecision method. This is used instead of making this a method on the
-// PolicyReconciler struct in order for easier unit testing. instance is the policy the placement
+// PolicyReconciler struct in order for easier unit testing. ctx is passed to handleDecision so that its spans are
+// part of the trace of the root policy reconcile. instance is the policy the placement
 // decision is about. decisions is the channel with the placement decisions for the input policy to
 // process. When this channel closes, it means that all decisions have been processed. results is a
 // channel this method will send the outcome of handling each placement decision. The calling Go
-// routine can use this to determine success.
+// routine can use this to determine success. deadline is when the propagation of the policy times out, after which
+// the decisions not yet handled fail with errPropagationTimeout. It's the deadline of the context of each decision so
+// that a cluster with hanging API requests doesn't block the others. A zero deadline means there is no timeout.
+// quarantine records the result for each cluster, and the decisions of quarantined clusters fail with
+// errClusterQuarantined without being handled. A nil quarantine never skips a decision.
 func handleDecisionWrapper(
+	ctx context.Context,
 	decisionHandler decisionHandler,
 	instance *policiesv1.Policy,
 	decisions <-chan clusterDecision,
 	results chan<- decisionResult,
+	deadline time.Time,
+	quarantine *ClusterQuarantine,
 ) {
 	for decision := range decisions {
 		log := log.WithValues(
@@ -260,15 +524,46 @@ func handleDecisionWrapper(
 			"decision", decision.Cluster,
 			"policyOverrides", decision.PolicyOverrides,
 		)
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			log.Info("The propagation timed out before the decision was handled")
+
+			results <- decisionResult{decision.Cluster, nil, errPropagationTimeout}
+
+			continue
+		}
+
+		if !quarantine.allow(decision.Cluster.ClusterNamespace, time.Now()) {
+			log.V(1).Info("Skipping the decision since the cluster is quarantined")
+
+			results <- decisionResult{decision.Cluster, nil, errClusterQuarantined}
+
+			continue
+		}
+
 		log.V(1).Info("Handling the decision")
 
 		instanceCopy := *instance.DeepCopy()
+		decisionCtx, cancel := ctx, context.CancelFunc(func() {})
+
+		if !deadline.IsZero() {
+			decisionCtx, cancel = context.WithDeadline(ctx, deadline)
+		}
 
-		templateRefObjs, err := decisionHandler.handleDecision(&instanceCopy, decision)
+		templateRefObjs, err := decisionHandler.handleDecision(decisionCtx, &instanceCopy, decision)
 		if err == nil {
 			log.V(1).Info("Replicated the policy")
+		} else if errors.Is(decisionCtx.Err(), context.DeadlineExceeded) {
+			log.Info("The propagation timed out while handling the decision", "error", err.Error())
+
+			// The underlying error isn't kept so that the timed out clusters share an entry in the status
+			err = errPropagationTimeout
 		}
 
+		cancel()
+
+		quarantine.record(decision.Cluster.ClusterNamespace, err, time.Now())
+
 		results <- decisionResult{decision.Cluster, templateRefObjs, err}
 	}
 }
@@ -287,6 +582,18 @@ func (set decisionSet) namespaces() []string {
 	return namespaces
 }
 
+// subjectBinds returns true if the input PlacementBinding subject binds the input root policy, directly or through a
+// PolicySet. When the root policy stands in for an object propagated by an ObjectReconciler, which is when its type
+// metadata has the kind of that object, the subject must select that object instead.
+func (r *PolicyReconciler) subjectBinds(instance *policiesv1.Policy, subject policiesv1.Subject) bool {
+	gvk := instance.GroupVersionKind()
+	if gvk.Kind != "" && (gvk.Group != policiesv1.SchemeGroupVersion.Group || gvk.Kind != policiesv1.Kind) {
+		return common.SubjectMatchesObject(subject, gvk.GroupKind(), instance.GetName())
+	}
+
+	return common.SubjectMatchesPolicy(subject, instance.GetName()) || r.isPolicySetSubject(instance, subject)
+}
+
 // getPolicyPlacementDecisions retrieves the placement decisions for a input
 // placement binding when the policy is bound within it.
 func (r *PolicyReconciler) getPolicyPlacementDecisions(
@@ -304,17 +611,21 @@ func (r *PolicyReconciler) getPolicyPlacementDecisions(
 
 	subjects := pb.Subjects
 	for _, subject := range subjects {
-		if !(subject.APIGroup == policiesv1.SchemeGroupVersion.Group &&
-			subject.Kind == policiesv1.Kind &&
-			subject.Name == instance.GetName()) && !r.isPolicySetSubject(instance, subject) {
+		if !r.subjectBinds(instance, subject) {
 			continue
 		}
 
 		decisions, placements, err = getPlacementDecisions(r.Client, pb, instance)
-		if err != nil {
+		if err != nil && !errors.Is(err, ErrPlacementNotFound) {
 			return nil, nil, err
 		}
 
+		if err != nil {
+			recordPlacementDecisionCount(&pb, 0)
+		} else {
+			recordPlacementDecisionCount(&pb, len(decisions))
+		}
+
 		if instance.Spec.Disabled {
 			// Only handle the first match in pb.spec.subjects
 			return nil, placements, nil
@@ -324,11 +635,22 @@ func (r *PolicyReconciler) getPolicyPlacementDecisions(
 			log.Info("No placement decisions to process on this policy")
 		}
 
+		// Show the clusters selected by the placement binding in the root policy status. They are sorted so that the
+		// status only changes when the selected clusters change.
+		sortedDecisions := append([]appsv1.PlacementDecision(nil), decisions...)
+		sort.Slice(sortedDecisions, func(i, j int) bool {
+			return sortedDecisions[i].ClusterName < sortedDecisions[j].ClusterName
+		})
+
+		for _, placement := range placements {
+			placement.Decisions = sortedDecisions
+		}
+
 		// Only handle the first match in pb.spec.subjects
 		break
 	}
 
-	return decisions, placements, nil
+	return decisions, placements, err
 }
 
 // getAllClusterDecisions retrieves all cluster decisions for the input policy, taking into
@@ -340,7 +662,8 @@ func (r *PolicyReconciler) getPolicyPlacementDecisions(
 // It returns:
 //   - allClusterDecisions: a slice of all the cluster decisions should be handled
 //   - placements: a slice of all the placement decisions discovered
-//   - err: error
+//   - err: error, which wraps ErrPlacementNotFound or ErrNoDecisions when a placement binding doesn't place the
+//     policy on any cluster. In that case, the cluster decisions from the other placement bindings are still returned.
 //
 // The rules for policy overrides are as follows:
 //
@@ -353,6 +676,7 @@ func (r *PolicyReconciler) getAllClusterDecisions(
 	allClusterDecisions []clusterDecision, placements []*policiesv1.Placement, err error,
 ) {
 	var pbsWithSubFilter []policiesv1.PlacementBinding
+	var noTargetErrs []error
 
 	allClusterDecisionsMap := map[appsv1.PlacementDecision]policiesv1.BindingOverrides{}
 
@@ -366,7 +690,15 @@ func (r *PolicyReconciler) getAllClusterDecisions(
 
 		plcDecisions, plcPlacements, err := r.getPolicyPlacementDecisions(instance, pb)
 		if err != nil {
-			return nil, nil, err
+			if !errors.Is(err, ErrPlacementNotFound) {
+				return nil, nil, err
+			}
+
+			noTargetErrs = append(noTargetErrs, err)
+		} else if len(plcPlacements) != 0 && len(plcDecisions) == 0 && !instance.Spec.Disabled {
+			noTargetErrs = append(
+				noTargetErrs, fmt.Errorf("%w by the placement binding %s", ErrNoDecisions, pb.GetName()),
+			)
 		}
 
 		for _, decision := range plcDecisions {
@@ -394,7 +726,11 @@ func (r *PolicyReconciler) getAllClusterDecisions(
 
 		plcDecisions, plcPlacements, err := r.getPolicyPlacementDecisions(instance, pb)
 		if err != nil {
-			return nil, nil, err
+			if !errors.Is(err, ErrPlacementNotFound) {
+				return nil, nil, err
+			}
+
+			noTargetErrs = append(noTargetErrs, err)
 		}
 
 		for _, decision := range plcDecisions {
@@ -423,31 +759,272 @@ func (r *PolicyReconciler) getAllClusterDecisions(
 		allClusterDecisions = append(allClusterDecisions, decision)
 	}
 
+	err = errors.Join(noTargetErrs...)
+
 	return
 }
 
+// resolveClusterDecisions returns the cluster decisions of the clusters that the input policy targets with the
+// placement bindings in the input placement binding list, and the placements that bind the policy. These are the
+// decisions from getAllClusterDecisions limited by the cluster selector of the policy. The returned error is the same
+// as from getAllClusterDecisions, or an error from applying the cluster selector.
+func (r *PolicyReconciler) resolveClusterDecisions(
+	instance *policiesv1.Policy, pbList *policiesv1.PlacementBindingList,
+) ([]clusterDecision, []*policiesv1.Placement, error) {
+	allClusterDecisions, placements, err := r.getAllClusterDecisions(instance, pbList)
+	if err != nil && !isNoTargetsError(err) {
+		return nil, nil, err
+	}
+
+	if instance.Spec.ClusterSelector != nil {
+		placedCount := len(allClusterDecisions)
+
+		var selectorErr error
+
+		allClusterDecisions, selectorErr = r.filterByClusterSelector(instance, allClusterDecisions)
+		if selectorErr != nil {
+			return nil, nil, fmt.Errorf("failed to filter the placed clusters with the cluster selector: %w", selectorErr)
+		}
+
+		log.V(1).Info(
+			"Limited the clusters the policy is replicated to based on the cluster selector",
+			"policyName", instance.GetName(),
+			"policyNamespace", instance.GetNamespace(),
+			"placedCount", placedCount,
+			"selectedCount", len(allClusterDecisions),
+		)
+	}
+
+	return allClusterDecisions, placements, err
+}
+
+// ResolvePolicyTargets returns the sorted cluster namespaces that the input root policy is replicated to, based on
+// the PlacementBindings in its namespace, their placements, and the cluster selector of the policy. The clusters are
+// resolved the same way as by the root policy controller, but the rolloutStrategy and decisionGroupRollout of the
+// policy aren't applied since they depend on the replication progress. A disabled policy has no targets.
+//
+// When a PlacementBinding doesn't place the policy on any cluster, the cluster namespaces from the other
+// PlacementBindings are returned along with an error wrapping ErrPlacementNotFound or ErrNoDecisions.
+func ResolvePolicyTargets(ctx context.Context, c client.Client, policy *policiesv1.Policy) ([]string, error) {
+	pbList := &policiesv1.PlacementBindingList{}
+
+	err := c.List(ctx, pbList, client.InNamespace(policy.GetNamespace()))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &PolicyReconciler{Client: c}
+
+	decisions, _, err := r.resolveClusterDecisions(policy, pbList)
+	if err != nil && !isNoTargetsError(err) {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(decisions))
+	for _, decision := range decisions {
+		namespaces = append(namespaces, decision.Cluster.ClusterNamespace)
+	}
+
+	sort.Strings(namespaces)
+
+	return namespaces, err
+}
+
+// filterByClusterSelector returns the input cluster decisions of the clusters whose labels match the cluster selector
+// of the policy. A cluster whose ManagedCluster doesn't exist is treated as having no labels.
+func (r *PolicyReconciler) filterByClusterSelector(
+	instance *policiesv1.Policy, decisions []clusterDecision,
+) ([]clusterDecision, error) {
+	selector, err := metav1.LabelSelectorAsSelector(instance.Spec.ClusterSelector)
+	if err != nil {
+		return nil, fmt.Errorf("the cluster selector is invalid: %w", err)
+	}
+
+	selected := make([]clusterDecision, 0, len(decisions))
+
+	for _, decision := range decisions {
+		managedCluster := &clusterv1.ManagedCluster{}
+
+		err := r.Get(context.TODO(), types.NamespacedName{Name: decision.Cluster.ClusterName}, managedCluster)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		if selector.Matches(labels.Set(managedCluster.GetLabels())) {
+			selected = append(selected, decision)
+		}
+	}
+
+	return selected, nil
+}
+
+// applyRolloutStrategy limits the input cluster decisions to the maxClusters value of the policy's rollout strategy.
+// The clusters that already have the replicated policy, as determined by the policy status, are always kept so that
+// changing the limit never removes a replicated policy. The remaining clusters are chosen in order of the cluster
+// name so that the same clusters get the policy first. If maxClusters is greater than or equal to the number of
+// cluster decisions, all the cluster decisions are returned.
+func applyRolloutStrategy(instance *policiesv1.Policy, decisions []clusterDecision) []clusterDecision {
+	if instance.Spec.RolloutStrategy == nil {
+		return decisions
+	}
+
+	maxClusters := int(instance.Spec.RolloutStrategy.MaxClusters)
+	if maxClusters >= len(decisions) {
+		return decisions
+	}
+
+	propagated := make(map[string]bool, len(instance.Status.Status))
+
+	for _, clusterStatus := range instance.Status.Status {
+		propagated[clusterStatus.ClusterNamespace] = true
+	}
+
+	sorted := make([]clusterDecision, len(decisions))
+	copy(sorted, decisions)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cluster.ClusterName < sorted[j].Cluster.ClusterName
+	})
+
+	selected := make([]clusterDecision, 0, maxClusters)
+	notPropagated := make([]clusterDecision, 0, len(sorted))
+
+	for _, decision := range sorted {
+		if propagated[decision.Cluster.ClusterNamespace] {
+			selected = append(selected, decision)
+		} else {
+			notPropagated = append(notPropagated, decision)
+		}
+	}
+
+	for _, decision := range notPropagated {
+		if len(selected) >= maxClusters {
+			break
+		}
+
+		selected = append(selected, decision)
+	}
+
+	return selected
+}
+
 // handleDecisions will get all the placement decisions based on the input policy and placement
 // binding list and propagate the policy. Note that this method performs concurrent operations.
+// When the replication spec is exported, the policy is not propagated, but allDecisions still has the placed clusters
+// so that they're listed in the status and their existing replicated policies aren't deleted as orphaned.
 // It returns the following:
 //   - placements - a slice of all the placement decisions discovered
 //   - allDecisions - a set of all the placement decisions encountered
 //   - failedClusters - a set of all the clusters that encountered an error during propagation
+//   - clusterErrs - the error for each cluster in failedClusters
 //   - allFailed - a bool that determines if all clusters encountered an error during propagation
+//   - targetErr - the error from resolving the clusters of the policy. When isNoTargetsError is true for it, the
+//     policy was still propagated to the clusters selected by the other placement bindings.
 func (r *PolicyReconciler) handleDecisions(
-	instance *policiesv1.Policy, pbList *policiesv1.PlacementBindingList,
+	ctx context.Context, instance *policiesv1.Policy, pbList *policiesv1.PlacementBindingList,
 ) (
-	placements []*policiesv1.Placement, allDecisions decisionSet, failedClusters decisionSet, allFailed bool,
+	placements []*policiesv1.Placement,
+	allDecisions decisionSet,
+	failedClusters decisionSet,
+	clusterErrs clusterErrors,
+	allFailed bool,
+	targetErr error,
 ) {
 	log := log.WithValues("policyName", instance.GetName(), "policyNamespace", instance.GetNamespace())
 	allDecisions = map[appsv1.PlacementDecision]bool{}
 	failedClusters = map[appsv1.PlacementDecision]bool{}
+	clusterErrs = clusterErrors{}
 
 	allTemplateRefObjs := getPolicySetDependencies(instance)
 
-	allClusterDecisions, placements, err := r.getAllClusterDecisions(instance, pbList)
-	if err != nil {
+	_, resolveSpan := tracer.Start(ctx, "resolve-targets")
+
+	allClusterDecisions, placements, targetErr := r.resolveClusterDecisions(instance, pbList)
+	if targetErr != nil && !isNoTargetsError(targetErr) {
 		allFailed = true
 
+		endSpan(resolveSpan, targetErr)
+
+		return
+	}
+
+	resolvedCount := len(allClusterDecisions)
+
+	var err error
+
+	if instance.Spec.DecisionGroupRollout != nil {
+		var rolloutStatus *policiesv1.DecisionGroupRolloutStatus
+		var held []clusterDecision
+
+		placedCount := len(allClusterDecisions)
+
+		allClusterDecisions, held, rolloutStatus, err = r.applyDecisionGroupRollout(
+			instance, placements, allClusterDecisions, time.Now(),
+		)
+		if err != nil {
+			log.Error(err, "Failed to get the decision groups of the placed clusters")
+
+			allFailed = true
+
+			endSpan(resolveSpan, err)
+
+			return
+		}
+
+		instance.Status.DecisionGroupRollout = rolloutStatus
+
+		// The replicated policies of the held clusters are left as is, but they're still placed so that they're kept
+		// in the status and aren't deleted as orphaned
+		for _, decision := range held {
+			allDecisions[decision.Cluster] = true
+		}
+
+		log.V(1).Info(
+			"Limited the clusters the policy is replicated to based on the decision group rollout",
+			"placedCount", placedCount,
+			"selectedCount", len(allClusterDecisions),
+			"heldCount", len(held),
+		)
+	} else {
+		instance.Status.DecisionGroupRollout = nil
+	}
+
+	if instance.Spec.RolloutStrategy != nil {
+		placedCount := len(allClusterDecisions)
+		allClusterDecisions = applyRolloutStrategy(instance, allClusterDecisions)
+
+		log.V(1).Info(
+			"Limited the clusters the policy is replicated to based on the rollout strategy",
+			"maxClusters", instance.Spec.RolloutStrategy.MaxClusters,
+			"placedCount", placedCount,
+			"selectedCount", len(allClusterDecisions),
+		)
+	}
+
+	endSpan(
+		resolveSpan,
+		nil,
+		attribute.Int("cluster.placed.count", resolvedCount),
+		attribute.Int("cluster.count", len(allClusterDecisions)),
+	)
+
+	// The regional hub replicates the policy from the exported spec, so no replicated policies are written here, and
+	// the hub templates aren't resolved or watched. The existing replicated policies of the placed clusters are left
+	// as is, since they're only deleted explicitly once the regional hubs replicate the policy.
+	if r.ExportReplicationSpec {
+		err = r.exportReplicationSpec(ctx, instance, allClusterDecisions)
+		if err != nil {
+			log.Error(err, "Failed to export the replication spec")
+
+			allFailed = true
+
+			return
+		}
+
+		for _, decision := range allClusterDecisions {
+			allDecisions[decision.Cluster] = true
+		}
+
 		return
 	}
 
@@ -460,8 +1037,13 @@ func (r *PolicyReconciler) handleDecisions(
 		resultsChan := make(chan decisionResult, len(allClusterDecisions))
 		numWorkers := common.GetNumWorkers(len(allClusterDecisions), concurrencyPerPolicy)
 
+		var deadline time.Time
+		if r.PropagationTimeout > 0 {
+			deadline = time.Now().Add(r.PropagationTimeout)
+		}
+
 		for i := 0; i < numWorkers; i++ {
-			go handleDecisionWrapper(r, instance, decisionsChan, resultsChan)
+			go handleDecisionWrapper(ctx, r, instance, decisionsChan, resultsChan, deadline, r.ClusterQuarantine)
 		}
 
 		log.Info("Handling the placement decisions", "count", len(allClusterDecisions))
@@ -484,6 +1066,7 @@ func (r *PolicyReconciler) handleDecisions(
 
 			if result.Err != nil {
 				failedClusters[result.Identifier] = true
+				clusterErrs[result.Identifier] = result.Err
 			}
 
 			processedResults++
@@ -510,6 +1093,21 @@ func (r *PolicyReconciler) handleDecisions(
 		Namespace: instance.Namespace,
 		Name:      instance.Name,
 	}
+
+	rootKey := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+
+	// A cluster that failed may have done so before its hub templates were resolved, in which case the objects it
+	// references are unknown. Keep watching the objects referenced previously so that a change that fixes the failure
+	// still reconciles the policy. They are no longer watched after a reconcile where no cluster fails.
+	if len(failedClusters) != 0 {
+		if previous, ok := templateRefIndex.Load(rootKey); ok {
+			//nolint:forcetypeassert
+			for refObj := range previous.(map[k8sdepwatches.ObjectIdentifier]bool) {
+				allTemplateRefObjs[refObj] = true
+			}
+		}
+	}
+
 	refObjs := make([]k8sdepwatches.ObjectIdentifier, 0, len(allTemplateRefObjs))
 
 	for refObj := range allTemplateRefObjs {
@@ -530,6 +1128,8 @@ func (r *PolicyReconciler) handleDecisions(
 			)
 
 			allFailed = true
+		} else {
+			templateRefIndex.Store(rootKey, allTemplateRefObjs)
 		}
 	} else {
 		err := r.DynamicWatcher.RemoveWatcher(instanceObjID)
@@ -545,6 +1145,8 @@ func (r *PolicyReconciler) handleDecisions(
 			)
 
 			allFailed = true
+		} else {
+			templateRefIndex.Delete(rootKey)
 		}
 	}
 
@@ -553,24 +1155,103 @@ func (r *PolicyReconciler) handleDecisions(
 
 // cleanUpOrphanedRplPolicies compares the status of the input policy against the input placement
 // decisions. If the cluster exists in the status but doesn't exist in the input placement
-// decisions, then it's considered stale and will be removed.
+// decisions, then it's considered stale and will be removed. The stale replicated policies are
+// deleted in order of the cluster namespace, and a failed deletion doesn't prevent the remaining
+// deletions. The returned error combines all the deletion errors.
+//
+// If the ReplicaDeletionGracePeriod is set, a stale replicated policy is instead marked with the
+// PendingDeletionAnnotation and is only deleted once the grace period has passed since it was
+// marked. This avoids deleting and recreating replicated policies when the placement decisions
+// briefly empty out. The clusters with replicated policies pending deletion are returned along
+// with the duration until the next one can be deleted.
 func (r *PolicyReconciler) cleanUpOrphanedRplPolicies(
 	instance *policiesv1.Policy, allDecisions decisionSet,
-) error {
+) (pendingDeletion decisionSet, requeueAfter time.Duration, err error) {
 	log := log.WithValues("policyName", instance.GetName(), "policyNamespace", instance.GetNamespace())
-	successful := true
 
-	for _, cluster := range instance.Status.Status {
-		key := appsv1.PlacementDecision{
-			ClusterName:      cluster.ClusterNamespace,
-			ClusterNamespace: cluster.ClusterNamespace,
-		}
-		if allDecisions[key] {
+	pendingDeletion = decisionSet{}
+	staleClusters := map[string]string{}
+	staleNamespaces := make([]string, 0, len(instance.Status.Status))
+
+	// Compare by namespace since the replicated policies may be in a namespace derived from the cluster namespace
+	decidedNamespaces := make(map[string]bool, len(allDecisions))
+
+	for decision, decided := range allDecisions {
+		if decided {
+			decidedNamespaces[decision.ClusterNamespace] = true
+		}
+	}
+
+	for _, cluster := range instance.Status.Status {
+		if decidedNamespaces[cluster.ClusterNamespace] {
 			continue
 		}
+
+		staleClusters[cluster.ClusterNamespace] = cluster.ClusterName
+		staleNamespaces = append(staleNamespaces, cluster.ClusterNamespace)
+	}
+
+	sort.Strings(staleNamespaces)
+
+	name := common.FullNameForPolicy(instance)
+
+	var deletionErrs []error
+
+	for _, namespace := range staleNamespaces {
+		log := log.WithValues("name", name, "namespace", namespace)
+
+		if common.HubID() != "" {
+			otherHub, err := r.isOtherHubReplica(namespace, name)
+			if err != nil {
+				log.Error(err, "Failed to get the orphaned replicated policy")
+
+				deletionErrs = append(
+					deletionErrs, fmt.Errorf("failed to get the replicated policy %s/%s: %w", namespace, name, err),
+				)
+
+				continue
+			}
+
+			if otherHub {
+				log.V(1).Info("Not deleting the orphaned replicated policy since it was created by another hub")
+
+				continue
+			}
+		}
+
+		if r.ReplicaDeletionGracePeriod > 0 {
+			remaining, err := r.markPendingDeletion(namespace, name)
+			if err != nil {
+				log.Error(err, "Failed to mark the orphaned replicated policy as pending deletion")
+
+				deletionErrs = append(
+					deletionErrs,
+					fmt.Errorf("failed to mark the replicated policy %s/%s as pending deletion: %w", namespace, name, err),
+				)
+
+				continue
+			}
+
+			if remaining > 0 {
+				log.V(1).Info(
+					"Not deleting the orphaned replicated policy until the grace period has passed",
+					"remaining", remaining.String(),
+				)
+
+				pendingDeletion[appsv1.PlacementDecision{
+					ClusterName:      staleClusters[namespace],
+					ClusterNamespace: namespace,
+				}] = true
+
+				if requeueAfter == 0 || remaining < requeueAfter {
+					requeueAfter = remaining
+				}
+
+				continue
+			}
+		}
+
 		// not found in allDecisions, orphan, delete it
-		name := common.FullNameForPolicy(instance)
-		log := log.WithValues("name", name, "namespace", cluster.ClusterNamespace)
 		log.Info("Deleting the orphaned replicated policy")
 
 		err := r.Delete(context.TODO(), &policiesv1.Policy{
@@ -580,35 +1261,125 @@ func (r *PolicyReconciler) cleanUpOrphanedRplPolicies(
 			},
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      name,
-				Namespace: cluster.ClusterNamespace,
+				Namespace: namespace,
 			},
 		})
 		if err != nil && !k8serrors.IsNotFound(err) {
-			successful = false
-
 			log.Error(err, "Failed to delete the orphaned replicated policy")
+
+			deletionErrs = append(
+				deletionErrs, fmt.Errorf("failed to delete the replicated policy %s/%s: %w", namespace, name, err),
+			)
 		}
 	}
 
-	if !successful {
-		return errors.New("one or more orphaned replicated policies failed to be deleted")
+	return pendingDeletion, requeueAfter, errors.Join(deletionErrs...)
+}
+
+// isOtherHubReplica returns true if the replicated policy exists and was created by another hub.
+func (r *PolicyReconciler) isOtherHubReplica(namespace string, name string) (bool, error) {
+	replicatedPlc := &policiesv1.Policy{}
+
+	err := r.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, replicatedPlc)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
 	}
 
-	return nil
+	return common.IsOwnedByOtherHub(replicatedPlc), nil
+}
+
+// markPendingDeletion sets the PendingDeletionAnnotation on the replicated policy if it's not already set and returns
+// the remaining time of the ReplicaDeletionGracePeriod. A zero duration is returned when the grace period has passed or
+// the replicated policy doesn't exist. The annotation is removed by handleDecision if the cluster is selected again,
+// since it's not on the desired replicated policy.
+func (r *PolicyReconciler) markPendingDeletion(namespace string, name string) (time.Duration, error) {
+	replicatedPlc := &policiesv1.Policy{}
+
+	err := r.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, replicatedPlc)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	markedAt, err := time.Parse(time.RFC3339, replicatedPlc.GetAnnotations()[PendingDeletionAnnotation])
+	if err == nil {
+		remaining := r.ReplicaDeletionGracePeriod - time.Since(markedAt)
+		if remaining < 0 {
+			return 0, nil
+		}
+
+		return remaining, nil
+	}
+
+	patch := client.MergeFromWithOptions(replicatedPlc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+	annotations := replicatedPlc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[PendingDeletionAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	replicatedPlc.SetAnnotations(annotations)
+
+	err = r.Patch(context.TODO(), replicatedPlc, patch)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.ReplicaDeletionGracePeriod, nil
 }
 
-// handleRootPolicy will properly replicate or clean up when a root policy is updated.
-func (r *PolicyReconciler) handleRootPolicy(instance *policiesv1.Policy) error {
-	// Generate a metric for elapsed handling time for each policy
+// recordReplicaDrift sets the policy_replica_drift metric of the root policy to the absolute difference between the
+// input number of expected replicated policies and the number of replicated policies in the cache. Since the cache may
+// briefly lag behind the changes made during the reconcile, only a sustained nonzero value indicates a problem.
+func (r *PolicyReconciler) recordReplicaDrift(instance *policiesv1.Policy, expected int) {
+	replicatedPlcList := &policiesv1.PolicyList{}
+
+	err := r.List(
+		context.TODO(), replicatedPlcList, client.MatchingLabels(common.LabelsForRootPolicy(instance)),
+	)
+	if err != nil {
+		log.Error(
+			err,
+			"Failed to list the replicated policies to determine the drift",
+			"policyName", instance.GetName(),
+			"policyNamespace", instance.GetNamespace(),
+		)
+
+		return
+	}
+
+	drift := expected - len(withoutOtherHubReplicas(replicatedPlcList.Items))
+	if drift < 0 {
+		drift = -drift
+	}
+
+	replicaDriftMetric.WithLabelValues(instance.GetName(), instance.GetNamespace()).Set(float64(drift))
+}
+
+// handleRootPolicy will properly replicate or clean up when a root policy is updated. The returned duration is when the
+// root policy should be reconciled again to delete the replicated policies pending deletion.
+func (r *PolicyReconciler) handleRootPolicy(ctx context.Context, instance *policiesv1.Policy) (time.Duration, error) {
+	// Generate a metric for elapsed handling time for each policy, linked to the trace of the propagation
 	entryTS := time.Now()
 	defer func() {
 		now := time.Now()
 		elapsed := now.Sub(entryTS).Seconds()
-		roothandlerMeasure.Observe(elapsed)
+		common.ObserveWithTraceExemplar(ctx, roothandlerMeasure, elapsed)
 	}()
 
 	log := log.WithValues("policyName", instance.GetName(), "policyNamespace", instance.GetNamespace())
 
+	// Keep this generation in case a cluster is pinned to it after the root policy changes
+	recordGeneration(instance)
+
 	// Clean up the replicated policies if the policy is disabled
 	if instance.Spec.Disabled {
 		log.Info("The policy is disabled, doing clean up")
@@ -617,7 +1388,7 @@ func (r *PolicyReconciler) handleRootPolicy(instance *policiesv1.Policy) error {
 		if err != nil {
 			log.Info("One or more replicated policies could not be deleted")
 
-			return err
+			return 0, err
 		}
 
 		r.Recorder.Event(instance, "Normal", "PolicyPropagation",
@@ -633,54 +1404,158 @@ func (r *PolicyReconciler) handleRootPolicy(instance *policiesv1.Policy) error {
 	if err != nil {
 		log.Error(err, "Could not list the placement bindings")
 
-		return err
+		return 0, err
+	}
+
+	// The metrics are only cleaned up on a best effort basis, so this doesn't fail the reconcile
+	if err := r.forgetStalePlacementBindings(ctx, instance.GetNamespace(), pbList); err != nil {
+		log.Error(err, "Failed to delete the metric series of the stale placement bindings")
+	}
+
+	placements, allDecisions, failedClusters, clusterErrs, allFailed, targetErr := r.handleDecisions(
+		ctx, instance, pbList,
+	)
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("cluster.count", len(allDecisions)),
+		attribute.Int("cluster.failed.count", len(failedClusters)),
+	)
+	if targetErr != nil {
+		targetResolutionErrorMetric.WithLabelValues(targetErrorReason(targetErr)).Inc()
 	}
 
-	placements, allDecisions, failedClusters, allFailed := r.handleDecisions(instance, pbList)
 	if allFailed {
+		if targetErr != nil && !isNoTargetsError(targetErr) {
+			log.Info("Failed to get the placement decisions. Giving up on the request.", "reason", targetErr.Error())
+
+			return 0, fmt.Errorf("could not get the placement decisions: %w", targetErr)
+		}
+
 		log.Info("Failed to get any placement decisions. Giving up on the request.")
 
-		return errors.New("could not get the placement decisions")
+		return 0, errors.New("could not get the placement decisions")
+	}
+
+	if targetErr != nil {
+		log.Info("Some placement bindings don't place the policy on any cluster", "reason", targetErr.Error())
 	}
 
 	// Clean up before the status update in case the status update fails
-	err = r.cleanUpOrphanedRplPolicies(instance, allDecisions)
+	pendingDeletion, requeueAfter, err := r.cleanUpOrphanedRplPolicies(instance, allDecisions)
+
+	// Keep the replicated policies pending deletion in the status so that they are found to be orphaned again
+	for decision := range pendingDeletion {
+		allDecisions[decision] = true
+	}
+
+	r.recordReplicaDrift(instance, len(allDecisions))
+
 	if err != nil {
 		log.Error(err, "Failed to delete orphaned replicated policies")
 
-		return err
+		return 0, err
+	}
+
+	// This isn't fatal since it's retried on the next reconcile
+	err = r.deleteDuplicateReplicas(instance)
+	if err != nil {
+		log.Error(err, "Failed to delete the duplicate replicated policies")
 	}
 
 	log.V(1).Info("Updating the root policy status")
 
-	cpcs, _ := r.calculatePerClusterStatus(instance, allDecisions, failedClusters)
+	cpcs, messages, _ := r.calculatePerClusterStatus(instance, allDecisions, failedClusters)
 
 	// loop through all pb, update status.placement
 	sort.Slice(placements, func(i, j int) bool {
 		return placements[i].PlacementBinding < placements[j].PlacementBinding
 	})
 
+	r.recordDebugState(instance, placements, allDecisions, pendingDeletion, clusterErrs, targetErr)
+
+	// The decision group rollout status was computed by handleDecisions and would be lost by the refresh
+	rolloutStatus := instance.Status.DecisionGroupRollout
+
 	err = r.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}, instance)
 	if err != nil {
 		log.Error(err, "Failed to refresh the cached policy. Will use existing policy.")
 	}
 
+	existingStatus := instance.Status.DeepCopy()
+
+	instance.Status.DecisionGroupRollout = rolloutStatus
 	instance.Status.Status = cpcs
 	instance.Status.ComplianceState = CalculateRootCompliance(cpcs)
+	instance.Status.ComplianceSummary = CalculateComplianceSummary(cpcs)
 	instance.Status.Placement = placements
+	instance.Status.PropagationErrors = buildPropagationErrors(clusterErrs)
+
+	RecordComplianceHistory(
+		instance, existingStatus.Status, messages, r.ComplianceHistoryDepth, r.ComplianceHistoryLimit, time.Now(),
+	)
+
+	if equality.Semantic.DeepEqual(existingStatus, &instance.Status) {
+		log.V(1).Info("The root policy status is already up to date")
+	} else {
+		err = r.Status().Update(context.TODO(), instance, common.StatusFieldOwner(r.StatusFieldManager))
+		if err != nil {
+			return 0, err
+		}
+	}
 
-	err = r.Status().Update(context.TODO(), instance)
+	// This isn't fatal since it's retried on the next reconcile
+	err = r.updateTemplateValidationAnnotation(ctx, instance, clusterErrs)
 	if err != nil {
-		return err
+		log.Error(err, "Failed to update the template validation error annotation")
 	}
 
 	if len(failedClusters) != 0 {
-		return errors.New("failed to handle cluster namespaces:" + strings.Join(failedClusters.namespaces(), ","))
+		pendingClusters := decisionSet{}
+		quarantinedClusters := 0
+
+		for decision, err := range clusterErrs {
+			switch {
+			case errors.Is(err, errNamespaceNotFound):
+				pendingClusters[decision] = true
+			case errors.Is(err, errClusterQuarantined):
+				quarantinedClusters++
+
+				// The policy is requeued for the next probe of the cluster rather than retried with a backoff
+				probeDelay := r.ClusterQuarantine.probeDelay(decision.ClusterNamespace, time.Now())
+				if requeueAfter == 0 || probeDelay < requeueAfter {
+					requeueAfter = probeDelay
+				}
+			}
+		}
+
+		if len(pendingClusters)+quarantinedClusters != len(failedClusters) {
+			return 0, errors.New(
+				"failed to handle cluster namespaces:" + strings.Join(failedClusters.namespaces(), ","),
+			)
+		}
+
+		// Don't treat clusters being onboarded as failures so that the caller can retry with a backoff
+		if len(pendingClusters) != 0 {
+			namespaces := pendingClusters.namespaces()
+			sort.Strings(namespaces)
+
+			return requeueAfter, &namespacesPendingError{namespaces: namespaces}
+		}
+
+		log.Info("Some clusters are quarantined. Requeueing the policy for their next probe.",
+			"quarantinedCount", quarantinedClusters, "requeueAfter", requeueAfter.String())
+	}
+
+	// Proceed to the next decision group once the rollout to the current one times out
+	if remaining := decisionGroupTimeoutRemaining(instance, time.Now()); remaining > 0 {
+		if requeueAfter == 0 || remaining < requeueAfter {
+			requeueAfter = remaining
+		}
 	}
 
 	log.Info("Reconciliation complete")
 
-	return nil
+	return requeueAfter, nil
 }
 
 // getApplicationPlacements return the placements from an application
@@ -743,7 +1618,7 @@ func getApplicationPlacements(
 					break
 				}
 			}
-		} else if subject.Kind == policiesv1.Kind && subject.Name == instance.GetName() && !plcPlacementAdded {
+		} else if common.SubjectMatchesPolicy(subject, instance.GetName()) && !plcPlacementAdded {
 			placement := &policiesv1.Placement{
 				PlacementBinding: pb.GetName(),
 				PlacementRule:    plr.GetName(),
@@ -765,15 +1640,18 @@ func getClusterPlacements(
 	log := log.WithValues("name", pb.PlacementRef.Name, "namespace", instance.GetNamespace())
 	pl := &clusterv1beta1.Placement{}
 
-	err := c.Get(context.TODO(), types.NamespacedName{
-		Namespace: instance.GetNamespace(),
-		Name:      pb.PlacementRef.Name,
-	}, pl)
-	// no error when not found
-	if err != nil && !k8serrors.IsNotFound(err) {
-		log.Error(err, "Failed to get the Placement")
+	// When the Placement API is not installed, the Placement is treated as not found
+	if common.PlacementAPIAvailable() {
+		err := c.Get(context.TODO(), types.NamespacedName{
+			Namespace: instance.GetNamespace(),
+			Name:      pb.PlacementRef.Name,
+		}, pl)
+		// no error when not found
+		if err != nil && !k8serrors.IsNotFound(err) {
+			log.Error(err, "Failed to get the Placement")
 
-		return nil, err
+			return nil, err
+		}
 	}
 
 	var placements []*policiesv1.Placement
@@ -813,7 +1691,7 @@ func getClusterPlacements(
 					break
 				}
 			}
-		} else if subject.Kind == policiesv1.Kind && subject.Name == instance.GetName() && !plcPlacementAdded {
+		} else if common.SubjectMatchesPolicy(subject, instance.GetName()) && !plcPlacementAdded {
 			// add current Placement to placement, if not found no decisions will be found
 			placement := &policiesv1.Placement{
 				PlacementBinding: pb.GetName(),
@@ -828,91 +1706,339 @@ func getClusterPlacements(
 	return placements, nil
 }
 
-// getPlacementDecisions gets the PlacementDecisions for a PlacementBinding
+// getClusterSetPlacements return the placements for a PlacementBinding that references a
+// ManagedClusterSet
+func getClusterSetPlacements(
+	c client.Client, pb policiesv1.PlacementBinding, instance *policiesv1.Policy,
+) []*policiesv1.Placement {
+	return getSubjectPlacements(c, pb, instance, policiesv1.Placement{
+		PlacementBinding:  pb.GetName(),
+		ManagedClusterSet: pb.PlacementRef.Name,
+	})
+}
+
+// getClusterSelectorPlacements return the placements for a PlacementBinding that selects the clusters with its
+// clusterSelector
+func getClusterSelectorPlacements(
+	c client.Client, pb policiesv1.PlacementBinding, instance *policiesv1.Policy,
+) []*policiesv1.Placement {
+	return getSubjectPlacements(c, pb, instance, policiesv1.Placement{PlacementBinding: pb.GetName()})
+}
+
+// getSubjectPlacements returns a copy of the input placement for each subject of the PlacementBinding that binds the
+// policy, either directly or with a PolicySet, for PlacementBindings that don't reference a resource with its own
+// placement decisions.
+func getSubjectPlacements(
+	c client.Client, pb policiesv1.PlacementBinding, instance *policiesv1.Policy, base policiesv1.Placement,
+) []*policiesv1.Placement {
+	var placements []*policiesv1.Placement
+
+	plcPlacementAdded := false
+
+	for _, subject := range pb.Subjects {
+		if subject.Kind == policiesv1.PolicySetKind {
+			// retrieve policyset to see if policy is part of it
+			plcset := &policiesv1beta1.PolicySet{}
+			err := c.Get(context.TODO(), types.NamespacedName{
+				Namespace: instance.GetNamespace(),
+				Name:      subject.Name,
+			}, plcset)
+			// no error when not found
+			if err != nil && !k8serrors.IsNotFound(err) {
+				log.Error(
+					err,
+					"Failed to get the policyset",
+					"namespace", instance.GetNamespace(),
+					"name", subject.Name,
+				)
+
+				continue
+			}
+
+			for _, plcName := range plcset.Spec.Policies {
+				if plcName == policiesv1beta1.NonEmptyString(instance.Name) {
+					// found matching policy in policyset, add placement to it
+					placement := base
+					placement.PolicySet = subject.Name
+					placements = append(placements, &placement)
+
+					break
+				}
+			}
+		} else if common.SubjectMatchesPolicy(subject, instance.GetName()) && !plcPlacementAdded {
+			placement := base
+			placements = append(placements, &placement)
+			// should only add policy placement once in case placement binding subjects contains duplicated policies
+			plcPlacementAdded = true
+		}
+	}
+
+	return placements
+}
+
+// getPlacementDecisions gets the PlacementDecisions for a PlacementBinding. If the resource referenced by the
+// placementRef doesn't exist, the returned error wraps ErrPlacementNotFound and the placements are still returned.
 func getPlacementDecisions(c client.Client, pb policiesv1.PlacementBinding,
 	instance *policiesv1.Policy,
 ) ([]appsv1.PlacementDecision, []*policiesv1.Placement, error) {
-	if pb.PlacementRef.APIGroup == appsv1.SchemeGroupVersion.Group &&
+	var decisions []appsv1.PlacementDecision
+	var placements []*policiesv1.Placement
+	var placementRef client.Object
+	var err error
+
+	if common.UsesClusterSelector(&pb) {
+		// The clusters are selected directly, so there's no referenced resource that may not exist
+		decisions, err = common.GetClusterSelectorPlacementDecisions(c, pb, log)
+		if errors.Is(err, common.ErrInvalidClusterSelector) {
+			return nil, nil, fmt.Errorf("%w: %v", ErrBindingInvalid, err)
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i := range decisions {
+			decisions[i].ClusterNamespace = common.ReplicaNamespace(decisions[i].ClusterNamespace)
+		}
+
+		return decisions, getClusterSelectorPlacements(c, pb, instance), nil
+	} else if pb.PlacementRef.APIGroup == appsv1.SchemeGroupVersion.Group &&
 		pb.PlacementRef.Kind == "PlacementRule" {
-		d, err := common.GetApplicationPlacementDecisions(c, pb, instance, log)
+		decisions, err = common.GetApplicationPlacementDecisions(c, pb, instance, log)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		placement, err := getApplicationPlacements(c, pb, instance)
+		placements, err = getApplicationPlacements(c, pb, instance)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		return d, placement, nil
+		placementRef = &appsv1.PlacementRule{}
 	} else if pb.PlacementRef.APIGroup == clusterv1beta1.SchemeGroupVersion.Group &&
 		pb.PlacementRef.Kind == "Placement" {
-		d, err := common.GetClusterPlacementDecisions(c, pb, instance, log)
+		decisions, err = common.GetClusterPlacementDecisions(c, pb, instance, log)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		placements, err = getClusterPlacements(c, pb, instance)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		placement, err := getClusterPlacements(c, pb, instance)
+		// When the Placement API is not installed, the Placement is treated as not found
+		if common.PlacementAPIAvailable() {
+			placementRef = &clusterv1beta1.Placement{}
+		}
+	} else if pb.PlacementRef.APIGroup == clusterv1beta1.SchemeGroupVersion.Group &&
+		pb.PlacementRef.Kind == "ManagedClusterSet" {
+		decisions, err = common.GetClusterSetPlacementDecisions(c, pb, instance, log)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		return d, placement, nil
+		placements = getClusterSetPlacements(c, pb, instance)
+
+		// A ManagedClusterSet can only be used for placement when it's bound to the namespace. When the
+		// ManagedClusterSet API is not installed, the ManagedClusterSet is treated as not found.
+		if common.ManagedClusterSetAPIAvailable() {
+			placementRef = &clusterv1beta2.ManagedClusterSetBinding{}
+		}
+	} else {
+		return nil, nil, fmt.Errorf(
+			"%w: the placementRef of the placement binding %s/%s is not supported",
+			ErrBindingInvalid, pb.Namespace, pb.Name,
+		)
+	}
+
+	// The replicated policies may be in a namespace derived from the cluster namespace
+	for i := range decisions {
+		decisions[i].ClusterNamespace = common.ReplicaNamespace(decisions[i].ClusterNamespace)
+	}
+
+	notFoundErr := fmt.Errorf(
+		"%w: the %s %s referenced by the placement binding %s was not found",
+		ErrPlacementNotFound, pb.PlacementRef.Kind, pb.PlacementRef.Name, pb.Name,
+	)
+
+	if placementRef == nil {
+		return decisions, placements, notFoundErr
+	}
+
+	err = c.Get(
+		context.TODO(), types.NamespacedName{Namespace: instance.GetNamespace(), Name: pb.PlacementRef.Name}, placementRef,
+	)
+	if k8serrors.IsNotFound(err) {
+		return decisions, placements, notFoundErr
 	}
 
-	return nil, nil, fmt.Errorf("placement binding %s/%s reference is not valid", pb.Name, pb.Namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decisions, placements, nil
 }
 
 // handleDecision puts the policy on the cluster, creating it or updating it as required,
 // including resolving hub templates. It will return an error if an API call fails; no
 // internal states will result in errors (eg invalid templates don't cause errors here)
 func (r *PolicyReconciler) handleDecision(
-	rootPlc *policiesv1.Policy, clusterDec clusterDecision,
+	ctx context.Context, rootPlc *policiesv1.Policy, clusterDec clusterDecision,
 ) (
-	map[k8sdepwatches.ObjectIdentifier]bool, error,
+	templateRefObjs map[k8sdepwatches.ObjectIdentifier]bool, err error,
 ) {
 	decision := clusterDec.Cluster
 
+	ctx, span := tracer.Start(ctx, "replicate-policy", trace.WithAttributes(
+		attribute.String("cluster.name", decision.ClusterName),
+		attribute.String("cluster.namespace", decision.ClusterNamespace),
+	))
+
+	outcome := "unchanged"
+
+	defer func() {
+		if err != nil {
+			outcome = "error"
+		}
+
+		endSpan(span, err, attribute.String("outcome", outcome))
+	}()
+
 	log := log.WithValues(
 		"policyName", rootPlc.GetName(),
 		"policyNamespace", rootPlc.GetNamespace(),
 		"replicatePolicyName", common.FullNameForPolicy(rootPlc),
 		"replicatedPolicyNamespace", decision.ClusterNamespace,
 	)
+
+	// The render error is recorded again if the hub templates still fail to resolve
+	r.recordRenderError(rootPlc, decision, nil)
+
+	// The agent-owned fields and the fields ignored for drift detection are never compared with the existing
+	// replicated policy since they may be changed on the managed cluster
+	ignoredPaths := r.ignoredSpecPaths()
+
 	// retrieve replicated policy in cluster namespace
 	replicatedPlc := &policiesv1.Policy{}
-	templateRefObjs := map[k8sdepwatches.ObjectIdentifier]bool{}
+	templateRefObjs = map[k8sdepwatches.ObjectIdentifier]bool{}
+
+	if err := common.ValidateReplicaNamespace(decision.ClusterNamespace); err != nil {
+		log.Info("The replicated policy can't be created since its namespace is invalid", "reason", err.Error())
+
+		return templateRefObjs, err
+	}
+
+	pinnedGeneration, err := r.getPinnedGeneration(rootPlc, decision.ClusterName)
+	if err != nil {
+		log.Error(err, "Failed to determine if the cluster is pinned to a generation of the root policy")
+
+		return templateRefObjs, err
+	}
+
+	// The replicated policy is built from the pinned generation of the root policy if it's not the current one. If
+	// that generation is no longer available, specSource is nil until it falls back to the current generation, and
+	// the cluster is then handled as if it weren't pinned.
+	pinned := pinnedGeneration != 0 && pinnedGeneration != rootPlc.Generation
+	specSource := rootPlc
 
-	err := r.Get(context.TODO(), types.NamespacedName{
+	if pinned {
+		specSource = getGeneration(
+			types.NamespacedName{Namespace: rootPlc.Namespace, Name: rootPlc.Name}, pinnedGeneration,
+		)
+	}
+
+	err = r.Get(ctx, types.NamespacedName{
 		Namespace: decision.ClusterNamespace,
 		Name:      common.FullNameForPolicy(rootPlc),
 	}, replicatedPlc)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			replicatedPlc, err = r.buildReplicatedPolicy(rootPlc, clusterDec)
+			suppressed, err := r.replicaSuppressed(ctx, rootPlc, decision.ClusterName)
 			if err != nil {
+				log.Error(err, "Failed to determine if the replicated policy is suppressed on the cluster")
+
 				return templateRefObjs, err
 			}
 
+			if suppressed {
+				log.Info("The replicated policy is suppressed on the cluster, so it won't be created",
+					"annotation", SuppressedReplicasAnnotation)
+
+				outcome = "suppressed"
+
+				return templateRefObjs, nil
+			}
+
+			if specSource == nil {
+				r.recordPinnedGenerationUnavailable(rootPlc, decision, pinnedGeneration)
+
+				specSource = rootPlc
+				pinned = false
+			}
+
+			replicatedPlc, err = r.buildReplicatedPolicy(specSource, clusterDec)
+			if err != nil {
+				return templateRefObjs, err
+			}
+
+			if pinned {
+				setRootGenerationAnnotation(replicatedPlc, pinnedGeneration)
+			}
+
 			// do a quick check for any template delims in the policy before putting it through
 			// template processor
-			if policyHasTemplates(rootPlc) {
+			if policyHasTemplates(specSource) {
 				// resolve hubTemplate before replicating
 				// #nosec G104 -- any errors are logged and recorded in the processTemplates method,
 				// but the ignored status will be handled appropriately by the policy controllers on
 				// the managed cluster(s).
-				templateRefObjs, _ = r.processTemplates(replicatedPlc, decision, rootPlc)
+				var renderErr error
+
+				templateRefObjs, renderErr = r.processTemplates(ctx, replicatedPlc, decision, rootPlc)
+				r.recordRenderError(rootPlc, decision, renderErr)
+			}
+
+			if len(specSource.Spec.ClusterOverrides) != 0 {
+				// The cluster labels determine which cluster overrides apply
+				templateRefObjs[managedClusterObjID(decision.ClusterName)] = true
+			}
+
+			err = setSpecHashAnnotation(replicatedPlc, r.AgentOwnedPaths)
+			if err != nil {
+				return templateRefObjs, err
+			}
+
+			if err := r.checkTemplateKinds(rootPlc, decision, replicatedPlc); err != nil {
+				return templateRefObjs, err
+			}
+
+			if err := r.validateTemplates(ctx, rootPlc, decision, replicatedPlc); err != nil {
+				return templateRefObjs, err
+			}
+
+			if err := r.checkReplicaSize(rootPlc, decision, replicatedPlc); err != nil {
+				return templateRefObjs, err
 			}
 
 			log.Info("Creating the replicated policy")
 
-			err = r.Create(context.TODO(), replicatedPlc)
+			err = r.Create(ctx, replicatedPlc)
 			if err != nil {
+				// The cluster namespace may not exist yet when the cluster is being onboarded
+				if k8serrors.IsNotFound(err) {
+					log.Info("The cluster namespace doesn't exist yet, so the replicated policy can't be created")
+
+					return templateRefObjs, fmt.Errorf("%w: %s", errNamespaceNotFound, decision.ClusterNamespace)
+				}
+
 				log.Error(err, "Failed to create the replicated policy")
 
 				return templateRefObjs, err
 			}
 
+			outcome = "created"
+
 			r.Recorder.Event(rootPlc, "Normal", "PolicyPropagation",
 				fmt.Sprintf("Policy %s/%s was propagated to cluster %s/%s", rootPlc.GetNamespace(),
 					rootPlc.GetName(), decision.ClusterNamespace, decision.ClusterName))
@@ -927,12 +2053,61 @@ func (r *PolicyReconciler) handleDecision(
 		return templateRefObjs, err
 	}
 
+	// Never overwrite a policy that belongs to another root policy or to no root policy, or that is a replica for
+	// another cluster, such as when they map to the same replicated policy name due to a misconfiguration
+	owner, _ := common.GetRootPolicyLabel(replicatedPlc)
+	replicaCluster := replicatedPlc.Labels[common.ClusterNameLabel]
+
+	if owner != common.FullNameForPolicy(rootPlc) || (replicaCluster != "" && replicaCluster != decision.ClusterName) {
+		log.Info("A policy that isn't a replica of the root policy already has the replicated policy name",
+			"owner", owner)
+
+		r.Recorder.Event(rootPlc, "Warning", "PolicyPropagation",
+			fmt.Sprintf("Policy %s/%s can't be propagated to cluster %s/%s since the policy %s/%s already exists and "+
+				"isn't a replica of this root policy", rootPlc.GetNamespace(), rootPlc.GetName(),
+				decision.ClusterNamespace, decision.ClusterName, replicatedPlc.Namespace, replicatedPlc.Name))
+
+		return templateRefObjs, fmt.Errorf(
+			"%w: the policy %s/%s has the root policy label %q and the cluster name label %q",
+			errReplicaNameConflict, replicatedPlc.Namespace, replicatedPlc.Name, owner, replicaCluster,
+		)
+	}
+
+	// Never overwrite a replicated policy created by another hub. A replicated policy without the source hub label is
+	// adopted and labeled by the update below.
+	if common.IsOwnedByOtherHub(replicatedPlc) {
+		owner := replicatedPlc.Labels[common.SourceHubLabel]
+
+		log.Info("The replicated policy was created by another hub", "hub", owner)
+
+		r.Recorder.Event(rootPlc, "Warning", "PolicyPropagation",
+			fmt.Sprintf("Policy %s/%s can't be propagated to cluster %s/%s since the replicated policy %s/%s was "+
+				"created by the hub %s", rootPlc.GetNamespace(), rootPlc.GetName(), decision.ClusterNamespace,
+				decision.ClusterName, replicatedPlc.Namespace, replicatedPlc.Name, owner))
+
+		return templateRefObjs, fmt.Errorf(
+			"%w: the policy %s/%s was created by the hub %q",
+			errReplicaNameConflict, replicatedPlc.Namespace, replicatedPlc.Name, owner,
+		)
+	}
+
+	if specSource == nil {
+		r.recordPinnedGenerationUnavailable(rootPlc, decision, pinnedGeneration)
+
+		specSource = rootPlc
+		pinned = false
+	}
+
 	// replicated policy already created, need to compare and patch
-	desiredReplicatedPolicy, err := r.buildReplicatedPolicy(rootPlc, clusterDec)
+	desiredReplicatedPolicy, err := r.buildReplicatedPolicy(specSource, clusterDec)
 	if err != nil {
 		return templateRefObjs, err
 	}
 
+	if pinned {
+		setRootGenerationAnnotation(desiredReplicatedPolicy, pinnedGeneration)
+	}
+
 	if policyHasTemplates(desiredReplicatedPolicy) {
 		// If the replicated policy has an initialization vector specified, set it for processing
 		if initializationVector, ok := replicatedPlc.Annotations[IVAnnotation]; ok {
@@ -949,26 +2124,97 @@ func (r *PolicyReconciler) handleDecision(
 		// #nosec G104 -- any errors are logged and recorded in the processTemplates method,
 		// but the ignored status will be handled appropriately by the policy controllers on
 		// the managed cluster(s).
-		templateRefObjs, _ = r.processTemplates(desiredReplicatedPolicy, decision, rootPlc)
+		var renderErr error
+
+		templateRefObjs, renderErr = r.processTemplates(ctx, desiredReplicatedPolicy, decision, rootPlc)
+		r.recordRenderError(rootPlc, decision, renderErr)
 	}
 
-	if !equivalentReplicatedPolicies(desiredReplicatedPolicy, replicatedPlc) {
+	if len(specSource.Spec.ClusterOverrides) != 0 {
+		// The cluster labels determine which cluster overrides apply
+		templateRefObjs[managedClusterObjID(decision.ClusterName)] = true
+	}
+
+	err = setSpecHashAnnotation(desiredReplicatedPolicy, r.AgentOwnedPaths)
+	if err != nil {
+		return templateRefObjs, err
+	}
+
+	// The existing replicated policy is left as is rather than updated to a spec that has a kind that isn't allowed, is
+	// invalid, or is too large. An existing replicated policy that itself has a kind that isn't allowed, such as one
+	// written before the kind was disallowed, is deleted instead so that the managed cluster stops enforcing it.
+	if err := r.checkTemplateKinds(rootPlc, decision, desiredReplicatedPolicy); err != nil {
+		if r.replicaKindDisallowed(replicatedPlc) {
+			log.Info("Deleting the replicated policy since a policy template has a kind that isn't allowed")
+
+			if err := r.deletePolicy(replicatedPlc); err != nil {
+				return templateRefObjs, err
+			}
+		}
+
+		return templateRefObjs, err
+	}
+
+	if err := r.validateTemplates(ctx, rootPlc, decision, desiredReplicatedPolicy); err != nil {
+		return templateRefObjs, err
+	}
+
+	if err := r.checkReplicaSize(rootPlc, decision, desiredReplicatedPolicy); err != nil {
+		return templateRefObjs, err
+	}
+
+	// If the desired hash matches the one previously written, the root policy is unchanged for this cluster, so any
+	// difference in the replicated policy spec was made outside of the propagator.
+	driftDetected, err := hasSpecDrift(desiredReplicatedPolicy, replicatedPlc, ignoredPaths)
+	if err != nil {
+		return templateRefObjs, err
+	}
+
+	if driftDetected || !equivalentReplicatedPolicies(desiredReplicatedPolicy, replicatedPlc, ignoredPaths) {
 		// update needed
 		log.Info("Root policy and replicated policy mismatch, updating replicated policy")
+		err = preserveSpecPaths(desiredReplicatedPolicy, replicatedPlc, r.AgentOwnedPaths)
+		if err != nil {
+			return templateRefObjs, err
+		}
+
+		adopted := isAdoptedReplica(replicatedPlc)
+
+		// Only the fields that the propagator owns are sent so that the status, which is set by the status sync on
+		// the managed cluster, is never overwritten
+		patch := client.MergeFromWithOptions(replicatedPlc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
 		replicatedPlc.SetAnnotations(desiredReplicatedPolicy.GetAnnotations())
 		replicatedPlc.SetLabels(desiredReplicatedPolicy.GetLabels())
+		replicatedPlc.SetOwnerReferences(desiredReplicatedPolicy.GetOwnerReferences())
 		replicatedPlc.Spec = desiredReplicatedPolicy.Spec
 
-		err = r.Update(context.TODO(), replicatedPlc)
+		err = r.Patch(ctx, replicatedPlc, patch)
 		if err != nil {
 			log.Error(err, "Failed to update the replicated policy")
 
 			return templateRefObjs, err
 		}
 
-		r.Recorder.Event(rootPlc, "Normal", "PolicyPropagation",
-			fmt.Sprintf("Policy %s/%s was updated for cluster %s/%s", rootPlc.GetNamespace(),
-				rootPlc.GetName(), decision.ClusterNamespace, decision.ClusterName))
+		if adopted {
+			log.Info("Took ownership of an existing replicated policy that was missing the propagator's labels")
+
+			replicasAdoptedMetric.WithLabelValues(rootPlc.GetName(), rootPlc.GetNamespace()).Inc()
+		}
+
+		outcome = "updated"
+
+		if driftDetected {
+			log.Info("Corrected the drift in the replicated policy spec")
+
+			r.Recorder.Event(rootPlc, "Warning", "PolicyPropagation",
+				fmt.Sprintf("Policy %s/%s drift was corrected for cluster %s/%s", rootPlc.GetNamespace(),
+					rootPlc.GetName(), decision.ClusterNamespace, decision.ClusterName))
+		} else {
+			r.Recorder.Event(rootPlc, "Normal", "PolicyPropagation",
+				fmt.Sprintf("Policy %s/%s was updated for cluster %s/%s", rootPlc.GetNamespace(),
+					rootPlc.GetName(), decision.ClusterNamespace, decision.ClusterName))
+		}
 	}
 
 	return templateRefObjs, nil
@@ -985,15 +2231,18 @@ func policyHasTemplates(instance *policiesv1.Policy) bool {
 	return false
 }
 
-// Iterates through policy definitions and processes hub templates. A special annotation
-// policy.open-cluster-management.io/trigger-update is used to trigger reprocessing of the templates
-// and ensure that replicated-policies in the cluster are updated only if there is a change. This
-// annotation is deleted from the replicated policies and not propagated to the cluster namespaces.
+// Iterates through policy definitions and processes hub templates.
 func (r *PolicyReconciler) processTemplates(
-	replicatedPlc *policiesv1.Policy, decision appsv1.PlacementDecision, rootPlc *policiesv1.Policy,
+	ctx context.Context,
+	replicatedPlc *policiesv1.Policy,
+	decision appsv1.PlacementDecision,
+	rootPlc *policiesv1.Policy,
 ) (
 	map[k8sdepwatches.ObjectIdentifier]bool, error,
 ) {
+	_, span := tracer.Start(ctx, "render-templates")
+	defer span.End()
+
 	log := log.WithValues(
 		"policyName", rootPlc.GetName(),
 		"policyNamespace", rootPlc.GetNamespace(),
@@ -1018,13 +2267,6 @@ func (r *PolicyReconciler) processTemplates(
 		}
 	}
 
-	// clear the trigger-update annotation, it's only for the root policy shouldn't be in replicated
-	// policies as it will cause an unnecessary update to the managed clusters
-	if _, ok := annotations[TriggerUpdateAnnotation]; ok {
-		delete(annotations, TriggerUpdateAnnotation)
-		replicatedPlc.SetAnnotations(annotations)
-	}
-
 	templateCfg := getTemplateCfg()
 	templateCfg.LookupNamespace = rootPlc.GetNamespace()
 
@@ -1066,13 +2308,7 @@ func (r *PolicyReconciler) processTemplates(
 		}
 
 		if strings.Contains(string(policyT.ObjectDefinition.Raw), "ManagedClusterLabels") {
-			templateRefObjs[k8sdepwatches.ObjectIdentifier{
-				Group:     "cluster.open-cluster-management.io",
-				Version:   "v1",
-				Kind:      "ManagedCluster",
-				Namespace: "",
-				Name:      decision.ClusterName,
-			}] = true
+			templateRefObjs[managedClusterObjID(decision.ClusterName)] = true
 
 			managedCluster := &clusterv1.ManagedCluster{}
 
@@ -0,0 +1,156 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"errors"
+	"sort"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	// maxPropagationErrors is the maximum number of entries in the propagationErrors status of a root policy. The
+	// errors affecting the most clusters are kept.
+	maxPropagationErrors = 10
+	// maxPropagationErrorClusters is the maximum number of cluster namespaces listed in a propagationErrors entry.
+	maxPropagationErrorClusters = 20
+	// reasonNamespaceNotFound is the propagationErrors reason when the cluster namespace doesn't exist yet.
+	reasonNamespaceNotFound = "NamespaceNotFound"
+	// reasonInvalidNamespace is the propagationErrors reason when the namespace derived for the replicated policy is
+	// invalid.
+	reasonInvalidNamespace = "InvalidNamespace"
+	// reasonNameConflict is the propagationErrors reason when a policy that isn't a replica of the root policy already
+	// has the replicated policy name.
+	reasonNameConflict = "NameConflict"
+	// reasonReplicaTooLarge is the propagationErrors reason when the rendered replicated policy spec exceeds the
+	// maximum size.
+	reasonReplicaTooLarge = "ReplicaTooLarge"
+	// reasonPropagationTimeout is the propagationErrors reason when the replicated policy wasn't written before the
+	// propagation timeout of the root policy passed.
+	reasonPropagationTimeout = "PropagationTimeout"
+	// reasonClusterQuarantined is the propagationErrors reason when the replicated policy wasn't written since the
+	// cluster namespace is quarantined after consecutive failures.
+	reasonClusterQuarantined = "ClusterQuarantined"
+	// reasonInvalidTemplate is the propagationErrors reason when a policy template of the replicated policy doesn't
+	// match the schema of its CRD on the hub.
+	reasonInvalidTemplate = "InvalidTemplate"
+	// reasonKindNotAllowed is the propagationErrors reason when a policy template of the replicated policy, or an
+	// object embedded in one, has a kind that isn't in the allowed policy template kinds.
+	reasonKindNotAllowed = "KindNotAllowed"
+)
+
+// clusterErrors maps the placement decisions that couldn't be handled to the error from handling them.
+type clusterErrors map[appsv1.PlacementDecision]error
+
+// propagationErrorReason returns a machine-readable reason for the input error from replicating a policy. This is the
+// reason of the Kubernetes API error when there is one.
+func propagationErrorReason(err error) string {
+	if errors.Is(err, errNamespaceNotFound) {
+		return reasonNamespaceNotFound
+	}
+
+	if errors.Is(err, common.ErrInvalidReplicaNamespace) {
+		return reasonInvalidNamespace
+	}
+
+	if errors.Is(err, errReplicaNameConflict) {
+		return reasonNameConflict
+	}
+
+	if errors.Is(err, errReplicaTooLarge) {
+		return reasonReplicaTooLarge
+	}
+
+	if errors.Is(err, errPropagationTimeout) {
+		return reasonPropagationTimeout
+	}
+
+	if errors.Is(err, errClusterQuarantined) {
+		return reasonClusterQuarantined
+	}
+
+	if errors.Is(err, errTemplateInvalid) {
+		return reasonInvalidTemplate
+	}
+
+	if errors.Is(err, errKindNotAllowed) {
+		return reasonKindNotAllowed
+	}
+
+	if reason := k8serrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return string(reason)
+	}
+
+	return "Unknown"
+}
+
+// buildPropagationErrors converts the input errors from replicating a policy to the propagationErrors status of the
+// root policy. The clusters with the same reason and message share an entry. The entries are sorted by the number of
+// clusters, from most to least, and are limited to maxPropagationErrors. nil is returned when there are no errors so
+// that the status field is omitted.
+func buildPropagationErrors(errs clusterErrors) []policiesv1.PropagationError {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	type errorKey struct {
+		reason  string
+		message string
+	}
+
+	namespacesByError := map[errorKey][]string{}
+
+	for decision, err := range errs {
+		key := errorKey{reason: propagationErrorReason(err), message: err.Error()}
+
+		// The cluster namespace is part of this error message, which would otherwise prevent collapsing them
+		if key.reason == reasonNamespaceNotFound {
+			key.message = errNamespaceNotFound.Error()
+		}
+
+		namespacesByError[key] = append(namespacesByError[key], decision.ClusterNamespace)
+	}
+
+	propagationErrs := make([]policiesv1.PropagationError, 0, len(namespacesByError))
+
+	for key, namespaces := range namespacesByError {
+		sort.Strings(namespaces)
+
+		propagationErr := policiesv1.PropagationError{
+			Reason:            key.reason,
+			Message:           key.message,
+			ClusterNamespaces: namespaces,
+			ClusterCount:      len(namespaces),
+		}
+
+		if len(namespaces) > maxPropagationErrorClusters {
+			propagationErr.ClusterNamespaces = namespaces[:maxPropagationErrorClusters]
+		}
+
+		propagationErrs = append(propagationErrs, propagationErr)
+	}
+
+	sort.Slice(propagationErrs, func(i, j int) bool {
+		if propagationErrs[i].ClusterCount != propagationErrs[j].ClusterCount {
+			return propagationErrs[i].ClusterCount > propagationErrs[j].ClusterCount
+		}
+
+		if propagationErrs[i].Reason != propagationErrs[j].Reason {
+			return propagationErrs[i].Reason < propagationErrs[j].Reason
+		}
+
+		return propagationErrs[i].Message < propagationErrs[j].Message
+	})
+
+	if len(propagationErrs) > maxPropagationErrors {
+		propagationErrs = propagationErrs[:maxPropagationErrors]
+	}
+
+	return propagationErrs
+}
@@ -0,0 +1,95 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+)
+
+func TestBuildPropagationErrors(t *testing.T) {
+	if propagationErrs := buildPropagationErrors(clusterErrors{}); propagationErrs != nil {
+		t.Fatalf("expected no propagation errors, got %v", propagationErrs)
+	}
+
+	decision := func(namespace string) appsv1.PlacementDecision {
+		return appsv1.PlacementDecision{ClusterName: namespace, ClusterNamespace: namespace}
+	}
+
+	forbidden := k8serrors.NewForbidden(
+		schema.GroupResource{Group: "policy.open-cluster-management.io", Resource: "policies"},
+		"policies.my-policy",
+		errors.New("not allowed"),
+	)
+
+	errs := clusterErrors{
+		decision("cluster3"): forbidden,
+		decision("cluster1"): forbidden,
+		decision("cluster2"): fmt.Errorf("%w: %s", errNamespaceNotFound, "cluster2"),
+		decision("cluster4"): fmt.Errorf("%w: %s", errNamespaceNotFound, "cluster4"),
+		decision("cluster5"): fmt.Errorf("%w: %s", errNamespaceNotFound, "cluster5"),
+		decision("cluster6"): errors.New("something went wrong"),
+	}
+
+	propagationErrs := buildPropagationErrors(errs)
+	if len(propagationErrs) != 3 {
+		t.Fatalf("expected an entry per distinct error, got %v", propagationErrs)
+	}
+
+	expectedReasons := []string{reasonNamespaceNotFound, "Forbidden", "Unknown"}
+	expectedCounts := []int{3, 2, 1}
+
+	for i, propagationErr := range propagationErrs {
+		if propagationErr.Reason != expectedReasons[i] || propagationErr.ClusterCount != expectedCounts[i] {
+			t.Fatalf(
+				"expected the entry %d to have the reason %s for %d clusters, got %v",
+				i, expectedReasons[i], expectedCounts[i], propagationErr,
+			)
+		}
+	}
+
+	if namespaces := propagationErrs[1].ClusterNamespaces; namespaces[0] != "cluster1" || namespaces[1] != "cluster3" {
+		t.Fatalf("expected the cluster namespaces to be sorted, got %v", namespaces)
+	}
+
+	if propagationErrs[0].Message != errNamespaceNotFound.Error() {
+		t.Fatalf("expected the cluster namespace to be omitted from the message, got %s", propagationErrs[0].Message)
+	}
+}
+
+func TestBuildPropagationErrorsLimits(t *testing.T) {
+	errs := clusterErrors{}
+
+	// Many clusters failing with the same error
+	for i := 0; i < maxPropagationErrorClusters+5; i++ {
+		namespace := fmt.Sprintf("managed%02d", i)
+		errs[appsv1.PlacementDecision{ClusterName: namespace, ClusterNamespace: namespace}] = errors.New("quota")
+	}
+
+	// Many distinct errors
+	for i := 0; i < maxPropagationErrors+5; i++ {
+		namespace := fmt.Sprintf("other%02d", i)
+		errs[appsv1.PlacementDecision{ClusterName: namespace, ClusterNamespace: namespace}] = fmt.Errorf("error %d", i)
+	}
+
+	propagationErrs := buildPropagationErrors(errs)
+	if len(propagationErrs) != maxPropagationErrors {
+		t.Fatalf("expected %d entries, got %d", maxPropagationErrors, len(propagationErrs))
+	}
+
+	if propagationErrs[0].Message != "quota" || propagationErrs[0].ClusterCount != maxPropagationErrorClusters+5 {
+		t.Fatalf("expected the error affecting the most clusters to be first, got %v", propagationErrs[0])
+	}
+
+	if len(propagationErrs[0].ClusterNamespaces) != maxPropagationErrorClusters {
+		t.Fatalf(
+			"expected %d cluster namespaces, got %d",
+			maxPropagationErrorClusters, len(propagationErrs[0].ClusterNamespaces),
+		)
+	}
+}
@@ -3,22 +3,37 @@
 package propagator
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
 func TestInitializeConcurrencyPerPolicyEnvName(t *testing.T) {
@@ -65,7 +80,7 @@ type MockPolicyReconciler struct {
 }
 
 func (r MockPolicyReconciler) handleDecision(
-	_ *policiesv1.Policy, _ clusterDecision,
+	_ context.Context, _ *policiesv1.Policy, _ clusterDecision,
 ) (
 	map[k8sdepwatches.ObjectIdentifier]bool, error,
 ) {
@@ -127,7 +142,7 @@ func TestHandleDecisionWrapper(t *testing.T) {
 			close(decisionsChan)
 		}()
 
-		handleDecisionWrapper(reconciler, &policy, decisionsChan, resultsChan)
+		handleDecisionWrapper(context.TODO(), reconciler, &policy, decisionsChan, resultsChan, time.Time{}, nil)
 
 		// Expect a 1x1 mapping of results to decisions.
 		if len(resultsChan) != len(clusterDecisions) {
@@ -161,6 +176,115 @@ func TestHandleDecisionWrapper(t *testing.T) {
 	}
 }
 
+// slowClusterHandler handles the decisions of all clusters but slowCluster immediately. The decision of slowCluster
+// hangs until its context is done.
+type slowClusterHandler struct {
+	slowCluster string
+}
+
+func (h slowClusterHandler) handleDecision(
+	ctx context.Context, _ *policiesv1.Policy, decision clusterDecision,
+) (
+	map[k8sdepwatches.ObjectIdentifier]bool, error,
+) {
+	if decision.Cluster.ClusterName == h.slowCluster {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	return map[k8sdepwatches.ObjectIdentifier]bool{}, nil
+}
+
+func TestHandleDecisionWrapperTimeout(t *testing.T) {
+	policy := fakeRootPolicy("my-policy", "policies")
+	decisions := fakePlacementDecisions(3)
+	decisionsChan := make(chan clusterDecision, len(decisions))
+	resultsChan := make(chan decisionResult, len(decisions))
+
+	// The slow cluster is handled first so that the decisions after it are also handled after the deadline
+	for _, decision := range []appsv1.PlacementDecision{decisions[1], decisions[0], decisions[2]} {
+		decisionsChan <- clusterDecision{Cluster: decision}
+	}
+
+	close(decisionsChan)
+
+	handleDecisionWrapper(
+		context.TODO(),
+		slowClusterHandler{slowCluster: "cluster2"},
+		&policy,
+		decisionsChan,
+		resultsChan,
+		time.Now().Add(100*time.Millisecond),
+		nil,
+	)
+
+	close(resultsChan)
+
+	clusterErrs := clusterErrors{}
+
+	for result := range resultsChan {
+		if result.Err != nil {
+			clusterErrs[result.Identifier] = result.Err
+		}
+	}
+
+	if len(clusterErrs) != 3 {
+		t.Fatalf("Expected all the clusters to time out with a single worker, got %v", clusterErrs)
+	}
+
+	propagationErrs := buildPropagationErrors(clusterErrs)
+	if len(propagationErrs) != 1 || propagationErrs[0].Reason != reasonPropagationTimeout {
+		t.Fatalf("Expected the timed out clusters to share a PropagationTimeout entry, got %+v", propagationErrs)
+	}
+
+	assert.ElementsMatch(t, []string{"cluster1", "cluster2", "cluster3"}, propagationErrs[0].ClusterNamespaces)
+
+	// With a worker per cluster, only the slow cluster times out
+	decisionsChan = make(chan clusterDecision, len(decisions))
+	resultsChan = make(chan decisionResult, len(decisions))
+	deadline := time.Now().Add(100 * time.Millisecond)
+
+	for _, decision := range decisions {
+		decisionsChan <- clusterDecision{Cluster: decision}
+	}
+
+	close(decisionsChan)
+
+	wg := sync.WaitGroup{}
+
+	for range decisions {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			handleDecisionWrapper(
+				context.TODO(),
+				slowClusterHandler{slowCluster: "cluster2"},
+				&policy,
+				decisionsChan,
+				resultsChan,
+				deadline,
+				nil,
+			)
+		}()
+	}
+
+	wg.Wait()
+	close(resultsChan)
+
+	for result := range resultsChan {
+		if result.Identifier.ClusterName == "cluster2" {
+			if !errors.Is(result.Err, errPropagationTimeout) {
+				t.Fatalf("Expected the slow cluster to time out, got %v", result.Err)
+			}
+		} else if result.Err != nil {
+			t.Fatalf("Expected %s to be replicated to, got %v", result.Identifier.ClusterName, result.Err)
+		}
+	}
+}
+
 func (r MockPolicyReconciler) deletePolicy(
 	_ *policiesv1.Policy,
 ) error {
@@ -382,8 +506,8 @@ func TestGetAllClusterDecisions(t *testing.T) {
 				}
 			},
 			expectedPlacements: []*policiesv1.Placement{
-				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name},
-				{PlacementBinding: pbSub.Name, PlacementRule: prSub.Name},
+				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name, Decisions: prInitial.Status.Decisions},
+				{PlacementBinding: pbSub.Name, PlacementRule: prSub.Name, Decisions: prSub.Status.Decisions},
 			},
 			expectedClusterDecisions: []clusterDecision{
 				{Cluster: clusters[0], PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: ""}},
@@ -406,8 +530,8 @@ func TestGetAllClusterDecisions(t *testing.T) {
 				}
 			},
 			expectedPlacements: []*policiesv1.Placement{
-				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name},
-				{PlacementBinding: pbSub.Name, PlacementRule: prSub.Name},
+				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name, Decisions: prInitial.Status.Decisions},
+				{PlacementBinding: pbSub.Name, PlacementRule: prSub.Name, Decisions: prSub.Status.Decisions},
 			},
 			expectedClusterDecisions: []clusterDecision{
 				{Cluster: clusters[0], PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: "enforce"}},
@@ -429,8 +553,8 @@ func TestGetAllClusterDecisions(t *testing.T) {
 				}
 			},
 			expectedPlacements: []*policiesv1.Placement{
-				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name},
-				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name},
+				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name, Decisions: prInitial.Status.Decisions},
+				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name, Decisions: prExtended.Status.Decisions},
 			},
 			expectedClusterDecisions: []clusterDecision{
 				{Cluster: clusters[0], PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: "enforce"}},
@@ -455,8 +579,8 @@ func TestGetAllClusterDecisions(t *testing.T) {
 				}
 			},
 			expectedPlacements: []*policiesv1.Placement{
-				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name},
-				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name},
+				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name, Decisions: prInitial.Status.Decisions},
+				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name, Decisions: prExtended.Status.Decisions},
 			},
 			expectedClusterDecisions: []clusterDecision{
 				{Cluster: clusters[0], PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: "enforce"}},
@@ -479,7 +603,7 @@ func TestGetAllClusterDecisions(t *testing.T) {
 				}
 			},
 			expectedPlacements: []*policiesv1.Placement{
-				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name},
+				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name, Decisions: prInitial.Status.Decisions},
 			},
 			expectedClusterDecisions: []clusterDecision{
 				{Cluster: clusters[0], PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: ""}},
@@ -502,9 +626,9 @@ func TestGetAllClusterDecisions(t *testing.T) {
 				}
 			},
 			expectedPlacements: []*policiesv1.Placement{
-				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name},
-				{PlacementBinding: pbSub2.Name, PlacementRule: prSub2.Name},
-				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name},
+				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name, Decisions: prInitial.Status.Decisions},
+				{PlacementBinding: pbSub2.Name, PlacementRule: prSub2.Name, Decisions: prSub2.Status.Decisions},
+				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name, Decisions: prExtended.Status.Decisions},
 			},
 			expectedClusterDecisions: []clusterDecision{
 				{Cluster: clusters[0], PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: "enforce"}},
@@ -529,9 +653,9 @@ func TestGetAllClusterDecisions(t *testing.T) {
 				}
 			},
 			expectedPlacements: []*policiesv1.Placement{
-				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name},
-				{PlacementBinding: pbSub.Name, PlacementRule: prSub.Name},
-				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name},
+				{PlacementBinding: pbInitial.Name, PlacementRule: prInitial.Name, Decisions: prInitial.Status.Decisions},
+				{PlacementBinding: pbSub.Name, PlacementRule: prSub.Name, Decisions: prSub.Status.Decisions},
+				{PlacementBinding: pbExtended.Name, PlacementRule: prExtended.Name, Decisions: prExtended.Status.Decisions},
 			},
 			expectedClusterDecisions: []clusterDecision{
 				{Cluster: clusters[0], PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: "enforce"}},
@@ -559,3 +683,2052 @@ func TestGetAllClusterDecisions(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAllClusterDecisionsClusterSets(t *testing.T) {
+	testPolicy := fakeRootPolicy("test-policy", "default")
+	clusters := fakePlacementDecisions(3)
+
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, clusterv1.AddToScheme, clusterv1beta2.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	// cluster1 is only in set-a, cluster2 is in set-a and set-b, and cluster3 is only in set-b
+	managedClusters := []clusterv1.ManagedCluster{
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   clusters[0].ClusterName,
+			Labels: map[string]string{clusterv1beta2.ClusterSetLabel: "set-a"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   clusters[1].ClusterName,
+			Labels: map[string]string{clusterv1beta2.ClusterSetLabel: "set-a", "env": "prod"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   clusters[2].ClusterName,
+			Labels: map[string]string{"env": "prod"},
+		}},
+	}
+
+	setA := clusterv1beta2.ManagedClusterSet{ObjectMeta: metav1.ObjectMeta{Name: "set-a"}}
+	setB := clusterv1beta2.ManagedClusterSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "set-b"},
+		Spec: clusterv1beta2.ManagedClusterSetSpec{
+			ClusterSelector: clusterv1beta2.ManagedClusterSelector{
+				SelectorType: clusterv1beta2.LabelSelector,
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"env": "prod"},
+				},
+			},
+		},
+	}
+	setEmpty := clusterv1beta2.ManagedClusterSet{ObjectMeta: metav1.ObjectMeta{Name: "set-empty"}}
+	// set-unbound has members but is not bound to the policy namespace
+	setUnbound := clusterv1beta2.ManagedClusterSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "set-unbound"},
+		Spec: clusterv1beta2.ManagedClusterSetSpec{
+			ClusterSelector: clusterv1beta2.ManagedClusterSelector{
+				SelectorType:  clusterv1beta2.LabelSelector,
+				LabelSelector: &metav1.LabelSelector{},
+			},
+		},
+	}
+
+	objects := []client.Object{&setA, &setB, &setEmpty, &setUnbound}
+
+	for i := range managedClusters {
+		objects = append(objects, &managedClusters[i])
+	}
+
+	for _, set := range []string{setA.Name, setB.Name, setEmpty.Name} {
+		objects = append(objects, &clusterv1beta2.ManagedClusterSetBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: set, Namespace: testPolicy.Namespace},
+			Spec:       clusterv1beta2.ManagedClusterSetBindingSpec{ClusterSet: set},
+		})
+	}
+
+	reconciler := &PolicyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build(),
+	}
+
+	subjects := []policiesv1.Subject{
+		{
+			APIGroup: policiesv1.SchemeGroupVersion.Group,
+			Kind:     policiesv1.Kind,
+			Name:     testPolicy.Name,
+		},
+	}
+
+	pbForSet := func(set string) policiesv1.PlacementBinding {
+		return fakePlacementBinding("pb-"+set, "default", policiesv1.PlacementSubject{
+			APIGroup: clusterv1beta2.GroupName,
+			Kind:     "ManagedClusterSet",
+			Name:     set,
+		}, subjects)
+	}
+
+	tests := map[string]struct {
+		pbList                   policiesv1.PlacementBindingList
+		expectedPlacements       []*policiesv1.Placement
+		expectedClusterDecisions []clusterDecision
+		expectedErr              error
+	}{
+		"Set with no members": {
+			pbList: policiesv1.PlacementBindingList{
+				Items: []policiesv1.PlacementBinding{pbForSet(setEmpty.Name)},
+			},
+			expectedPlacements: []*policiesv1.Placement{
+				{PlacementBinding: "pb-set-empty", ManagedClusterSet: setEmpty.Name},
+			},
+			expectedClusterDecisions: []clusterDecision{},
+			expectedErr:              ErrNoDecisions,
+		},
+		"Set not bound to the namespace": {
+			pbList: policiesv1.PlacementBindingList{
+				Items: []policiesv1.PlacementBinding{pbForSet(setUnbound.Name)},
+			},
+			expectedPlacements: []*policiesv1.Placement{
+				{PlacementBinding: "pb-set-unbound", ManagedClusterSet: setUnbound.Name},
+			},
+			expectedClusterDecisions: []clusterDecision{},
+			expectedErr:              ErrPlacementNotFound,
+		},
+		"Cluster in multiple bound sets": {
+			pbList: policiesv1.PlacementBindingList{
+				Items: []policiesv1.PlacementBinding{pbForSet(setA.Name), pbForSet(setB.Name)},
+			},
+			expectedPlacements: []*policiesv1.Placement{
+				{
+					PlacementBinding:  "pb-set-a",
+					ManagedClusterSet: setA.Name,
+					Decisions:         []appsv1.PlacementDecision{clusters[0], clusters[1]},
+				},
+				{
+					PlacementBinding:  "pb-set-b",
+					ManagedClusterSet: setB.Name,
+					Decisions:         []appsv1.PlacementDecision{clusters[1], clusters[2]},
+				},
+			},
+			expectedClusterDecisions: []clusterDecision{
+				{Cluster: clusters[0]},
+				{Cluster: clusters[1]},
+				{Cluster: clusters[2]},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			actualAllClusterDecisions, actualPlacements, err := reconciler.getAllClusterDecisions(
+				&testPolicy, &test.pbList)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected the error %v, got %v", test.expectedErr, err)
+			}
+
+			assert.ElementsMatch(t, actualAllClusterDecisions, test.expectedClusterDecisions)
+			assert.ElementsMatch(t, actualPlacements, test.expectedPlacements)
+		})
+	}
+}
+
+func TestGetAllClusterDecisionsBindingClusterSelector(t *testing.T) {
+	testPolicy := fakeRootPolicy("test-policy", "default")
+	clusters := fakePlacementDecisions(3)
+
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	objects := []client.Object{
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+			Name: clusters[0].ClusterName, Labels: map[string]string{"env": "prod"},
+		}},
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+			Name: clusters[1].ClusterName, Labels: map[string]string{"env": "prod"},
+		}},
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+			Name: clusters[2].ClusterName, Labels: map[string]string{"env": "dev"},
+		}},
+	}
+
+	reconciler := &PolicyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build(),
+	}
+
+	subjects := []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: testPolicy.Name},
+	}
+
+	pbForSelector := func(selector *metav1.LabelSelector) policiesv1.PlacementBindingList {
+		pb := fakePlacementBinding("pb-selector", "default", policiesv1.PlacementSubject{}, subjects)
+		pb.ClusterSelector = selector
+
+		return policiesv1.PlacementBindingList{Items: []policiesv1.PlacementBinding{pb}}
+	}
+
+	tests := map[string]struct {
+		enabled                  bool
+		pbList                   policiesv1.PlacementBindingList
+		expectedErr              error
+		expectedPlacements       []*policiesv1.Placement
+		expectedClusterDecisions []clusterDecision
+	}{
+		"Matching clusters": {
+			enabled: true,
+			pbList:  pbForSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}),
+			expectedPlacements: []*policiesv1.Placement{
+				{
+					PlacementBinding: "pb-selector",
+					Decisions:        []appsv1.PlacementDecision{clusters[0], clusters[1]},
+				},
+			},
+			expectedClusterDecisions: []clusterDecision{{Cluster: clusters[0]}, {Cluster: clusters[1]}},
+		},
+		"Empty selector matches all the clusters": {
+			enabled: true,
+			pbList:  pbForSelector(&metav1.LabelSelector{}),
+			expectedPlacements: []*policiesv1.Placement{
+				{PlacementBinding: "pb-selector", Decisions: clusters},
+			},
+			expectedClusterDecisions: []clusterDecision{
+				{Cluster: clusters[0]}, {Cluster: clusters[1]}, {Cluster: clusters[2]},
+			},
+		},
+		"No matching clusters": {
+			enabled:                  true,
+			pbList:                   pbForSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"env": "qa"}}),
+			expectedErr:              ErrNoDecisions,
+			expectedPlacements:       []*policiesv1.Placement{{PlacementBinding: "pb-selector"}},
+			expectedClusterDecisions: []clusterDecision{},
+		},
+		"Invalid selector": {
+			enabled: true,
+			pbList: pbForSelector(&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: "Matches"},
+			}}),
+			expectedErr: ErrBindingInvalid,
+		},
+		"Feature disabled": {
+			enabled:     false,
+			pbList:      pbForSelector(&metav1.LabelSelector{}),
+			expectedErr: ErrBindingInvalid,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			common.SetBindingClusterSelectorEnabled(test.enabled)
+			defer common.SetBindingClusterSelectorEnabled(false)
+
+			actualAllClusterDecisions, actualPlacements, err := reconciler.getAllClusterDecisions(
+				&testPolicy, &test.pbList)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected the error %v, got %v", test.expectedErr, err)
+			}
+
+			assert.ElementsMatch(t, actualAllClusterDecisions, test.expectedClusterDecisions)
+			assert.ElementsMatch(t, actualPlacements, test.expectedPlacements)
+		})
+	}
+}
+
+func TestOrphanGracePeriodRemaining(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	rootPolicy := types.NamespacedName{Namespace: "default", Name: "test-policy"}
+
+	fakeReplicatedPolicy := func(namespace string, age time.Duration) *policiesv1.Policy {
+		return &policiesv1.Policy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "default.test-policy",
+				Namespace:         namespace,
+				Labels:            map[string]string{common.RootPolicyLabel: "default.test-policy"},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		replicatedPolicies []client.Object
+		expectRequeue      bool
+	}{
+		"No replicated policies": {
+			replicatedPolicies: []client.Object{},
+			expectRequeue:      false,
+		},
+		"Old replicated policies": {
+			replicatedPolicies: []client.Object{
+				fakeReplicatedPolicy("cluster1", time.Hour), fakeReplicatedPolicy("cluster2", 2*time.Hour),
+			},
+			expectRequeue: false,
+		},
+		"Recently created replicated policy": {
+			replicatedPolicies: []client.Object{
+				fakeReplicatedPolicy("cluster1", time.Hour), fakeReplicatedPolicy("cluster2", time.Second),
+			},
+			expectRequeue: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			reconciler := &PolicyReconciler{
+				Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(test.replicatedPolicies...).Build(),
+			}
+
+			remaining, err := reconciler.orphanGracePeriodRemaining(rootPolicy)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if test.expectRequeue && (remaining <= 0 || remaining > orphanedReplicaGracePeriod) {
+				t.Fatalf("Expected a requeue within the grace period, got %s", remaining)
+			}
+
+			if !test.expectRequeue && remaining > 0 {
+				t.Fatalf("Expected no requeue, got %s", remaining)
+			}
+		})
+	}
+}
+
+// deleteRecordingClient records the namespaces of the deleted objects and fails the deletions in failNamespace.
+type deleteRecordingClient struct {
+	client.Client
+	failNamespace string
+	deleted       []string
+}
+
+func (c *deleteRecordingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.deleted = append(c.deleted, obj.GetNamespace())
+
+	if obj.GetNamespace() == c.failNamespace {
+		return errors.New("injected deletion failure")
+	}
+
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestCleanUpOrphanedRplPolicies(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	rootPolicy := fakeRootPolicy("test-policy", "default")
+	clusters := fakePlacementDecisions(5)
+	objects := []client.Object{}
+
+	// Add the status in reverse order to verify the deletions are sorted
+	for i := len(clusters) - 1; i >= 0; i-- {
+		rootPolicy.Status.Status = append(rootPolicy.Status.Status, &policiesv1.CompliancePerClusterStatus{
+			ClusterName:      clusters[i].ClusterName,
+			ClusterNamespace: clusters[i].ClusterNamespace,
+		})
+
+		replicatedPolicy := fakeRootPolicy(common.FullNameForPolicy(&rootPolicy), clusters[i].ClusterNamespace)
+		objects = append(objects, &replicatedPolicy)
+	}
+
+	recordingClient := &deleteRecordingClient{
+		Client:        fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build(),
+		failNamespace: "cluster3",
+	}
+	reconciler := &PolicyReconciler{Client: recordingClient}
+
+	// Only cluster1 remains placed
+	_, _, err := reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, decisionSet{clusters[0]: true})
+	if err == nil {
+		t.Fatal("Expected an error for the failed deletion")
+	}
+
+	assert.Contains(t, err.Error(), "cluster3")
+	assert.Equal(t, []string{"cluster2", "cluster3", "cluster4", "cluster5"}, recordingClient.deleted)
+
+	for _, cluster := range clusters {
+		err := recordingClient.Get(
+			context.TODO(),
+			types.NamespacedName{Namespace: cluster.ClusterNamespace, Name: common.FullNameForPolicy(&rootPolicy)},
+			&policiesv1.Policy{},
+		)
+
+		shouldExist := cluster.ClusterNamespace == "cluster1" || cluster.ClusterNamespace == "cluster3"
+		if shouldExist && err != nil {
+			t.Fatalf("Expected the replicated policy in %s to exist: %v", cluster.ClusterNamespace, err)
+		}
+
+		if !shouldExist && !k8serrors.IsNotFound(err) {
+			t.Fatalf("Expected the replicated policy in %s to be deleted, got: %v", cluster.ClusterNamespace, err)
+		}
+	}
+
+	// The retry deletes the remaining replicated policy and ignores the ones that were already deleted
+	recordingClient.failNamespace = ""
+	recordingClient.deleted = nil
+
+	_, _, err = reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, decisionSet{clusters[0]: true})
+	if err != nil {
+		t.Fatalf("Unexpected error on the retry: %v", err)
+	}
+}
+
+func TestCleanUpOrphanedRplPoliciesGracePeriod(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	rootPolicy := fakeRootPolicy("test-policy", "default")
+	clusters := fakePlacementDecisions(2)
+	objects := []client.Object{}
+
+	for _, cluster := range clusters {
+		rootPolicy.Status.Status = append(rootPolicy.Status.Status, &policiesv1.CompliancePerClusterStatus{
+			ClusterName:      cluster.ClusterName,
+			ClusterNamespace: cluster.ClusterNamespace,
+		})
+
+		replicatedPolicy := fakeRootPolicy(common.FullNameForPolicy(&rootPolicy), cluster.ClusterNamespace)
+		objects = append(objects, &replicatedPolicy)
+	}
+
+	recordingClient := &deleteRecordingClient{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build(),
+	}
+	reconciler := &PolicyReconciler{Client: recordingClient, ReplicaDeletionGracePeriod: time.Hour}
+	cluster2Policy := types.NamespacedName{Namespace: "cluster2", Name: common.FullNameForPolicy(&rootPolicy)}
+
+	// The placement flaps to only cluster1, so the cluster2 replicated policy is marked instead of deleted
+	pending, requeueAfter, err := reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, decisionSet{clusters[0]: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assert.Equal(t, decisionSet{clusters[1]: true}, pending)
+	assert.Equal(t, time.Hour, requeueAfter)
+	assert.Empty(t, recordingClient.deleted)
+
+	replicatedPolicy := &policiesv1.Policy{}
+	if err := recordingClient.Get(context.TODO(), cluster2Policy, replicatedPolicy); err != nil {
+		t.Fatalf("Expected the replicated policy in cluster2 to exist: %v", err)
+	}
+
+	if _, ok := replicatedPolicy.Annotations[PendingDeletionAnnotation]; !ok {
+		t.Fatalf("Expected the replicated policy in cluster2 to have the %s annotation", PendingDeletionAnnotation)
+	}
+
+	// The mark isn't reset by later reconciles within the grace period
+	_, requeueAfter, err = reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, decisionSet{clusters[0]: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if requeueAfter <= 0 || requeueAfter > time.Hour {
+		t.Fatalf("Expected the remaining grace period to be within an hour, got %v", requeueAfter)
+	}
+
+	// Once the grace period has passed, the replicated policy is deleted
+	replicatedPolicy.Annotations[PendingDeletionAnnotation] = time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	if err := recordingClient.Update(context.TODO(), replicatedPolicy); err != nil {
+		t.Fatalf("Failed to update the replicated policy: %v", err)
+	}
+
+	pending, requeueAfter, err = reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, decisionSet{clusters[0]: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assert.Empty(t, pending)
+	assert.Zero(t, requeueAfter)
+	assert.Equal(t, []string{"cluster2"}, recordingClient.deleted)
+}
+
+func TestApplyRolloutStrategy(t *testing.T) {
+	clusters := fakePlacementDecisions(5)
+
+	// Provide the decisions out of order to verify the selection is by cluster name
+	decisions := []clusterDecision{
+		{Cluster: clusters[3]}, {Cluster: clusters[0]}, {Cluster: clusters[4]}, {Cluster: clusters[2]},
+		{Cluster: clusters[1]},
+	}
+
+	tests := map[string]struct {
+		rolloutStrategy   *policiesv1.RolloutStrategy
+		propagatedIndexes []int
+		expectedIndexes   []int
+	}{
+		"No rollout strategy":  {nil, nil, []int{0, 1, 2, 3, 4}},
+		"Limit to two":         {&policiesv1.RolloutStrategy{MaxClusters: 2}, nil, []int{0, 1}},
+		"Limit of zero":        {&policiesv1.RolloutStrategy{MaxClusters: 0}, nil, []int{}},
+		"Limit exceeds placed": {&policiesv1.RolloutStrategy{MaxClusters: 10}, nil, []int{0, 1, 2, 3, 4}},
+		"Raised limit keeps propagated clusters": {
+			&policiesv1.RolloutStrategy{MaxClusters: 3}, []int{1, 3}, []int{0, 1, 3},
+		},
+		"Lowered limit keeps propagated clusters": {
+			&policiesv1.RolloutStrategy{MaxClusters: 1}, []int{1, 3}, []int{1, 3},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			policy := fakeRootPolicy("test-policy", "default")
+			policy.Spec.RolloutStrategy = test.rolloutStrategy
+
+			for _, i := range test.propagatedIndexes {
+				policy.Status.Status = append(policy.Status.Status, &policiesv1.CompliancePerClusterStatus{
+					ClusterName:      clusters[i].ClusterName,
+					ClusterNamespace: clusters[i].ClusterNamespace,
+				})
+			}
+
+			expected := make([]clusterDecision, 0, len(test.expectedIndexes))
+			for _, i := range test.expectedIndexes {
+				expected = append(expected, clusterDecision{Cluster: clusters[i]})
+			}
+
+			assert.ElementsMatch(t, expected, applyRolloutStrategy(&policy, decisions))
+		})
+	}
+}
+
+func TestRecordReplicaDrift(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	rootPolicy := fakeRootPolicy("drift-policy", "default")
+	objects := []client.Object{}
+
+	for _, cluster := range fakePlacementDecisions(2) {
+		replicatedPolicy := fakeRootPolicy(common.FullNameForPolicy(&rootPolicy), cluster.ClusterNamespace)
+		replicatedPolicy.SetLabels(common.LabelsForRootPolicy(&rootPolicy))
+		objects = append(objects, &replicatedPolicy)
+	}
+
+	reconciler := &PolicyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build(),
+	}
+
+	for expected, expectedDrift := range map[int]float64{0: 2, 2: 0, 5: 3} {
+		reconciler.recordReplicaDrift(&rootPolicy, expected)
+
+		drift := testutil.ToFloat64(replicaDriftMetric.WithLabelValues(rootPolicy.Name, rootPolicy.Namespace))
+		if drift != expectedDrift {
+			t.Fatalf("Expected a drift of %v with %d expected replicas, got %v", expectedDrift, expected, drift)
+		}
+	}
+
+	replicaDriftMetric.DeleteLabelValues(rootPolicy.Name, rootPolicy.Namespace)
+}
+
+// namespaceLagClient fails the creation of objects in namespaces that don't exist yet with a NotFound error, like the
+// API server does.
+type namespaceLagClient struct {
+	client.Client
+	existingNamespaces map[string]bool
+}
+
+func (c *namespaceLagClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if !c.existingNamespaces[obj.GetNamespace()] {
+		return k8serrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, obj.GetNamespace())
+	}
+
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestHandleDecisionNamespaceLag(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("onboarding-policy", "default")
+	lagClient := &namespaceLagClient{
+		Client:             fake.NewClientBuilder().WithScheme(testscheme).Build(),
+		existingNamespaces: map[string]bool{},
+	}
+	reconciler := &PolicyReconciler{Client: lagClient, Recorder: record.NewFakeRecorder(10)}
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+
+	_, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision)
+	if !errors.Is(err, errNamespaceNotFound) {
+		t.Fatalf("Expected the error to be errNamespaceNotFound, got %v", err)
+	}
+
+	// The cluster namespace is created after the delay
+	lagClient.existingNamespaces["managed1"] = true
+
+	_, err = reconciler.handleDecision(context.TODO(), &rootPolicy, decision)
+	if err != nil {
+		t.Fatalf("Unexpected error after the namespace was created: %v", err)
+	}
+
+	replicatedPolicy := &policiesv1.Policy{}
+
+	err = lagClient.Get(
+		context.TODO(),
+		types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(&rootPolicy)},
+		replicatedPolicy,
+	)
+	if err != nil {
+		t.Fatalf("Expected the replicated policy to be created: %v", err)
+	}
+}
+
+func TestNextNamespaceRetryDelay(t *testing.T) {
+	rootPolicy := types.NamespacedName{Namespace: "default", Name: "onboarding-policy"}
+	defer namespaceRetryAttempts.Delete(rootPolicy)
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		expected := namespaceRetryBaseDelay << (attempt - 1)
+		if expected > namespaceRetryMaxDelay {
+			expected = namespaceRetryMaxDelay
+		}
+
+		delay := nextNamespaceRetryDelay(rootPolicy)
+		if delay < expected/2 || delay > expected {
+			t.Fatalf("Expected the delay of attempt %d to be in [%v, %v], got %v", attempt, expected/2, expected, delay)
+		}
+	}
+}
+
+func TestHandleDecisionTriggerUpdate(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	c := fake.NewClientBuilder().WithScheme(testscheme).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+	key := types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(&rootPolicy)}
+
+	getReplicatedPolicy := func() *policiesv1.Policy {
+		replicatedPolicy := &policiesv1.Policy{}
+
+		if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+			t.Fatalf("Failed to get the replicated policy: %v", err)
+		}
+
+		return replicatedPolicy
+	}
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+		t.Fatalf("Unexpected error creating the replicated policy: %v", err)
+	}
+
+	resourceVersion := getReplicatedPolicy().ResourceVersion
+
+	for _, value := range []string{"1", "2"} {
+		rootPolicy.SetAnnotations(map[string]string{TriggerUpdateAnnotation: value})
+
+		if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+			t.Fatalf("Unexpected error updating the replicated policy: %v", err)
+		}
+
+		replicatedPolicy := getReplicatedPolicy()
+
+		if replicatedPolicy.ResourceVersion == resourceVersion {
+			t.Fatalf("Expected the replicated policy to be rewritten after setting the trigger-update to %s", value)
+		}
+
+		if _, ok := replicatedPolicy.Annotations[TriggerUpdateAnnotation]; ok {
+			t.Fatal("Expected the trigger-update annotation to not be on the replicated policy")
+		}
+
+		if replicatedPolicy.Annotations[LastTriggerUpdateAnnotation] != value {
+			t.Fatalf(
+				"Expected the last-trigger-update annotation to be %s, got %s",
+				value, replicatedPolicy.Annotations[LastTriggerUpdateAnnotation],
+			)
+		}
+
+		resourceVersion = replicatedPolicy.ResourceVersion
+	}
+
+	// Reconciling again with the same value doesn't rewrite the replicated policy
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if getReplicatedPolicy().ResourceVersion != resourceVersion {
+		t.Fatal("Expected the replicated policy to not be rewritten when the trigger-update is unchanged")
+	}
+}
+
+func TestHandleDecisionDriftIgnoredPaths(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	ignoredPaths, err := ParseSpecPaths([]string{"policy-templates.*.objectDefinition.spec.evaluationInterval"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	rootPolicy.Spec.PolicyTemplates = []*policiesv1.PolicyTemplate{{ObjectDefinition: k8sruntime.RawExtension{
+		Raw: []byte(`{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},"spec":{"severity":"low"}}`),
+	}}}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10), DriftIgnoredPaths: ignoredPaths}
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+	key := types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(&rootPolicy)}
+
+	// mutateReplicatedPolicy sets the object definition of the replicated policy as if it was done on the managed
+	// cluster, handles the decision, and returns the resulting object definition and whether it was rewritten.
+	mutateReplicatedPolicy := func(objectDefinition string) (string, bool) {
+		replicatedPolicy := &policiesv1.Policy{}
+
+		if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+			t.Fatalf("Failed to get the replicated policy: %v", err)
+		}
+
+		replicatedPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw = []byte(objectDefinition)
+
+		if err := c.Update(context.TODO(), replicatedPolicy); err != nil {
+			t.Fatalf("Failed to update the replicated policy: %v", err)
+		}
+
+		resourceVersion := replicatedPolicy.ResourceVersion
+
+		if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+			t.Fatalf("Failed to get the replicated policy: %v", err)
+		}
+
+		return string(replicatedPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw),
+			replicatedPolicy.ResourceVersion != resourceVersion
+	}
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+		t.Fatalf("Unexpected error creating the replicated policy: %v", err)
+	}
+
+	defaulted := `{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},` +
+		`"spec":{"evaluationInterval":{"compliant":"10m"},"severity":"low"}}`
+
+	objectDefinition, rewritten := mutateReplicatedPolicy(defaulted)
+	if rewritten || objectDefinition != defaulted {
+		t.Fatalf("Expected the change to the ignored field to be kept, got %s", objectDefinition)
+	}
+
+	objectDefinition, rewritten = mutateReplicatedPolicy(
+		`{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},"spec":{"severity":"high"}}`,
+	)
+	if !rewritten || objectDefinition != string(rootPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw) {
+		t.Fatalf("Expected the change to another field to be reverted, got %s", objectDefinition)
+	}
+
+	// A change to the ignored field on the root policy is still propagated
+	rootPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw = []byte(
+		`{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},` +
+			`"spec":{"evaluationInterval":{"compliant":"30m"},"severity":"low"}}`,
+	)
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	replicatedPolicy := &policiesv1.Policy{}
+
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	objectDefinition = string(replicatedPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw)
+	if objectDefinition != string(rootPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw) {
+		t.Fatalf("Expected the change to the ignored field on the root policy to be propagated, got %s", objectDefinition)
+	}
+}
+
+func TestHandleDecisionAgentOwnedPaths(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	agentOwnedPaths, err := ParseSpecPaths([]string{"policy-templates.*.objectDefinition.spec.evaluationInterval"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	objectDefinition := func(severity string, interval string) string {
+		return `{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},` +
+			`"spec":{"evaluationInterval":{"compliant":"` + interval + `"},"severity":"` + severity + `"}}`
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	rootPolicy.Spec.PolicyTemplates = []*policiesv1.PolicyTemplate{{ObjectDefinition: k8sruntime.RawExtension{
+		Raw: []byte(objectDefinition("low", "5m")),
+	}}}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10), AgentOwnedPaths: agentOwnedPaths}
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+	key := types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(&rootPolicy)}
+
+	// handle optionally sets the object definition of the replicated policy as if it was done on the managed cluster,
+	// handles the decision, and returns the resulting object definition.
+	handle := func(agentObjectDefinition string) string {
+		replicatedPolicy := &policiesv1.Policy{}
+
+		if agentObjectDefinition != "" {
+			if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+				t.Fatalf("Failed to get the replicated policy: %v", err)
+			}
+
+			replicatedPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw = []byte(agentObjectDefinition)
+
+			if err := c.Update(context.TODO(), replicatedPolicy); err != nil {
+				t.Fatalf("Failed to update the replicated policy: %v", err)
+			}
+		}
+
+		if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+			t.Fatalf("Failed to get the replicated policy: %v", err)
+		}
+
+		return string(replicatedPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw)
+	}
+
+	if result := handle(""); result != objectDefinition("low", "5m") {
+		t.Fatalf("Expected the agent-owned field to be set on creation, got %s", result)
+	}
+
+	if result := handle(objectDefinition("low", "10m")); result != objectDefinition("low", "10m") {
+		t.Fatalf("Expected the change to the agent-owned field to persist, got %s", result)
+	}
+
+	if result := handle(objectDefinition("high", "10m")); result != objectDefinition("low", "10m") {
+		t.Fatalf("Expected only the change to the propagator-owned field to be reverted, got %s", result)
+	}
+
+	rootPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw = []byte(objectDefinition("medium", "1m"))
+
+	if result := handle(""); result != objectDefinition("medium", "10m") {
+		t.Fatalf("Expected the root policy change to keep the agent-owned field, got %s", result)
+	}
+}
+
+func TestFilterByClusterSelector(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	if err := clusterv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	selectedPolicy := fakeRootPolicy("selected-policy", "policies")
+	selectedPolicy.Spec.ClusterSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	otherPolicy := fakeRootPolicy("other-policy", "policies")
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "prod1", Labels: map[string]string{"env": "prod"}},
+		},
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev1", Labels: map[string]string{"env": "dev"}},
+		},
+		&selectedPolicy,
+		&otherPolicy,
+	).Build()
+	reconciler := &PolicyReconciler{Client: c}
+
+	decisions := []clusterDecision{}
+	for _, cluster := range []string{"prod1", "dev1", "missing"} {
+		decisions = append(decisions, clusterDecision{
+			Cluster: appsv1.PlacementDecision{ClusterName: cluster, ClusterNamespace: cluster},
+		})
+	}
+
+	tests := map[string]struct {
+		selector *metav1.LabelSelector
+		expected []string
+	}{
+		"matching labels": {
+			&metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}, []string{"prod1"},
+		},
+		"matching expressions": {
+			&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod"}},
+			}},
+			[]string{"dev1", "missing"},
+		},
+		"empty intersection": {
+			&metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging"}}, []string{},
+		},
+		"empty selector": {&metav1.LabelSelector{}, []string{"prod1", "dev1", "missing"}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			policy := fakeRootPolicy("my-policy", "policies")
+			policy.Spec.ClusterSelector = test.selector
+
+			selected, err := reconciler.filterByClusterSelector(&policy, decisions)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			selectedClusters := make([]string, 0, len(selected))
+			for _, decision := range selected {
+				selectedClusters = append(selectedClusters, decision.Cluster.ClusterName)
+			}
+
+			assert.ElementsMatch(t, test.expected, selectedClusters)
+		})
+	}
+
+	// Only the root policies with a cluster selector are reconciled when the cluster labels change
+	requests := managedClusterMapper(c)(&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "prod1"}})
+	if len(requests) != 1 || requests[0].Name != "selected-policy" {
+		t.Fatalf("Expected only the policy with a cluster selector to be reconciled, got %v", requests)
+	}
+}
+
+func TestResolvePolicyTargets(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	clusters := fakePlacementDecisions(3)
+	policy := fakeRootPolicy("my-policy", "policies")
+	subjects := []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: policy.Name},
+	}
+	placementRef := policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule"}
+
+	pr := fakePlacementRule("my-rule", "policies", []appsv1.PlacementDecision{clusters[2], clusters[0], clusters[1]})
+	pb := fakePlacementBinding("my-binding", "policies", placementRef, subjects)
+	pb.PlacementRef.Name = pr.Name
+	missingPb := fakePlacementBinding("missing-binding", "policies", placementRef, subjects)
+	missingPb.PlacementRef.Name = "missing-rule"
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&pr,
+		&pb,
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: clusters[1].ClusterName, Labels: map[string]string{"env": "prod"}},
+		},
+	).Build()
+
+	targets, err := ResolvePolicyTargets(context.TODO(), c, &policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assert.Equal(t, []string{"cluster1", "cluster2", "cluster3"}, targets)
+
+	policy.Spec.ClusterSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+
+	targets, err = ResolvePolicyTargets(context.TODO(), c, &policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assert.Equal(t, []string{"cluster2"}, targets)
+
+	// The targets from the other placement bindings are still returned when a placement is missing
+	if err := c.Create(context.TODO(), &missingPb); err != nil {
+		t.Fatalf("Failed to create the placement binding: %v", err)
+	}
+
+	targets, err = ResolvePolicyTargets(context.TODO(), c, &policy)
+	if !errors.Is(err, ErrPlacementNotFound) {
+		t.Fatalf("Expected an error wrapping ErrPlacementNotFound, got %v", err)
+	}
+
+	assert.Equal(t, []string{"cluster2"}, targets)
+
+	policy.Spec.Disabled = true
+
+	targets, err = ResolvePolicyTargets(context.TODO(), c, &policy)
+	if err != nil && !isNoTargetsError(err) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(targets) != 0 {
+		t.Fatalf("Expected a disabled policy to have no targets, got %v", targets)
+	}
+}
+
+func TestPlacementBindingDeletionCleanup(t *testing.T) {
+	clusters := fakePlacementDecisions(3)
+
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	prA := fakePlacementRule("pr-a", "default", []appsv1.PlacementDecision{clusters[0], clusters[1]})
+	prB := fakePlacementRule("pr-b", "default", []appsv1.PlacementDecision{clusters[1], clusters[2]})
+
+	policySubject := []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "test-policy"},
+	}
+	// The PolicySet no longer exists, so the subject can't be resolved to the policy
+	policySetSubject := []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.PolicySetKind, Name: "deleted-set"},
+	}
+
+	pbFor := func(name string, rule string, subjects []policiesv1.Subject) policiesv1.PlacementBinding {
+		return fakePlacementBinding(name, "default", policiesv1.PlacementSubject{
+			APIGroup: appsv1.SchemeGroupVersion.Group,
+			Kind:     "PlacementRule",
+			Name:     rule,
+		}, subjects)
+	}
+
+	tests := map[string]struct {
+		deleted           policiesv1.PlacementBinding
+		remaining         []policiesv1.PlacementBinding
+		replicatedIn      []appsv1.PlacementDecision
+		expectedRemaining []string
+	}{
+		"Single binding": {
+			deleted:           pbFor("pb-a", "pr-a", policySetSubject),
+			replicatedIn:      []appsv1.PlacementDecision{clusters[0], clusters[1]},
+			expectedRemaining: []string{},
+		},
+		"Multiple bindings with overlapping clusters": {
+			deleted:           pbFor("pb-a", "pr-a", policySubject),
+			remaining:         []policiesv1.PlacementBinding{pbFor("pb-b", "pr-b", policySubject)},
+			replicatedIn:      clusters,
+			expectedRemaining: []string{clusters[1].ClusterNamespace, clusters[2].ClusterNamespace},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			rootPolicy := fakeRootPolicy("test-policy", "default")
+			rootPolicy.Status.Placement = []*policiesv1.Placement{{PlacementBinding: test.deleted.Name}}
+
+			objects := []client.Object{&rootPolicy, &prA, &prB}
+
+			for _, decision := range test.replicatedIn {
+				rootPolicy.Status.Status = append(rootPolicy.Status.Status, &policiesv1.CompliancePerClusterStatus{
+					ClusterName:      decision.ClusterName,
+					ClusterNamespace: decision.ClusterNamespace,
+				})
+
+				objects = append(objects, &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{
+					Name:      "default.test-policy",
+					Namespace: decision.ClusterNamespace,
+					Labels:    map[string]string{common.RootPolicyLabel: "default.test-policy"},
+				}})
+			}
+
+			for i := range test.remaining {
+				objects = append(objects, &test.remaining[i])
+			}
+
+			c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build()
+			reconciler := &PolicyReconciler{Client: c}
+
+			// The deleted PlacementBinding must queue the root policy that it previously bound
+			requests := placementBindingMapper(c)(&test.deleted)
+			expectedRequest := types.NamespacedName{Namespace: "default", Name: "test-policy"}
+
+			found := false
+
+			for _, request := range requests {
+				if request.NamespacedName == expectedRequest {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Fatalf("expected the previously bound policy to be queued, got %v", requests)
+			}
+
+			pbList := &policiesv1.PlacementBindingList{Items: test.remaining}
+
+			clusterDecisions, _, err := reconciler.getAllClusterDecisions(&rootPolicy, pbList)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			allDecisions := decisionSet{}
+			for _, decision := range clusterDecisions {
+				allDecisions[decision.Cluster] = true
+			}
+
+			_, _, err = reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, allDecisions)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			replicatedPolicies := &policiesv1.PolicyList{}
+
+			err = c.List(context.TODO(), replicatedPolicies, client.MatchingLabels{
+				common.RootPolicyLabel: "default.test-policy",
+			})
+			if err != nil {
+				t.Fatalf("failed to list the replicated policies: %v", err)
+			}
+
+			remaining := []string{}
+			for _, replicatedPolicy := range replicatedPolicies.Items {
+				remaining = append(remaining, replicatedPolicy.Namespace)
+			}
+
+			assert.ElementsMatch(t, test.expectedRemaining, remaining)
+		})
+	}
+}
+
+func TestGetPlacementDecisionsErrors(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := appsv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	testPolicy := fakeRootPolicy("test-policy", "default")
+	pr := fakePlacementRule("pr", "default", fakePlacementDecisions(1))
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&pr).Build()
+
+	subjects := []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: testPolicy.Name},
+	}
+
+	tests := map[string]struct {
+		placementRef   policiesv1.PlacementSubject
+		expectedErr    error
+		expectedReason string
+	}{
+		"Found": {
+			placementRef: policiesv1.PlacementSubject{
+				APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "pr",
+			},
+		},
+		"Not found": {
+			placementRef: policiesv1.PlacementSubject{
+				APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "pr-typo",
+			},
+			expectedErr:    ErrPlacementNotFound,
+			expectedReason: "placement_not_found",
+		},
+		"Unsupported placementRef": {
+			placementRef: policiesv1.PlacementSubject{
+				APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "Placement", Name: "pr",
+			},
+			expectedErr:    ErrBindingInvalid,
+			expectedReason: "binding_invalid",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			pb := fakePlacementBinding("pb", "default", test.placementRef, subjects)
+
+			_, _, err := getPlacementDecisions(c, pb, &testPolicy)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected the error %v, got %v", test.expectedErr, err)
+			}
+
+			if err != nil && targetErrorReason(err) != test.expectedReason {
+				t.Fatalf("expected the reason %s, got %s", test.expectedReason, targetErrorReason(err))
+			}
+		})
+	}
+
+	joinedErr := errors.Join(
+		fmt.Errorf("%w: pb1", ErrPlacementNotFound), fmt.Errorf("%w: pb2", ErrNoDecisions),
+	)
+	if !isNoTargetsError(joinedErr) {
+		t.Fatal("expected the combined errors to only indicate that there are no targets")
+	}
+
+	if isNoTargetsError(errors.Join(joinedErr, errors.New("the API server is unavailable"))) {
+		t.Fatal("expected an API error to not be treated as having no targets")
+	}
+}
+
+func TestLocalClusterReplication(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	localCluster := appsv1.PlacementDecision{ClusterName: "local-cluster", ClusterNamespace: "local-cluster"}
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	pr := fakePlacementRule("my-rule", "policies", []appsv1.PlacementDecision{localCluster})
+	pb := fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "my-policy"}},
+	)
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&rootPolicy, &pr, &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "local-cluster"}},
+	).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+	decisions, _, err := reconciler.getAllClusterDecisions(
+		&rootPolicy, &policiesv1.PlacementBindingList{Items: []policiesv1.PlacementBinding{pb}},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(decisions) != 1 || decisions[0].Cluster != localCluster {
+		t.Fatalf("Expected the policy to be placed on local-cluster, got %v", decisions)
+	}
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decisions[0]); err != nil {
+		t.Fatalf("Unexpected error replicating the policy to local-cluster: %v", err)
+	}
+
+	replicatedPolicy := &policiesv1.Policy{}
+	key := types.NamespacedName{Namespace: "local-cluster", Name: "policies.my-policy"}
+
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Expected the replicated policy in the local-cluster namespace: %v", err)
+	}
+
+	// The replicated policy on the hub must be mapped to the root policy rather than be treated as a root policy
+	expected := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+
+	requests := common.PolicyMapper(c)(replicatedPolicy)
+	if len(requests) != 1 || requests[0].NamespacedName != expected {
+		t.Fatalf("Expected the replicated policy to map to the root policy, got %v", requests)
+	}
+
+	// Handling the decision again doesn't rewrite the replicated policy, so its watch event doesn't cause a loop
+	resourceVersion := replicatedPolicy.ResourceVersion
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decisions[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	if replicatedPolicy.ResourceVersion != resourceVersion {
+		t.Fatal("Expected the replicated policy in the local-cluster namespace to not be rewritten")
+	}
+}
+
+// fakeDynamicWatcher records the objects watched for each watcher.
+type fakeDynamicWatcher struct {
+	k8sdepwatches.DynamicWatcher
+	watched map[k8sdepwatches.ObjectIdentifier][]k8sdepwatches.ObjectIdentifier
+}
+
+func (w *fakeDynamicWatcher) AddOrUpdateWatcher(
+	watcher k8sdepwatches.ObjectIdentifier, watchedObjects ...k8sdepwatches.ObjectIdentifier,
+) error {
+	w.watched[watcher] = watchedObjects
+
+	return nil
+}
+
+func (w *fakeDynamicWatcher) RemoveWatcher(watcher k8sdepwatches.ObjectIdentifier) error {
+	delete(w.watched, watcher)
+
+	return nil
+}
+
+func (w *fakeDynamicWatcher) GetWatchCount() uint {
+	return uint(len(w.watched))
+}
+
+// failingGetClient fails to get the replicated policies while failing is true.
+type failingGetClient struct {
+	client.Client
+	failing bool
+}
+
+func (c *failingGetClient) Get(
+	ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption,
+) error {
+	if _, ok := obj.(*policiesv1.Policy); ok && c.failing && key.Namespace != "policies" {
+		return k8serrors.NewServiceUnavailable("the API server is unavailable")
+	}
+
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func TestHandleDecisionsKeepsTemplateWatches(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	rootPolicy.SetGroupVersionKind(policiesv1.GroupVersion.WithKind(policiesv1.Kind))
+	pr := fakePlacementRule("my-rule", "policies", fakePlacementDecisions(2))
+	pbList := &policiesv1.PlacementBindingList{Items: []policiesv1.PlacementBinding{fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "my-policy"}},
+	)}}
+
+	c := &failingGetClient{
+		Client:  fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&rootPolicy, &pr).Build(),
+		failing: true,
+	}
+	watcher := &fakeDynamicWatcher{watched: map[k8sdepwatches.ObjectIdentifier][]k8sdepwatches.ObjectIdentifier{}}
+	reconciler := &PolicyReconciler{Client: c, DynamicWatcher: watcher, Recorder: record.NewFakeRecorder(10)}
+
+	rootKey := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+	rootID := k8sdepwatches.ObjectIdentifier{
+		Group: common.APIGroup, Version: "v1", Kind: policiesv1.Kind, Namespace: "policies", Name: "my-policy",
+	}
+	configMapID := k8sdepwatches.ObjectIdentifier{Version: "v1", Kind: "ConfigMap", Namespace: "policies", Name: "cm"}
+
+	// The ConfigMap was referenced by a hub template in a previous reconcile
+	templateRefIndex.Store(rootKey, map[k8sdepwatches.ObjectIdentifier]bool{configMapID: true})
+	defer templateRefIndex.Delete(rootKey)
+
+	_, _, failedClusters, _, _, _ := reconciler.handleDecisions(context.TODO(), &rootPolicy, pbList)
+	if len(failedClusters) != 2 {
+		t.Fatalf("Expected every cluster to fail, got %v", failedClusters)
+	}
+
+	if watched := watcher.watched[rootID]; len(watched) != 1 || watched[0] != configMapID {
+		t.Fatalf("Expected the ConfigMap to still be watched after the failure, got %v", watched)
+	}
+
+	c.failing = false
+
+	_, _, failedClusters, _, _, _ = reconciler.handleDecisions(context.TODO(), &rootPolicy, pbList)
+	if len(failedClusters) != 0 {
+		t.Fatalf("Expected no clusters to fail, got %v", failedClusters)
+	}
+
+	if _, ok := watcher.watched[rootID]; ok {
+		t.Fatal("Expected the ConfigMap to no longer be watched once the policy no longer references it")
+	}
+
+	if _, ok := templateRefIndex.Load(rootKey); ok {
+		t.Fatal("Expected the policy to be removed from the template reference index")
+	}
+}
+
+func TestReplicaNamespaceTemplate(t *testing.T) {
+	if err := common.SetReplicaNamespaceTemplate("{cluster}-policies"); err != nil {
+		t.Fatalf("Unexpected error setting the template: %v", err)
+	}
+
+	defer func() { _ = common.SetReplicaNamespaceTemplate("") }()
+
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	clusters := fakePlacementDecisions(3)
+	rootPolicy := fakeRootPolicy("test-policy", "default")
+	pr := fakePlacementRule("pr", "default", []appsv1.PlacementDecision{clusters[0], clusters[1]})
+	pb := fakePlacementBinding("pb", "default", policiesv1.PlacementSubject{
+		APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: pr.Name,
+	}, []policiesv1.Subject{
+		{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: rootPolicy.Name},
+	})
+
+	// The replicated policy for cluster3, which is no longer placed
+	orphan := &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{
+		Name:      "default.test-policy",
+		Namespace: "cluster3-policies",
+		Labels:    map[string]string{common.RootPolicyLabel: "default.test-policy"},
+	}}
+
+	objects := []client.Object{&rootPolicy, &pr, orphan}
+	for _, cluster := range clusters {
+		objects = append(objects, &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: cluster.ClusterName}})
+
+		rootPolicy.Status.Status = append(rootPolicy.Status.Status, &policiesv1.CompliancePerClusterStatus{
+			ClusterName:      cluster.ClusterName,
+			ClusterNamespace: common.ReplicaNamespace(cluster.ClusterNamespace),
+		})
+	}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+	clusterDecisions, _, err := reconciler.getAllClusterDecisions(
+		&rootPolicy, &policiesv1.PlacementBindingList{Items: []policiesv1.PlacementBinding{pb}},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	allDecisions := decisionSet{}
+	namespaces := []string{}
+
+	for _, decision := range clusterDecisions {
+		allDecisions[decision.Cluster] = true
+		namespaces = append(namespaces, decision.Cluster.ClusterNamespace)
+
+		if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+			t.Fatalf("Unexpected error replicating the policy: %v", err)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"cluster1-policies", "cluster2-policies"}, namespaces)
+
+	_, _, err = reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, allDecisions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	replicatedPolicies := &policiesv1.PolicyList{}
+
+	err = c.List(context.TODO(), replicatedPolicies, client.MatchingLabels{common.RootPolicyLabel: "default.test-policy"})
+	if err != nil {
+		t.Fatalf("Failed to list the replicated policies: %v", err)
+	}
+
+	remaining := []string{}
+	for _, replicatedPolicy := range replicatedPolicies.Items {
+		remaining = append(remaining, replicatedPolicy.Namespace)
+	}
+
+	assert.ElementsMatch(t, []string{"cluster1-policies", "cluster2-policies"}, remaining)
+
+	// A change to a replicated policy in a derived namespace queues its root policy
+	requests := common.PolicyMapper(c)(&replicatedPolicies.Items[0])
+	if len(requests) != 1 || requests[0].Namespace != "default" || requests[0].Name != "test-policy" {
+		t.Fatalf("Expected the root policy to be queued, got %v", requests)
+	}
+
+	// The derived namespace of a cluster with a long name exceeds the namespace name limit
+	longName := strings.Repeat("a", 60)
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: longName, ClusterNamespace: common.ReplicaNamespace(longName)},
+	}
+
+	_, err = reconciler.handleDecision(context.TODO(), &rootPolicy, decision)
+	if !errors.Is(err, common.ErrInvalidReplicaNamespace) {
+		t.Fatalf("Expected an error wrapping ErrInvalidReplicaNamespace, got %v", err)
+	}
+}
+
+func TestHandleDecisionNameConflict(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+
+	tests := map[string]map[string]string{
+		"Another root policy": {common.RootPolicyLabel: "other.my-policy"},
+		"No root policy":      nil,
+	}
+
+	for name, labels := range tests {
+		labels := labels
+
+		t.Run(name, func(t *testing.T) {
+			foreign := &policiesv1.Policy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      common.FullNameForPolicy(&rootPolicy),
+					Namespace: "managed1",
+					Labels:    labels,
+				},
+				Spec: policiesv1.PolicySpec{RemediationAction: policiesv1.Enforce},
+			}
+
+			c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(foreign).Build()
+			reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+			_, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision)
+			if !errors.Is(err, errReplicaNameConflict) {
+				t.Fatalf("Expected an error wrapping errReplicaNameConflict, got %v", err)
+			}
+
+			if reason := propagationErrorReason(err); reason != reasonNameConflict {
+				t.Fatalf("Expected the reason %s, got %s", reasonNameConflict, reason)
+			}
+
+			existing := &policiesv1.Policy{}
+
+			if err := c.Get(context.TODO(), client.ObjectKeyFromObject(foreign), existing); err != nil {
+				t.Fatalf("Failed to get the policy: %v", err)
+			}
+
+			if existing.ResourceVersion != foreign.ResourceVersion || existing.Spec.RemediationAction != policiesv1.Enforce {
+				t.Fatal("Expected the policy that isn't a replica of the root policy to not be modified")
+			}
+		})
+	}
+}
+
+func TestHandleDecisionMaxReplicaSpecSize(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	c := fake.NewClientBuilder().WithScheme(testscheme).Build()
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &PolicyReconciler{Client: c, Recorder: recorder, MaxReplicaSpecSize: 1024}
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+	key := types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(&rootPolicy)}
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+		t.Fatalf("Unexpected error creating the replicated policy: %v", err)
+	}
+
+	replicatedPolicy := &policiesv1.Policy{}
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	// The rendered policy template is larger than the maximum size
+	rootPolicy.Spec.PolicyTemplates = []*policiesv1.PolicyTemplate{{
+		ObjectDefinition: k8sruntime.RawExtension{
+			Raw: []byte(`{"kind":"ConfigurationPolicy","data":"` + strings.Repeat("a", 2048) + `"}`),
+		},
+	}}
+
+	initialSizeErrors := testutil.ToFloat64(replicaSizeExceededMetric.WithLabelValues("my-policy", "default"))
+
+	// Both the update of the existing replicated policy and the creation of a new one are skipped
+	for _, cluster := range []string{"managed1", "managed2"} {
+		decision := clusterDecision{Cluster: appsv1.PlacementDecision{ClusterName: cluster, ClusterNamespace: cluster}}
+
+		_, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision)
+		if !errors.Is(err, errReplicaTooLarge) {
+			t.Fatalf("Expected an error wrapping errReplicaTooLarge for %s, got %v", cluster, err)
+		}
+
+		if reason := propagationErrorReason(err); reason != reasonReplicaTooLarge {
+			t.Fatalf("Expected the reason %s, got %s", reasonReplicaTooLarge, reason)
+		}
+	}
+
+	sizeErrors := testutil.ToFloat64(replicaSizeExceededMetric.WithLabelValues("my-policy", "default"))
+	if sizeErrors-initialSizeErrors != 2 {
+		t.Fatalf("Expected the size exceeded metric to be incremented twice, got %v", sizeErrors-initialSizeErrors)
+	}
+
+	existing := &policiesv1.Policy{}
+	if err := c.Get(context.TODO(), key, existing); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	if existing.ResourceVersion != replicatedPolicy.ResourceVersion {
+		t.Fatal("Expected the existing replicated policy to not be updated")
+	}
+
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: "managed2", Name: key.Name}, &policiesv1.Policy{})
+	if !k8serrors.IsNotFound(err) {
+		t.Fatalf("Expected the replicated policy to not be created, got %v", err)
+	}
+
+	foundEvent := false
+
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, "exceeds the maximum of 1024 bytes") {
+			foundEvent = true
+		}
+	}
+
+	if !foundEvent {
+		t.Fatal("Expected a warning event naming the size")
+	}
+}
+
+func TestHandleDecisionSourceHub(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	if err := common.SetHubID("hub1"); err != nil {
+		t.Fatalf("Unexpected error setting the hub identifier: %v", err)
+	}
+
+	defer func() { _ = common.SetHubID("") }()
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+
+	replica := func(namespace string, sourceHub string) *policiesv1.Policy {
+		labels := map[string]string{common.RootPolicyLabel: common.FullNameForPolicy(&rootPolicy)}
+		if sourceHub != "" {
+			labels[common.SourceHubLabel] = sourceHub
+		}
+
+		return &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{
+			Name:      common.FullNameForPolicy(&rootPolicy),
+			Namespace: namespace,
+			Labels:    labels,
+		}}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		replica("legacy", ""), replica("other", "hub2"),
+	).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+	getReplicatedPolicy := func(namespace string) *policiesv1.Policy {
+		replicatedPolicy := &policiesv1.Policy{}
+		key := types.NamespacedName{Namespace: namespace, Name: common.FullNameForPolicy(&rootPolicy)}
+
+		if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+			t.Fatalf("Failed to get the replicated policy: %v", err)
+		}
+
+		return replicatedPolicy
+	}
+
+	// A new replicated policy and one without the label are both labeled with this hub
+	for _, namespace := range []string{"managed1", "legacy"} {
+		decision := clusterDecision{
+			Cluster: appsv1.PlacementDecision{ClusterName: namespace, ClusterNamespace: namespace},
+		}
+
+		if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+			t.Fatalf("Unexpected error handling the decision for %s: %v", namespace, err)
+		}
+
+		if hub := getReplicatedPolicy(namespace).Labels[common.SourceHubLabel]; hub != "hub1" {
+			t.Fatalf("Expected the replicated policy in %s to have the source hub hub1, got %q", namespace, hub)
+		}
+	}
+
+	// A replicated policy created by another hub is not modified
+	resourceVersion := getReplicatedPolicy("other").ResourceVersion
+	decision := clusterDecision{Cluster: appsv1.PlacementDecision{ClusterName: "other", ClusterNamespace: "other"}}
+
+	_, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision)
+	if !errors.Is(err, errReplicaNameConflict) {
+		t.Fatalf("Expected an error wrapping errReplicaNameConflict, got %v", err)
+	}
+
+	if getReplicatedPolicy("other").ResourceVersion != resourceVersion {
+		t.Fatal("Expected the replicated policy created by another hub to not be modified")
+	}
+
+	// Nor is it deleted when the cluster is no longer selected
+	rootPolicy.Status.Status = []*policiesv1.CompliancePerClusterStatus{
+		{ClusterName: "managed1", ClusterNamespace: "managed1"},
+		{ClusterName: "legacy", ClusterNamespace: "legacy"},
+		{ClusterName: "other", ClusterNamespace: "other"},
+	}
+
+	if _, _, err := reconciler.cleanUpOrphanedRplPolicies(&rootPolicy, decisionSet{}); err != nil {
+		t.Fatalf("Unexpected error cleaning up the replicated policies: %v", err)
+	}
+
+	replicatedPolicies := &policiesv1.PolicyList{}
+
+	if err := c.List(context.TODO(), replicatedPolicies); err != nil {
+		t.Fatalf("Failed to list the replicated policies: %v", err)
+	}
+
+	if len(replicatedPolicies.Items) != 1 || replicatedPolicies.Items[0].Namespace != "other" {
+		t.Fatalf("Expected only the replicated policy created by another hub to remain, got %v", replicatedPolicies.Items)
+	}
+}
+
+func TestDeleteDuplicateReplicas(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	rootPolicy := fakeRootPolicy("test-policy", "default")
+	replica := func(namespace, name string) *policiesv1.Policy {
+		return &policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{common.RootPolicyLabel: "default.test-policy"},
+		}}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&rootPolicy,
+		// managed1 has the canonical replicated policy and a duplicate
+		replica("managed1", "default.test-policy"),
+		replica("managed1", "default.test-policy-copy"),
+		// managed2 only has duplicates, so the canonical one is recreated by the propagation
+		replica("managed2", "default.test-policy-a"),
+		replica("managed2", "default.test-policy-b"),
+		// A single replicated policy in a namespace is never a duplicate
+		replica("managed3", "default.test-policy"),
+	).Build()
+	reconciler := &PolicyReconciler{Client: c}
+
+	// Running it again after converging is a no-op
+	for i := 0; i < 2; i++ {
+		if err := reconciler.deleteDuplicateReplicas(&rootPolicy); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		replicatedPolicies := &policiesv1.PolicyList{}
+
+		err := c.List(context.TODO(), replicatedPolicies, client.MatchingLabels{
+			common.RootPolicyLabel: "default.test-policy",
+		})
+		if err != nil {
+			t.Fatalf("Failed to list the replicated policies: %v", err)
+		}
+
+		remaining := []string{}
+		for _, replicatedPolicy := range replicatedPolicies.Items {
+			remaining = append(remaining, replicatedPolicy.Namespace+"/"+replicatedPolicy.Name)
+		}
+
+		assert.ElementsMatch(t, []string{"managed1/default.test-policy", "managed3/default.test-policy"}, remaining)
+	}
+}
+
+// statusGuardClient records the replicated policy writes that would set the status.
+type statusGuardClient struct {
+	client.Client
+	statusWrites []string
+}
+
+func (c *statusGuardClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.statusWrites = append(c.statusWrites, "update of "+obj.GetNamespace()+"/"+obj.GetName())
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *statusGuardClient) Patch(
+	ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption,
+) error {
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(string(data), `"status"`) {
+		c.statusWrites = append(c.statusWrites, "patch of "+obj.GetNamespace()+"/"+obj.GetName()+": "+string(data))
+	}
+
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestHandleDecisionKeepsReplicaStatus(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	rootPolicy.Spec.RemediationAction = policiesv1.Inform
+	rootPolicy.Status = policiesv1.PolicyStatus{
+		ComplianceState: policiesv1.Compliant,
+		Status: []*policiesv1.CompliancePerClusterStatus{
+			{ClusterName: "managed1", ClusterNamespace: "managed1", ComplianceState: policiesv1.Compliant},
+		},
+	}
+
+	c := &statusGuardClient{Client: fake.NewClientBuilder().WithScheme(testscheme).Build()}
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+	decision := clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+	}
+	key := types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(&rootPolicy)}
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+		t.Fatalf("Unexpected error creating the replicated policy: %v", err)
+	}
+
+	replicatedPolicy := &policiesv1.Policy{}
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	if !equality.Semantic.DeepEqual(replicatedPolicy.Status, policiesv1.PolicyStatus{}) {
+		t.Fatalf("Expected the root policy status to not be copied, got %+v", replicatedPolicy.Status)
+	}
+
+	// The status sync on the managed cluster sets the status, and the spec drifts out-of-band
+	replicatedPolicy.Status = policiesv1.PolicyStatus{
+		ComplianceState: policiesv1.NonCompliant,
+		Details:         []*policiesv1.DetailsPerTemplate{{ComplianceState: policiesv1.NonCompliant}},
+	}
+	replicatedPolicy.Spec.RemediationAction = policiesv1.Enforce
+
+	if err := c.Client.Update(context.TODO(), replicatedPolicy); err != nil {
+		t.Fatalf("Failed to update the replicated policy: %v", err)
+	}
+
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision); err != nil {
+		t.Fatalf("Unexpected error updating the replicated policy: %v", err)
+	}
+
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	if replicatedPolicy.Spec.RemediationAction != policiesv1.Inform {
+		t.Fatalf("Expected the spec drift to be corrected, got %s", replicatedPolicy.Spec.RemediationAction)
+	}
+
+	if replicatedPolicy.Status.ComplianceState != policiesv1.NonCompliant || len(replicatedPolicy.Status.Details) != 1 {
+		t.Fatalf("Expected the replicated policy status to be untouched, got %+v", replicatedPolicy.Status)
+	}
+
+	if len(c.statusWrites) != 0 {
+		t.Fatalf("Expected the replicated policy status to never be sent, got %v", c.statusWrites)
+	}
+}
+
+func TestPlacementBindingSubjectPattern(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, policiesv1beta1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	pr := fakePlacementRule("my-rule", "policies", fakePlacementDecisions(2))
+	// The pattern and the exact name overlap for team-a-certs
+	pb := fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{
+			{
+				APIGroup:      policiesv1.SchemeGroupVersion.Group,
+				Kind:          policiesv1.Kind,
+				Name:          "team-a-*",
+				NameIsPattern: true,
+			},
+			{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "team-a-certs"},
+		},
+	)
+	pbList := &policiesv1.PlacementBindingList{Items: []policiesv1.PlacementBinding{pb}}
+
+	teamACerts := fakeRootPolicy("team-a-certs", "policies")
+	teamARbac := fakeRootPolicy("team-a-rbac", "policies")
+	teamBCerts := fakeRootPolicy("team-b-certs", "policies")
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&pr, &pb, &teamACerts, &teamARbac, &teamBCerts,
+	).Build()
+	reconciler := &PolicyReconciler{Client: c}
+
+	requested := func() []string {
+		t.Helper()
+
+		names := map[string]bool{}
+		for _, request := range placementBindingMapper(c)(&pb) {
+			names[request.Name] = true
+		}
+
+		result := []string{}
+		for name := range names {
+			result = append(result, name)
+		}
+
+		return result
+	}
+
+	assert.ElementsMatch(t, []string{"team-a-certs", "team-a-rbac"}, requested())
+
+	// The overlapping subjects only result in a single placement
+	decisions, placements, err := reconciler.getAllClusterDecisions(&teamACerts, pbList)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(decisions) != 2 || len(placements) != 1 {
+		t.Fatalf("Expected 2 decisions from a single placement, got %v and %v", decisions, placements)
+	}
+
+	decisions, _, err = reconciler.getAllClusterDecisions(&teamBCerts, pbList)
+	if err != nil || len(decisions) != 0 {
+		t.Fatalf("Expected the policy not matching the pattern to not be placed, got %v and %v", decisions, err)
+	}
+
+	// A policy created later is bound without editing the placement binding
+	teamANew := fakeRootPolicy("team-a-new", "policies")
+	if err := c.Create(context.TODO(), &teamANew); err != nil {
+		t.Fatalf("Failed to create the policy: %v", err)
+	}
+
+	assert.ElementsMatch(t, []string{"team-a-certs", "team-a-rbac", "team-a-new"}, requested())
+
+	decisions, _, err = reconciler.getAllClusterDecisions(&teamANew, pbList)
+	if err != nil || len(decisions) != 2 {
+		t.Fatalf("Expected the new policy to be placed on 2 clusters, got %v and %v", decisions, err)
+	}
+}
+
+func TestHandleDecisionAdoptsReplica(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("adopted-policy", "default")
+	replicaName := common.FullNameForPolicy(&rootPolicy)
+
+	// The first replica predates the cluster labels, and the second one is labeled for another cluster
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{
+			Name:      replicaName,
+			Namespace: "managed1",
+			Labels:    map[string]string{common.RootPolicyLabel: replicaName},
+		}},
+		&policiesv1.Policy{ObjectMeta: metav1.ObjectMeta{
+			Name:      replicaName,
+			Namespace: "managed2",
+			Labels: map[string]string{
+				common.RootPolicyLabel:       replicaName,
+				common.ClusterNameLabel:      "other",
+				common.ClusterNamespaceLabel: "other",
+			},
+		}},
+	).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+	adopted := func() float64 {
+		return testutil.ToFloat64(replicasAdoptedMetric.WithLabelValues("adopted-policy", "default"))
+	}
+
+	defer replicasAdoptedMetric.DeleteLabelValues("adopted-policy", "default")
+
+	decision := func(cluster string) clusterDecision {
+		return clusterDecision{Cluster: appsv1.PlacementDecision{ClusterName: cluster, ClusterNamespace: cluster}}
+	}
+
+	// The replica is only adopted once since it has the labels afterwards
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision("managed1")); err != nil {
+			t.Fatalf("Unexpected error handling the decision: %v", err)
+		}
+	}
+
+	if adopted() != 1 {
+		t.Fatalf("Expected one adopted replicated policy, got %v", adopted())
+	}
+
+	replicatedPolicy := &policiesv1.Policy{}
+	key := types.NamespacedName{Namespace: "managed1", Name: replicaName}
+
+	if err := c.Get(context.TODO(), key, replicatedPolicy); err != nil {
+		t.Fatalf("Failed to get the replicated policy: %v", err)
+	}
+
+	if replicatedPolicy.Labels[common.ClusterNameLabel] != "managed1" {
+		t.Fatalf("Expected the adopted replicated policy to be labeled, got %v", replicatedPolicy.Labels)
+	}
+
+	// A new replicated policy isn't adopted
+	if _, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision("managed3")); err != nil {
+		t.Fatalf("Unexpected error handling the decision: %v", err)
+	}
+
+	// A replica for another cluster isn't a clear match, so it's not adopted
+	_, err := reconciler.handleDecision(context.TODO(), &rootPolicy, decision("managed2"))
+	if !errors.Is(err, errReplicaNameConflict) {
+		t.Fatalf("Expected a name conflict for the replica of another cluster, got %v", err)
+	}
+
+	if adopted() != 1 {
+		t.Fatalf("Expected only one adopted replicated policy, got %v", adopted())
+	}
+}
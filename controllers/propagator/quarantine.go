@@ -0,0 +1,161 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+// errClusterQuarantined is returned when a replicated policy isn't written because its cluster namespace is
+// quarantined after consecutive failures.
+var errClusterQuarantined = errors.New("the cluster is quarantined after consecutive failures to replicate policies")
+
+// minProbeDelay is the shortest delay returned by probeDelay, so that a root policy with quarantined clusters is always
+// requeued, even when the probe is already due or the cluster was released in the meantime.
+const minProbeDelay = time.Second
+
+// ClusterQuarantine is a circuit breaker for the cluster namespaces that the replicated policies can't be written to,
+// such as when the RBAC in a cluster namespace is broken. Once the writes to a cluster namespace fail the threshold
+// number of times in a row, across all root policies, no more writes are attempted until the probe interval passes.
+// A single write is then attempted as a probe, and the cluster namespace is released from the quarantine once a write
+// succeeds. A nil ClusterQuarantine never quarantines a cluster namespace.
+type ClusterQuarantine struct {
+	threshold     int
+	probeInterval time.Duration
+	lock          sync.Mutex
+	clusters      map[string]*clusterFailures
+}
+
+// clusterFailures is the state of a cluster namespace that writes have failed for.
+type clusterFailures struct {
+	consecutive int
+	quarantined bool
+	// When a write may be attempted again as a probe while the cluster namespace is quarantined
+	nextProbe time.Time
+}
+
+// NewClusterQuarantine returns a ClusterQuarantine that quarantines a cluster namespace after the input number of
+// consecutive failures, and that probes it again after each input probe interval.
+func NewClusterQuarantine(threshold int, probeInterval time.Duration) *ClusterQuarantine {
+	return &ClusterQuarantine{
+		threshold:     threshold,
+		probeInterval: probeInterval,
+		clusters:      map[string]*clusterFailures{},
+	}
+}
+
+// isClusterFailure returns true if the input error from replicating a policy indicates a problem with the cluster
+// namespace rather than with the root policy or the onboarding of the cluster.
+func isClusterFailure(err error) bool {
+	return !errors.Is(err, errNamespaceNotFound) &&
+		!errors.Is(err, common.ErrInvalidReplicaNamespace) &&
+		!errors.Is(err, errReplicaNameConflict) &&
+		!errors.Is(err, errReplicaTooLarge) &&
+		!errors.Is(err, errTemplateInvalid) &&
+		!errors.Is(err, errKindNotAllowed) &&
+		!errors.Is(err, errClusterQuarantined)
+}
+
+// allow returns true if a replicated policy may be written to the input cluster namespace. When the cluster namespace
+// is quarantined, this is only true once per probe interval, so that a single write probes it.
+func (q *ClusterQuarantine) allow(clusterNamespace string, now time.Time) bool {
+	if q == nil {
+		return true
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	state := q.clusters[clusterNamespace]
+	if state == nil || !state.quarantined {
+		return true
+	}
+
+	if now.Before(state.nextProbe) {
+		return false
+	}
+
+	state.nextProbe = now.Add(q.probeInterval)
+
+	return true
+}
+
+// probeDelay returns how long until a write may be attempted again as a probe of the input cluster namespace, so that
+// the root policies that skipped it while quarantined are requeued for the probe rather than retried with a backoff.
+func (q *ClusterQuarantine) probeDelay(clusterNamespace string, now time.Time) time.Duration {
+	var delay time.Duration
+
+	if q != nil {
+		q.lock.Lock()
+
+		if state := q.clusters[clusterNamespace]; state != nil && state.quarantined {
+			delay = state.nextProbe.Sub(now)
+		}
+
+		q.lock.Unlock()
+	}
+
+	if delay < minProbeDelay {
+		return minProbeDelay
+	}
+
+	return delay
+}
+
+// record updates the state of the input cluster namespace with the result of writing a replicated policy to it. A
+// success resets the consecutive failures and releases the cluster namespace from the quarantine. Errors that don't
+// indicate a problem with the cluster namespace are ignored.
+func (q *ClusterQuarantine) record(clusterNamespace string, err error, now time.Time) {
+	if q == nil || (err != nil && !isClusterFailure(err)) {
+		return
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	state := q.clusters[clusterNamespace]
+
+	if err == nil {
+		if state == nil {
+			return
+		}
+
+		if state.quarantined {
+			log.Info("Released the cluster from the quarantine after a replicated policy was written",
+				"clusterNamespace", clusterNamespace)
+
+			clusterQuarantinedMetric.DeleteLabelValues(clusterNamespace)
+		}
+
+		delete(q.clusters, clusterNamespace)
+
+		return
+	}
+
+	if state == nil {
+		state = &clusterFailures{}
+		q.clusters[clusterNamespace] = state
+	}
+
+	state.consecutive++
+
+	if state.quarantined || state.consecutive < q.threshold {
+		return
+	}
+
+	state.quarantined = true
+	state.nextProbe = now.Add(q.probeInterval)
+
+	log.Info("Quarantined the cluster after consecutive failures to write replicated policies",
+		"clusterNamespace", clusterNamespace,
+		"failures", state.consecutive,
+		"probeInterval", q.probeInterval.String(),
+		"lastError", err.Error(),
+	)
+
+	clusterQuarantinedMetric.WithLabelValues(clusterNamespace).Set(1)
+}
@@ -0,0 +1,199 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestClusterQuarantine(t *testing.T) {
+	clusterQuarantinedMetric.Reset()
+	defer clusterQuarantinedMetric.Reset()
+
+	quarantine := NewClusterQuarantine(3, time.Minute)
+	now := time.Now()
+	writeErr := errors.New("forbidden")
+
+	// Errors that aren't caused by the cluster namespace don't count toward the threshold
+	for i := 0; i < 5; i++ {
+		quarantine.record("cluster1", errNamespaceNotFound, now)
+		quarantine.record("cluster1", errReplicaTooLarge, now)
+	}
+
+	if !quarantine.allow("cluster1", now) {
+		t.Fatal("Expected cluster1 not to be quarantined by errors unrelated to the cluster")
+	}
+
+	// A success resets the consecutive failures
+	quarantine.record("cluster1", writeErr, now)
+	quarantine.record("cluster1", writeErr, now)
+	quarantine.record("cluster1", nil, now)
+	quarantine.record("cluster1", writeErr, now)
+	quarantine.record("cluster1", writeErr, now)
+
+	if !quarantine.allow("cluster1", now) {
+		t.Fatal("Expected cluster1 not to be quarantined after a success")
+	}
+
+	quarantine.record("cluster1", writeErr, now)
+
+	if quarantine.allow("cluster1", now) {
+		t.Fatal("Expected cluster1 to be quarantined after three consecutive failures")
+	}
+
+	if !quarantine.allow("cluster2", now) {
+		t.Fatal("Expected cluster2 not to be affected by the quarantine of cluster1")
+	}
+
+	if value := testutil.ToFloat64(clusterQuarantinedMetric.WithLabelValues("cluster1")); value != 1 {
+		t.Fatalf("Expected the quarantined metric to be 1, got %v", value)
+	}
+
+	if delay := quarantine.probeDelay("cluster1", now); delay != time.Minute {
+		t.Fatalf("Expected the next probe of cluster1 in a minute, got %s", delay)
+	}
+
+	if delay := quarantine.probeDelay("cluster2", now); delay != minProbeDelay {
+		t.Fatalf("Expected the minimum probe delay for cluster2, got %s", delay)
+	}
+
+	// A single probe is allowed once the probe interval passes
+	probeTime := now.Add(time.Minute)
+
+	if !quarantine.allow("cluster1", probeTime) {
+		t.Fatal("Expected a probe to be allowed after the probe interval")
+	}
+
+	if quarantine.allow("cluster1", probeTime) {
+		t.Fatal("Expected only one probe to be allowed per probe interval")
+	}
+
+	// A failed probe keeps the cluster quarantined until the next probe
+	quarantine.record("cluster1", writeErr, probeTime)
+
+	if quarantine.allow("cluster1", probeTime.Add(time.Second)) {
+		t.Fatal("Expected cluster1 to stay quarantined after a failed probe")
+	}
+
+	probeTime = probeTime.Add(time.Minute)
+
+	if !quarantine.allow("cluster1", probeTime) {
+		t.Fatal("Expected another probe to be allowed after the probe interval")
+	}
+
+	quarantine.record("cluster1", nil, probeTime)
+
+	if !quarantine.allow("cluster1", probeTime) {
+		t.Fatal("Expected cluster1 to be released from the quarantine after a successful probe")
+	}
+
+	if count := testutil.CollectAndCount(clusterQuarantinedMetric); count != 0 {
+		t.Fatalf("Expected the quarantined metric series to be deleted, got %d series", count)
+	}
+}
+
+// failingClusterHandler fails to handle the decisions of failingCluster and counts the decisions it handles.
+type failingClusterHandler struct {
+	failingCluster string
+	handled        map[string]int
+}
+
+func (h failingClusterHandler) handleDecision(
+	_ context.Context, _ *policiesv1.Policy, decision clusterDecision,
+) (
+	map[k8sdepwatches.ObjectIdentifier]bool, error,
+) {
+	h.handled[decision.Cluster.ClusterName]++
+
+	if decision.Cluster.ClusterName == h.failingCluster {
+		return nil, errors.New("forbidden")
+	}
+
+	return map[k8sdepwatches.ObjectIdentifier]bool{}, nil
+}
+
+func TestHandleDecisionWrapperQuarantine(t *testing.T) {
+	clusterQuarantinedMetric.Reset()
+	defer clusterQuarantinedMetric.Reset()
+
+	policy := fakeRootPolicy("my-policy", "policies")
+	decisions := fakePlacementDecisions(2)
+	handler := failingClusterHandler{failingCluster: "cluster1", handled: map[string]int{}}
+	quarantine := NewClusterQuarantine(2, time.Hour)
+
+	var lastErrs map[string]error
+
+	for i := 0; i < 3; i++ {
+		decisionsChan := make(chan clusterDecision, len(decisions))
+		resultsChan := make(chan decisionResult, len(decisions))
+
+		for _, decision := range decisions {
+			decisionsChan <- clusterDecision{Cluster: decision}
+		}
+
+		close(decisionsChan)
+
+		handleDecisionWrapper(
+			context.TODO(), handler, &policy, decisionsChan, resultsChan, time.Time{}, quarantine,
+		)
+
+		close(resultsChan)
+
+		lastErrs = map[string]error{}
+
+		for result := range resultsChan {
+			lastErrs[result.Identifier.ClusterName] = result.Err
+		}
+	}
+
+	// The failing cluster is no longer handled once it's quarantined, and the other cluster is unaffected
+	if handler.handled["cluster1"] != 2 || handler.handled["cluster2"] != 3 {
+		t.Fatalf("Expected cluster1 to be handled twice and cluster2 three times, got %v", handler.handled)
+	}
+
+	if !errors.Is(lastErrs["cluster1"], errClusterQuarantined) || lastErrs["cluster2"] != nil {
+		t.Fatalf("Expected only cluster1 to fail as quarantined, got %v", lastErrs)
+	}
+
+	if reason := propagationErrorReason(lastErrs["cluster1"]); reason != reasonClusterQuarantined {
+		t.Fatalf("Expected the %s reason, got %s", reasonClusterQuarantined, reason)
+	}
+}
+
+func TestReconcileQuarantinedCluster(t *testing.T) {
+	clusterQuarantinedMetric.Reset()
+	defer clusterQuarantinedMetric.Reset()
+
+	root := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+	defer forgetDesiredState(root)
+
+	reconciler, c := desiredStateReconciler(t, 2, false)
+	reconciler.ClusterQuarantine = NewClusterQuarantine(1, time.Hour)
+	reconciler.ClusterQuarantine.record("cluster1", errors.New("forbidden"), time.Now())
+
+	// The quarantined cluster doesn't fail the reconcile, which is requeued for its next probe instead
+	result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: root})
+	if err != nil {
+		t.Fatalf("Unexpected error reconciling the policy: %v", err)
+	}
+
+	if result.RequeueAfter <= time.Hour-time.Minute || result.RequeueAfter > time.Hour {
+		t.Fatalf("Expected the policy to be requeued for the next probe of cluster1, got %s", result.RequeueAfter)
+	}
+
+	err = c.Get(context.TODO(), types.NamespacedName{Namespace: "cluster2", Name: "policies.my-policy"},
+		&policiesv1.Policy{})
+	if err != nil {
+		t.Fatalf("Expected the policy to be replicated to cluster2: %v", err)
+	}
+}
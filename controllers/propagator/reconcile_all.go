@@ -0,0 +1,214 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	// ReconcileAllPath is the path on the metrics server that triggers a reconcile of all root policies.
+	ReconcileAllPath = "/reconcile-all"
+	// The number of policies to retrieve per List call when enqueuing all root policies.
+	reconcileAllPageSize = 500
+)
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// ReconcileAllHandler returns an HTTP handler that sends a generic event for every root policy on the input channel.
+// The channel is meant to be used as an additional source of the PolicyReconciler, so each root policy is enqueued
+// for reconciliation. The workqueue deduplicates the requests and the controller only processes as many at a time as
+// its configured concurrency allows, so this doesn't cause a burst of concurrent reconciles.
+//
+// Callers must provide a bearer token of a user who is allowed to update policies at the cluster scope. The apiReader
+// should not be backed by the cache so that the policies can be retrieved in pages. The cached client is used to
+// determine if a policy is a replicated policy. The channel is only drained by the PolicyReconciler once this replica
+// is the leader, which is signaled by closing the elected channel, so until then, the handler responds with
+// http.StatusServiceUnavailable rather than blocking.
+func ReconcileAllHandler(
+	apiReader client.Reader,
+	c client.Client,
+	authClient kubernetes.Interface,
+	elected <-chan struct{},
+	events chan<- event.GenericEvent,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		log := log.WithName("reconcile-all")
+
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		authStatus, err := authorize(req.Context(), authClient, req, "update")
+		if err != nil {
+			log.Error(err, "Failed to authorize the request")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if authStatus != http.StatusOK {
+			w.WriteHeader(authStatus)
+
+			return
+		}
+
+		select {
+		case <-elected:
+		default:
+			log.Info("Not reconciling all root policies since this replica isn't the leader")
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		log.Info("Received a request to reconcile all root policies")
+
+		enqueued, err := enqueueAllRootPolicies(req.Context(), apiReader, c, events)
+		if err != nil {
+			log.Error(err, "Failed to enqueue all the root policies", "enqueued", enqueued)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		log.Info("Enqueued all the root policies for reconciliation", "enqueued", enqueued)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(map[string]int{"enqueued": enqueued})
+	})
+}
+
+// authorize authenticates the bearer token in the request with a TokenReview and then verifies with a
+// SubjectAccessReview that the user may perform the input verb on policies at the cluster scope. It returns the HTTP
+// status to respond with: http.StatusOK if the request is authorized, http.StatusUnauthorized if the token is missing
+// or doesn't authenticate, and http.StatusForbidden if the authenticated user isn't allowed.
+func authorize(
+	ctx context.Context, authClient kubernetes.Interface, req *http.Request, verb string,
+) (int, error) {
+	token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return http.StatusUnauthorized, nil
+	}
+
+	tokenReview, err := authClient.AuthenticationV1().TokenReviews().Create(
+		ctx, &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}, metav1.CreateOptions{},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if !tokenReview.Status.Authenticated {
+		return http.StatusUnauthorized, nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(tokenReview.Status.User.Extra))
+	for key, value := range tokenReview.Status.User.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	sar, err := authClient.AuthorizationV1().SubjectAccessReviews().Create(
+		ctx,
+		&authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Verb:     verb,
+					Group:    policiesv1.GroupVersion.Group,
+					Resource: "policies",
+				},
+				User:   tokenReview.Status.User.Username,
+				Groups: tokenReview.Status.User.Groups,
+				UID:    tokenReview.Status.User.UID,
+				Extra:  extra,
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if !sar.Status.Allowed {
+		return http.StatusForbidden, nil
+	}
+
+	return http.StatusOK, nil
+}
+
+// enqueueAllRootPolicies lists all the policies in pages and sends a generic event for each watched root policy. It
+// returns the number of root policies that were enqueued.
+func enqueueAllRootPolicies(
+	ctx context.Context, apiReader client.Reader, c client.Client, events chan<- event.GenericEvent,
+) (int, error) {
+	enqueued := 0
+
+	err := forEachRootPolicy(ctx, apiReader, c, func(policy *policiesv1.Policy) error {
+		forgetDesiredState(types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name})
+
+		select {
+		case events <- event.GenericEvent{Object: policy}:
+			enqueued++
+
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	return enqueued, err
+}
+
+// forEachRootPolicy lists all the policies in pages and calls the input function with each watched root policy. It
+// stops at the first error, which is returned.
+func forEachRootPolicy(
+	ctx context.Context, apiReader client.Reader, c client.Client, fn func(*policiesv1.Policy) error,
+) error {
+	continueToken := ""
+
+	for {
+		policyList := &policiesv1.PolicyList{}
+
+		err := apiReader.List(ctx, policyList, client.Limit(reconcileAllPageSize), client.Continue(continueToken))
+		if err != nil {
+			return err
+		}
+
+		for i := range policyList.Items {
+			isRoot, err := common.IsRootPolicy(c, &policyList.Items[i])
+			if err != nil {
+				return err
+			}
+
+			if !isRoot || !common.IsWatchedRootNamespace(policyList.Items[i].Namespace) {
+				continue
+			}
+
+			if err := fn(&policyList.Items[i]); err != nil {
+				return err
+			}
+		}
+
+		continueToken = policyList.GetContinue()
+		if continueToken == "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// fakeAuthClient returns a fake clientset where the token "valid-token" authenticates and the subject access review
+// returns the input allowed value.
+func fakeAuthClient(allowed bool) *k8sfake.Clientset {
+	authClient := k8sfake.NewSimpleClientset()
+
+	authClient.PrependReactor(
+		"create", "tokenreviews", func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+			//nolint:forcetypeassert
+			review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+			review.Status.Authenticated = review.Spec.Token == "valid-token"
+			review.Status.User.Username = "admin"
+
+			return true, review, nil
+		},
+	)
+
+	authClient.PrependReactor(
+		"create", "subjectaccessreviews", func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+			//nolint:forcetypeassert
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			review.Status.Allowed = allowed && review.Spec.User == "admin"
+
+			return true, review, nil
+		},
+	)
+
+	return authClient
+}
+
+func TestReconcileAllHandler(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	rootPolicy1 := fakeRootPolicy("policy1", "policies")
+	rootPolicy2 := fakeRootPolicy("policy2", "policies")
+	replicatedPolicy := fakeRootPolicy("policies.policy1", "managed1")
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rootPolicy1,
+		&rootPolicy2,
+		&replicatedPolicy,
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	).Build()
+
+	tests := map[string]struct {
+		token          string
+		allowed        bool
+		notLeader      bool
+		method         string
+		expectedStatus int
+		expectedEvents int
+	}{
+		"authorized":     {"valid-token", true, false, http.MethodPost, http.StatusOK, 2},
+		"not authorized": {"valid-token", false, false, http.MethodPost, http.StatusForbidden, 0},
+		"invalid token":  {"other-token", true, false, http.MethodPost, http.StatusUnauthorized, 0},
+		"no token":       {"", true, false, http.MethodPost, http.StatusUnauthorized, 0},
+		"wrong method":   {"valid-token", true, false, http.MethodGet, http.StatusMethodNotAllowed, 0},
+		"not the leader": {"valid-token", true, true, http.MethodPost, http.StatusServiceUnavailable, 0},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			elected := make(chan struct{})
+			if !test.notLeader {
+				close(elected)
+			}
+
+			events := make(chan event.GenericEvent, 10)
+			handler := ReconcileAllHandler(c, c, fakeAuthClient(test.allowed), elected, events)
+
+			req := httptest.NewRequest(test.method, ReconcileAllPath, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+
+			close(events)
+
+			enqueued := map[string]bool{}
+			for evt := range events {
+				enqueued[evt.Object.GetNamespace()+"/"+evt.Object.GetName()] = true
+			}
+
+			if len(enqueued) != test.expectedEvents {
+				t.Fatalf("expected %d enqueued policies, got %v", test.expectedEvents, enqueued)
+			}
+
+			if enqueued["managed1/policies.policy1"] {
+				t.Fatal("expected the replicated policy not to be enqueued")
+			}
+		})
+	}
+}
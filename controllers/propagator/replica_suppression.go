@@ -0,0 +1,127 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+// SuppressedReplicasAnnotation is set on a ManagedCluster so that the propagator doesn't create the replicated
+// policies of some root policies on the cluster, such as when one was deleted on purpose. The value is a comma
+// separated list of replicated policy names in the format `<root policy namespace>.<root policy name>`, or `*` for all
+// of them. Existing replicated policies are still updated, and the missing ones are created once the annotation is
+// removed.
+const SuppressedReplicasAnnotation = "policy.open-cluster-management.io/suppressed-replicas"
+
+// isSuppressedReplica returns true if the input value of the suppressed-replicas annotation includes the replicated
+// policy with the input name.
+func isSuppressedReplica(annotation string, replicatedName string) bool {
+	for _, entry := range strings.Split(annotation, ",") {
+		entry = strings.TrimSpace(entry)
+
+		if entry == "*" || entry == replicatedName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replicaSuppressed returns true if the ManagedCluster with the input name suppresses the creation of the replicated
+// policy of the root policy with the suppressed-replicas annotation.
+func (r *PolicyReconciler) replicaSuppressed(
+	ctx context.Context, rootPlc *policiesv1.Policy, clusterName string,
+) (bool, error) {
+	managedCluster := &clusterv1.ManagedCluster{}
+
+	err := r.Get(ctx, types.NamespacedName{Name: clusterName}, managedCluster)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	annotation := managedCluster.GetAnnotations()[SuppressedReplicasAnnotation]
+	if annotation == "" {
+		return false, nil
+	}
+
+	return isSuppressedReplica(annotation, common.FullNameForPolicy(rootPlc)), nil
+}
+
+// suppressedReplicaRequests returns the reconcile requests for the root policies whose replicated policies are
+// suppressed on the input ManagedCluster. The update of a ManagedCluster is mapped with both its old and new versions,
+// so this queues the root policies whose suppression was removed in order to create their replicated policies.
+func suppressedReplicaRequests(c client.Client, managedCluster client.Object) []reconcile.Request {
+	annotation := managedCluster.GetAnnotations()[SuppressedReplicasAnnotation]
+	if annotation == "" {
+		return nil
+	}
+
+	var result []reconcile.Request
+
+	allSuppressed := false
+
+	for _, entry := range strings.Split(annotation, ",") {
+		entry = strings.TrimSpace(entry)
+
+		if entry == "*" {
+			allSuppressed = true
+
+			continue
+		}
+
+		name, namespace, err := common.ParseRootPolicyLabel(entry)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: namespace,
+			Name:      name,
+		}})
+	}
+
+	if !allSuppressed {
+		return result
+	}
+
+	// The root policies placed on the cluster list it in their status even when their replicated policy is suppressed
+	policyList := &policiesv1.PolicyList{}
+
+	err := c.List(context.TODO(), policyList)
+	if err != nil {
+		log.Error(err, "Failed to list the policies", "cluster", managedCluster.GetName())
+
+		return result
+	}
+
+	for _, policy := range policyList.Items {
+		for _, clusterStatus := range policy.Status.Status {
+			if clusterStatus.ClusterName != managedCluster.GetName() {
+				continue
+			}
+
+			result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: policy.Namespace,
+				Name:      policy.Name,
+			}})
+
+			break
+		}
+	}
+
+	return result
+}
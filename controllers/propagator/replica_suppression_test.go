@@ -0,0 +1,136 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func TestIsSuppressedReplica(t *testing.T) {
+	tests := map[string]struct {
+		annotation string
+		expected   bool
+	}{
+		"Suppressed":     {"default.other, default.my-policy", true},
+		"All suppressed": {"*", true},
+		"Not suppressed": {"default.other", false},
+		"Empty":          {"", false},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if suppressed := isSuppressedReplica(test.annotation, "default.my-policy"); suppressed != test.expected {
+				t.Fatalf("Expected %v, got %v", test.expected, suppressed)
+			}
+		})
+	}
+}
+
+func TestHandleDecisionSuppressedReplica(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "default")
+	otherPolicy := fakeRootPolicy("other", "default")
+	managed1 := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "managed1",
+		Annotations: map[string]string{SuppressedReplicasAnnotation: "default.my-policy"},
+	}}
+	managed2 := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed2"}}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(managed1, managed2).Build()
+	reconciler := &PolicyReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+	handle := func(policy *policiesv1.Policy, cluster string) {
+		t.Helper()
+
+		decision := clusterDecision{Cluster: appsv1.PlacementDecision{ClusterName: cluster, ClusterNamespace: cluster}}
+
+		if _, err := reconciler.handleDecision(context.TODO(), policy, decision); err != nil {
+			t.Fatalf("Unexpected error handling the decision for %s: %v", cluster, err)
+		}
+	}
+
+	replicaExists := func(policy *policiesv1.Policy, cluster string) bool {
+		t.Helper()
+
+		key := types.NamespacedName{Namespace: cluster, Name: common.FullNameForPolicy(policy)}
+
+		err := c.Get(context.TODO(), key, &policiesv1.Policy{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			t.Fatalf("Failed to get the replicated policy: %v", err)
+		}
+
+		return err == nil
+	}
+
+	// The suppressed replicated policy isn't created on every reconcile, and the other replicas are unaffected
+	for i := 0; i < 2; i++ {
+		handle(&rootPolicy, "managed1")
+		handle(&rootPolicy, "managed2")
+		handle(&otherPolicy, "managed1")
+	}
+
+	if replicaExists(&rootPolicy, "managed1") {
+		t.Fatal("Expected the suppressed replicated policy to not be created")
+	}
+
+	if !replicaExists(&rootPolicy, "managed2") || !replicaExists(&otherPolicy, "managed1") {
+		t.Fatal("Expected the replicated policies that aren't suppressed to be created")
+	}
+
+	// Clearing the suppression queues the root policy, which then creates the replicated policy
+	cleared := managed1.DeepCopy()
+	cleared.Annotations = nil
+
+	if err := c.Update(context.TODO(), cleared); err != nil {
+		t.Fatalf("Failed to update the ManagedCluster: %v", err)
+	}
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	handler.EnqueueRequestsFromMapFunc(managedClusterMapper(c)).Update(
+		event.UpdateEvent{ObjectOld: managed1, ObjectNew: cleared}, queue,
+	)
+
+	requests := []string{}
+
+	for queue.Len() > 0 {
+		item, _ := queue.Get()
+		requests = append(requests, item.(reconcile.Request).String()) //nolint:forcetypeassert
+		queue.Done(item)
+	}
+
+	assert.Contains(t, requests, "default/my-policy")
+
+	handle(&rootPolicy, "managed1")
+
+	if !replicaExists(&rootPolicy, "managed1") {
+		t.Fatal("Expected the replicated policy to be created once the suppression was cleared")
+	}
+}
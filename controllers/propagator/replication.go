@@ -2,23 +2,146 @@ package propagator
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
 	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
 
 	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 	policiesv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
 	"open-cluster-management.io/governance-policy-propagator/controllers/common"
 )
 
-const argoCDCompareOptionsAnnotation = "argocd.argoproj.io/compare-options"
+const (
+	argoCDCompareOptionsAnnotation = "argocd.argoproj.io/compare-options"
+	// SpecHashAnnotation is set on replicated policies to the hash of the rendered spec written by the propagator. It
+	// is used to detect when the spec of a replicated policy was modified by something other than the propagator.
+	SpecHashAnnotation = "policy.open-cluster-management.io/spec-hash"
+)
+
+// ErrInvalidSpecPath is returned when a replicated policy spec path to ignore for drift detection is invalid.
+var ErrInvalidSpecPath = errors.New("invalid spec path")
+
+// ParseSpecPaths parses the input paths of replicated policy spec fields to ignore when comparing the desired and
+// actual replicated policy specs. A path is the dot-separated field names relative to the spec, such as
+// policy-templates.*.objectDefinition.spec.pruneObjectBehavior. A * matches every item of a list or every value of an
+// object, and a number matches the list item at that index. The last segment must be a field name.
+func ParseSpecPaths(rawPaths []string) ([][]string, error) {
+	paths := make([][]string, 0, len(rawPaths))
+
+	for _, rawPath := range rawPaths {
+		path := strings.Split(rawPath, ".")
+
+		for _, segment := range path {
+			if segment == "" {
+				return nil, fmt.Errorf("%w: %q has an empty field name", ErrInvalidSpecPath, rawPath)
+			}
+		}
+
+		if last := path[len(path)-1]; last == "*" {
+			return nil, fmt.Errorf("%w: %q must end with a field name", ErrInvalidSpecPath, rawPath)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// removeSpecPath deletes the field at the input path from the input value, which is the JSON representation of a
+// spec or part of it. Missing fields are ignored.
+func removeSpecPath(value interface{}, path []string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(typed, path[0])
+
+			return
+		}
+
+		if path[0] == "*" {
+			for _, child := range typed {
+				removeSpecPath(child, path[1:])
+			}
+
+			return
+		}
+
+		if child, ok := typed[path[0]]; ok {
+			removeSpecPath(child, path[1:])
+		}
+	case []interface{}:
+		if len(path) == 1 {
+			return
+		}
+
+		if path[0] == "*" {
+			for _, child := range typed {
+				removeSpecPath(child, path[1:])
+			}
 
-// equivalentReplicatedPolicies compares replicated policies. Returns true if they match.
-func equivalentReplicatedPolicies(plc1 *policiesv1.Policy, plc2 *policiesv1.Policy) bool {
+			return
+		}
+
+		if index, err := strconv.Atoi(path[0]); err == nil && index >= 0 && index < len(typed) {
+			removeSpecPath(typed[index], path[1:])
+		}
+	}
+}
+
+// specObject returns the JSON representation of the input replicated policy spec as a map.
+func specObject(spec policiesv1.PolicySpec) (map[string]interface{}, error) {
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+
+	err = json.Unmarshal(specJSON, &object)
+	if err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// canonicalSpec returns the representation of the input replicated policy spec used to compare it and to compute its
+// hash. When there are paths to ignore, it's the JSON representation of the spec without those fields. Otherwise, it's
+// the spec itself so that the hashes are the same as when no paths are configured.
+func canonicalSpec(spec policiesv1.PolicySpec, ignoredPaths [][]string) (interface{}, error) {
+	if len(ignoredPaths) == 0 {
+		return spec, nil
+	}
+
+	canonical, err := specObject(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range ignoredPaths {
+		removeSpecPath(canonical, path)
+	}
+
+	return canonical, nil
+}
+
+// equivalentReplicatedPolicies compares replicated policies. Returns true if they match. The spec fields at the input
+// paths are not compared.
+func equivalentReplicatedPolicies(plc1 *policiesv1.Policy, plc2 *policiesv1.Policy, ignoredPaths [][]string) bool {
 	// Compare annotations
 	if !equality.Semantic.DeepEqual(plc1.GetAnnotations(), plc2.GetAnnotations()) {
 		return false
@@ -29,8 +152,249 @@ func equivalentReplicatedPolicies(plc1 *policiesv1.Policy, plc2 *policiesv1.Poli
 		return false
 	}
 
-	// Compare the specs
-	return equality.Semantic.DeepEqual(plc1.Spec, plc2.Spec)
+	// Compare owner references, which depend on the owner reference strategy
+	if !equality.Semantic.DeepEqual(plc1.GetOwnerReferences(), plc2.GetOwnerReferences()) {
+		return false
+	}
+
+	if len(ignoredPaths) == 0 {
+		// Compare the specs
+		return equality.Semantic.DeepEqual(plc1.Spec, plc2.Spec)
+	}
+
+	// Compare the specs without the ignored fields. If they can't be converted, treat them as different so that the
+	// replicated policy is rewritten.
+	spec1, err := canonicalSpec(plc1.Spec, ignoredPaths)
+	if err != nil {
+		return false
+	}
+
+	spec2, err := canonicalSpec(plc2.Spec, ignoredPaths)
+	if err != nil {
+		return false
+	}
+
+	return equality.Semantic.DeepEqual(spec1, spec2)
+}
+
+// ignoredSpecPaths returns the paths of the replicated policy spec fields that are not compared, which are those
+// ignored for drift detection and those owned by the agents.
+func (r *PolicyReconciler) ignoredSpecPaths() [][]string {
+	if len(r.AgentOwnedPaths) == 0 {
+		return r.DriftIgnoredPaths
+	}
+
+	paths := make([][]string, 0, len(r.DriftIgnoredPaths)+len(r.AgentOwnedPaths))
+	paths = append(paths, r.DriftIgnoredPaths...)
+
+	return append(paths, r.AgentOwnedPaths...)
+}
+
+// copySpecPath sets the field at the input path in dst, which is the JSON representation of a spec or part of it, to
+// its value in src, which has the same structure. The field is deleted from dst when it's not set in src. List items
+// are matched by their index, and fields whose parents are missing from either are left as is.
+func copySpecPath(dst interface{}, src interface{}, path []string) {
+	switch typed := dst.(type) {
+	case map[string]interface{}:
+		srcMap, ok := src.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		if len(path) == 1 {
+			if value, ok := srcMap[path[0]]; ok {
+				typed[path[0]] = value
+			} else {
+				delete(typed, path[0])
+			}
+
+			return
+		}
+
+		if path[0] == "*" {
+			for key, child := range typed {
+				copySpecPath(child, srcMap[key], path[1:])
+			}
+
+			return
+		}
+
+		copySpecPath(typed[path[0]], srcMap[path[0]], path[1:])
+	case []interface{}:
+		srcList, ok := src.([]interface{})
+		if !ok || len(path) == 1 {
+			return
+		}
+
+		if path[0] == "*" {
+			for i := range typed {
+				if i < len(srcList) {
+					copySpecPath(typed[i], srcList[i], path[1:])
+				}
+			}
+
+			return
+		}
+
+		if index, err := strconv.Atoi(path[0]); err == nil && index >= 0 && index < len(typed) && index < len(srcList) {
+			copySpecPath(typed[index], srcList[index], path[1:])
+		}
+	}
+}
+
+// preserveSpecPaths sets the fields at the input paths in the spec of the desired replicated policy to their values
+// in the spec of the existing replicated policy, so that updating the replicated policy leaves them untouched.
+func preserveSpecPaths(desired *policiesv1.Policy, existing *policiesv1.Policy, paths [][]string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	desiredSpec, err := specObject(desired.Spec)
+	if err != nil {
+		return err
+	}
+
+	existingSpec, err := specObject(existing.Spec)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		copySpecPath(desiredSpec, existingSpec, path)
+	}
+
+	specJSON, err := json.Marshal(desiredSpec)
+	if err != nil {
+		return err
+	}
+
+	spec := policiesv1.PolicySpec{}
+
+	err = json.Unmarshal(specJSON, &spec)
+	if err != nil {
+		return err
+	}
+
+	desired.Spec = spec
+
+	return nil
+}
+
+// specHash returns the hex encoded SHA256 hash of the JSON representation of the input spec, such as a policy spec.
+func specHash(spec interface{}) (string, error) {
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(specJSON)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// setSpecHashAnnotation sets the SpecHashAnnotation on the input replicated policy based on its current spec, without
+// the fields at the input paths, which are the agent-owned fields that the propagator never updates. This must be
+// called after the hub templates are resolved so that the hash represents what is written to the cluster namespace.
+// The fields ignored for drift detection are hashed so that a change to them on the root policy is still propagated.
+func setSpecHashAnnotation(replicated *policiesv1.Policy, ignoredPaths [][]string) error {
+	spec, err := canonicalSpec(replicated.Spec, ignoredPaths)
+	if err != nil {
+		return err
+	}
+
+	hash, err := specHash(spec)
+	if err != nil {
+		return err
+	}
+
+	annotations := replicated.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[SpecHashAnnotation] = hash
+
+	replicated.SetAnnotations(annotations)
+
+	return nil
+}
+
+// checkReplicaSize returns an error wrapping errReplicaTooLarge if the JSON encoded spec of the input replicated
+// policy, after the hub templates are resolved, is larger than the MaxReplicaSpecSize. A warning event naming the size
+// is emitted on the root policy and the policy_replica_size_exceeded_total metric is incremented, so that this is a
+// clear failure rather than the API server rejecting the replicated policy or the hub storage growing with every
+// cluster.
+func (r *PolicyReconciler) checkReplicaSize(
+	rootPlc *policiesv1.Policy, decision appsv1.PlacementDecision, replicated *policiesv1.Policy,
+) error {
+	if r.MaxReplicaSpecSize <= 0 {
+		return nil
+	}
+
+	spec, err := json.Marshal(replicated.Spec)
+	if err != nil {
+		return err
+	}
+
+	if len(spec) <= r.MaxReplicaSpecSize {
+		return nil
+	}
+
+	log.Info(
+		"Not writing the replicated policy since its spec exceeds the maximum size",
+		"policyName", rootPlc.GetName(),
+		"policyNamespace", rootPlc.GetNamespace(),
+		"clusterNamespace", decision.ClusterNamespace,
+		"size", len(spec),
+		"maxSize", r.MaxReplicaSpecSize,
+	)
+
+	replicaSizeExceededMetric.WithLabelValues(rootPlc.GetName(), rootPlc.GetNamespace()).Inc()
+
+	r.Recorder.Event(rootPlc, "Warning", "PolicyPropagation",
+		fmt.Sprintf("Policy %s/%s can't be propagated to cluster %s/%s since the replicated policy spec is %d bytes, "+
+			"which exceeds the maximum of %d bytes", rootPlc.GetNamespace(), rootPlc.GetName(),
+			decision.ClusterNamespace, decision.ClusterName, len(spec), r.MaxReplicaSpecSize))
+
+	return fmt.Errorf("%w of %d bytes: the spec is %d bytes", errReplicaTooLarge, r.MaxReplicaSpecSize, len(spec))
+}
+
+// hasSpecDrift returns true if the spec of the existing replicated policy was modified outside of the propagator. This
+// is the case when its SpecHashAnnotation matches the one of the desired replicated policy, which means the root policy
+// is unchanged for this cluster, but its spec differs from the desired spec. Policies without the annotation are not
+// considered to have drifted. Status changes never cause drift since only the spec is compared, and changes to the
+// spec fields at the input paths are ignored. They can't be compared through the hash since it includes the fields
+// ignored for drift detection.
+func hasSpecDrift(desired *policiesv1.Policy, existing *policiesv1.Policy, ignoredPaths [][]string) (bool, error) {
+	expectedHash, ok := existing.GetAnnotations()[SpecHashAnnotation]
+	if !ok || expectedHash != desired.GetAnnotations()[SpecHashAnnotation] {
+		return false, nil
+	}
+
+	desiredSpec, err := canonicalSpec(desired.Spec, ignoredPaths)
+	if err != nil {
+		return false, err
+	}
+
+	existingSpec, err := canonicalSpec(existing.Spec, ignoredPaths)
+	if err != nil {
+		return false, err
+	}
+
+	return !equality.Semantic.DeepEqual(desiredSpec, existingSpec), nil
+}
+
+// isAdoptedReplica returns true if the input existing replicated policy is missing the labels that the propagator sets
+// on the replicated policies it creates, such as when it was created before the hub identifier was configured.
+// Updating it takes ownership of it. The caller must verify that it's a replica of the root policy first.
+func isAdoptedReplica(replicatedPlc *policiesv1.Policy) bool {
+	labels := replicatedPlc.GetLabels()
+
+	if labels[common.ClusterNameLabel] == "" || labels[common.ClusterNamespaceLabel] == "" {
+		return true
+	}
+
+	return common.HubID() != "" && labels[common.SourceHubLabel] == ""
 }
 
 // buildReplicatedPolicy constructs a replicated policy based on a root policy and a placementDecision.
@@ -49,6 +413,8 @@ func (r *PolicyReconciler) buildReplicatedPolicy(
 	replicated.SetResourceVersion("")
 	replicated.SetFinalizers(nil)
 	replicated.SetOwnerReferences(nil)
+	// The status of a replicated policy is only set by the status sync on the managed cluster
+	replicated.Status = policiesv1.PolicyStatus{}
 
 	labels := root.GetLabels()
 	if labels == nil {
@@ -70,6 +436,10 @@ func (r *PolicyReconciler) buildReplicatedPolicy(
 	labels[common.ClusterNamespaceLabel] = decision.ClusterNamespace
 	labels[common.RootPolicyLabel] = replicatedName
 
+	if hubID := common.HubID(); hubID != "" {
+		labels[common.SourceHubLabel] = hubID
+	}
+
 	replicated.SetLabels(labels)
 
 	annotations := replicated.GetAnnotations()
@@ -90,7 +460,20 @@ func (r *PolicyReconciler) buildReplicatedPolicy(
 	// Always set IgnoreExtraneous to avoid ArgoCD managing the replicated policy.
 	annotations[argoCDCompareOptionsAnnotation] = "IgnoreExtraneous"
 
+	// The trigger-update annotation is only for the root policy. Its value is recorded in a separate annotation on the
+	// replicated policy so that changing it rewrites every replicated policy, even if the resolved hub templates are
+	// unchanged.
+	if triggerUpdate := annotations[TriggerUpdateAnnotation]; triggerUpdate != "" {
+		annotations[LastTriggerUpdateAnnotation] = triggerUpdate
+	}
+
+	delete(annotations, TriggerUpdateAnnotation)
+
+	// The template validation error only applies to the root policy on the hub
+	delete(annotations, TemplateValidationErrorAnnotation)
+
 	replicated.SetAnnotations(annotations)
+	r.setReplicaOwner(root, replicated)
 
 	// Override the replicated policy remediationAction when it's selected to be enforced
 	if !strings.EqualFold(string(replicated.Spec.RemediationAction), string(policiesv1.Enforce)) {
@@ -100,7 +483,15 @@ func (r *PolicyReconciler) buildReplicatedPolicy(
 		}
 	}
 
-	var err error
+	// The cluster selector, the rollout strategy, and the decision group rollout only apply on the hub
+	replicated.Spec.ClusterSelector = nil
+	replicated.Spec.RolloutStrategy = nil
+	replicated.Spec.DecisionGroupRollout = nil
+
+	err := r.applyClusterOverrides(replicated, decision.ClusterName)
+	if err != nil {
+		return replicated, err
+	}
 
 	replicated.Spec.Dependencies, err = r.canonicalizeDependencies(replicated.Spec.Dependencies, root.Namespace)
 	if err != nil {
@@ -171,14 +562,14 @@ func (r *PolicyReconciler) canonicalizeDependencies(
 						Kind:       policiesv1.Kind,
 						APIVersion: policiesv1.GroupVersion.String(),
 					},
-					Name:       dep.Namespace + "." + string(plc),
+					Name:       common.ReplicatedPolicyName(dep.Namespace, string(plc)),
 					Namespace:  "",
 					Compliance: dep.Compliance,
 				})
 			}
 		} else if depIsPolicy(dep) {
-			split := strings.Split(dep.Name, ".")
-			if len(split) == 2 { // assume it's already in the correct <namespace>.<name> format
+			// Assume it's already in the correct <namespace>.<name> format, where the name may also have a `.`
+			if _, _, ok := common.ParseReplicatedName(dep.Name); ok {
 				deps = append(deps, dep)
 			} else {
 				if dep.Namespace == "" {
@@ -186,7 +577,7 @@ func (r *PolicyReconciler) canonicalizeDependencies(
 					dep.Namespace = defaultNamespace
 				}
 
-				dep.Name = dep.Namespace + "." + dep.Name
+				dep.Name = common.ReplicatedPolicyName(dep.Namespace, dep.Name)
 				dep.Namespace = ""
 
 				deps = append(deps, dep)
@@ -242,3 +633,84 @@ func getPolicySetDependencies(root *policiesv1.Policy) map[k8sdepwatches.ObjectI
 
 	return policySetIDs
 }
+
+// applyClusterOverrides patches the policy templates of the input replicated policy with the cluster overrides that
+// match the labels of the input managed cluster, in the order they are defined. The cluster overrides are then removed
+// from the replicated policy since they only apply on the hub. Since this is done before the spec hash is calculated,
+// a replicated policy with the overrides applied isn't considered to have drifted.
+func (r *PolicyReconciler) applyClusterOverrides(replicated *policiesv1.Policy, clusterName string) error {
+	overrides := replicated.Spec.ClusterOverrides
+	replicated.Spec.ClusterOverrides = nil
+
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	managedCluster := &clusterv1.ManagedCluster{}
+
+	// If the ManagedCluster doesn't exist, only the overrides with an empty cluster selector apply
+	err := r.Get(context.TODO(), types.NamespacedName{Name: clusterName}, managedCluster)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	clusterLabels := labels.Set(managedCluster.GetLabels())
+
+	for i, override := range overrides {
+		selector, err := v1.LabelSelectorAsSelector(&override.ClusterSelector)
+		if err != nil {
+			return fmt.Errorf("the cluster selector of the cluster override at index %d is invalid: %w", i, err)
+		}
+
+		if !selector.Matches(clusterLabels) {
+			continue
+		}
+
+		for _, templateOverride := range override.PolicyTemplates {
+			for _, template := range replicated.Spec.PolicyTemplates {
+				if getTemplateName(template) != templateOverride.Name {
+					continue
+				}
+
+				patched, err := jsonpatch.MergePatch(template.ObjectDefinition.Raw, templateOverride.Patch.Raw)
+				if err != nil {
+					return fmt.Errorf(
+						"failed to apply the cluster override at index %d to the policy template %s: %w",
+						i, templateOverride.Name, err,
+					)
+				}
+
+				template.ObjectDefinition.Raw = patched
+				template.ObjectDefinition.Object = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// managedClusterObjID returns the identifier of the ManagedCluster to watch when the replicated policy depends on its
+// labels.
+func managedClusterObjID(clusterName string) k8sdepwatches.ObjectIdentifier {
+	return k8sdepwatches.ObjectIdentifier{
+		Group:     clusterv1.GroupName,
+		Version:   clusterv1.GroupVersion.Version,
+		Kind:      "ManagedCluster",
+		Namespace: "",
+		Name:      clusterName,
+	}
+}
+
+// getTemplateName returns the metadata.name of the objectDefinition of the input policy template. An empty string is
+// returned if it can't be determined.
+func getTemplateName(template *policiesv1.PolicyTemplate) string {
+	objectDefinition := struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}{}
+
+	_ = json.Unmarshal(template.ObjectDefinition.Raw, &objectDefinition)
+
+	return objectDefinition.Metadata.Name
+}
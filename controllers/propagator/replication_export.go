@@ -0,0 +1,206 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;delete
+
+const (
+	// ReplicationSpecLabel is set on every replication spec ConfigMap exported by the propagator.
+	ReplicationSpecLabel string = common.APIGroup + "/replication-spec"
+	// ReplicationSpecDataKey is the ConfigMap data key holding the JSON encoded ReplicationSpec.
+	ReplicationSpecDataKey string = "replication-spec.json"
+)
+
+// errAPIReaderRequired is returned when the replication spec is exported without an APIReader to read the ConfigMaps.
+var errAPIReaderRequired = errors.New("the APIReader is required to export the replication spec")
+
+// ReplicationSpec is what a regional hub needs to replicate a root policy to its managed clusters itself, rather than
+// receiving the rendered replicated policies. The hub templates are resolved by the regional hub.
+type ReplicationSpec struct {
+	// The root policy to replicate. The regional hub reads its spec from its own copy of the root policy.
+	RootPolicy ReplicationSpecRootPolicy `json:"rootPolicy"`
+	// The identifier of the hub that exported the spec, from the --hub-id flag
+	SourceHub string `json:"sourceHub,omitempty"`
+	// The clusters to replicate the root policy to, sorted by cluster name
+	Clusters []ReplicationSpecCluster `json:"clusters"`
+}
+
+// ReplicationSpecRootPolicy references the root policy of a ReplicationSpec.
+type ReplicationSpecRootPolicy struct {
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Generation int64  `json:"generation"`
+}
+
+// ReplicationSpecCluster is a cluster that the root policy of a ReplicationSpec is replicated to.
+type ReplicationSpecCluster struct {
+	ClusterName      string `json:"clusterName"`
+	ClusterNamespace string `json:"clusterNamespace"`
+	// The overrides of the placement binding that selected the cluster
+	Overrides *policiesv1.BindingOverrides `json:"overrides,omitempty"`
+}
+
+// ReplicationSpecName returns the name of the replication spec ConfigMap for the input root policy name.
+func ReplicationSpecName(policyName string) string {
+	return policyName + "-replication-spec"
+}
+
+// buildReplicationSpec returns the ReplicationSpec of the input root policy for the input cluster decisions.
+func buildReplicationSpec(instance *policiesv1.Policy, decisions []clusterDecision) *ReplicationSpec {
+	spec := &ReplicationSpec{
+		RootPolicy: ReplicationSpecRootPolicy{
+			Namespace:  instance.Namespace,
+			Name:       instance.Name,
+			Generation: instance.Generation,
+		},
+		SourceHub: common.HubID(),
+		Clusters:  make([]ReplicationSpecCluster, 0, len(decisions)),
+	}
+
+	for _, decision := range decisions {
+		cluster := ReplicationSpecCluster{
+			ClusterName:      decision.Cluster.ClusterName,
+			ClusterNamespace: decision.Cluster.ClusterNamespace,
+		}
+
+		if decision.PolicyOverrides != (policiesv1.BindingOverrides{}) {
+			overrides := decision.PolicyOverrides
+			cluster.Overrides = &overrides
+		}
+
+		spec.Clusters = append(spec.Clusters, cluster)
+	}
+
+	sort.Slice(spec.Clusters, func(i, j int) bool {
+		return spec.Clusters[i].ClusterName < spec.Clusters[j].ClusterName
+	})
+
+	return spec
+}
+
+// exportReplicationSpec creates or updates the replication spec ConfigMap of the input root policy, next to it, for
+// the input cluster decisions. The ConfigMap is owned by the root policy so that it's deleted along with it. The
+// ConfigMap is read with the APIReader, which is required in export mode, since the manager cache doesn't have the
+// replication spec ConfigMaps, and it's only written when its spec differs, so that a ConfigMap modified or deleted
+// outside of the propagator is corrected.
+func (r *PolicyReconciler) exportReplicationSpec(
+	ctx context.Context, instance *policiesv1.Policy, decisions []clusterDecision,
+) error {
+	log := log.WithValues("policyName", instance.GetName(), "policyNamespace", instance.GetNamespace())
+
+	specJSON, err := json.Marshal(buildReplicationSpec(instance, decisions))
+	if err != nil {
+		return err
+	}
+
+	if r.APIReader == nil {
+		return errAPIReaderRequired
+	}
+
+	cmKey := types.NamespacedName{Namespace: instance.Namespace, Name: ReplicationSpecName(instance.Name)}
+	data := map[string]string{ReplicationSpecDataKey: string(specJSON)}
+	configMap := &corev1.ConfigMap{}
+
+	err = r.APIReader.Get(ctx, cmKey, configMap)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cmKey.Name,
+				Namespace: cmKey.Namespace,
+				Labels:    map[string]string{ReplicationSpecLabel: "true"},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(instance, policiesv1.GroupVersion.WithKind(policiesv1.Kind)),
+				},
+			},
+			Data: data,
+		}
+
+		log.Info("Creating the replication spec ConfigMap", "configMap", cmKey.Name, "clusters", len(decisions))
+
+		err = r.Create(ctx, configMap)
+	} else if configMap.Data[ReplicationSpecDataKey] != string(specJSON) {
+		log.Info("Updating the replication spec ConfigMap", "configMap", cmKey.Name, "clusters", len(decisions))
+
+		configMap.Data = data
+
+		err = r.Update(ctx, configMap)
+	} else {
+		log.V(2).Info("The replication spec is already exported")
+	}
+
+	return err
+}
+
+// deleteReplicationSpec deletes the replication spec ConfigMap of the input root policy that was deleted, rather than
+// waiting for the garbage collector, so that the regional hub stops replicating it right away.
+func (r *PolicyReconciler) deleteReplicationSpec(ctx context.Context, rootPolicy types.NamespacedName) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ReplicationSpecName(rootPolicy.Name), Namespace: rootPolicy.Namespace},
+	}
+
+	err := r.Delete(ctx, configMap)
+	if err == nil {
+		log.Info(
+			"Deleted the replication spec ConfigMap of the deleted root policy",
+			"configMap", configMap.Name, "namespace", configMap.Namespace,
+		)
+	}
+
+	return client.IgnoreNotFound(err)
+}
+
+// DeleteReplicationSpecs returns a runnable that deletes the replication spec ConfigMaps when the propagator starts,
+// so that the regional hubs stop replicating the root policies from stale specs once the replication spec export is
+// turned off. The apiReader should not be backed by the cache since the manager cache only has the compliance
+// ConfigMaps.
+func DeleteReplicationSpecs(apiReader client.Reader, c client.Client) manager.RunnableFunc {
+	return func(ctx context.Context) error {
+		configMapList := &corev1.ConfigMapList{}
+
+		err := apiReader.List(ctx, configMapList, client.MatchingLabels{ReplicationSpecLabel: "true"})
+		if err != nil {
+			log.Error(err, "Failed to list the replication spec ConfigMaps to delete")
+
+			return nil
+		}
+
+		for i := range configMapList.Items {
+			configMap := &configMapList.Items[i]
+
+			log.Info(
+				"Deleting the replication spec ConfigMap since the replication spec export is disabled",
+				"configMap", configMap.Name, "namespace", configMap.Namespace,
+			)
+
+			if err := c.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+				log.Error(
+					err, "Failed to delete the replication spec ConfigMap",
+					"configMap", configMap.Name, "namespace", configMap.Namespace,
+				)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestExportReplicationSpec(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, corev1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&rootPolicy).Build()
+	reconciler := &PolicyReconciler{Client: c, APIReader: c, ExportReplicationSpec: true}
+
+	decisions := []clusterDecision{
+		{Cluster: appsv1.PlacementDecision{ClusterName: "zapdos", ClusterNamespace: "zapdos"}},
+		{
+			Cluster:         appsv1.PlacementDecision{ClusterName: "articuno", ClusterNamespace: "articuno"},
+			PolicyOverrides: policiesv1.BindingOverrides{RemediationAction: "enforce"},
+		},
+	}
+
+	getSpec := func() (*corev1.ConfigMap, ReplicationSpec) {
+		t.Helper()
+
+		configMap := &corev1.ConfigMap{}
+
+		err := c.Get(context.TODO(), types.NamespacedName{
+			Namespace: "policies", Name: ReplicationSpecName("my-policy"),
+		}, configMap)
+		if err != nil {
+			t.Fatalf("Failed to get the replication spec ConfigMap: %v", err)
+		}
+
+		spec := ReplicationSpec{}
+
+		if err := json.Unmarshal([]byte(configMap.Data[ReplicationSpecDataKey]), &spec); err != nil {
+			t.Fatalf("Failed to unmarshal the replication spec: %v", err)
+		}
+
+		return configMap, spec
+	}
+
+	if err := reconciler.exportReplicationSpec(context.TODO(), &rootPolicy, decisions); err != nil {
+		t.Fatalf("Unexpected error exporting the replication spec: %v", err)
+	}
+
+	configMap, spec := getSpec()
+
+	if configMap.Labels[ReplicationSpecLabel] != "true" || len(configMap.OwnerReferences) != 1 {
+		t.Fatalf("Expected the ConfigMap to be labeled and owned by the root policy, got %+v", configMap.ObjectMeta)
+	}
+
+	if spec.RootPolicy.Namespace != "policies" || spec.RootPolicy.Name != "my-policy" {
+		t.Fatalf("Expected the spec to reference the root policy, got %+v", spec.RootPolicy)
+	}
+
+	if len(spec.Clusters) != 2 || spec.Clusters[0].ClusterName != "articuno" ||
+		spec.Clusters[0].Overrides == nil || spec.Clusters[0].Overrides.RemediationAction != "enforce" ||
+		spec.Clusters[1].Overrides != nil {
+		t.Fatalf("Expected the sorted clusters with their overrides, got %+v", spec.Clusters)
+	}
+
+	// An unchanged spec isn't written again
+	if err := reconciler.exportReplicationSpec(context.TODO(), &rootPolicy, decisions); err != nil {
+		t.Fatalf("Unexpected error exporting the replication spec: %v", err)
+	}
+
+	if unchanged, _ := getSpec(); unchanged.ResourceVersion != configMap.ResourceVersion {
+		t.Fatalf("Expected the ConfigMap not to be updated")
+	}
+
+	if err := reconciler.exportReplicationSpec(context.TODO(), &rootPolicy, decisions[:1]); err != nil {
+		t.Fatalf("Unexpected error exporting the replication spec: %v", err)
+	}
+
+	if _, spec := getSpec(); len(spec.Clusters) != 1 || spec.Clusters[0].ClusterName != "zapdos" {
+		t.Fatalf("Expected the spec to be updated to the remaining cluster, got %+v", spec.Clusters)
+	}
+
+	// A spec modified outside of the propagator is corrected even though the desired spec is unchanged
+	configMap, _ = getSpec()
+	configMap.Data[ReplicationSpecDataKey] = `{"clusters":[]}`
+
+	if err := c.Update(context.TODO(), configMap); err != nil {
+		t.Fatalf("Failed to update the replication spec ConfigMap: %v", err)
+	}
+
+	if err := reconciler.exportReplicationSpec(context.TODO(), &rootPolicy, decisions[:1]); err != nil {
+		t.Fatalf("Unexpected error exporting the replication spec: %v", err)
+	}
+
+	if _, spec := getSpec(); len(spec.Clusters) != 1 || spec.Clusters[0].ClusterName != "zapdos" {
+		t.Fatalf("Expected the modified spec to be corrected, got %+v", spec.Clusters)
+	}
+
+	// The spec isn't exported without the APIReader rather than being read from the cache
+	reconciler.APIReader = nil
+
+	err := reconciler.exportReplicationSpec(context.TODO(), &rootPolicy, decisions)
+	if !errors.Is(err, errAPIReaderRequired) {
+		t.Fatalf("Expected the export to require the APIReader, got %v", err)
+	}
+}
+
+func TestReconcileExportReplicationSpec(t *testing.T) {
+	root := types.NamespacedName{Namespace: "policies", Name: "my-policy"}
+	defer forgetDesiredState(root)
+
+	reconciler, c := desiredStateReconciler(t, 2, false)
+	request := reconcile.Request{NamespacedName: root}
+
+	// The replicated policies are created before the replication spec export is enabled
+	if _, err := reconciler.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("Unexpected error reconciling the policy: %v", err)
+	}
+
+	reconciler.ExportReplicationSpec = true
+	reconciler.APIReader = c
+
+	if _, err := reconciler.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("Unexpected error reconciling the policy: %v", err)
+	}
+
+	if err := c.Get(context.TODO(), types.NamespacedName{
+		Namespace: "policies", Name: ReplicationSpecName("my-policy"),
+	}, &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("Failed to get the replication spec ConfigMap: %v", err)
+	}
+
+	// The replicated policies of the placed clusters are kept and the clusters are still listed in the status
+	for _, namespace := range []string{"cluster1", "cluster2"} {
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "policies.my-policy"},
+			&policiesv1.Policy{})
+		if err != nil {
+			t.Fatalf("Expected the replicated policy in %s to be kept: %v", namespace, err)
+		}
+	}
+
+	rootPolicy := &policiesv1.Policy{}
+	if err := c.Get(context.TODO(), root, rootPolicy); err != nil {
+		t.Fatalf("Failed to get the root policy: %v", err)
+	}
+
+	if len(rootPolicy.Status.Status) != 2 {
+		t.Fatalf("Expected the placed clusters in the root policy status, got %+v", rootPolicy.Status.Status)
+	}
+}
+
+func TestDeleteReplicationSpec(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, corev1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	replicationSpec := func(namespace, policyName string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      ReplicationSpecName(policyName),
+			Namespace: namespace,
+			Labels:    map[string]string{ReplicationSpecLabel: "true"},
+		}}
+	}
+
+	otherConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "policies"}}
+
+	exists := func(c client.Client, configMap *corev1.ConfigMap) bool {
+		t.Helper()
+
+		err := c.Get(context.TODO(), client.ObjectKeyFromObject(configMap), &corev1.ConfigMap{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			t.Fatalf("Failed to get the ConfigMap: %v", err)
+		}
+
+		return err == nil
+	}
+
+	t.Run("Root policy deleted", func(t *testing.T) {
+		deleted := replicationSpec("policies", "deleted-policy")
+		kept := replicationSpec("policies", "my-policy")
+		c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(deleted, kept).Build()
+		reconciler := &PolicyReconciler{Client: c, APIReader: c, ExportReplicationSpec: true}
+
+		rootKey := types.NamespacedName{Namespace: "policies", Name: "deleted-policy"}
+
+		if err := reconciler.deleteReplicationSpec(context.TODO(), rootKey); err != nil {
+			t.Fatalf("Unexpected error deleting the replication spec: %v", err)
+		}
+
+		if exists(c, deleted) || !exists(c, kept) {
+			t.Fatal("Expected only the replication spec of the deleted root policy to be deleted")
+		}
+
+		// Deleting it again, such as when the reconcile is retried, isn't an error
+		if err := reconciler.deleteReplicationSpec(context.TODO(), rootKey); err != nil {
+			t.Fatalf("Unexpected error deleting the missing replication spec: %v", err)
+		}
+	})
+
+	t.Run("Export disabled", func(t *testing.T) {
+		specs := []*corev1.ConfigMap{replicationSpec("policies", "my-policy"), replicationSpec("other", "my-policy")}
+		c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(specs[0], specs[1], otherConfigMap).Build()
+
+		if err := DeleteReplicationSpecs(c, c)(context.TODO()); err != nil {
+			t.Fatalf("Unexpected error deleting the replication specs: %v", err)
+		}
+
+		if exists(c, specs[0]) || exists(c, specs[1]) || !exists(c, otherConfigMap) {
+			t.Fatal("Expected only the labeled replication spec ConfigMaps to be deleted")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package propagator
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -8,6 +9,8 @@ import (
 	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -109,7 +112,7 @@ func TestEquivalentReplicatedPolicies(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			if equivalentReplicatedPolicies(basePolicy, test.comparePlc) != test.expected {
+			if equivalentReplicatedPolicies(basePolicy, test.comparePlc, nil) != test.expected {
 				if test.expected {
 					t.Fatalf("Expected policies to be equivalent: %+v, %+v", basePolicy, test.comparePlc)
 				} else {
@@ -120,6 +123,41 @@ func TestEquivalentReplicatedPolicies(t *testing.T) {
 	}
 }
 
+func TestBuildReplicatedPolicyHubOnlyFields(t *testing.T) {
+	decision := clusterDecision{Cluster: appsv1.PlacementDecision{ClusterName: "cluster1", ClusterNamespace: "cluster1"}}
+	reconciler := &PolicyReconciler{}
+
+	var previous *policiesv1.Policy
+
+	for _, maxClusters := range []int32{1, 5} {
+		rootPolicy := fakeBasicPolicy("my-policy", "policies")
+		rootPolicy.Spec.ClusterSelector = &v1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}
+		rootPolicy.Spec.RolloutStrategy = &policiesv1.RolloutStrategy{MaxClusters: maxClusters}
+		rootPolicy.Spec.DecisionGroupRollout = &policiesv1.DecisionGroupRollout{}
+
+		replicated, err := reconciler.buildReplicatedPolicy(rootPolicy, decision)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if replicated.Spec.ClusterSelector != nil || replicated.Spec.RolloutStrategy != nil ||
+			replicated.Spec.DecisionGroupRollout != nil {
+			t.Fatalf("Expected the hub only fields to not be replicated, got %+v", replicated.Spec)
+		}
+
+		if err := setSpecHashAnnotation(replicated, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Changing the rollout strategy of the root policy doesn't change the replicated policies
+		if previous != nil && !equivalentReplicatedPolicies(previous, replicated, nil) {
+			t.Fatalf("Expected the replicated policies to be equivalent: %+v, %+v", previous, replicated)
+		}
+
+		previous = replicated
+	}
+}
+
 func TestCanonicalizeDependencies(t *testing.T) {
 	depPol := func(name, namespace, compliance string) policiesv1.PolicyDependency {
 		return fakeDependencyFromObj(fakeBasicPolicy(name, namespace), compliance)
@@ -204,10 +242,12 @@ func TestCanonicalizeDependencies(t *testing.T) {
 			input: []policiesv1.PolicyDependency{
 				depPol("legendary.lugia", "", "Terminating"),
 				depPol("legendary.ho-oh", "", "Terminating"),
+				depPol("legendary.mr.mime", "", "Terminating"),
 			},
 			want: []policiesv1.PolicyDependency{
 				depPol("legendary.lugia", "", "Terminating"),
 				depPol("legendary.ho-oh", "", "Terminating"),
+				depPol("legendary.mr.mime", "", "Terminating"),
 			},
 		},
 		"unusual dependency should be unchanged": {
@@ -311,3 +351,222 @@ func TestGetPolicySetDependencies(t *testing.T) {
 		})
 	}
 }
+
+func TestHasSpecDrift(t *testing.T) {
+	replicated := fakeBasicPolicy("policies.my-policy", "managed1")
+
+	modified := replicated.DeepCopy()
+	modified.Spec.RemediationAction = policiesv1.Enforce
+
+	drift, err := hasSpecDrift(replicated, modified, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if drift {
+		t.Fatal("expected no drift when the spec hash annotation is not set")
+	}
+
+	if err := setSpecHashAnnotation(replicated, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	drift, err = hasSpecDrift(replicated, replicated.DeepCopy(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if drift {
+		t.Fatal("expected no drift right after setting the spec hash annotation")
+	}
+
+	withStatus := replicated.DeepCopy()
+	withStatus.Status.ComplianceState = policiesv1.NonCompliant
+
+	drift, err = hasSpecDrift(replicated, withStatus, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if drift {
+		t.Fatal("expected a status change not to be considered drift")
+	}
+
+	modified = replicated.DeepCopy()
+	modified.Spec.RemediationAction = policiesv1.Enforce
+
+	drift, err = hasSpecDrift(replicated, modified, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !drift {
+		t.Fatal("expected a spec change to be considered drift")
+	}
+}
+
+func TestParseSpecPaths(t *testing.T) {
+	paths, err := ParseSpecPaths([]string{"policy-templates.*.objectDefinition.spec.severity", "disabled"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]string{{"policy-templates", "*", "objectDefinition", "spec", "severity"}, {"disabled"}}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected the paths %v, got %v", expected, paths)
+	}
+
+	for _, rawPath := range []string{"", "policy-templates..objectDefinition", "policy-templates.*"} {
+		if _, err := ParseSpecPaths([]string{rawPath}); !errors.Is(err, ErrInvalidSpecPath) {
+			t.Fatalf("expected the path %q to be invalid, got %v", rawPath, err)
+		}
+	}
+}
+
+func TestHasSpecDriftIgnoredPaths(t *testing.T) {
+	ignoredPaths, err := ParseSpecPaths([]string{"policy-templates.*.objectDefinition.spec.evaluationInterval"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	replicated := fakeBasicPolicy("policies.my-policy", "managed1")
+	replicated.Spec.PolicyTemplates = []*policiesv1.PolicyTemplate{{ObjectDefinition: k8sruntime.RawExtension{
+		Raw: []byte(`{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},"spec":{"severity":"low"}}`),
+	}}}
+
+	if err := setSpecHashAnnotation(replicated, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		objectDefinition string
+		expected         bool
+	}{
+		"ignored field set": {
+			`{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},` +
+				`"spec":{"evaluationInterval":{"compliant":"10m"},"severity":"low"}}`,
+			false,
+		},
+		"other field changed": {
+			`{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},"spec":{"severity":"high"}}`,
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			modified := replicated.DeepCopy()
+			modified.Spec.PolicyTemplates[0].ObjectDefinition.Raw = []byte(test.objectDefinition)
+
+			drift, err := hasSpecDrift(replicated, modified, ignoredPaths)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if drift != test.expected {
+				t.Fatalf("expected drift to be %v, got %v", test.expected, drift)
+			}
+
+			if equivalent := equivalentReplicatedPolicies(replicated, modified, ignoredPaths); equivalent == test.expected {
+				t.Fatalf("expected the policies to be equivalent: %v", !test.expected)
+			}
+		})
+	}
+}
+
+func TestApplyClusterOverrides(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := clusterv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("unexpected error building the scheme: %v", err)
+	}
+
+	largeCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{Name: "large", Labels: map[string]string{"size": "large", "env": "prod"}},
+	}
+	smallCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{Name: "small", Labels: map[string]string{"size": "small"}},
+	}
+
+	r := &PolicyReconciler{
+		Client: fakeClient.NewClientBuilder().WithScheme(testscheme).WithObjects(largeCluster, smallCluster).Build(),
+	}
+
+	override := func(selector map[string]string, patch string) policiesv1.ClusterOverride {
+		return policiesv1.ClusterOverride{
+			ClusterSelector: v1.LabelSelector{MatchLabels: selector},
+			PolicyTemplates: []policiesv1.PolicyTemplateOverride{
+				{Name: "limits", Patch: k8sruntime.RawExtension{Raw: []byte(patch)}},
+			},
+		}
+	}
+
+	root := fakeBasicPolicy("my-policy", "policies")
+	root.Spec.PolicyTemplates = []*policiesv1.PolicyTemplate{
+		{ObjectDefinition: k8sruntime.RawExtension{
+			Raw: []byte(`{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},"spec":{"max":10,"min":1}}`),
+		}},
+		{ObjectDefinition: k8sruntime.RawExtension{
+			Raw: []byte(`{"kind":"ConfigurationPolicy","metadata":{"name":"other"},"spec":{"max":10}}`),
+		}},
+	}
+	root.Spec.ClusterOverrides = []policiesv1.ClusterOverride{
+		override(map[string]string{"size": "large"}, `{"spec":{"max":100,"min":5}}`),
+		// The last matching override takes precedence
+		override(map[string]string{"env": "prod"}, `{"spec":{"max":50}}`),
+	}
+
+	tests := map[string]struct {
+		cluster  string
+		expected string
+	}{
+		"multiple overrides match": {"large", `{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},` +
+			`"spec":{"max":50,"min":5}}`},
+		"no overrides match": {"small", `{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},` +
+			`"spec":{"max":10,"min":1}}`},
+		"cluster not found": {"missing", `{"kind":"ConfigurationPolicy","metadata":{"name":"limits"},` +
+			`"spec":{"max":10,"min":1}}`},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			replicated := root.DeepCopy()
+
+			if err := r.applyClusterOverrides(replicated, test.cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if replicated.Spec.ClusterOverrides != nil {
+				t.Fatal("expected the cluster overrides to be removed from the replicated policy")
+			}
+
+			if string(replicated.Spec.PolicyTemplates[0].ObjectDefinition.Raw) != test.expected {
+				t.Fatalf(
+					"expected the template %s, got %s",
+					test.expected, replicated.Spec.PolicyTemplates[0].ObjectDefinition.Raw,
+				)
+			}
+
+			if !reflect.DeepEqual(replicated.Spec.PolicyTemplates[1], root.Spec.PolicyTemplates[1]) {
+				t.Fatal("expected the template without an override to be unchanged")
+			}
+
+			// A replicated policy with the overrides applied is not considered to have drifted
+			if err := setSpecHashAnnotation(replicated, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			drift, err := hasSpecDrift(replicated, replicated.DeepCopy(), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if drift {
+				t.Fatal("expected no drift for the overridden replicated policy")
+			}
+		})
+	}
+}
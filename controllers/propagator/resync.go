@@ -0,0 +1,107 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// PeriodicResync returns a runnable that sends a generic event for every root policy on the input channel once per
+// period, so that the replicated policies are verified against their root policies even when a change to them was
+// missed by the watches. The events of each period are spread evenly over the period rather than sent at once, and
+// the workqueue limits the concurrent reconciles to the configured concurrency, so this doesn't cause a burst of
+// requests to the API server on large fleets. The first resync starts one period after the runnable starts since the
+// controller already reconciles all root policies on startup.
+//
+// The apiReader should not be backed by the cache so that the policies can be retrieved in pages. The cached client
+// is used to determine if a policy is a replicated policy.
+func PeriodicResync(
+	apiReader client.Reader, c client.Client, events chan<- event.GenericEvent, period time.Duration,
+) manager.RunnableFunc {
+	return func(ctx context.Context) error {
+		log := log.WithName("periodic-resync")
+		next := time.Now().Add(period)
+
+		for {
+			select {
+			case <-time.After(time.Until(next)):
+			case <-ctx.Done():
+				return nil
+			}
+
+			start := time.Now()
+			next = start.Add(period)
+
+			enqueued, err := resyncRootPolicies(ctx, apiReader, c, events, period)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				log.Error(err, "Failed to enqueue the root policies for the periodic resync", "enqueued", enqueued)
+			} else {
+				log.V(1).Info(
+					"Enqueued the root policies for the periodic resync",
+					"enqueued", enqueued, "duration", time.Since(start),
+				)
+			}
+		}
+	}
+}
+
+// resyncRootPolicies sends a generic event for every root policy on the input channel, spacing the events evenly over
+// the input period. It returns the number of root policies that were enqueued.
+func resyncRootPolicies(
+	ctx context.Context, apiReader client.Reader, c client.Client, events chan<- event.GenericEvent,
+	period time.Duration,
+) (int, error) {
+	// Only the namespaced names are kept since the policies may change before they are enqueued
+	policies := []*policiesv1.Policy{}
+
+	err := forEachRootPolicy(ctx, apiReader, c, func(policy *policiesv1.Policy) error {
+		policies = append(policies, &policiesv1.Policy{
+			ObjectMeta: metav1.ObjectMeta{Name: policy.Name, Namespace: policy.Namespace},
+		})
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(policies) == 0 {
+		return 0, nil
+	}
+
+	interval := period / time.Duration(len(policies))
+
+	for i, policy := range policies {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return i, ctx.Err()
+			}
+		}
+
+		// The resync corrects changes that were missed, so it must not be skipped by the desired state cache
+		forgetDesiredState(types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name})
+
+		select {
+		case events <- event.GenericEvent{Object: policy}:
+		case <-ctx.Done():
+			return i, ctx.Err()
+		}
+	}
+
+	return len(policies), nil
+}
@@ -0,0 +1,85 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestPeriodicResync(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	rootPolicy1 := fakeRootPolicy("policy1", "policies")
+	rootPolicy2 := fakeRootPolicy("policy2", "policies")
+	replicatedPolicy := fakeRootPolicy("policies.policy1", "managed1")
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rootPolicy1,
+		&rootPolicy2,
+		&replicatedPolicy,
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	).Build()
+
+	const period = 200 * time.Millisecond
+
+	events := make(chan event.GenericEvent, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	started := time.Now()
+
+	go func() {
+		done <- PeriodicResync(c, c, events, period)(ctx)
+	}()
+
+	enqueued := map[string]time.Time{}
+
+	for len(enqueued) < 2 {
+		select {
+		case evt := <-events:
+			enqueued[evt.Object.GetNamespace()+"/"+evt.Object.GetName()] = time.Now()
+		case <-time.After(5 * period):
+			t.Fatalf("expected two enqueued policies, got %v", enqueued)
+		}
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error from the periodic resync: %v", err)
+	}
+
+	first, ok1 := enqueued["policies/policy1"]
+	second, ok2 := enqueued["policies/policy2"]
+
+	if !ok1 || !ok2 {
+		t.Fatalf("expected only the root policies to be enqueued, got %v", enqueued)
+	}
+
+	if first.Sub(started) < period {
+		t.Fatalf("expected the first resync to start after one period, started after %v", first.Sub(started))
+	}
+
+	// The two events are spread over the period, so they are half a period apart
+	if second.Sub(first) < period/2 {
+		t.Fatalf("expected the enqueues to be spread over the period, got %v between them", second.Sub(first))
+	}
+}
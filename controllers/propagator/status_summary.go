@@ -0,0 +1,143 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+// StatusSummaryPath is the path on the metrics server that returns a JSON summary of the propagation status.
+const StatusSummaryPath = "/propagation-status"
+
+// lastReconcileTimes maps the namespaced name of a root policy to the time.Time its last reconcile finished.
+var lastReconcileTimes sync.Map
+
+// propagationStatus is the response of the StatusSummaryHandler.
+type propagationStatus struct {
+	RootPolicies            int                    `json:"rootPolicies"`
+	ReplicatedPolicies      int                    `json:"replicatedPolicies"`
+	NonCompliantByNamespace map[string]int         `json:"nonCompliantByNamespace"`
+	LastReconcileTimes      map[string]metav1.Time `json:"lastReconcileTimes"`
+}
+
+// recordReconcileTime sets the last reconcile time of the root policy to now.
+func recordReconcileTime(rootPolicy types.NamespacedName) {
+	lastReconcileTimes.Store(rootPolicy.String(), time.Now())
+}
+
+// forgetReconcileTime removes the last reconcile time of a root policy that was deleted.
+func forgetReconcileTime(rootPolicy types.NamespacedName) {
+	lastReconcileTimes.Delete(rootPolicy.String())
+}
+
+// StatusSummaryHandler returns a read-only HTTP handler that responds with a JSON summary of the root and replicated
+// policies. The summary is built from the input client, which should be backed by the cache so that the endpoint is
+// cheap to poll. Callers must provide a bearer token of a user who is allowed to list policies at the cluster scope.
+func StatusSummaryHandler(c client.Client, authClient kubernetes.Interface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		log := log.WithName("status-summary")
+
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		authStatus, err := authorize(req.Context(), authClient, req, "list")
+		if err != nil {
+			log.Error(err, "Failed to authorize the request")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if authStatus != http.StatusOK {
+			w.WriteHeader(authStatus)
+
+			return
+		}
+
+		status, err := getPropagationStatus(req.Context(), c)
+		if err != nil {
+			log.Error(err, "Failed to build the propagation status summary")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
+
+// getPropagationStatus lists the policies and summarizes them. The NonCompliant counts are of the root policies by
+// namespace.
+func getPropagationStatus(ctx context.Context, c client.Client) (*propagationStatus, error) {
+	policyList := &policiesv1.PolicyList{}
+
+	err := c.List(ctx, policyList)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &propagationStatus{
+		NonCompliantByNamespace: map[string]int{},
+		LastReconcileTimes:      map[string]metav1.Time{},
+	}
+
+	// Cache whether a namespace is a cluster namespace since most policies share a few namespaces
+	clusterNamespaces := map[string]bool{}
+
+	for i := range policyList.Items {
+		policy := &policyList.Items[i]
+
+		// The policies handled by another propagator sharing the hub are not summarized
+		if !common.IsWatchedPolicy(policy) {
+			continue
+		}
+
+		inClusterNs, ok := clusterNamespaces[policy.Namespace]
+		if !ok {
+			inClusterNs, err = common.IsInClusterNamespace(c, policy.Namespace)
+			if err != nil {
+				return nil, err
+			}
+
+			clusterNamespaces[policy.Namespace] = inClusterNs
+		}
+
+		if inClusterNs {
+			status.ReplicatedPolicies++
+
+			continue
+		}
+
+		status.RootPolicies++
+
+		if policy.Status.ComplianceState == policiesv1.NonCompliant {
+			status.NonCompliantByNamespace[policy.Namespace]++
+		}
+	}
+
+	lastReconcileTimes.Range(func(key, value any) bool {
+		//nolint:forcetypeassert
+		status.LastReconcileTimes[key.(string)] = metav1.NewTime(value.(time.Time))
+
+		return true
+	})
+
+	return status, nil
+}
@@ -0,0 +1,104 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestStatusSummaryHandler(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := policiesv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to set up the scheme: %v", err)
+	}
+
+	rootPolicy1 := fakeRootPolicy("policy1", "policies")
+	rootPolicy1.Status.ComplianceState = policiesv1.NonCompliant
+	rootPolicy2 := fakeRootPolicy("policy2", "policies")
+	rootPolicy2.Status.ComplianceState = policiesv1.Compliant
+	rootPolicy3 := fakeRootPolicy("policy3", "other")
+	rootPolicy3.Status.ComplianceState = policiesv1.NonCompliant
+	replicatedPolicy1 := fakeRootPolicy("policies.policy1", "managed1")
+	replicatedPolicy1.Status.ComplianceState = policiesv1.NonCompliant
+	replicatedPolicy2 := fakeRootPolicy("policies.policy2", "managed1")
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rootPolicy1,
+		&rootPolicy2,
+		&rootPolicy3,
+		&replicatedPolicy1,
+		&replicatedPolicy2,
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managed1"}},
+	).Build()
+
+	recordReconcileTime(types.NamespacedName{Namespace: "policies", Name: "policy1"})
+	defer forgetReconcileTime(types.NamespacedName{Namespace: "policies", Name: "policy1"})
+
+	tests := map[string]struct {
+		token          string
+		allowed        bool
+		method         string
+		expectedStatus int
+	}{
+		"authorized":     {"valid-token", true, http.MethodGet, http.StatusOK},
+		"not authorized": {"valid-token", false, http.MethodGet, http.StatusForbidden},
+		"no token":       {"", true, http.MethodGet, http.StatusUnauthorized},
+		"wrong method":   {"valid-token", true, http.MethodPost, http.StatusMethodNotAllowed},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, StatusSummaryPath, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			StatusSummaryHandler(c, fakeAuthClient(test.allowed)).ServeHTTP(recorder, req)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			status := propagationStatus{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+				t.Fatalf("failed to unmarshal the response: %v", err)
+			}
+
+			if status.RootPolicies != 3 || status.ReplicatedPolicies != 2 {
+				t.Fatalf("expected 3 root and 2 replicated policies, got %+v", status)
+			}
+
+			if len(status.NonCompliantByNamespace) != 2 || status.NonCompliantByNamespace["policies"] != 1 ||
+				status.NonCompliantByNamespace["other"] != 1 {
+				t.Fatalf("unexpected NonCompliant counts: %v", status.NonCompliantByNamespace)
+			}
+
+			if _, ok := status.LastReconcileTimes["policies/policy1"]; !ok {
+				t.Fatalf("expected a last reconcile time for policies/policy1, got %v", status.LastReconcileTimes)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// SubjectWebhookPath is the path on the webhook server of the SubjectValidator.
+const SubjectWebhookPath = "/validate-placementbinding-subjects"
+
+// SubjectValidator is an admission handler for the creations and updates of PlacementBindings. A subject must be a
+// Policy or a PolicySet, or of one of the PropagatedKinds, so that a typo in a subject is denied rather than silently
+// bound to nothing. The PlacementBinding CRD only allows the Policy and PolicySet kinds, so this replaces that
+// validation when it's relaxed for the PropagatedKinds.
+type SubjectValidator struct {
+	// PropagatedKinds are the kinds that the ObjectReconcilers propagate directly, as returned by ParsePropagatedKinds.
+	PropagatedKinds []schema.GroupVersionKind
+}
+
+// blank assignment to verify that SubjectValidator implements admission.Handler
+var _ admission.Handler = &SubjectValidator{}
+
+// Handle denies a PlacementBinding with a subject that is neither a Policy, a PolicySet, nor of a propagated kind.
+func (v *SubjectValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	if req.Kind.Group != policiesv1.GroupVersion.Group || req.Kind.Kind != "PlacementBinding" {
+		return admission.Allowed("")
+	}
+
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	pb := &policiesv1.PlacementBinding{}
+
+	if err := json.Unmarshal(req.Object.Raw, pb); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var invalid []string
+
+	for _, subject := range pb.Subjects {
+		if !v.subjectAllowed(subject) {
+			invalid = append(invalid, fmt.Sprintf("%s/%s %s", subject.APIGroup, subject.Kind, subject.Name))
+		}
+	}
+
+	if len(invalid) == 0 {
+		return admission.Allowed("")
+	}
+
+	return admission.Denied(fmt.Sprintf(
+		"the subjects %s must be a Policy or a PolicySet in the %s API group, or of a kind set in --propagated-kinds",
+		strings.Join(invalid, ", "), policiesv1.GroupVersion.Group,
+	))
+}
+
+// subjectAllowed returns true if the input subject is a Policy, a PolicySet, or of one of the propagated kinds.
+func (v *SubjectValidator) subjectAllowed(subject policiesv1.Subject) bool {
+	if subject.APIGroup == policiesv1.GroupVersion.Group &&
+		(subject.Kind == policiesv1.Kind || subject.Kind == policiesv1.PolicySetKind) {
+		return true
+	}
+
+	for _, gvk := range v.PropagatedKinds {
+		if subject.APIGroup == gvk.Group && subject.Kind == gvk.Kind {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestSubjectValidator(t *testing.T) {
+	propagatedKinds, err := ParsePropagatedKinds([]string{"ConfigurationPolicy.v1.policy.open-cluster-management.io"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	subject := func(apiGroup, kind string) policiesv1.Subject {
+		return policiesv1.Subject{APIGroup: apiGroup, Kind: kind, Name: "my-subject"}
+	}
+
+	tests := map[string]struct {
+		subjects        []policiesv1.Subject
+		propagatedKinds bool
+		expectedAllowed bool
+	}{
+		"Policy and PolicySet": {
+			subjects: []policiesv1.Subject{
+				subject(policiesv1.GroupVersion.Group, policiesv1.Kind),
+				subject(policiesv1.GroupVersion.Group, policiesv1.PolicySetKind),
+			},
+			expectedAllowed: true,
+		},
+		"Propagated kind": {
+			subjects:        []policiesv1.Subject{subject(policiesv1.GroupVersion.Group, "ConfigurationPolicy")},
+			propagatedKinds: true,
+			expectedAllowed: true,
+		},
+		"Kind that isn't propagated": {
+			subjects: []policiesv1.Subject{subject(policiesv1.GroupVersion.Group, "ConfigurationPolicy")},
+		},
+		"Misspelled kind": {
+			subjects: []policiesv1.Subject{
+				subject(policiesv1.GroupVersion.Group, policiesv1.Kind),
+				subject(policiesv1.GroupVersion.Group, "Polcy"),
+			},
+			propagatedKinds: true,
+		},
+		"Propagated kind in another API group": {
+			subjects:        []policiesv1.Subject{subject("example.com", "ConfigurationPolicy")},
+			propagatedKinds: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			pb := &policiesv1.PlacementBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-pb", Namespace: "policies"},
+				Subjects:   test.subjects,
+			}
+
+			raw, err := json.Marshal(pb)
+			if err != nil {
+				t.Fatalf("Failed to marshal the PlacementBinding: %v", err)
+			}
+
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{
+					Group: policiesv1.GroupVersion.Group, Version: "v1", Kind: "PlacementBinding",
+				},
+				Operation: admissionv1.Create,
+				Namespace: pb.Namespace,
+				Name:      pb.Name,
+			}}
+			req.Object.Raw = raw
+
+			validator := &SubjectValidator{}
+			if test.propagatedKinds {
+				validator.PropagatedKinds = propagatedKinds
+			}
+
+			resp := validator.Handle(context.TODO(), req)
+
+			if resp.Allowed != test.expectedAllowed {
+				t.Fatalf("Expected the request to be allowed to be %v, got %+v", test.expectedAllowed, resp.Result)
+			}
+
+			if !resp.Allowed && !strings.Contains(string(resp.Result.Reason), "my-subject") {
+				t.Fatalf("Expected the message to name the invalid subject, got %q", resp.Result.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+const (
+	// ReplicaTeardownFinalizer is set on the root policies when the coordinated teardown is enabled, so that their
+	// replicated policies are only deleted once the agents on the managed clusters acknowledge the teardown or the
+	// ReplicaTeardownTimeout passes.
+	ReplicaTeardownFinalizer = "policy.open-cluster-management.io/replica-teardown"
+	// TeardownRequestedAnnotation is set on a replicated policy to the RFC 3339 time the deletion of its root policy
+	// was requested. The agent on the managed cluster sets the TeardownCompleteCondition on the replicated policy once
+	// it has cleaned up the resources enforced by the policy.
+	TeardownRequestedAnnotation = "policy.open-cluster-management.io/teardown-requested"
+)
+
+// ensureTeardownFinalizer adds the ReplicaTeardownFinalizer to the input root policy if the coordinated teardown is
+// enabled and the root policy doesn't have it yet.
+func (r *PolicyReconciler) ensureTeardownFinalizer(ctx context.Context, instance *policiesv1.Policy) error {
+	if r.ReplicaTeardownTimeout <= 0 || controllerutil.ContainsFinalizer(instance, ReplicaTeardownFinalizer) {
+		return nil
+	}
+
+	patch := client.MergeFromWithOptions(instance.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+	controllerutil.AddFinalizer(instance, ReplicaTeardownFinalizer)
+
+	return r.Patch(ctx, instance, patch)
+}
+
+// teardownAcknowledged returns true if the agent on the managed cluster has cleaned up the resources enforced by the
+// input replicated policy.
+func teardownAcknowledged(replicatedPlc *policiesv1.Policy) bool {
+	return meta.IsStatusConditionTrue(replicatedPlc.Status.Conditions, policiesv1.TeardownCompleteCondition)
+}
+
+// handleRootPolicyDeletion tears down the replicated policies of the input root policy, which is being deleted and has
+// the ReplicaTeardownFinalizer. Each replicated policy is marked with the TeardownRequestedAnnotation and is deleted
+// once the agent on its managed cluster acknowledges the teardown. Once every replicated policy is acknowledged, or the
+// ReplicaTeardownTimeout passes since the deletion was requested, the remaining replicated policies are deleted and
+// the finalizer is removed. The returned duration is when the root policy should be reconciled again if the teardown
+// is still pending.
+func (r *PolicyReconciler) handleRootPolicyDeletion(
+	ctx context.Context, instance *policiesv1.Policy, now time.Time,
+) (time.Duration, error) {
+	log := log.WithValues("policyName", instance.GetName(), "policyNamespace", instance.GetNamespace())
+
+	replicatedPlcList := &policiesv1.PolicyList{}
+
+	err := r.List(ctx, replicatedPlcList, client.MatchingLabels(common.LabelsForRootPolicy(instance)))
+	if err != nil {
+		return 0, err
+	}
+
+	requestedAt := instance.GetDeletionTimestamp().Time
+	remaining := requestedAt.Add(r.ReplicaTeardownTimeout).Sub(now)
+	replicatedPlcs := withoutOtherHubReplicas(replicatedPlcList.Items)
+	pending := []string{}
+
+	var teardownErrs []error
+
+	for i := range replicatedPlcs {
+		replicatedPlc := &replicatedPlcs[i]
+
+		// The root policy's own namespace is never a cluster namespace
+		if replicatedPlc.Namespace == instance.Namespace {
+			continue
+		}
+
+		if teardownAcknowledged(replicatedPlc) {
+			log.V(1).Info(
+				"The teardown of the replicated policy was acknowledged, deleting it",
+				"namespace", replicatedPlc.Namespace, "name", replicatedPlc.Name,
+			)
+
+			if err := r.deletePolicy(replicatedPlc); err != nil {
+				teardownErrs = append(teardownErrs, err)
+			}
+
+			continue
+		}
+
+		pending = append(pending, replicatedPlc.Namespace)
+
+		if remaining <= 0 || replicatedPlc.GetAnnotations()[TeardownRequestedAnnotation] != "" {
+			continue
+		}
+
+		patch := client.MergeFrom(replicatedPlc.DeepCopy())
+
+		annotations := replicatedPlc.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[TeardownRequestedAnnotation] = requestedAt.UTC().Format(time.RFC3339)
+		replicatedPlc.SetAnnotations(annotations)
+
+		err := r.Patch(ctx, replicatedPlc, patch)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			teardownErrs = append(teardownErrs, fmt.Errorf(
+				"failed to request the teardown of the replicated policy %s/%s: %w",
+				replicatedPlc.Namespace, replicatedPlc.Name, err,
+			))
+		}
+	}
+
+	if len(teardownErrs) != 0 {
+		return 0, errors.Join(teardownErrs...)
+	}
+
+	if len(pending) != 0 {
+		sort.Strings(pending)
+
+		if remaining > 0 {
+			log.Info(
+				"Waiting for the managed clusters to acknowledge the teardown of the replicated policies",
+				"clusterNamespaces", pending, "requeueAfter", remaining.String(),
+			)
+
+			return remaining, nil
+		}
+
+		log.Info(
+			"The teardown timed out, so deleting the replicated policies of the clusters that didn't acknowledge it",
+			"clusterNamespaces", pending, "timeout", r.ReplicaTeardownTimeout.String(),
+		)
+
+		r.Recorder.Event(instance, "Warning", "PolicyPropagation",
+			fmt.Sprintf("The teardown of policy %s/%s wasn't acknowledged by the clusters %s within %s",
+				instance.GetNamespace(), instance.GetName(), strings.Join(pending, ","), r.ReplicaTeardownTimeout))
+	}
+
+	err = r.cleanUpPolicy(instance)
+	if err != nil {
+		return 0, err
+	}
+
+	patch := client.MergeFromWithOptions(instance.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+	controllerutil.RemoveFinalizer(instance, ReplicaTeardownFinalizer)
+
+	err = r.Patch(ctx, instance, patch)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return 0, err
+	}
+
+	log.Info("The replicated policies were torn down")
+
+	return 0, nil
+}
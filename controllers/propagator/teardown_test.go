@@ -0,0 +1,238 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// setupTeardown returns a reconciler with a root policy that is being deleted since the input time, and its replicated
+// policies on cluster1 and cluster2.
+func setupTeardown(t *testing.T, deletedAt time.Time) (*PolicyReconciler, client.Client, *record.FakeRecorder) {
+	t.Helper()
+
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	rootPolicy.SetGroupVersionKind(policiesv1.GroupVersion.WithKind(policiesv1.Kind))
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &PolicyReconciler{
+		Recorder: recorder,
+		DynamicWatcher: &fakeDynamicWatcher{
+			watched: map[k8sdepwatches.ObjectIdentifier][]k8sdepwatches.ObjectIdentifier{},
+		},
+		ReplicaTeardownTimeout: 10 * time.Minute,
+	}
+
+	objects := []client.Object{&rootPolicy}
+
+	for _, decision := range fakePlacementDecisions(2) {
+		replicated, err := reconciler.buildReplicatedPolicy(&rootPolicy, clusterDecision{Cluster: decision})
+		if err != nil {
+			t.Fatalf("Unexpected error building the replicated policy: %v", err)
+		}
+
+		objects = append(objects, replicated)
+	}
+
+	rootPolicy.SetFinalizers([]string{ReplicaTeardownFinalizer})
+	rootPolicy.SetDeletionTimestamp(&metav1.Time{Time: deletedAt})
+
+	reconciler.Client = fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objects...).Build()
+
+	return reconciler, reconciler.Client, recorder
+}
+
+func getTeardownPolicy(t *testing.T, c client.Client, namespace, name string) *policiesv1.Policy {
+	t.Helper()
+
+	policy := &policiesv1.Policy{}
+
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, policy)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		t.Fatalf("Failed to get the policy %s/%s: %v", namespace, name, err)
+	}
+
+	return policy
+}
+
+func acknowledgeTeardown(t *testing.T, c client.Client, cluster string) {
+	t.Helper()
+
+	replicated := getTeardownPolicy(t, c, cluster, "policies.my-policy")
+
+	meta.SetStatusCondition(&replicated.Status.Conditions, metav1.Condition{
+		Type:    policiesv1.TeardownCompleteCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ResourcesCleanedUp",
+		Message: "the enforced resources were cleaned up",
+	})
+
+	if err := c.Status().Update(context.TODO(), replicated); err != nil {
+		t.Fatalf("Failed to acknowledge the teardown on %s: %v", cluster, err)
+	}
+}
+
+func TestHandleRootPolicyDeletionAcknowledged(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	reconciler, c, _ := setupTeardown(t, deletedAt)
+
+	teardown := func() time.Duration {
+		t.Helper()
+
+		rootPolicy := getTeardownPolicy(t, c, "policies", "my-policy")
+		if rootPolicy == nil {
+			t.Fatal("Expected the root policy to exist until the teardown completes")
+		}
+
+		requeueAfter, err := reconciler.handleRootPolicyDeletion(
+			context.TODO(), rootPolicy, deletedAt.Add(time.Minute),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error tearing down the replicated policies: %v", err)
+		}
+
+		return requeueAfter
+	}
+
+	if requeueAfter := teardown(); requeueAfter != 9*time.Minute {
+		t.Fatalf("Expected to wait until the teardown times out in 9m, got %s", requeueAfter)
+	}
+
+	for _, cluster := range []string{"cluster1", "cluster2"} {
+		replicated := getTeardownPolicy(t, c, cluster, "policies.my-policy")
+		if replicated == nil {
+			t.Fatalf("Expected the replicated policy on %s to be kept until the teardown is acknowledged", cluster)
+		}
+
+		marker := replicated.Annotations[TeardownRequestedAnnotation]
+		if marker != deletedAt.UTC().Format(time.RFC3339) {
+			t.Fatalf("Expected the replicated policy on %s to be marked for teardown, got %q", cluster, marker)
+		}
+	}
+
+	acknowledgeTeardown(t, c, "cluster1")
+
+	if requeueAfter := teardown(); requeueAfter == 0 {
+		t.Fatal("Expected to keep waiting for cluster2 to acknowledge the teardown")
+	}
+
+	if getTeardownPolicy(t, c, "cluster1", "policies.my-policy") != nil {
+		t.Fatal("Expected the acknowledged replicated policy on cluster1 to be deleted")
+	}
+
+	if getTeardownPolicy(t, c, "cluster2", "policies.my-policy") == nil {
+		t.Fatal("Expected the replicated policy on cluster2 to be kept until it acknowledges the teardown")
+	}
+
+	acknowledgeTeardown(t, c, "cluster2")
+
+	if requeueAfter := teardown(); requeueAfter != 0 {
+		t.Fatalf("Expected the teardown to be complete, got a requeue after %s", requeueAfter)
+	}
+
+	if getTeardownPolicy(t, c, "cluster2", "policies.my-policy") != nil {
+		t.Fatal("Expected the acknowledged replicated policy on cluster2 to be deleted")
+	}
+
+	rootPolicy := getTeardownPolicy(t, c, "policies", "my-policy")
+	if rootPolicy != nil && controllerutil.ContainsFinalizer(rootPolicy, ReplicaTeardownFinalizer) {
+		t.Fatal("Expected the finalizer to be removed once the teardown completes")
+	}
+}
+
+func TestHandleRootPolicyDeletionTimeout(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	reconciler, c, recorder := setupTeardown(t, deletedAt)
+
+	acknowledgeTeardown(t, c, "cluster1")
+
+	rootPolicy := getTeardownPolicy(t, c, "policies", "my-policy")
+
+	requeueAfter, err := reconciler.handleRootPolicyDeletion(
+		context.TODO(), rootPolicy, deletedAt.Add(11*time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error tearing down the replicated policies: %v", err)
+	}
+
+	if requeueAfter != 0 {
+		t.Fatalf("Expected the teardown to complete once it timed out, got a requeue after %s", requeueAfter)
+	}
+
+	for _, cluster := range []string{"cluster1", "cluster2"} {
+		if getTeardownPolicy(t, c, cluster, "policies.my-policy") != nil {
+			t.Fatalf("Expected the replicated policy on %s to be deleted after the timeout", cluster)
+		}
+	}
+
+	rootPolicy = getTeardownPolicy(t, c, "policies", "my-policy")
+	if rootPolicy != nil && controllerutil.ContainsFinalizer(rootPolicy, ReplicaTeardownFinalizer) {
+		t.Fatal("Expected the finalizer to be removed after the timeout")
+	}
+
+	// Only the cluster that didn't acknowledge the teardown is reported
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "cluster2") || strings.Contains(event, "cluster1") {
+			t.Fatalf("Expected the event to only name cluster2, got %q", event)
+		}
+	default:
+		t.Fatal("Expected a warning event for the clusters that didn't acknowledge the teardown")
+	}
+}
+
+func TestEnsureTeardownFinalizer(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&rootPolicy).Build()
+	reconciler := &PolicyReconciler{Client: c}
+
+	if err := reconciler.ensureTeardownFinalizer(context.TODO(), &rootPolicy); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if controllerutil.ContainsFinalizer(getTeardownPolicy(t, c, "policies", "my-policy"), ReplicaTeardownFinalizer) {
+		t.Fatal("Expected no finalizer when the coordinated teardown is disabled")
+	}
+
+	reconciler.ReplicaTeardownTimeout = time.Minute
+
+	if err := reconciler.ensureTeardownFinalizer(context.TODO(), &rootPolicy); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !controllerutil.ContainsFinalizer(getTeardownPolicy(t, c, "policies", "my-policy"), ReplicaTeardownFinalizer) {
+		t.Fatal("Expected the finalizer to be added when the coordinated teardown is enabled")
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/yaml"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+var (
+	// ErrInvalidAllowedTemplateKind is returned by ParseAllowedTemplateKinds when a kind isn't in the expected format.
+	ErrInvalidAllowedTemplateKind = errors.New("invalid allowed template kind")
+	// errKindNotAllowed is returned when a replicated policy isn't written because one of its policy templates, or an
+	// object embedded in one, has a kind that isn't in the AllowedTemplateKinds.
+	errKindNotAllowed = errors.New("the kind is not allowed in policy templates")
+)
+
+// anyKindOrVersion matches any kind or version in an allowed template kind.
+const anyKindOrVersion = "*"
+
+// ParseAllowedTemplateKinds parses the input kinds in the Kind.version.group format, such as
+// ConfigurationPolicy.v1.policy.open-cluster-management.io, or the Kind.version format for the core group, such as
+// ConfigMap.v1. The kind or the version can be * to match any kind or version in the group. nil is returned when there
+// are no input kinds so that all kinds are allowed.
+func ParseAllowedTemplateKinds(rawKinds []string) ([]schema.GroupVersionKind, error) {
+	if len(rawKinds) == 0 {
+		return nil, nil
+	}
+
+	gvks := make([]schema.GroupVersionKind, 0, len(rawKinds))
+
+	for _, rawKind := range rawKinds {
+		parts := strings.SplitN(rawKind, ".", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf(
+				"%w: %q is not in the format Kind.version.group or Kind.version", ErrInvalidAllowedTemplateKind, rawKind,
+			)
+		}
+
+		gvk := schema.GroupVersionKind{Kind: parts[0], Version: parts[1]}
+		if len(parts) == 3 {
+			gvk.Group = parts[2]
+		}
+
+		gvks = append(gvks, gvk)
+	}
+
+	return gvks, nil
+}
+
+// kindAllowed returns true if the input kind matches one of the input allowed kinds.
+func kindAllowed(allowed []schema.GroupVersionKind, gvk schema.GroupVersionKind) bool {
+	for _, allowedGVK := range allowed {
+		if allowedGVK.Group == gvk.Group &&
+			(allowedGVK.Version == anyKindOrVersion || allowedGVK.Version == gvk.Version) &&
+			(allowedGVK.Kind == anyKindOrVersion || allowedGVK.Kind == gvk.Kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// embeddedObjects returns the objects in the object-templates or object-templates-raw of the input policy template if
+// it's a ConfigurationPolicy. An error is returned if the object-templates-raw can't be parsed, such as when it has
+// managed cluster templates that generate the objects, since their kinds can't be determined on the hub.
+func embeddedObjects(policyT *unstructured.Unstructured) ([]map[string]interface{}, error) {
+	if policyT.GetKind() != "ConfigurationPolicy" || policyT.GroupVersionKind().Group != policiesv1.GroupVersion.Group {
+		return nil, nil
+	}
+
+	var objects []map[string]interface{}
+
+	objectTemplates, _, _ := unstructured.NestedSlice(policyT.Object, "spec", "object-templates")
+	for _, objectTemplate := range objectTemplates {
+		objectTemplate, ok := objectTemplate.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if object, ok := objectTemplate["objectDefinition"].(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+
+	rawObjectTemplates, _, _ := unstructured.NestedString(policyT.Object, "spec", "object-templates-raw")
+	if rawObjectTemplates == "" {
+		return objects, nil
+	}
+
+	var parsedTemplates []map[string]interface{}
+
+	if err := yaml.Unmarshal([]byte(rawObjectTemplates), &parsedTemplates); err != nil {
+		return nil, fmt.Errorf(
+			"the object-templates-raw of the ConfigurationPolicy %s can't be parsed to verify the kinds of its objects",
+			policyT.GetName(),
+		)
+	}
+
+	for _, objectTemplate := range parsedTemplates {
+		if object, ok := objectTemplate["objectDefinition"].(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
+
+// disallowedObjectKind returns the kind of the input object, or of an object embedded in it if it's a
+// ConfigurationPolicy, that isn't in the input allowed kinds. An empty kind with an error is returned when the embedded
+// objects can't be verified.
+func disallowedObjectKind(
+	allowed []schema.GroupVersionKind, obj *unstructured.Unstructured,
+) (schema.GroupVersionKind, error) {
+	if !kindAllowed(allowed, obj.GroupVersionKind()) {
+		return obj.GroupVersionKind(), nil
+	}
+
+	objects, err := embeddedObjects(obj)
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+
+	for _, object := range objects {
+		gvk := (&unstructured.Unstructured{Object: object}).GroupVersionKind()
+
+		if !kindAllowed(allowed, gvk) {
+			return gvk, nil
+		}
+	}
+
+	return schema.GroupVersionKind{}, nil
+}
+
+// disallowedTemplateKind returns the first kind in the policy templates of the input replicated policy, including the
+// objects embedded in its ConfigurationPolicies, that isn't in the input allowed kinds. An empty kind with an error is
+// returned when the embedded objects can't be verified.
+func disallowedTemplateKind(
+	allowed []schema.GroupVersionKind, replicated *policiesv1.Policy,
+) (schema.GroupVersionKind, error) {
+	for _, policyT := range replicated.Spec.PolicyTemplates {
+		if policyT == nil {
+			continue
+		}
+
+		templateObj := &unstructured.Unstructured{}
+
+		if err := templateObj.UnmarshalJSON(policyT.ObjectDefinition.Raw); err != nil {
+			return schema.GroupVersionKind{}, err
+		}
+
+		gvk, err := disallowedObjectKind(allowed, templateObj)
+		if err != nil || !gvk.Empty() {
+			return gvk, err
+		}
+	}
+
+	return schema.GroupVersionKind{}, nil
+}
+
+// templateKindRejection returns the kind label of the policy_template_kind_rejected_total metric and the error
+// wrapping errKindNotAllowed for the result of disallowedTemplateKind or disallowedObjectKind.
+func templateKindRejection(gvk schema.GroupVersionKind, err error) (string, error) {
+	if err != nil {
+		return "unknown", fmt.Errorf("%w: %s", errKindNotAllowed, err.Error())
+	}
+
+	return gvk.GroupKind().String(), fmt.Errorf(
+		"%w: the policy templates have the %s kind, which isn't allowed", errKindNotAllowed, gvk.String(),
+	)
+}
+
+// replicaKindDisallowed returns true if the input existing replicated policy has a kind that isn't in the
+// AllowedTemplateKinds, such as when it was written before the kind was disallowed, or has embedded objects whose
+// kinds can't be verified.
+func (r *PolicyReconciler) replicaKindDisallowed(replicated *policiesv1.Policy) bool {
+	if r.AllowedTemplateKinds == nil {
+		return false
+	}
+
+	gvk, err := disallowedTemplateKind(r.AllowedTemplateKinds, replicated)
+
+	return err != nil || !gvk.Empty()
+}
+
+// checkTemplateKinds returns an error wrapping errKindNotAllowed if the policy templates of the input replicated policy
+// have a kind that isn't in the AllowedTemplateKinds, in which case a warning event is emitted on the root policy and
+// the policy_template_kind_rejected_total metric is incremented. This does nothing if AllowedTemplateKinds isn't set.
+func (r *PolicyReconciler) checkTemplateKinds(
+	rootPlc *policiesv1.Policy, decision appsv1.PlacementDecision, replicated *policiesv1.Policy,
+) error {
+	if r.AllowedTemplateKinds == nil {
+		return nil
+	}
+
+	gvk, err := disallowedTemplateKind(r.AllowedTemplateKinds, replicated)
+	if err == nil && gvk.Empty() {
+		return nil
+	}
+
+	metricKind, kindErr := templateKindRejection(gvk, err)
+
+	log.Info(
+		"Not writing the replicated policy since a policy template has a kind that isn't allowed",
+		"policyName", rootPlc.GetName(),
+		"policyNamespace", rootPlc.GetNamespace(),
+		"clusterNamespace", decision.ClusterNamespace,
+		"reason", kindErr.Error(),
+	)
+
+	templateKindRejectedMetric.WithLabelValues(rootPlc.GetName(), rootPlc.GetNamespace(), metricKind).Inc()
+
+	r.Recorder.Event(rootPlc, "Warning", "PolicyPropagation",
+		fmt.Sprintf("Policy %s/%s can't be propagated to cluster %s/%s: %s", rootPlc.GetNamespace(),
+			rootPlc.GetName(), decision.ClusterNamespace, decision.ClusterName, kindErr.Error()))
+
+	return kindErr
+}
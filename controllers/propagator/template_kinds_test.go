@@ -0,0 +1,251 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+)
+
+func TestParseAllowedTemplateKinds(t *testing.T) {
+	gvks, err := ParseAllowedTemplateKinds(nil)
+	if err != nil || gvks != nil {
+		t.Fatalf("Expected nil to allow all kinds, got %v with the error %v", gvks, err)
+	}
+
+	gvks, err = ParseAllowedTemplateKinds([]string{
+		"ConfigurationPolicy.v1.policy.open-cluster-management.io", "ConfigMap.v1", "*.*.apps",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []schema.GroupVersionKind{
+		{Group: "policy.open-cluster-management.io", Version: "v1", Kind: "ConfigurationPolicy"},
+		{Version: "v1", Kind: "ConfigMap"},
+		{Group: "apps", Version: "*", Kind: "*"},
+	}
+
+	for i := range expected {
+		if gvks[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected[i], gvks[i])
+		}
+	}
+
+	for _, invalid := range []string{"ConfigMap", ".v1", "ConfigMap."} {
+		if _, err := ParseAllowedTemplateKinds([]string{invalid}); !errors.Is(err, ErrInvalidAllowedTemplateKind) {
+			t.Fatalf("Expected %q to be invalid, got %v", invalid, err)
+		}
+	}
+}
+
+func TestCheckTemplateKinds(t *testing.T) {
+	allowed, err := ParseAllowedTemplateKinds([]string{
+		"ConfigurationPolicy.v1.policy.open-cluster-management.io",
+		"CertificatePolicy.*.policy.open-cluster-management.io",
+		"ConfigMap.v1",
+		"*.v1.apps",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		allowed          []schema.GroupVersionKind
+		objectDefinition string
+		expectedKind     string
+	}{
+		"All kinds allowed by default": {
+			objectDefinition: `{"apiVersion": "example.com/v1", "kind": "Unknown", "metadata": {"name": "a"}}`,
+		},
+		"Allowed kind": {
+			allowed: allowed,
+			objectDefinition: `{"apiVersion": "policy.open-cluster-management.io/v1beta1", "kind": "CertificatePolicy",
+				"metadata": {"name": "a"}}`,
+		},
+		"Allowed embedded objects": {
+			allowed: allowed,
+			objectDefinition: `{"apiVersion": "policy.open-cluster-management.io/v1", "kind": "ConfigurationPolicy",
+				"metadata": {"name": "a"}, "spec": {"object-templates": [
+					{"objectDefinition": {"apiVersion": "v1", "kind": "ConfigMap"}},
+					{"objectDefinition": {"apiVersion": "apps/v1", "kind": "Deployment"}}
+				]}}`,
+		},
+		"Denied kind": {
+			allowed: allowed,
+			objectDefinition: `{"apiVersion": "policy.open-cluster-management.io/v1beta1", "kind": "OperatorPolicy",
+				"metadata": {"name": "a"}}`,
+			expectedKind: "OperatorPolicy.policy.open-cluster-management.io",
+		},
+		"Denied version": {
+			allowed: allowed,
+			objectDefinition: `{"apiVersion": "policy.open-cluster-management.io/v1beta1",
+				"kind": "ConfigurationPolicy", "metadata": {"name": "a"}}`,
+			expectedKind: "ConfigurationPolicy.policy.open-cluster-management.io",
+		},
+		"Denied embedded object": {
+			allowed: allowed,
+			objectDefinition: `{"apiVersion": "policy.open-cluster-management.io/v1", "kind": "ConfigurationPolicy",
+				"metadata": {"name": "a"}, "spec": {"object-templates": [
+					{"objectDefinition": {"apiVersion": "v1", "kind": "ConfigMap"}},
+					{"objectDefinition": {"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "ClusterRoleBinding"}}
+				]}}`,
+			expectedKind: "ClusterRoleBinding.rbac.authorization.k8s.io",
+		},
+		"Denied embedded object in the raw object templates": {
+			allowed: allowed,
+			objectDefinition: `{"apiVersion": "policy.open-cluster-management.io/v1", "kind": "ConfigurationPolicy",
+				"metadata": {"name": "a"}, "spec": {"object-templates-raw":
+					"- complianceType: musthave\n  objectDefinition:\n    apiVersion: v1\n    kind: Secret\n"
+				}}`,
+			expectedKind: "Secret",
+		},
+		"Unparsable raw object templates": {
+			allowed: allowed,
+			objectDefinition: `{"apiVersion": "policy.open-cluster-management.io/v1", "kind": "ConfigurationPolicy",
+				"metadata": {"name": "a"}, "spec": {"object-templates-raw":
+					"{{ range $i := until 2 }}\n- objectDefinition: {}\n{{ end }}"
+				}}`,
+			expectedKind: "unknown",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &PolicyReconciler{Recorder: recorder, AllowedTemplateKinds: test.allowed}
+			replicated := policyWithTemplate(test.objectDefinition)
+			decision := appsv1.PlacementDecision{ClusterName: "cluster1", ClusterNamespace: "cluster1"}
+
+			t.Cleanup(templateKindRejectedMetric.Reset)
+
+			err := reconciler.checkTemplateKinds(replicated, decision, replicated)
+
+			if test.expectedKind == "" {
+				if err != nil {
+					t.Fatalf("Expected the kinds to be allowed, got %v", err)
+				}
+
+				if len(recorder.Events) != 0 {
+					t.Fatalf("Expected no events, got %q", <-recorder.Events)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, errKindNotAllowed) {
+				t.Fatalf("Expected the kinds to be denied, got %v", err)
+			}
+
+			if reason := propagationErrorReason(err); reason != reasonKindNotAllowed {
+				t.Fatalf("Expected the %s propagation error reason, got %s", reasonKindNotAllowed, reason)
+			}
+
+			if isClusterFailure(err) {
+				t.Fatal("Expected a denied kind to not count as a cluster failure")
+			}
+
+			rejected := testutil.ToFloat64(
+				templateKindRejectedMetric.WithLabelValues("my-policy", "policies", test.expectedKind),
+			)
+			if rejected != 1 {
+				t.Fatalf("Expected the rejection metric for %s to be 1, got %v", test.expectedKind, rejected)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if !strings.HasPrefix(event, "Warning PolicyPropagation") || !strings.Contains(event, "cluster1") {
+					t.Fatalf("Unexpected event: %q", event)
+				}
+			default:
+				t.Fatal("Expected a warning event on the root policy")
+			}
+		})
+	}
+}
+
+func TestHandleDecisionDisallowedExistingReplica(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{policiesv1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	allowed, err := ParseAllowedTemplateKinds([]string{"ConfigurationPolicy.v1.policy.open-cluster-management.io"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	allowedTemplate := `{"apiVersion": "policy.open-cluster-management.io/v1", "kind": "ConfigurationPolicy",
+		"metadata": {"name": "a"}, "spec": {"object-templates": []}}`
+	deniedTemplate := `{"apiVersion": "policy.open-cluster-management.io/v1", "kind": "ConfigurationPolicy",
+		"metadata": {"name": "a"}, "spec": {"object-templates": [{"complianceType": "musthave", "objectDefinition":
+			{"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "ClusterRoleBinding", "metadata": {"name": "b"}}}
+		]}}`
+
+	tests := map[string]struct {
+		existingTemplate string
+		expectedDeleted  bool
+	}{
+		"The existing replicated policy has the denied kind": {deniedTemplate, true},
+		"The existing replicated policy is allowed":          {allowedTemplate, false},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Cleanup(templateKindRejectedMetric.Reset)
+
+			reconciler := &PolicyReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			decision := clusterDecision{
+				Cluster: appsv1.PlacementDecision{ClusterName: "managed1", ClusterNamespace: "managed1"},
+			}
+
+			// The replicated policy is written before the allowed kinds are set
+			rootPolicy := policyWithTemplate(test.existingTemplate)
+
+			if _, err := reconciler.handleDecision(context.TODO(), rootPolicy, decision); err != nil {
+				t.Fatalf("Unexpected error creating the replicated policy: %v", err)
+			}
+
+			reconciler.AllowedTemplateKinds = allowed
+			rootPolicy.Spec.PolicyTemplates[0].ObjectDefinition.Raw = []byte(deniedTemplate)
+
+			_, err := reconciler.handleDecision(context.TODO(), rootPolicy, decision)
+			if !errors.Is(err, errKindNotAllowed) {
+				t.Fatalf("Expected the kinds to be denied, got %v", err)
+			}
+
+			err = reconciler.Get(
+				context.TODO(),
+				types.NamespacedName{Namespace: "managed1", Name: common.FullNameForPolicy(rootPolicy)},
+				&policiesv1.Policy{},
+			)
+			if deleted := k8serrors.IsNotFound(err); deleted != test.expectedDeleted {
+				t.Fatalf("Expected the replicated policy to be deleted to be %v, got the error %v", test.expectedDeleted, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// TemplateValidationErrorAnnotation is set on a root policy to the error from validating its policy templates against
+// the schemas of their CRDs on the hub. It's removed once the policy templates are valid for every cluster.
+const TemplateValidationErrorAnnotation = "policy.open-cluster-management.io/template-validation-error"
+
+// errTemplateInvalid is returned when a replicated policy isn't written because one of its policy templates doesn't
+// match the schema of its CRD on the hub.
+var errTemplateInvalid = errors.New("the policy template is invalid")
+
+// TemplateValidator validates the policy templates of the replicated policies against the OpenAPI schemas of their
+// CRDs on the hub, so that an invalid policy template fails on the hub rather than on the managed clusters. Kinds that
+// aren't installed on the hub, or that aren't defined by a CRD, aren't validated.
+type TemplateValidator struct {
+	client client.Reader
+	mapper meta.RESTMapper
+	// validators maps the group, version, and kind of a policy template to its cachedSchemaValidator
+	validators sync.Map
+}
+
+// cachedSchemaValidator is the schema validator built from a version of a CRD. It's rebuilt when the CRD changes.
+type cachedSchemaValidator struct {
+	uid             types.UID
+	resourceVersion string
+	validator       *validate.SchemaValidator
+}
+
+// NewTemplateValidator returns a TemplateValidator that maps the kinds of the policy templates to their resources with
+// the input RESTMapper and reads their CRDs with the input client. The client should be backed by the cache since the
+// CRDs are read for every replicated policy.
+func NewTemplateValidator(c client.Reader, mapper meta.RESTMapper) *TemplateValidator {
+	return &TemplateValidator{client: c, mapper: mapper}
+}
+
+// schemaValidator returns the schema validator for the input kind, or nil if the kind isn't installed on the hub, isn't
+// defined by a CRD, or its CRD has no schema for the version.
+func (v *TemplateValidator) schemaValidator(
+	ctx context.Context, gvk schema.GroupVersionKind,
+) (*validate.SchemaValidator, error) {
+	mapping, err := v.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+
+	err = v.client.Get(ctx, types.NamespacedName{Name: mapping.Resource.Resource + "." + gvk.Group}, crd)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if cached, ok := v.validators.Load(gvk); ok {
+		//nolint:forcetypeassert
+		cached := cached.(cachedSchemaValidator)
+		if cached.uid == crd.UID && cached.resourceVersion == crd.ResourceVersion {
+			return cached.validator, nil
+		}
+	}
+
+	var crdValidation *apiextensionsv1.CustomResourceValidation
+
+	for _, version := range crd.Spec.Versions {
+		if version.Name == gvk.Version {
+			crdValidation = version.Schema
+
+			break
+		}
+	}
+
+	var schemaValidator *validate.SchemaValidator
+
+	if crdValidation != nil && crdValidation.OpenAPIV3Schema != nil {
+		internalValidation := &apiextensions.CustomResourceValidation{}
+
+		err = apiextensionsv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(
+			crdValidation, internalValidation, nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		schemaValidator, _, err = validation.NewSchemaValidator(internalValidation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	v.validators.Store(gvk, cachedSchemaValidator{
+		uid:             crd.UID,
+		resourceVersion: crd.ResourceVersion,
+		validator:       schemaValidator,
+	})
+
+	return schemaValidator, nil
+}
+
+// validate returns an error wrapping errTemplateInvalid if a policy template of the input replicated policy is missing
+// its apiVersion or kind, or doesn't match the schema of its CRD. Policy templates with managed cluster templates
+// aren't validated since their values are only known on the managed clusters.
+func (v *TemplateValidator) validate(ctx context.Context, replicated *policiesv1.Policy) error {
+	for _, policyT := range replicated.Spec.PolicyTemplates {
+		if policyT == nil || strings.Contains(string(policyT.ObjectDefinition.Raw), "{{") {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+
+		if err := obj.UnmarshalJSON(policyT.ObjectDefinition.Raw); err != nil {
+			return fmt.Errorf("%w: %s", errTemplateInvalid, err.Error())
+		}
+
+		gvk := obj.GroupVersionKind()
+		if gvk.Kind == "" || gvk.Version == "" {
+			return fmt.Errorf("%w: the policy template %s is missing its apiVersion or kind", errTemplateInvalid,
+				obj.GetName())
+		}
+
+		schemaValidator, err := v.schemaValidator(ctx, gvk)
+		if err != nil {
+			return err
+		}
+
+		if schemaValidator == nil {
+			continue
+		}
+
+		errs := validation.ValidateCustomResource(nil, obj.UnstructuredContent(), schemaValidator)
+		if len(errs) != 0 {
+			return fmt.Errorf(
+				"%w: the %s %s doesn't match the schema of its CRD: %s",
+				errTemplateInvalid, gvk.Kind, obj.GetName(), errs.ToAggregate().Error(),
+			)
+		}
+	}
+
+	return nil
+}
+
+// validateTemplates returns an error wrapping errTemplateInvalid if the policy templates of the input replicated
+// policy are invalid, in which case a warning event is emitted on the root policy. This does nothing if the
+// TemplateValidator isn't set.
+func (r *PolicyReconciler) validateTemplates(
+	ctx context.Context, rootPlc *policiesv1.Policy, decision appsv1.PlacementDecision, replicated *policiesv1.Policy,
+) error {
+	if r.TemplateValidator == nil {
+		return nil
+	}
+
+	err := r.TemplateValidator.validate(ctx, replicated)
+	if err == nil || !errors.Is(err, errTemplateInvalid) {
+		return err
+	}
+
+	log.Info(
+		"Not writing the replicated policy since a policy template is invalid",
+		"policyName", rootPlc.GetName(),
+		"policyNamespace", rootPlc.GetNamespace(),
+		"clusterNamespace", decision.ClusterNamespace,
+		"reason", err.Error(),
+	)
+
+	r.Recorder.Event(rootPlc, "Warning", "PolicyPropagation",
+		fmt.Sprintf("Policy %s/%s can't be propagated to cluster %s/%s: %s", rootPlc.GetNamespace(),
+			rootPlc.GetName(), decision.ClusterNamespace, decision.ClusterName, err.Error()))
+
+	return err
+}
+
+// templateValidationError returns the message of the first of the input errors that wraps errTemplateInvalid, ordered
+// by cluster namespace so that it's stable between reconciles, or an empty string if there are none.
+func templateValidationError(clusterErrs clusterErrors) string {
+	messages := map[string]string{}
+	namespaces := []string{}
+
+	for decision, err := range clusterErrs {
+		if errors.Is(err, errTemplateInvalid) {
+			messages[decision.ClusterNamespace] = err.Error()
+			namespaces = append(namespaces, decision.ClusterNamespace)
+		}
+	}
+
+	if len(namespaces) == 0 {
+		return ""
+	}
+
+	sort.Strings(namespaces)
+
+	return messages[namespaces[0]]
+}
+
+// updateTemplateValidationAnnotation sets the TemplateValidationErrorAnnotation on the input root policy to the first
+// template validation error in the input errors, or removes it if there are none. The root policy is only patched when
+// the annotation changes.
+func (r *PolicyReconciler) updateTemplateValidationAnnotation(
+	ctx context.Context, rootPlc *policiesv1.Policy, clusterErrs clusterErrors,
+) error {
+	if r.TemplateValidator == nil {
+		return nil
+	}
+
+	message := templateValidationError(clusterErrs)
+	if rootPlc.GetAnnotations()[TemplateValidationErrorAnnotation] == message {
+		return nil
+	}
+
+	patch := client.MergeFrom(rootPlc.DeepCopy())
+	annotations := rootPlc.GetAnnotations()
+
+	if message == "" {
+		delete(annotations, TemplateValidationErrorAnnotation)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[TemplateValidationErrorAnnotation] = message
+	}
+
+	rootPlc.SetAnnotations(annotations)
+
+	return r.Patch(ctx, rootPlc, patch)
+}
@@ -0,0 +1,212 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func fakeConfigPolicyCRD() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "configurationpolicies.policy.open-cluster-management.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "policy.open-cluster-management.io",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind: "ConfigurationPolicy", Plural: "configurationpolicies",
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    "v1",
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"apiVersion": {Type: "string"},
+							"kind":       {Type: "string"},
+							"metadata":   {Type: "object"},
+							"spec": {
+								Type:     "object",
+								Required: []string{"severity"},
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"severity": {
+										Type: "string",
+										Enum: []apiextensionsv1.JSON{{Raw: []byte(`"low"`)}, {Raw: []byte(`"high"`)}},
+									},
+								},
+							},
+						},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func policyWithTemplate(objectDefinition string) *policiesv1.Policy {
+	policy := fakeRootPolicy("my-policy", "policies")
+	policy.Spec.PolicyTemplates = []*policiesv1.PolicyTemplate{
+		{ObjectDefinition: k8sruntime.RawExtension{Raw: []byte(objectDefinition)}},
+	}
+
+	return &policy
+}
+
+func TestTemplateValidatorValidate(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, apiextensionsv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{
+		Group: "policy.open-cluster-management.io", Version: "v1", Kind: "ConfigurationPolicy",
+	}, meta.RESTScopeNamespace)
+	// A kind that is installed on the hub but isn't defined by a CRD
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(fakeConfigPolicyCRD()).Build()
+	validator := NewTemplateValidator(c, mapper)
+
+	tests := map[string]struct {
+		objectDefinition string
+		expectInvalid    bool
+	}{
+		"Valid": {
+			`{"apiVersion":"policy.open-cluster-management.io/v1","kind":"ConfigurationPolicy",` +
+				`"metadata":{"name":"my-config"},"spec":{"severity":"low"}}`,
+			false,
+		},
+		"Missing required field": {
+			`{"apiVersion":"policy.open-cluster-management.io/v1","kind":"ConfigurationPolicy",` +
+				`"metadata":{"name":"my-config"},"spec":{}}`,
+			true,
+		},
+		"Invalid enum value": {
+			`{"apiVersion":"policy.open-cluster-management.io/v1","kind":"ConfigurationPolicy",` +
+				`"metadata":{"name":"my-config"},"spec":{"severity":"extreme"}}`,
+			true,
+		},
+		"Managed cluster templates": {
+			`{"apiVersion":"policy.open-cluster-management.io/v1","kind":"ConfigurationPolicy",` +
+				`"metadata":{"name":"my-config"},"spec":{"severity":"{{ fromConfigMap \"a\" \"b\" \"c\" }}"}}`,
+			false,
+		},
+		"Kind not installed": {
+			`{"apiVersion":"policy.open-cluster-management.io/v1","kind":"CertificatePolicy",` +
+				`"metadata":{"name":"my-cert"},"spec":{"severity":"extreme"}}`,
+			false,
+		},
+		"Missing kind": {
+			`{"apiVersion":"policy.open-cluster-management.io/v1","metadata":{"name":"my-config"}}`, true,
+		},
+		"Kind without a CRD": {`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"my-cm"}}`, false},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			err := validator.validate(context.TODO(), policyWithTemplate(test.objectDefinition))
+
+			if test.expectInvalid {
+				if !errors.Is(err, errTemplateInvalid) {
+					t.Fatalf("Expected the policy template to be invalid, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected the policy template to be valid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateTemplateValidationAnnotation(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+	if err := policiesv1.AddToScheme(testscheme); err != nil {
+		t.Fatalf("Unexpected error building scheme: %v", err)
+	}
+
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	c := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&rootPolicy).Build()
+	reconciler := &PolicyReconciler{
+		Client:            c,
+		Recorder:          record.NewFakeRecorder(10),
+		TemplateValidator: NewTemplateValidator(c, meta.NewDefaultRESTMapper(nil)),
+	}
+
+	clusterErrs := clusterErrors{
+		appsv1.PlacementDecision{ClusterName: "cluster2", ClusterNamespace: "cluster2"}: fmt.Errorf(
+			"%w: the ConfigurationPolicy in cluster2 is invalid", errTemplateInvalid,
+		),
+		appsv1.PlacementDecision{ClusterName: "cluster1", ClusterNamespace: "cluster1"}: fmt.Errorf(
+			"%w: the ConfigurationPolicy in cluster1 is invalid", errTemplateInvalid,
+		),
+		appsv1.PlacementDecision{ClusterName: "cluster3", ClusterNamespace: "cluster3"}: errNamespaceNotFound,
+	}
+
+	getAnnotation := func() (string, bool) {
+		t.Helper()
+
+		policy := &policiesv1.Policy{}
+
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: "policies", Name: "my-policy"}, policy)
+		if err != nil {
+			t.Fatalf("Failed to get the root policy: %v", err)
+		}
+
+		value, ok := policy.Annotations[TemplateValidationErrorAnnotation]
+
+		return value, ok
+	}
+
+	if err := reconciler.updateTemplateValidationAnnotation(context.TODO(), &rootPolicy, clusterErrs); err != nil {
+		t.Fatalf("Unexpected error updating the annotation: %v", err)
+	}
+
+	// The error of the first cluster namespace is used so that the annotation is stable
+	if value, _ := getAnnotation(); value != clusterErrs[appsv1.PlacementDecision{
+		ClusterName: "cluster1", ClusterNamespace: "cluster1",
+	}].Error() {
+		t.Fatalf("Expected the annotation to have the error of cluster1, got %q", value)
+	}
+
+	// The annotation is never copied to the replicated policies
+	replicated, err := reconciler.buildReplicatedPolicy(&rootPolicy, clusterDecision{
+		Cluster: appsv1.PlacementDecision{ClusterName: "cluster1", ClusterNamespace: "cluster1"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error building the replicated policy: %v", err)
+	}
+
+	if _, ok := replicated.Annotations[TemplateValidationErrorAnnotation]; ok {
+		t.Fatal("Expected the annotation not to be copied to the replicated policy")
+	}
+
+	if err := reconciler.updateTemplateValidationAnnotation(context.TODO(), &rootPolicy, nil); err != nil {
+		t.Fatalf("Unexpected error updating the annotation: %v", err)
+	}
+
+	if _, ok := getAnnotation(); ok {
+		t.Fatal("Expected the annotation to be removed once the policy templates are valid")
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of the propagation of root policies. It uses the global tracer provider, which doesn't
+// record anything unless common.SetupTracing configured an exporter.
+var tracer = otel.Tracer("open-cluster-management.io/governance-policy-propagator/controllers/propagator")
+
+// endSpan sets the input attributes on the span and ends it. If err is not nil, it's recorded on the span and the
+// span status is set to an error.
+func endSpan(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,90 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package propagator
+
+import (
+	"context"
+	"testing"
+
+	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+func TestHandleDecisionsSpans(t *testing.T) {
+	testscheme := k8sruntime.NewScheme()
+
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{
+		policiesv1.AddToScheme, appsv1.AddToScheme, clusterv1.AddToScheme,
+	} {
+		if err := addToScheme(testscheme); err != nil {
+			t.Fatalf("Unexpected error building scheme: %v", err)
+		}
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	concurrencyPerPolicy = concurrencyPerPolicyDefault
+
+	rootPolicy := fakeRootPolicy("my-policy", "policies")
+	rootPolicy.SetGroupVersionKind(policiesv1.GroupVersion.WithKind(policiesv1.Kind))
+	pr := fakePlacementRule("my-rule", "policies", fakePlacementDecisions(2))
+	pbList := &policiesv1.PlacementBindingList{Items: []policiesv1.PlacementBinding{fakePlacementBinding(
+		"my-pb",
+		"policies",
+		policiesv1.PlacementSubject{APIGroup: appsv1.SchemeGroupVersion.Group, Kind: "PlacementRule", Name: "my-rule"},
+		[]policiesv1.Subject{{APIGroup: policiesv1.SchemeGroupVersion.Group, Kind: policiesv1.Kind, Name: "my-policy"}},
+	)}}
+
+	reconciler := &PolicyReconciler{
+		Client:         fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&rootPolicy, &pr).Build(),
+		DynamicWatcher: &fakeDynamicWatcher{watched: map[k8sdepwatches.ObjectIdentifier][]k8sdepwatches.ObjectIdentifier{}},
+		Recorder:       record.NewFakeRecorder(10),
+	}
+
+	for _, expectedOutcome := range []string{"created", "unchanged"} {
+		previouslyEnded := len(recorder.Ended())
+
+		_, _, failedClusters, _, _, _ := reconciler.handleDecisions(context.TODO(), &rootPolicy, pbList)
+		if len(failedClusters) != 0 {
+			t.Fatalf("Expected no clusters to fail, got %v", failedClusters)
+		}
+
+		outcomes := map[string]string{}
+		clusterCount := int64(-1)
+
+		for _, span := range recorder.Ended()[previouslyEnded:] {
+			attrs := attribute.NewSet(span.Attributes()...)
+
+			switch span.Name() {
+			case "resolve-targets":
+				value, _ := attrs.Value("cluster.count")
+				clusterCount = value.AsInt64()
+			case "replicate-policy":
+				cluster, _ := attrs.Value("cluster.name")
+				outcome, _ := attrs.Value("outcome")
+				outcomes[cluster.AsString()] = outcome.AsString()
+			}
+		}
+
+		if clusterCount != 2 {
+			t.Fatalf("Expected the resolve-targets span to have a cluster count of 2, got %d", clusterCount)
+		}
+
+		expected := map[string]string{"cluster1": expectedOutcome, "cluster2": expectedOutcome}
+		if len(outcomes) != len(expected) || outcomes["cluster1"] != expectedOutcome ||
+			outcomes["cluster2"] != expectedOutcome {
+			t.Fatalf("Expected the replicate-policy span outcomes %v, got %v", expected, outcomes)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package policystatus
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// debouncer coalesces the reconcile requests of each root policy over a window so that a burst of replicated policy
+// status updates results in a single root policy status update. Each root policy has its own timer, so unrelated root
+// policies don't delay each other.
+type debouncer struct {
+	window  time.Duration
+	lock    sync.Mutex
+	pending map[types.NamespacedName]*time.Timer
+	stopped bool
+}
+
+func newDebouncer(window time.Duration) *debouncer {
+	return &debouncer{window: window, pending: map[types.NamespacedName]*time.Timer{}}
+}
+
+// add queues the request once the window has passed since the first request for the root policy that is not yet
+// queued. Additional requests during the window are coalesced into that one. If the window is not positive, the
+// request is queued immediately.
+func (d *debouncer) add(q workqueue.RateLimitingInterface, request reconcile.Request) {
+	if d.window <= 0 {
+		q.Add(request)
+
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.stopped {
+		q.Add(request)
+
+		return
+	}
+
+	if _, ok := d.pending[request.NamespacedName]; ok {
+		return
+	}
+
+	d.pending[request.NamespacedName] = time.AfterFunc(d.window, func() {
+		d.lock.Lock()
+
+		// The request was returned by drain instead
+		if d.stopped {
+			d.lock.Unlock()
+
+			return
+		}
+
+		delete(d.pending, request.NamespacedName)
+		d.lock.Unlock()
+
+		q.Add(request)
+	})
+}
+
+// drain stops all the timers and returns the requests which were not yet queued. Requests added afterwards are queued
+// immediately.
+func (d *debouncer) drain() []reconcile.Request {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.stopped = true
+
+	requests := make([]reconcile.Request, 0, len(d.pending))
+
+	for namespacedName, timer := range d.pending {
+		timer.Stop()
+
+		requests = append(requests, reconcile.Request{NamespacedName: namespacedName})
+	}
+
+	d.pending = map[types.NamespacedName]*time.Timer{}
+
+	return requests
+}
+
+// handler returns an event handler that maps the event objects with the input mapper and passes the resulting
+// requests through the debouncer.
+func (d *debouncer) handler(mapper handler.MapFunc) handler.EventHandler {
+	add := func(obj client.Object, q workqueue.RateLimitingInterface) {
+		for _, request := range mapper(obj) {
+			d.add(q, request)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) { add(e.Object, q) },
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) { add(e.ObjectNew, q) },
+		DeleteFunc: func(e event.DeleteEvent, q workqueue.RateLimitingInterface) { add(e.Object, q) },
+		GenericFunc: func(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			add(e.Object, q)
+		},
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package policystatus
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDebouncer(t *testing.T) {
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+
+	d := newDebouncer(100 * time.Millisecond)
+
+	policy1 := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "policy1"}}
+	policy2 := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "policy2"}}
+
+	// A burst of requests followed by no further requests
+	for i := 0; i < 10; i++ {
+		d.add(q, policy1)
+	}
+
+	d.add(q, policy2)
+
+	if q.Len() != 0 {
+		t.Fatalf("expected no requests to be queued during the window, got %d", q.Len())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Len() != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if q.Len() != 2 {
+		t.Fatalf("expected the burst to be flushed as one request per root policy, got %d", q.Len())
+	}
+
+	for i := 0; i < 2; i++ {
+		item, _ := q.Get()
+		q.Done(item)
+	}
+
+	// Requests after the flush start a new window
+	d.add(q, policy1)
+
+	pending := d.drain()
+	if len(pending) != 1 || pending[0] != policy1 {
+		t.Fatalf("expected the pending request to be returned by drain, got %v", pending)
+	}
+
+	// Requests after the drain are queued immediately
+	d.add(q, policy2)
+
+	time.Sleep(200 * time.Millisecond)
+
+	if q.Len() != 1 {
+		t.Fatalf("expected only the request after the drain to be queued, got %d queued", q.Len())
+	}
+}
+
+func TestDebouncerNoWindow(t *testing.T) {
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+
+	d := newDebouncer(0)
+
+	d.add(q, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "policies", Name: "policy1"}})
+
+	if q.Len() != 1 {
+		t.Fatalf("expected the request to be queued immediately, got %d", q.Len())
+	}
+}
@@ -5,6 +5,7 @@ package policystatus
 import (
 	"context"
 	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -13,7 +14,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -31,6 +32,21 @@ var log = ctrl.Log.WithName(ControllerName)
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RootPolicyStatusReconciler) SetupWithManager(mgr ctrl.Manager, _ ...source.Source) error {
+	r.debouncer = newDebouncer(r.StatusUpdateWindow)
+
+	// Flush the pending status updates when the manager is stopped. This runs with leader election, so it's stopped
+	// before the cache.
+	err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+
+		r.flushPending()
+
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{MaxConcurrentReconciles: int(r.MaxConcurrentReconciles)}).
 		Named(ControllerName).
@@ -43,8 +59,8 @@ func (r *RootPolicyStatusReconciler) SetupWithManager(mgr ctrl.Manager, _ ...sou
 		// particular way, so we will define that in a separate "Watches"
 		Watches(
 			&source.Kind{Type: &policiesv1.Policy{}},
-			handler.EnqueueRequestsFromMapFunc(common.PolicyMapper(mgr.GetClient())),
-			builder.WithPredicates(policyStatusPredicate()),
+			r.debouncer.handler(common.PolicyMapper(mgr.GetClient())),
+			builder.WithPredicates(common.WatchedPolicyPredicate, policyStatusPredicate()),
 		).
 		Complete(r)
 }
@@ -59,6 +75,40 @@ type RootPolicyStatusReconciler struct {
 	// Use a shared lock with the main policy controller to avoid conflicting updates.
 	RootPolicyLocks *sync.Map
 	Scheme          *runtime.Scheme
+	// The window over which replicated policy status changes are collected before the root policy status is
+	// updated. If this is not positive, each change is handled immediately.
+	StatusUpdateWindow time.Duration
+	// ComplianceHistoryLimit is the number of compliance state changes kept in the status of each root policy. If
+	// it's zero, the compliance history is not recorded.
+	ComplianceHistoryLimit int
+	// ComplianceHistoryDepth is the number of compliance state changes kept in the compliance history for each
+	// cluster. If it's zero, the compliance history is only limited by ComplianceHistoryLimit.
+	ComplianceHistoryDepth int
+	// StatusUpdate configures the field manager of the root policy status writes and how the writes that conflict with
+	// another write are retried.
+	StatusUpdate common.StatusUpdateOptions
+	debouncer    *debouncer
+}
+
+// flushPending reconciles the root policies which have status updates waiting on the debouncer window.
+func (r *RootPolicyStatusReconciler) flushPending() {
+	requests := r.debouncer.drain()
+	if len(requests) == 0 {
+		return
+	}
+
+	log.Info("Flushing the pending root policy status updates", "count", len(requests))
+
+	for _, request := range requests {
+		if _, err := r.Reconcile(context.Background(), request); err != nil {
+			log.Error(
+				err,
+				"Failed to flush the pending root policy status update",
+				"namespace", request.Namespace,
+				"name", request.Name,
+			)
+		}
+	}
 }
 
 // Reconcile will update the root policy status based on the current state whenever a root or replicated policy status
@@ -69,6 +119,12 @@ func (r *RootPolicyStatusReconciler) Reconcile(ctx context.Context, request ctrl
 	log := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	log.V(1).Info("Reconciling the root policy status")
 
+	if !common.IsWatchedRootNamespace(request.Namespace) {
+		log.V(2).Info("The root policy is not in a watched namespace. Ignoring it.")
+
+		return reconcile.Result{}, nil
+	}
+
 	log.V(3).Info("Acquiring the lock for the root policy")
 
 	lock, _ := r.RootPolicyLocks.LoadOrStore(request.NamespacedName, &sync.Mutex{})
@@ -109,19 +165,44 @@ func (r *RootPolicyStatusReconciler) Reconcile(ctx context.Context, request ctrl
 		clusterToReplicatedPolicy[replicatedPolicyList.Items[i].Namespace] = &replicatedPolicyList.Items[i]
 	}
 
-	updatedStatus := false
-
 	err = r.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: request.Name}, rootPolicy)
 	if err != nil {
 		log.Error(err, "Failed to refresh the cached policy. Will use existing policy.")
 	}
 
+	// The root policy status is recomputed on the latest version of the root policy if the update conflicts
+	err = common.UpdatePolicyStatus(ctx, r.Client, rootPolicy, r.StatusUpdate, func(latest *policiesv1.Policy) bool {
+		return r.aggregateStatus(latest, clusterToReplicatedPolicy)
+	})
+	if err != nil {
+		log.Error(err, "Failed to update the root policy status. Will Requeue.")
+
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// aggregateStatus sets the compliance of each cluster in the status of the input root policy to the compliance of its
+// replicated policy, and then the overall compliance, the compliance summary, and the compliance history. It returns
+// false without changing the root policy if the compliance of every cluster is already up to date.
+func (r *RootPolicyStatusReconciler) aggregateStatus(
+	rootPolicy *policiesv1.Policy, clusterToReplicatedPolicy map[string]*policiesv1.Policy,
+) bool {
+	log := log.WithValues("Request.Namespace", rootPolicy.Namespace, "Request.Name", rootPolicy.Name)
+
+	updatedStatus := false
+	previousStatus := rootPolicy.Status.DeepCopy().Status
+	messages := make(map[string]string, len(clusterToReplicatedPolicy))
+
 	for _, status := range rootPolicy.Status.Status {
 		replicatedPolicy := clusterToReplicatedPolicy[status.ClusterNamespace]
 		if replicatedPolicy == nil {
 			continue
 		}
 
+		messages[status.ClusterNamespace] = propagator.ComplianceMessage(replicatedPolicy)
+
 		if status.ComplianceState != replicatedPolicy.Status.ComplianceState {
 			updatedStatus = true
 			status.ComplianceState = replicatedPolicy.Status.ComplianceState
@@ -131,17 +212,14 @@ func (r *RootPolicyStatusReconciler) Reconcile(ctx context.Context, request ctrl
 	if !updatedStatus {
 		log.V(1).Info("No status changes required in the root policy. Doing nothing.")
 
-		return reconcile.Result{}, nil
+		return false
 	}
 
 	rootPolicy.Status.ComplianceState = propagator.CalculateRootCompliance(rootPolicy.Status.Status)
+	rootPolicy.Status.ComplianceSummary = propagator.CalculateComplianceSummary(rootPolicy.Status.Status)
+	propagator.RecordComplianceHistory(
+		rootPolicy, previousStatus, messages, r.ComplianceHistoryDepth, r.ComplianceHistoryLimit, time.Now(),
+	)
 
-	err = r.Status().Update(context.TODO(), rootPolicy)
-	if err != nil {
-		log.Error(err, "Failed to update the root policy status. Will Requeue.")
-
-		return reconcile.Result{}, err
-	}
-
-	return reconcile.Result{}, nil
+	return true
 }
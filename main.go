@@ -12,13 +12,16 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-logr/zapr"
 	"github.com/spf13/pflag"
 	"github.com/stolostron/go-log-utils/zaputil"
 	k8sdepwatches "github.com/stolostron/kubernetes-dependency-watches/client"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/dynamic"
@@ -28,18 +31,27 @@ import (
 	"k8s.io/klog/v2"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	//+kubebuilder:scaffold:imports
 	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 	policyv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
+	autobindctrl "open-cluster-management.io/governance-policy-propagator/controllers/autobind"
 	automationctrl "open-cluster-management.io/governance-policy-propagator/controllers/automation"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+	complianceconfigmapctrl "open-cluster-management.io/governance-policy-propagator/controllers/complianceconfigmap"
+	compliancereportctrl "open-cluster-management.io/governance-policy-propagator/controllers/compliancereport"
 	encryptionkeysctrl "open-cluster-management.io/governance-policy-propagator/controllers/encryptionkeys"
+	placementbindingctrl "open-cluster-management.io/governance-policy-propagator/controllers/placementbinding"
 	metricsctrl "open-cluster-management.io/governance-policy-propagator/controllers/policymetrics"
 	policysetctrl "open-cluster-management.io/governance-policy-propagator/controllers/policyset"
 	propagatorctrl "open-cluster-management.io/governance-policy-propagator/controllers/propagator"
@@ -64,9 +76,11 @@ func printVersion() {
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 
 	utilruntime.Must(clusterv1.AddToScheme(scheme))
 	utilruntime.Must(clusterv1beta1.AddToScheme(scheme))
+	utilruntime.Must(clusterv1beta2.AddToScheme(scheme))
 	utilruntime.Must(appsv1.AddToScheme(scheme))
 
 	//+kubebuilder:scaffold:scheme
@@ -87,15 +101,85 @@ func main() {
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
 	var metricsAddr string
-	var enableLeaderElection bool
+	var enableLeaderElection, enableComplianceConfigMaps, enableReconcileAll, enablePropagatedMetrics bool
+	var enableStatusSummary, enableClusterNamespaceLabel, enableAutoBind, enablePolicyMetrics bool
+	var enableBindingClusterSelector, enablePolicyDebug, enableDesiredStateCache, enableReplicationSpecExport bool
+	var enableTemplateValidation, ignoreCopiedMetadataChanges, enableNonCompliantClusters, enableOpenMetrics bool
+	var enableDependencyWebhook, dependencyWebhookWarnOnly, enableSubjectWebhook bool
 	var probeAddr string
 	var keyRotationDays, keyRotationMaxConcurrency, policyMetricsMaxConcurrency, policyStatusMaxConcurrency uint
+	var policyMetricsPolicyLabels, policyMetricsStaticLabels map[string]string
+	var rootPolicyLabelKeys, propagatedKinds, driftIgnoredPaths, agentOwnedPaths, watchedNamespaces []string
+	var allowedTemplateKinds []string
+	var policyStatusUpdateWindow, replicaDeletionGracePeriod, policyPriorityWindow, resyncPeriod time.Duration
+	var stuckPendingThreshold, propagationTimeout, clusterQuarantineProbeInterval, replicaTeardownTimeout time.Duration
+	var maintenanceWindow, maintenanceWindowTimezone, replicaNamespaceTemplate, hubID, ownerReferenceStrategy string
+	var statusFieldManager, complianceReportNamespace string
+	var complianceHistoryLimit, complianceHistoryDepth, clusterQuarantineThreshold, complianceReportRetention uint
+	var maxReplicaSpecSize, statusConflictRetries int
+	var statusConflictBackoff, complianceReportInterval time.Duration
 
 	pflag.StringVar(&metricsAddr, "metrics-bind-address", ":8383", "The address the metric endpoint binds to.")
 	pflag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	pflag.BoolVar(&enableLeaderElection, "leader-elect", true,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	pflag.BoolVar(&enableComplianceConfigMaps, "enable-compliance-configmaps", false,
+		"Enable writing a ConfigMap per root policy summarizing the compliance state of each cluster.")
+	pflag.BoolVar(&enableAutoBind, "enable-auto-bind", false,
+		"Enable generating a PlacementBinding for each root policy with the "+autobindctrl.AutoBindLabel+" label set "+
+			"to the name of a Placement or PlacementRule.")
+	pflag.BoolVar(&enableReconcileAll, "enable-reconcile-all-endpoint", false,
+		"Serve the "+propagatorctrl.ReconcileAllPath+" endpoint on the metrics server to reconcile all root policies.")
+	pflag.BoolVar(&enableStatusSummary, "enable-propagation-status-endpoint", false,
+		"Serve the "+propagatorctrl.StatusSummaryPath+" endpoint on the metrics server with a JSON summary of the "+
+			"propagation status.")
+	pflag.BoolVar(&enablePolicyDebug, "enable-policy-debug-endpoint", false,
+		"Serve the "+propagatorctrl.PolicyDebugPath+"<namespace>/<name> endpoint on the metrics server with the "+
+			"placements, clusters, and hub template render errors that the last reconcile of the root policy acted on.")
+	pflag.BoolVar(&enableNonCompliantClusters, "enable-noncompliant-clusters-endpoint", false,
+		"Serve the "+propagatorctrl.NonCompliantClustersPath+"<namespace>/<name> endpoint on the metrics server with "+
+			"the NonCompliant clusters of the root policy and their messages, read from its replicated policies in pages.")
+	pflag.BoolVar(&enableOpenMetrics, "enable-openmetrics-endpoint", false,
+		"Serve the "+common.OpenMetricsPath+" endpoint on the metrics server with the metrics in the OpenMetrics "+
+			"format for the scrapers that request it, including the exemplars linking the root policy propagation "+
+			"durations to their traces when tracing is enabled.")
+	pflag.BoolVar(&enableDependencyWebhook, "enable-dependency-webhook", false,
+		"Serve the "+propagatorctrl.DependencyWebhookPath+" validating webhook, which denies disabling or deleting a "+
+			"root policy that other policies depend on unless it has the "+
+			propagatorctrl.ForceDependencyOverrideAnnotation+" annotation set to true.")
+	pflag.BoolVar(&dependencyWebhookWarnOnly, "dependency-webhook-warn-only", false,
+		"Allow disabling or deleting a root policy that other policies depend on with a warning naming them instead "+
+			"of denying it.")
+	pflag.BoolVar(&enableSubjectWebhook, "enable-subject-webhook", false,
+		"Serve the "+propagatorctrl.SubjectWebhookPath+" validating webhook, which denies a PlacementBinding with a "+
+			"subject that is neither a Policy, a PolicySet, nor of one of the --propagated-kinds.")
+	pflag.BoolVar(&enableDesiredStateCache, "enable-desired-state-cache", false,
+		"Skip the reconcile of a root policy when none of its inputs changed since it was last propagated. Root "+
+			"policies with hub templates are always reconciled.")
+	pflag.BoolVar(&enableReplicationSpecExport, "enable-replication-spec-export", false,
+		"Instead of writing the replicated policies, export a ConfigMap next to each root policy with the clusters to "+
+			"replicate it to, so that a regional hub in a global hub deployment replicates the policy itself.")
+	pflag.BoolVar(&enableTemplateValidation, "enable-template-validation", false,
+		"Validate the policy templates of the replicated policies against the schemas of their CRDs on the hub before "+
+			"writing them. The CRDs are cached, and kinds that aren't defined by a CRD on the hub aren't validated.")
+	pflag.BoolVar(&ignoreCopiedMetadataChanges, "ignore-copied-metadata-changes", false,
+		"Don't reconcile a root policy when only its labels and annotations outside of the "+
+			policyv1.GroupVersion.Group+" group change. These are copied to the replicated policies, which then get "+
+			"the changes in the next reconcile of the root policy.")
+	pflag.BoolVar(&enableClusterNamespaceLabel, "enable-cluster-namespace-label", false,
+		"Consider a namespace with the "+common.ClusterNamespaceSignalLabel+" label a managed cluster namespace "+
+			"before its ManagedCluster exists, so that policies can be propagated to clusters being onboarded.")
+	pflag.BoolVar(&enableBindingClusterSelector, "enable-binding-cluster-selector", false,
+		"Select the managed clusters of a PlacementBinding with its clusterSelector instead of its placementRef when "+
+			"the clusterSelector is set.")
+	pflag.BoolVar(&enablePolicyMetrics, "enable-policy-metrics", true,
+		"Enable the policy-metrics controller, which exports the policy_governance_info and "+
+			"policy_governance_control_info metrics. When disabled, the metrics endpoint still serves the process and "+
+			"controller-runtime metrics.")
+	pflag.BoolVar(&enablePropagatedMetrics, "enable-propagated-policy-metrics", false,
+		"Export the policy_governance_info metric for each replicated policy in addition to the root policies. "+
+			"This results in a metric series per policy per managed cluster.")
 	pflag.UintVar(
 		&keyRotationDays,
 		"encryption-key-rotation",
@@ -114,15 +198,304 @@ func main() {
 		5,
 		"The maximum number of concurrent reconciles for the policy-metrics controller",
 	)
+	pflag.StringToStringVar(
+		&policyMetricsPolicyLabels,
+		"policy-metrics-policy-labels",
+		nil,
+		"Additional labels on the policy_governance_info metric in the format metric_label=policy-label-key. The "+
+			"value of each metric label is taken from the policy label with the given key.",
+	)
+	pflag.StringToStringVar(
+		&policyMetricsStaticLabels,
+		"policy-metrics-static-labels",
+		nil,
+		"Additional labels with constant values on the policy_governance_info metric in the format label=value",
+	)
+	pflag.DurationVar(
+		&stuckPendingThreshold,
+		"policy-metrics-stuck-pending-threshold",
+		0,
+		"How long a policy can have no compliance state, or a Pending one, before the policy_stuck_pending metric is "+
+			"set for it and a warning event is emitted. Set to 0 to not track the pending policies.",
+	)
 	pflag.UintVar(
 		&policyStatusMaxConcurrency,
 		"policy-status-max-concurrency",
 		5,
 		"The maximum number of concurrent reconciles for the policy-status controller",
 	)
+	pflag.DurationVar(
+		&policyStatusUpdateWindow,
+		"policy-status-update-window",
+		time.Second,
+		"The window over which replicated policy status changes are collected before a single root policy status "+
+			"update is made. Set to 0 to update the root policy status for each change.",
+	)
+	pflag.StringVar(
+		&statusFieldManager,
+		"status-field-manager",
+		common.DefaultStatusFieldManager,
+		"The field manager of the policy status writes of the propagator, which tells them apart from the writes of "+
+			"the agents.",
+	)
+	pflag.IntVar(
+		&statusConflictRetries,
+		"status-conflict-retries",
+		3,
+		"The number of times a root policy status update that conflicts with another write is retried on the latest "+
+			"version of the policy before the reconcile is requeued. Set to 0 to not retry.",
+	)
+	pflag.DurationVar(
+		&statusConflictBackoff,
+		"status-conflict-backoff",
+		100*time.Millisecond,
+		"How long to wait before retrying a root policy status update that conflicts with another write. It doubles "+
+			"with each retry.",
+	)
+	pflag.DurationVar(
+		&replicaDeletionGracePeriod,
+		"replica-deletion-grace-period",
+		0,
+		"How long a cluster must no longer be selected by a placement before its replicated policy is deleted. This "+
+			"avoids recreating replicated policies when the placement decisions briefly empty out. Set to 0 to delete "+
+			"the replicated policies immediately.",
+	)
+	pflag.DurationVar(
+		&replicaTeardownTimeout,
+		"replica-teardown-timeout",
+		0,
+		"How long the deletion of a root policy waits for the agents on the managed clusters to acknowledge the "+
+			"teardown of its replicated policies with the TeardownComplete condition before they're deleted anyway. "+
+			"This adds a finalizer to the root policies. Set to 0 to delete the replicated policies immediately.",
+	)
+	pflag.DurationVar(
+		&propagationTimeout,
+		"propagation-timeout",
+		5*time.Minute,
+		"How long the replicated policies of a root policy can take to be written in a reconcile. The clusters that "+
+			"aren't done by then are reported with the PropagationTimeout reason in the propagationErrors status of "+
+			"the root policy and are retried. Set to 0 to not time out.",
+	)
+	pflag.UintVar(
+		&clusterQuarantineThreshold,
+		"cluster-quarantine-threshold",
+		0,
+		"The number of consecutive failures to write replicated policies to a cluster namespace, across all root "+
+			"policies, after which no more writes are attempted there until a probe succeeds. The skipped clusters are "+
+			"reported with the ClusterQuarantined reason in the propagationErrors status of the root policies. Set to "+
+			"0 to never quarantine a cluster.",
+	)
+	pflag.DurationVar(
+		&clusterQuarantineProbeInterval,
+		"cluster-quarantine-probe-interval",
+		5*time.Minute,
+		"How often a single replicated policy write is attempted to a quarantined cluster namespace to determine if it "+
+			"can be released from the quarantine.",
+	)
+	pflag.DurationVar(
+		&policyPriorityWindow,
+		"policy-priority-window",
+		0,
+		"How long the root policy reconcile requests are held so that those enqueued together are reconciled in "+
+			"order of their policy.open-cluster-management.io/priority annotation, highest first. Set to 0 to not "+
+			"reorder the requests.",
+	)
+	pflag.DurationVar(
+		&resyncPeriod,
+		"propagation-resync-period",
+		0,
+		"How often all root policies are reconciled to correct changes to the replicated policies that were missed by "+
+			"the watches. The reconciles are spread over the period. Set to 0 to disable the periodic resync.",
+	)
+	pflag.StringVar(
+		&maintenanceWindow,
+		"maintenance-window",
+		"",
+		"The semicolon separated maintenance windows during which changes to replicated policies are applied, such "+
+			"as \"Mon-Fri 22:00-06:00;Sat,Sun 00:00-23:59\". Outside of them, changes are held until a window opens. "+
+			"The "+propagatorctrl.MaintenanceWindowAnnotation+" annotation on a root policy takes precedence. If "+
+			"it's empty, changes are always applied.",
+	)
+	pflag.StringVar(
+		&maintenanceWindowTimezone,
+		"maintenance-window-timezone",
+		"UTC",
+		"The IANA time zone in which the maintenance windows are evaluated",
+	)
+	pflag.UintVar(
+		&complianceHistoryLimit,
+		"compliance-history-limit",
+		0,
+		"The number of compliance state changes of the managed clusters kept in the status of each root policy as an "+
+			"audit timeline. Set to 0 to not record the compliance history.",
+	)
+	pflag.UintVar(
+		&complianceHistoryDepth,
+		"compliance-history-depth",
+		5,
+		"The number of compliance state changes kept in the compliance history for each managed cluster. Set to 0 to "+
+			"only limit the compliance history by the --compliance-history-limit flag.",
+	)
+	pflag.StringSliceVar(
+		&rootPolicyLabelKeys,
+		"root-policy-label-keys",
+		[]string{common.RootPolicyLabel},
+		"The label keys, in priority order, checked for the root policy of a replicated policy. The first key set "+
+			"to a valid value is used.",
+	)
+
+	pflag.StringSliceVar(
+		&propagatedKinds,
+		"propagated-kinds",
+		nil,
+		"The kinds in the Kind.version.group format, such as "+
+			"ConfigurationPolicy.v1.policy.open-cluster-management.io, that are propagated directly to the cluster "+
+			"namespaces when they are a subject of a PlacementBinding, without being wrapped in a Policy.",
+	)
+	pflag.StringSliceVar(
+		&allowedTemplateKinds,
+		"allowed-template-kinds",
+		nil,
+		"The kinds in the Kind.version.group format, or Kind.version for the core group, that the policy templates "+
+			"and the objects in their ConfigurationPolicies may have. The kind or version can be * to match any in "+
+			"the group. A replicated policy with another kind isn't written. When unset, all kinds are allowed.",
+	)
+	pflag.StringSliceVar(
+		&driftIgnoredPaths,
+		"drift-ignored-paths",
+		nil,
+		"The dot-separated paths of replicated policy spec fields, such as "+
+			"policy-templates.*.objectDefinition.spec.evaluationInterval, that are ignored when comparing the "+
+			"desired and actual replicated policies. Changes to these fields on the replicated policies are kept.",
+	)
+	pflag.StringSliceVar(
+		&agentOwnedPaths,
+		"agent-owned-paths",
+		nil,
+		"The dot-separated paths of replicated policy spec fields that the agents on the managed clusters own. They "+
+			"are set when the replicated policy is created, and their values on the replicated policies are then "+
+			"kept when the propagator updates the other fields, which are always reverted to the desired values.",
+	)
+	pflag.StringVar(
+		&replicaNamespaceTemplate,
+		"replica-namespace-template",
+		common.ReplicaNamespacePlaceholder,
+		"The namespace of the replicated policies for each managed cluster, where "+common.ReplicaNamespacePlaceholder+
+			" is replaced by the cluster namespace, such as "+common.ReplicaNamespacePlaceholder+"-policies. The "+
+			"namespaces must already exist.",
+	)
+	pflag.StringVar(
+		&ownerReferenceStrategy,
+		"owner-reference-strategy",
+		string(propagatorctrl.OwnerReferenceNone),
+		"How the replicated policies refer back to their root policy: "+string(propagatorctrl.OwnerReferenceNone)+
+			" doesn't set an owner, "+string(propagatorctrl.OwnerReferenceCrossNamespaceAnnotation)+" sets the "+
+			propagatorctrl.OwnerAnnotation+" and "+propagatorctrl.OwnerUIDAnnotation+" annotations, and "+
+			string(propagatorctrl.OwnerReferenceSameNamespace)+" sets an owner reference when the replicated policy "+
+			"is in the namespace of its root policy and the annotations otherwise.",
+	)
+	pflag.IntVar(
+		&maxReplicaSpecSize,
+		"max-replica-spec-size",
+		0,
+		"The maximum size in bytes of the JSON encoded spec of a replicated policy after its hub templates are "+
+			"resolved. A replicated policy that exceeds it isn't written, and the error is reported on the root "+
+			"policy. Set to 0 to not limit the size.",
+	)
+	pflag.StringVar(
+		&hubID,
+		"hub-id",
+		"",
+		"The identifier of this hub that is set in the "+common.SourceHubLabel+" label of the replicated policies, "+
+			"such as when multiple hubs propagate policies to the same managed clusters. The replicated policies "+
+			"labeled by another hub are not updated or deleted, and those without the label are adopted.",
+	)
+	pflag.StringSliceVar(
+		&watchedNamespaces,
+		"watched-namespaces",
+		nil,
+		"The namespaces of the root policies that are propagated and reported in the policy metrics. The replicated "+
+			"policies of these root policies are still handled in the cluster namespaces. The root policies in "+
+			"other namespaces are ignored. When not set, the root policies in every namespace are handled.",
+	)
+	pflag.DurationVar(
+		&complianceReportInterval,
+		"compliance-report-interval",
+		0,
+		"How often a compliance report ConfigMap with the compliance of every root policy on each cluster is "+
+			"generated. A report is skipped when nothing changed since the last report. Set to 0 to disable the "+
+			"compliance reports.",
+	)
+	pflag.StringVar(
+		&complianceReportNamespace,
+		"compliance-report-namespace",
+		"",
+		"The namespace of the compliance report ConfigMaps. This is required when the compliance reports are enabled.",
+	)
+	pflag.UintVar(
+		&complianceReportRetention,
+		"compliance-report-retention",
+		10,
+		"The number of compliance report ConfigMaps to keep. The oldest reports are deleted when there are more.",
+	)
 
 	pflag.Parse()
 
+	maintenanceWindowLocation, err := time.LoadLocation(maintenanceWindowTimezone)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid maintenance window time zone %s: %v", maintenanceWindowTimezone, err))
+	}
+
+	maintenanceSchedule, err := propagatorctrl.ParseMaintenanceSchedule(maintenanceWindow, maintenanceWindowLocation)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid maintenance window: %v", err))
+	}
+
+	var clusterQuarantine *propagatorctrl.ClusterQuarantine
+	if clusterQuarantineThreshold > 0 {
+		clusterQuarantine = propagatorctrl.NewClusterQuarantine(
+			int(clusterQuarantineThreshold), clusterQuarantineProbeInterval,
+		)
+	}
+
+	propagatedGVKs, err := propagatorctrl.ParsePropagatedKinds(propagatedKinds)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid propagated kinds: %v", err))
+	}
+
+	replicaOwnerStrategy, err := propagatorctrl.ParseOwnerReferenceStrategy(ownerReferenceStrategy)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid owner reference strategy: %v", err))
+	}
+
+	allowedTemplateGVKs, err := propagatorctrl.ParseAllowedTemplateKinds(allowedTemplateKinds)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid allowed template kinds: %v", err))
+	}
+
+	driftIgnoredSpecPaths, err := propagatorctrl.ParseSpecPaths(driftIgnoredPaths)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid drift ignored paths: %v", err))
+	}
+
+	agentOwnedSpecPaths, err := propagatorctrl.ParseSpecPaths(agentOwnedPaths)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid agent owned paths: %v", err))
+	}
+
+	common.SetRootPolicyLabelKeys(rootPolicyLabelKeys)
+	common.SetClusterNamespaceLabelEnabled(enableClusterNamespaceLabel)
+	common.SetBindingClusterSelectorEnabled(enableBindingClusterSelector)
+	common.SetWatchedRootNamespaces(watchedNamespaces)
+
+	if err := common.SetReplicaNamespaceTemplate(replicaNamespaceTemplate); err != nil {
+		panic(fmt.Sprintf("Invalid replica namespace template: %v", err))
+	}
+
+	if err := common.SetHubID(hubID); err != nil {
+		panic(fmt.Sprintf("Invalid hub identifier: %v", err))
+	}
+
 	ctrlZap, err := zflags.BuildForCtrl()
 	if err != nil {
 		panic(fmt.Sprintf("Failed to build zap logger for controller: %v", err))
@@ -157,6 +530,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if complianceReportInterval > 0 && complianceReportNamespace == "" {
+		log.Info("the compliance-report-namespace flag must be set when the compliance reports are enabled")
+		os.Exit(1)
+	}
+
+	if complianceReportRetention < 1 {
+		log.Info("the compliance-report-retention flag must be greater than 0")
+		os.Exit(1)
+	}
+
 	namespace, err := getWatchNamespace()
 	if err != nil {
 		log.Error(err, "Failed to get watch namespace")
@@ -190,15 +573,21 @@ func main() {
 
 	// Set a field selector so that a watch on secrets will be limited to just the secret with the policy template
 	// encryption key.
-	newCacheFunc := cache.BuilderWithOptions(
-		cache.Options{
-			SelectorsByObject: cache.SelectorsByObject{
-				&corev1.Secret{}: {
-					Field: fields.SelectorFromSet(fields.Set{"metadata.name": propagatorctrl.EncryptionKeySecret}),
-				},
-			},
+	cacheSelectors := cache.SelectorsByObject{
+		&corev1.Secret{}: {
+			Field: fields.SelectorFromSet(fields.Set{"metadata.name": propagatorctrl.EncryptionKeySecret}),
 		},
-	)
+	}
+
+	// Similarly, the watch on ConfigMaps of the compliance ConfigMap controller is limited to the compliance
+	// ConfigMaps managed by the propagator.
+	if enableComplianceConfigMaps {
+		cacheSelectors[&corev1.ConfigMap{}] = cache.ObjectSelector{
+			Label: labels.SelectorFromSet(labels.Set{complianceconfigmapctrl.ConfigMapLabel: "true"}),
+		}
+	}
+
+	newCacheFunc := cache.BuilderWithOptions(cache.Options{SelectorsByObject: cacheSelectors})
 
 	// Set default manager options
 	options := ctrl.Options{
@@ -227,10 +616,28 @@ func main() {
 		os.Exit(1)
 	}
 
+	err = common.DetectPlacementAPI(mgr.GetRESTMapper(), log)
+	if err != nil {
+		log.Error(err, "Unable to determine if the Placement API is installed")
+		os.Exit(1)
+	}
+
+	err = common.DetectManagedClusterSetAPI(mgr.GetRESTMapper(), log)
+	if err != nil {
+		log.Error(err, "Unable to determine if the ManagedClusterSet API is installed")
+		os.Exit(1)
+	}
+
 	log.Info("Registering components")
 
 	controllerCtx := ctrl.SetupSignalHandler()
 
+	shutdownTracing, err := common.SetupTracing(controllerCtx, "governance-policy-propagator")
+	if err != nil {
+		log.Error(err, "Unable to set up the OpenTelemetry tracing")
+		os.Exit(1)
+	}
+
 	dynamicWatcherReconciler, dynamicWatcherSource := k8sdepwatches.NewControllerRuntimeSource()
 
 	dynamicWatcher, err := k8sdepwatches.New(cfg, dynamicWatcherReconciler, nil)
@@ -249,22 +656,64 @@ func main() {
 
 	policiesLock := &sync.Map{}
 
-	if err = (&propagatorctrl.PolicyReconciler{
-		Client:          mgr.GetClient(),
-		Scheme:          mgr.GetScheme(),
-		Recorder:        mgr.GetEventRecorderFor(propagatorctrl.ControllerName),
-		DynamicWatcher:  dynamicWatcher,
-		RootPolicyLocks: policiesLock,
-	}).SetupWithManager(mgr, dynamicWatcherSource); err != nil {
+	// Events sent on this channel trigger a reconcile of the root policy by the propagator
+	reconcileAllEvents := make(chan event.GenericEvent)
+
+	var templateValidator *propagatorctrl.TemplateValidator
+	if enableTemplateValidation {
+		templateValidator = propagatorctrl.NewTemplateValidator(mgr.GetClient(), mgr.GetRESTMapper())
+	}
+
+	policyReconciler := &propagatorctrl.PolicyReconciler{
+		Client:                      mgr.GetClient(),
+		Scheme:                      mgr.GetScheme(),
+		Recorder:                    mgr.GetEventRecorderFor(propagatorctrl.ControllerName),
+		DynamicWatcher:              dynamicWatcher,
+		RootPolicyLocks:             policiesLock,
+		ReplicaDeletionGracePeriod:  replicaDeletionGracePeriod,
+		ReplicaTeardownTimeout:      replicaTeardownTimeout,
+		MaintenanceSchedule:         maintenanceSchedule,
+		ComplianceHistoryLimit:      int(complianceHistoryLimit),
+		ComplianceHistoryDepth:      int(complianceHistoryDepth),
+		DriftIgnoredPaths:           driftIgnoredSpecPaths,
+		AgentOwnedPaths:             agentOwnedSpecPaths,
+		PriorityWindow:              policyPriorityWindow,
+		RecordDebugState:            enablePolicyDebug,
+		MaxReplicaSpecSize:          maxReplicaSpecSize,
+		PropagationTimeout:          propagationTimeout,
+		ClusterQuarantine:           clusterQuarantine,
+		OwnerReferenceStrategy:      replicaOwnerStrategy,
+		StatusFieldManager:          statusFieldManager,
+		AllowedTemplateKinds:        allowedTemplateGVKs,
+		TemplateValidator:           templateValidator,
+		IgnoreCopiedMetadataChanges: ignoreCopiedMetadataChanges,
+		DesiredStateCache:           enableDesiredStateCache,
+		ExportReplicationSpec:       enableReplicationSpecExport,
+		APIReader:                   mgr.GetAPIReader(),
+	}
+
+	if err = policyReconciler.SetupWithManager(
+		mgr, dynamicWatcherSource, &source.Channel{Source: reconcileAllEvents},
+	); err != nil {
 		log.Error(err, "Unable to create the controller", "controller", propagatorctrl.ControllerName)
 		os.Exit(1)
 	}
 
-	if reportMetrics() {
+	if enablePolicyMetrics && reportMetrics() {
+		err := metricsctrl.RegisterStatusGauge(policyMetricsPolicyLabels, policyMetricsStaticLabels)
+		if err != nil {
+			log.Error(err, "Unable to configure the policy metric labels", "controller", metricsctrl.ControllerName)
+			os.Exit(1)
+		}
+
 		if err = (&metricsctrl.MetricReconciler{
 			Client:                  mgr.GetClient(),
+			APIReader:               mgr.GetAPIReader(),
 			MaxConcurrentReconciles: policyMetricsMaxConcurrency,
+			ReportPropagatedMetrics: enablePropagatedMetrics,
 			Scheme:                  mgr.GetScheme(),
+			StuckPendingThreshold:   stuckPendingThreshold,
+			Recorder:                mgr.GetEventRecorderFor(metricsctrl.ControllerName),
 		}).SetupWithManager(mgr); err != nil {
 			log.Error(err, "Unable to create the controller", "controller", metricsctrl.ControllerName)
 			os.Exit(1)
@@ -305,11 +754,60 @@ func main() {
 		MaxConcurrentReconciles: policyStatusMaxConcurrency,
 		RootPolicyLocks:         policiesLock,
 		Scheme:                  mgr.GetScheme(),
+		StatusUpdateWindow:      policyStatusUpdateWindow,
+		ComplianceHistoryLimit:  int(complianceHistoryLimit),
+		ComplianceHistoryDepth:  int(complianceHistoryDepth),
+		StatusUpdate: common.StatusUpdateOptions{
+			FieldManager:    statusFieldManager,
+			ConflictRetries: statusConflictRetries,
+			ConflictBackoff: statusConflictBackoff,
+		},
 	}).SetupWithManager(mgr); err != nil {
 		log.Error(err, "Unable to create controller", "controller", rootpolicystatusctrl.ControllerName)
 		os.Exit(1)
 	}
 
+	if err = (&placementbindingctrl.PlacementBindingReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor(placementbindingctrl.ControllerName),
+	}).SetupWithManager(mgr); err != nil {
+		log.Error(err, "Unable to create controller", "controller", placementbindingctrl.ControllerName)
+		os.Exit(1)
+	}
+
+	for _, gvk := range propagatedGVKs {
+		if err = (&propagatorctrl.ObjectReconciler{
+			PolicyReconciler: policyReconciler,
+			GVK:              gvk,
+		}).SetupWithManager(mgr); err != nil {
+			log.Error(err, "Unable to create controller", "controller", propagatorctrl.ObjectControllerName, "kind", gvk)
+			os.Exit(1)
+		}
+	}
+
+	if enableAutoBind {
+		if err = (&autobindctrl.AutoBindReconciler{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor(autobindctrl.ControllerName),
+			Scheme:   mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			log.Error(err, "Unable to create controller", "controller", autobindctrl.ControllerName)
+			os.Exit(1)
+		}
+	}
+
+	if enableComplianceConfigMaps {
+		if err = (&complianceconfigmapctrl.ComplianceConfigMapReconciler{
+			Client:                  mgr.GetClient(),
+			MaxConcurrentReconciles: policyStatusMaxConcurrency,
+			Scheme:                  mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			log.Error(err, "Unable to create controller", "controller", complianceconfigmapctrl.ControllerName)
+			os.Exit(1)
+		}
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -327,6 +825,107 @@ func main() {
 
 	propagatorctrl.Initialize(cfg, &generatedClient)
 
+	if enableReconcileAll {
+		err := mgr.AddMetricsExtraHandler(
+			propagatorctrl.ReconcileAllPath,
+			propagatorctrl.ReconcileAllHandler(
+				mgr.GetAPIReader(), mgr.GetClient(), generatedClient, mgr.Elected(), reconcileAllEvents,
+			),
+		)
+		if err != nil {
+			log.Error(err, "Unable to add the endpoint", "path", propagatorctrl.ReconcileAllPath)
+			os.Exit(1)
+		}
+	}
+
+	if enablePolicyDebug {
+		err := mgr.AddMetricsExtraHandler(
+			propagatorctrl.PolicyDebugPath, propagatorctrl.PolicyDebugHandler(generatedClient),
+		)
+		if err != nil {
+			log.Error(err, "Unable to add the endpoint", "path", propagatorctrl.PolicyDebugPath)
+			os.Exit(1)
+		}
+	}
+
+	if enableDependencyWebhook {
+		mgr.GetWebhookServer().Register(
+			propagatorctrl.DependencyWebhookPath,
+			&webhook.Admission{Handler: &propagatorctrl.DependencyValidator{
+				Client:   mgr.GetClient(),
+				WarnOnly: dependencyWebhookWarnOnly,
+			}},
+		)
+	}
+
+	if enableSubjectWebhook {
+		mgr.GetWebhookServer().Register(
+			propagatorctrl.SubjectWebhookPath,
+			&webhook.Admission{Handler: &propagatorctrl.SubjectValidator{PropagatedKinds: propagatedGVKs}},
+		)
+	}
+
+	if enableOpenMetrics {
+		err := mgr.AddMetricsExtraHandler(common.OpenMetricsPath, common.OpenMetricsHandler())
+		if err != nil {
+			log.Error(err, "Unable to add the endpoint", "path", common.OpenMetricsPath)
+			os.Exit(1)
+		}
+	}
+
+	if enableNonCompliantClusters {
+		err := mgr.AddMetricsExtraHandler(
+			propagatorctrl.NonCompliantClustersPath,
+			propagatorctrl.NonCompliantClustersHandler(mgr.GetAPIReader(), generatedClient),
+		)
+		if err != nil {
+			log.Error(err, "Unable to add the endpoint", "path", propagatorctrl.NonCompliantClustersPath)
+			os.Exit(1)
+		}
+	}
+
+	if complianceReportInterval > 0 {
+		err := mgr.Add(&compliancereportctrl.ComplianceReporter{
+			APIReader: mgr.GetAPIReader(),
+			Client:    mgr.GetClient(),
+			Namespace: complianceReportNamespace,
+			Interval:  complianceReportInterval,
+			Retention: complianceReportRetention,
+		})
+		if err != nil {
+			log.Error(err, "Unable to add the compliance reports", "controller", compliancereportctrl.ControllerName)
+			os.Exit(1)
+		}
+	}
+
+	if !enableReplicationSpecExport {
+		err := mgr.Add(propagatorctrl.DeleteReplicationSpecs(mgr.GetAPIReader(), mgr.GetClient()))
+		if err != nil {
+			log.Error(err, "Unable to add the replication spec cleanup")
+			os.Exit(1)
+		}
+	}
+
+	if resyncPeriod > 0 {
+		err := mgr.Add(propagatorctrl.PeriodicResync(
+			mgr.GetAPIReader(), mgr.GetClient(), reconcileAllEvents, resyncPeriod,
+		))
+		if err != nil {
+			log.Error(err, "Unable to add the periodic resync")
+			os.Exit(1)
+		}
+	}
+
+	if enableStatusSummary {
+		err := mgr.AddMetricsExtraHandler(
+			propagatorctrl.StatusSummaryPath, propagatorctrl.StatusSummaryHandler(mgr.GetClient(), generatedClient),
+		)
+		if err != nil {
+			log.Error(err, "Unable to add the endpoint", "path", propagatorctrl.StatusSummaryPath)
+			os.Exit(1)
+		}
+	}
+
 	cache := mgr.GetCache()
 
 	// The following index for the PlacementRef Name is being added to the
@@ -347,7 +946,14 @@ func main() {
 
 	log.Info("Starting manager")
 
-	if err := mgr.Start(controllerCtx); err != nil {
+	err = mgr.Start(controllerCtx)
+
+	// Flush the remaining spans before exiting
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		log.Error(shutdownErr, "Failed to flush the OpenTelemetry spans")
+	}
+
+	if err != nil {
 		log.Error(err, "Problem running manager")
 		os.Exit(1)
 	}
@@ -95,6 +95,105 @@ var _ = Describe("Test CRD validation", func() {
 		}
 	})
 
+	Describe("Test status conditions", func() {
+		It("stores the conditions set on the policy status", func() {
+			_, err := policyClient().Create(context.TODO(), basicPolicy(), v1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			// Retry on conflicts since the propagator may update the status of the policy in the meantime
+			Eventually(func(g Gomega) {
+				pol, err := policyClient().Get(context.TODO(), "basic", v1.GetOptions{})
+				g.Expect(err).ToNot(HaveOccurred())
+
+				conditions := []interface{}{map[string]interface{}{
+					"type":               "TeardownComplete",
+					"status":             "True",
+					"reason":             "ResourcesCleanedUp",
+					"message":            "the resources enforced by the policy were cleaned up",
+					"lastTransitionTime": "2023-06-01T12:00:00Z",
+				}}
+
+				err = unstructured.SetNestedSlice(pol.Object, conditions, "status", "conditions")
+				g.Expect(err).ToNot(HaveOccurred())
+
+				_, err = policyClient().UpdateStatus(context.TODO(), pol, v1.UpdateOptions{})
+				g.Expect(err).ToNot(HaveOccurred())
+			}, defaultTimeoutSeconds, 1).Should(Succeed())
+
+			pol, err := policyClient().Get(context.TODO(), "basic", v1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			conditions, found, err := unstructured.NestedSlice(pol.Object, "status", "conditions")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(conditions).To(HaveLen(1))
+			Expect(conditions[0]).To(HaveKeyWithValue("type", "TeardownComplete"))
+			Expect(conditions[0]).To(HaveKeyWithValue("status", "True"))
+		})
+	})
+
+	Describe("Test placement binding target validation", func() {
+		bindingClient := func() dynamic.ResourceInterface {
+			return clientHubDynamic.Resource(gvrPlacementBinding).Namespace("default")
+		}
+
+		placementBinding := func(placementRef, clusterSelector bool) *unstructured.Unstructured {
+			pb := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "policy.open-cluster-management.io/v1",
+				"kind":       "PlacementBinding",
+				"metadata":   map[string]interface{}{"name": "case15-pb"},
+				"subjects": []interface{}{map[string]interface{}{
+					"apiGroup": "policy.open-cluster-management.io",
+					"kind":     "Policy",
+					"name":     "basic",
+				}},
+			}}
+
+			if placementRef {
+				pb.Object["placementRef"] = map[string]interface{}{
+					"apiGroup": "cluster.open-cluster-management.io",
+					"kind":     "Placement",
+					"name":     "case15-placement",
+				}
+			}
+
+			if clusterSelector {
+				pb.Object["clusterSelector"] = map[string]interface{}{
+					"matchLabels": map[string]interface{}{"environment": "prod"},
+				}
+			}
+
+			return pb
+		}
+
+		AfterEach(func() {
+			// ignore error, because invalid placement bindings will not have been created
+			_ = bindingClient().Delete(context.TODO(), "case15-pb", v1.DeleteOptions{})
+		})
+
+		tests := map[string]struct {
+			placementRef    bool
+			clusterSelector bool
+			valid           bool
+		}{
+			"only a placementRef":                  {true, false, true},
+			"only a clusterSelector":               {false, true, true},
+			"a placementRef and a clusterSelector": {true, true, false},
+			"no placementRef or clusterSelector":   {false, false, false},
+		}
+
+		for name, tc := range tests {
+			name := name
+			tc := tc
+
+			It("checks creating a placement binding with "+name, func() {
+				pb := placementBinding(tc.placementRef, tc.clusterSelector)
+				_, err := bindingClient().Create(context.TODO(), pb, v1.CreateOptions{})
+				Expect(err == nil).To(Equal(tc.valid))
+			})
+		}
+	})
+
 	Describe("Test extraDependency namespace validation", func() {
 		tests := map[string]struct {
 			validWithNamespace    bool
@@ -110,10 +110,19 @@ var _ = Describe("Test selective policy enforcement", Ordered, func() {
 				map[string]interface{}{
 					"placementBinding": case16PolicyName + "-pb",
 					"placementRule":    case16PolicyName + "-plr",
+					"decisions": []interface{}{
+						map[string]interface{}{"clusterName": "managed1", "clusterNamespace": "managed1"},
+						map[string]interface{}{"clusterName": "managed2", "clusterNamespace": "managed2"},
+					},
 				},
 				map[string]interface{}{
 					"placementBinding": case16PolicyName + "-pb-enforce",
 					"placementRule":    case16PolicyName + "-plr-enforce",
+					"decisions": []interface{}{
+						map[string]interface{}{"clusterName": "managed1", "clusterNamespace": "managed1"},
+						map[string]interface{}{"clusterName": "managed2", "clusterNamespace": "managed2"},
+						map[string]interface{}{"clusterName": "managed3", "clusterNamespace": "managed3"},
+					},
 				},
 			}
 			Eventually(func() interface{} {
@@ -183,6 +192,9 @@ var _ = Describe("Test selective policy enforcement", Ordered, func() {
 				map[string]interface{}{
 					"placementBinding": case16PolicyName + "-pb",
 					"placementRule":    case16PolicyName + "-plr",
+					"decisions": []interface{}{
+						map[string]interface{}{"clusterName": "managed1", "clusterNamespace": "managed1"},
+					},
 				},
 			}
 			Eventually(func() interface{} {
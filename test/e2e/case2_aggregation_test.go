@@ -163,7 +163,7 @@ var _ = Describe("Test policy status aggregation", func() {
 			)
 			Expect(err).ToNot(HaveOccurred())
 			By("Checking the status of root policy")
-			yamlPlc := utils.ParseYaml("../resources/case2_aggregation/managed-both-placement-status.yaml")
+			yamlPlc := utils.ParseYaml("../resources/case2_aggregation/managed-both-placement-status-both-plr2.yaml")
 			Eventually(func() interface{} {
 				rootPlc := utils.GetWithTimeout(
 					clientHubDynamic, gvrPolicy, case2PolicyName, testNamespace, true, defaultTimeoutSeconds,